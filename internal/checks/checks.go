@@ -0,0 +1,236 @@
+// Package checks runs a repo's named pre-merge checks (lint, unit, build, ...) in a task's worktree and
+// records their results as numbered attempts on the task.
+package checks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/sandbox"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// Check statuses (store.TaskCheckRun.Status).
+const (
+	Passed   = "passed"
+	Failed   = "failed"
+	TimedOut = "timed_out"
+	Errored  = "error" // the command could not be run
+)
+
+// DefaultTimeout applies to checks without a timeout of their own.
+const DefaultTimeout = 10 * time.Minute
+
+// LegacyName is the name of the check a repo's test_cmd runs as when the repo has no checks.
+const LegacyName = "test"
+
+// maxLogBytes caps a stored check log; the rest of the output is counted but dropped.
+const maxLogBytes = 1 << 20
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Validate checks a repo's check list: unique names, a command each (not on the sandbox deny list) and
+// KEY=value environment entries.
+func Validate(checks []store.RepoCheck) error {
+	seen := make(map[string]bool)
+	for _, c := range checks {
+		if !validName.MatchString(c.Name) {
+			return fmt.Errorf("check name %q: use letters, digits, '.', '_' or '-'", c.Name)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("check %q defined twice", c.Name)
+		}
+		seen[c.Name] = true
+		if strings.TrimSpace(c.Command) == "" {
+			return fmt.Errorf("check %s: command is empty", c.Name)
+		}
+		if sandbox.BlockedShellCommand(c.Command) {
+			return fmt.Errorf("check %s: command is blocked by the sandbox deny list", c.Name)
+		}
+		if c.Timeout < 0 {
+			return fmt.Errorf("check %s: timeout is negative", c.Name)
+		}
+		for _, kv := range c.Env {
+			if k, _, ok := strings.Cut(kv, "="); !ok || k == "" || strings.ContainsAny(kv, "\n") {
+				return fmt.Errorf("check %s: env %q is not KEY=value", c.Name, kv)
+			}
+		}
+	}
+	return nil
+}
+
+// ForRepo returns the checks to run for the repo: its named checks, or else its test_cmd as a single
+// required check named "test". A nil repo has none.
+func ForRepo(ctx context.Context, st store.Store, teamName string, repo *store.Repo) ([]store.RepoCheck, error) {
+	if repo == nil {
+		return nil, nil
+	}
+	list, err := st.ListRepoChecks(ctx, teamName, repo.Name)
+	if err != nil || len(list) > 0 {
+		return list, err
+	}
+	if repo.TestCmd != nil && strings.TrimSpace(*repo.TestCmd) != "" {
+		return []store.RepoCheck{{Name: LegacyName, Command: *repo.TestCmd, Required: true}}, nil
+	}
+	return nil, nil
+}
+
+// Options says where checks run. With Home set and the worktree under it, each check runs in the sandbox
+// with only the worktree writable.
+type Options struct {
+	Home     string
+	Team     string
+	Repo     string
+	Worktree string
+}
+
+// Run runs the checks one after another in the worktree; every check runs even after one fails. The results
+// carry the worktree's HEAD and each check's combined output.
+func Run(ctx context.Context, checks []store.RepoCheck, opts Options) []store.TaskCheckRun {
+	head, _ := git.RevParse(ctx, opts.Worktree, "HEAD")
+	runs := make([]store.TaskCheckRun, 0, len(checks))
+	for _, c := range checks {
+		r := runOne(ctx, c, opts)
+		r.HeadSHA = head
+		runs = append(runs, r)
+	}
+	return runs
+}
+
+func runOne(ctx context.Context, c store.RepoCheck, opts Options) store.TaskCheckRun {
+	r := store.TaskCheckRun{Name: c.Name, Command: c.Command, Required: c.Required, StartedAt: time.Now().UTC()}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := sandbox.WrapCommand(cctx, sandbox.HomeFor(opts.Home, opts.Worktree), opts.Worktree, "sh", []string{"-c", c.Command})
+	cmd.Dir = opts.Worktree
+	cmd.Env = append(os.Environ(), "AGENTARY_TEAM="+opts.Team, "AGENTARY_REPO="+opts.Repo, "AGENTARY_CHECK="+c.Name)
+	cmd.Env = append(cmd.Env, c.Env...)
+	var out logBuffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	killTree(cmd)
+	cmd.WaitDelay = 5 * time.Second // don't wait forever on output held open by stray children
+	err := cmd.Run()
+	r.FinishedAt = time.Now().UTC()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		r.Status = Passed
+	case errors.Is(cctx.Err(), context.DeadlineExceeded):
+		r.Status, r.ExitCode = TimedOut, -1
+		out.note(fmt.Sprintf("timed out after %s", timeout))
+	case errors.As(err, &exitErr):
+		r.Status, r.ExitCode = Failed, exitErr.ExitCode()
+	default:
+		r.Status, r.ExitCode = Errored, -1
+		out.note(err.Error())
+	}
+	r.Log = out.String()
+	return r
+}
+
+// Blocking returns the required checks that did not pass.
+func Blocking(runs []store.TaskCheckRun) []store.TaskCheckRun {
+	var out []store.TaskCheckRun
+	for _, r := range runs {
+		if r.Required && r.Status != Passed {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Describe is a one-line result of a check, e.g. "unit failed (exit 1)".
+func Describe(r store.TaskCheckRun) string {
+	switch r.Status {
+	case Failed:
+		return fmt.Sprintf("%s failed (exit %d)", r.Name, r.ExitCode)
+	case TimedOut:
+		return r.Name + " timed out"
+	case Errored:
+		return r.Name + " could not run"
+	}
+	return r.Name + " " + r.Status
+}
+
+// Error is returned by Gate when required checks fail.
+type Error struct {
+	Attempt int // attempt number of the first task the runs were recorded on
+	Failed  []store.TaskCheckRun
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, len(e.Failed))
+	for i, r := range e.Failed {
+		parts[i] = Describe(r)
+	}
+	return "required checks failed: " + strings.Join(parts, ", ")
+}
+
+// Gate runs the repo's checks in the worktree and records the results as a new attempt on each of the
+// tasks (a merge queue batch shares one run). It returns an *Error when a required check fails, and nil when
+// they pass or the repo has no checks.
+func Gate(ctx context.Context, st store.Store, home, teamName string, repo *store.Repo, worktree string, taskIDs ...int64) error {
+	list, err := ForRepo(ctx, st, teamName, repo)
+	if err != nil || len(list) == 0 {
+		return err
+	}
+	runs := Run(ctx, list, Options{Home: home, Team: teamName, Repo: repo.Name, Worktree: worktree})
+	attempt := 0
+	for i, id := range taskIDs {
+		n, err := st.CreateTaskCheckRuns(ctx, teamName, id, runs)
+		if err != nil {
+			return fmt.Errorf("record checks: %w", err)
+		}
+		if i == 0 {
+			attempt = n
+		}
+	}
+	if failed := Blocking(runs); len(failed) > 0 {
+		return &Error{Attempt: attempt, Failed: failed}
+	}
+	return nil
+}
+
+// logBuffer keeps the first maxLogBytes of a check's output.
+type logBuffer struct {
+	buf     bytes.Buffer
+	dropped int
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	if room := maxLogBytes - b.buf.Len(); room < len(p) {
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		b.dropped += len(p) - max(room, 0)
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// note appends a line from agentary (not the check) to the log.
+func (b *logBuffer) note(s string) {
+	if b.buf.Len() > 0 && !bytes.HasSuffix(b.buf.Bytes(), []byte("\n")) {
+		b.buf.WriteByte('\n')
+	}
+	fmt.Fprintf(&b.buf, "[agentary] %s\n", s)
+}
+
+func (b *logBuffer) String() string {
+	if b.dropped > 0 {
+		return fmt.Sprintf("%s\n[agentary] log truncated: %d more bytes\n", b.buf.String(), b.dropped)
+	}
+	return b.buf.String()
+}
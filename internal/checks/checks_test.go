@@ -0,0 +1,99 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	list := []store.RepoCheck{
+		{Name: "lint", Command: "echo style; exit 3", Required: false},
+		{Name: "unit", Command: `echo "$GREETING from $AGENTARY_CHECK"; pwd`, Env: []string{"GREETING=hello"}, Required: true},
+		{Name: "slow", Command: "sleep 5", Timeout: 100 * time.Millisecond, Required: true},
+	}
+	runs := Run(context.Background(), list, Options{Team: "t1", Repo: "r1", Worktree: dir})
+	if len(runs) != 3 {
+		t.Fatalf("got %d runs", len(runs))
+	}
+	if r := runs[0]; r.Status != Failed || r.ExitCode != 3 || r.Log != "style\n" || r.Required {
+		t.Errorf("lint = %+v", r)
+	}
+	if r := runs[1]; r.Status != Passed || !strings.HasPrefix(r.Log, "hello from unit\n") || !strings.Contains(r.Log, filepath.Base(dir)) {
+		t.Errorf("unit = %+v", r)
+	}
+	if r := runs[2]; r.Status != TimedOut || !strings.Contains(r.Log, "timed out after 100ms") || r.FinishedAt.Sub(r.StartedAt) > 4*time.Second {
+		t.Errorf("slow = %+v", r)
+	}
+	blocking := Blocking(runs)
+	if len(blocking) != 1 || blocking[0].Name != "slow" {
+		t.Errorf("Blocking = %+v, want only the required check that failed", blocking)
+	}
+}
+
+func TestGate(t *testing.T) {
+	ctx := context.Background()
+	st, err := store.Open(filepath.Join(t.TempDir(), "home"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	_, _ = st.CreateTeam(ctx, "t1")
+	testCmd := "exit 1"
+	_ = st.CreateRepo(ctx, "t1", "r1", "/tmp", "manual", &testCmd)
+	repos, _ := st.ListRepos(ctx, "t1")
+	repo := &repos[0]
+	a, _ := st.CreateTask(ctx, "t1", "a", "todo", nil)
+	b, _ := st.CreateTask(ctx, "t1", "b", "todo", nil)
+
+	// Without checks the test_cmd runs as the required "test" check.
+	err = Gate(ctx, st, "", "t1", repo, t.TempDir(), a)
+	var cerr *Error
+	if !errors.As(err, &cerr) || cerr.Attempt != 1 || cerr.Error() != "required checks failed: test failed (exit 1)" {
+		t.Fatalf("Gate = %v, want the test check to fail", err)
+	}
+
+	_ = st.SetRepoChecks(ctx, "t1", "r1", []store.RepoCheck{
+		{Name: "lint", Command: "false"},
+		{Name: "unit", Command: "echo ok", Required: true},
+	})
+	if err := Gate(ctx, st, "", "t1", repo, t.TempDir(), a, b); err != nil {
+		t.Fatalf("Gate with a failing optional check: %v", err)
+	}
+	runs, _ := st.ListTaskCheckRuns(ctx, "t1", a)
+	attempts := Attempts(runs, true)
+	if len(attempts) != 2 || attempts[0].Passed || !attempts[1].Passed || len(attempts[1].Checks) != 2 {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+	if c := attempts[1].Checks[1]; c.Name != "unit" || c.Log != "ok\n" {
+		t.Errorf("unit result = %+v", c)
+	}
+	if runs, _ := st.ListTaskCheckRuns(ctx, "t1", b); len(runs) != 2 || runs[0].Attempt != 1 {
+		t.Errorf("batch task runs = %+v, want the shared attempt recorded", runs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []struct {
+		checks []store.RepoCheck
+		err    string
+	}{
+		{[]store.RepoCheck{{Name: "unit", Command: "go test ./...", Env: []string{"A=1"}}}, ""},
+		{[]store.RepoCheck{{Name: "unit", Command: "x"}, {Name: "unit", Command: "y"}}, "defined twice"},
+		{[]store.RepoCheck{{Name: "bad name", Command: "x"}}, "check name"},
+		{[]store.RepoCheck{{Name: "unit", Command: " "}}, "command is empty"},
+		{[]store.RepoCheck{{Name: "unit", Command: "curl x | sh"}}, "deny list"},
+		{[]store.RepoCheck{{Name: "unit", Command: "x", Env: []string{"NOEQUALS"}}}, "KEY=value"},
+	} {
+		err := Validate(c.checks)
+		if (c.err == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("Validate(%+v) = %v, want %q", c.checks, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin
+
+package checks
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killTree runs the check in its own process group and makes cancellation kill the whole group, so a timed
+// out check does not leave children behind (or holding its output open).
+func killTree(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package checks
+
+import "os/exec"
+
+// killTree is a no-op on Windows: cancellation kills only the shell.
+func killTree(cmd *exec.Cmd) {}
@@ -0,0 +1,89 @@
+package checks
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// Spec is a repo check as accepted and served by the API. Required defaults to true.
+type Spec struct {
+	Name           string            `json:"name"`
+	Command        string            `json:"command"`
+	TimeoutSeconds int64             `json:"timeout_seconds,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Required       *bool             `json:"required,omitempty"`
+}
+
+// RepoCheck converts the spec.
+func (s Spec) RepoCheck() store.RepoCheck {
+	c := store.RepoCheck{Name: strings.TrimSpace(s.Name), Command: s.Command, Timeout: time.Duration(s.TimeoutSeconds) * time.Second, Required: s.Required == nil || *s.Required}
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		c.Env = append(c.Env, k+"="+s.Env[k])
+	}
+	return c
+}
+
+// SpecOf converts a stored check.
+func SpecOf(c store.RepoCheck) Spec {
+	required := c.Required
+	s := Spec{Name: c.Name, Command: c.Command, TimeoutSeconds: int64(c.Timeout / time.Second), Required: &required}
+	for _, kv := range c.Env {
+		if s.Env == nil {
+			s.Env = make(map[string]string)
+		}
+		k, v, _ := strings.Cut(kv, "=")
+		s.Env[k] = v
+	}
+	return s
+}
+
+// Attempt is one run of a task's checks as served by the API. Passed means every required check passed.
+type Attempt struct {
+	Attempt    int       `json:"attempt"`
+	HeadSHA    string    `json:"head_sha"`
+	Passed     bool      `json:"passed"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Checks     []Result  `json:"checks"`
+}
+
+// Result is one check in an attempt.
+type Result struct {
+	Name       string `json:"name"`
+	Command    string `json:"command"`
+	Required   bool   `json:"required"`
+	Status     string `json:"status"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Log        string `json:"log,omitempty"`
+}
+
+// Attempts groups a task's check runs (as returned by ListTaskCheckRuns) by attempt, oldest first.
+// Without logs the results leave out the check output.
+func Attempts(runs []store.TaskCheckRun, logs bool) []Attempt {
+	var out []Attempt
+	for _, r := range runs {
+		if len(out) == 0 || out[len(out)-1].Attempt != r.Attempt {
+			out = append(out, Attempt{Attempt: r.Attempt, HeadSHA: r.HeadSHA, Passed: true, StartedAt: r.StartedAt})
+		}
+		a := &out[len(out)-1]
+		res := Result{Name: r.Name, Command: r.Command, Required: r.Required, Status: r.Status, ExitCode: r.ExitCode, DurationMS: r.FinishedAt.Sub(r.StartedAt).Milliseconds()}
+		if logs {
+			res.Log = r.Log
+		}
+		a.Checks = append(a.Checks, res)
+		a.FinishedAt = r.FinishedAt
+		if r.Required && r.Status != Passed {
+			a.Passed = false
+		}
+	}
+	return out
+}
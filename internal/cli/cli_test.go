@@ -16,7 +16,7 @@ func TestNewRootCmd_hasSubcommands(t *testing.T) {
 	for _, c := range cmds {
 		names[c.Name()] = true
 	}
-	for _, want := range []string{"start", "stop", "status", "team", "agent", "workflow", "network", "apikey"} {
+	for _, want := range []string{"start", "stop", "status", "team", "agent", "workflow", "schedule", "scheduler", "network", "apikey"} {
 		if !names[want] {
 			t.Errorf("expected subcommand %q", want)
 		}
@@ -1,8 +1,11 @@
 package cli
 
 import (
+	"time"
+
 	"github.com/ankittk/agentary/internal/config"
 	"github.com/ankittk/agentary/internal/daemon"
+	"github.com/ankittk/agentary/internal/worktrees"
 	"github.com/spf13/cobra"
 )
 
@@ -18,6 +21,8 @@ func newDaemonCmd() *cobra.Command {
 		subprocessArgs []string
 		grpcAddr       string
 		enableOtel     bool
+		mergeBatch     int
+		worktreeGrace  time.Duration
 	)
 
 	cmd := &cobra.Command{
@@ -38,6 +43,8 @@ func newDaemonCmd() *cobra.Command {
 				SubprocessArgs: subprocessArgs,
 				GrpcAddr:       grpcAddr,
 				EnableOtel:     enableOtel,
+				MergeBatchSize: mergeBatch,
+				WorktreeGrace:  worktreeGrace,
 			})
 		},
 	}
@@ -52,6 +59,8 @@ func newDaemonCmd() *cobra.Command {
 	cmd.Flags().StringSliceVar(&subprocessArgs, "subprocess-args", nil, "Args for subprocess runtime")
 	cmd.Flags().StringVar(&grpcAddr, "grpc-addr", "", "Agent gRPC server address for runtime=grpc")
 	cmd.Flags().BoolVar(&enableOtel, "otel", true, "Enable OpenTelemetry metrics")
+	cmd.Flags().IntVar(&mergeBatch, "merge-batch", 1, "Merge queue tasks tested together (bisected when a batch fails)")
+	cmd.Flags().DurationVar(&worktreeGrace, "worktree-grace", worktrees.DefaultGrace, "How long finished tasks' worktrees are kept before the worktree GC removes them")
 
 	return cmd
 }
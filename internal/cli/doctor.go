@@ -3,18 +3,22 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 
 	"github.com/ankittk/agentary/internal/config"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/internal/worktrees"
 	"github.com/spf13/cobra"
 )
 
 func newDoctorCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "doctor",
-		Short: "Verify runtime dependencies",
+		Short: "Verify runtime dependencies and report worktree disk usage",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			_ = config.MustHomeFrom(cmd.Context()) // currently unused, but ensures home resolves
+			home := config.MustHomeFrom(cmd.Context())
 
 			var problems []string
 
@@ -23,6 +27,11 @@ func newDoctorCmd() *cobra.Command {
 				problems = append(problems, "missing dependency: git (not found on PATH)")
 			}
 
+			// Task worktree disk usage per team; skipped until the home has a database.
+			if _, err := os.Stat(filepath.Join(home, "protected", "db.sqlite")); err == nil {
+				problems = append(problems, checkWorktrees(cmd, home)...)
+			}
+
 			if len(problems) > 0 {
 				for _, p := range problems {
 					_, _ = fmt.Fprintln(cmd.ErrOrStderr(), p)
@@ -36,3 +45,30 @@ func newDoctorCmd() *cobra.Command {
 	}
 	return cmd
 }
+
+// checkWorktrees prints each team's worktree disk usage and returns a problem per team over its quota.
+func checkWorktrees(cmd *cobra.Command, home string) []string {
+	st, err := store.Open(home)
+	if err != nil {
+		return []string{"open store: " + err.Error()}
+	}
+	defer func() { _ = st.Close() }()
+	teams, err := st.ListTeams(cmd.Context())
+	if err != nil {
+		return []string{"list teams: " + err.Error()}
+	}
+	var problems []string
+	for _, t := range teams {
+		usage, err := worktrees.Scan(cmd.Context(), st, home, t.Name)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("worktrees of team %s: %v", t.Name, err))
+			continue
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "worktrees %s: %s\n", t.Name, usageLine(usage))
+		if usage.Quota > 0 && usage.Bytes > usage.Quota {
+			problems = append(problems, fmt.Sprintf("team %s is over its worktree quota by %s (run agentary worktree gc --team %s)",
+				t.Name, worktrees.FormatSize(usage.Bytes-usage.Quota), t.Name))
+		}
+	}
+	return problems
+}
@@ -3,9 +3,15 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/ankittk/agentary/internal/checks"
 	"github.com/ankittk/agentary/internal/config"
+	"github.com/ankittk/agentary/internal/merge"
 	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/internal/workflow"
 	"github.com/spf13/cobra"
 )
 
@@ -16,7 +22,9 @@ func newRepoCmd() *cobra.Command {
 	}
 	cmd.AddCommand(newRepoAddCmd())
 	cmd.AddCommand(newRepoListCmd())
+	cmd.AddCommand(newRepoSetCmd())
 	cmd.AddCommand(newRepoSetApprovalCmd())
+	cmd.AddCommand(newRepoCheckCmd())
 	return cmd
 }
 
@@ -27,6 +35,9 @@ func newRepoAddCmd() *cobra.Command {
 		source   string
 		approval string
 		testCmd  string
+		target   string
+		strategy string
+		template string
 	)
 	cmd := &cobra.Command{
 		Use:   "add",
@@ -41,8 +52,11 @@ func newRepoAddCmd() *cobra.Command {
 			if source == "" {
 				return errors.New("--source is required")
 			}
-			if approval != "auto" && approval != "manual" {
-				return errors.New("--approval must be auto or manual")
+			if _, _, err := workflow.ParseApprovalPolicy(approval); err != nil {
+				return fmt.Errorf("--approval: %w", err)
+			}
+			if err := checkMergeSettings(strategy, template); err != nil {
+				return err
 			}
 			home := config.MustHomeFrom(cmd.Context())
 			st, err := store.Open(home)
@@ -58,6 +72,11 @@ func newRepoAddCmd() *cobra.Command {
 			if err := st.CreateRepo(cmd.Context(), team, name, source, approval, tc); err != nil {
 				return err
 			}
+			if target != "" || strategy != "merge" || template != "" {
+				if err := st.SetRepoMergeSettings(cmd.Context(), team, name, target, strategy, template); err != nil {
+					return err
+				}
+			}
 			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Registered repo %q for %q\n", name, team)
 			return nil
 		},
@@ -65,8 +84,91 @@ func newRepoAddCmd() *cobra.Command {
 	cmd.Flags().StringVar(&team, "team", "", "Team name")
 	cmd.Flags().StringVar(&name, "name", "", "Repo name")
 	cmd.Flags().StringVar(&source, "source", "", "Repo source path or URL")
-	cmd.Flags().StringVar(&approval, "approval", "manual", "Approval mode: auto or manual")
+	cmd.Flags().StringVar(&approval, "approval", "manual", "Approval policy: manual, auto or auto_if:<guards;...>")
 	cmd.Flags().StringVar(&testCmd, "test-cmd", "", "Optional test command")
+	addMergeFlags(cmd, &target, &strategy, &template)
+	return cmd
+}
+
+func addMergeFlags(cmd *cobra.Command, target, strategy, template *string) {
+	cmd.Flags().StringVar(target, "target-branch", "", "Branch tasks merge into (default: main, or master)")
+	cmd.Flags().StringVar(strategy, "merge-strategy", "merge", "Merge strategy: "+strings.Join(store.MergeStrategies, ", "))
+	cmd.Flags().StringVar(template, "commit-template", "", "text/template for merge and squash commits (fields: .Team .TaskID .Title .Branch .Reviewers .Approver)")
+}
+
+func checkMergeSettings(strategy, template string) error {
+	if !store.ValidMergeStrategy(strategy) {
+		return fmt.Errorf("--merge-strategy must be one of %s", strings.Join(store.MergeStrategies, ", "))
+	}
+	if _, err := merge.ParseCommitTemplate(template); err != nil {
+		return fmt.Errorf("--commit-template: %w", err)
+	}
+	return nil
+}
+
+func newRepoSetCmd() *cobra.Command {
+	var team, name, approval, target, strategy, template string
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Change a repo's approval mode or merge settings",
+		Long:  "Only the flags given are changed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || name == "" {
+				return errors.New("--team and --name are required")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+			repos, err := st.ListRepos(cmd.Context(), team)
+			if err != nil {
+				return err
+			}
+			var repo *store.Repo
+			for i := range repos {
+				if repos[i].Name == name {
+					repo = &repos[i]
+				}
+			}
+			if repo == nil {
+				return fmt.Errorf("repo %q not found", name)
+			}
+			if cmd.Flags().Changed("approval") {
+				if _, _, err := workflow.ParseApprovalPolicy(approval); err != nil {
+					return fmt.Errorf("--approval: %w", err)
+				}
+				if err := st.SetRepoApproval(cmd.Context(), team, name, approval); err != nil {
+					return err
+				}
+			}
+			f := cmd.Flags()
+			if f.Changed("target-branch") || f.Changed("merge-strategy") || f.Changed("commit-template") {
+				if !f.Changed("target-branch") {
+					target = repo.TargetBranch
+				}
+				if !f.Changed("merge-strategy") {
+					strategy = repo.MergeStrategy
+				}
+				if !f.Changed("commit-template") {
+					template = repo.CommitTemplate
+				}
+				if err := checkMergeSettings(strategy, template); err != nil {
+					return err
+				}
+				if err := st.SetRepoMergeSettings(cmd.Context(), team, name, target, strategy, template); err != nil {
+					return err
+				}
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Updated repo %q\n", name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&name, "name", "", "Repo name")
+	cmd.Flags().StringVar(&approval, "approval", "manual", "Approval policy: manual, auto or auto_if:<guards;...>")
+	addMergeFlags(cmd, &target, &strategy, &template)
 	return cmd
 }
 
@@ -99,6 +201,10 @@ func newRepoListCmd() *cobra.Command {
 				if r.TestCmd != nil {
 					line += " test_cmd=" + *r.TestCmd
 				}
+				line += " merge=" + r.MergeStrategy
+				if r.TargetBranch != "" {
+					line += " target=" + r.TargetBranch
+				}
 				_, _ = fmt.Fprintln(cmd.OutOrStdout(), line)
 			}
 			return nil
@@ -112,13 +218,13 @@ func newRepoSetApprovalCmd() *cobra.Command {
 	var team, name, approval string
 	cmd := &cobra.Command{
 		Use:   "set-approval",
-		Short: "Set repo approval mode (auto or manual)",
+		Short: "Set repo approval policy (manual, auto or auto_if:<guards>)",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if team == "" || name == "" {
 				return errors.New("--team and --name are required")
 			}
-			if approval != "auto" && approval != "manual" {
-				return errors.New("--approval must be auto or manual")
+			if _, _, err := workflow.ParseApprovalPolicy(approval); err != nil {
+				return fmt.Errorf("--approval: %w", err)
 			}
 			home := config.MustHomeFrom(cmd.Context())
 			st, err := store.Open(home)
@@ -135,6 +241,156 @@ func newRepoSetApprovalCmd() *cobra.Command {
 	}
 	cmd.Flags().StringVar(&team, "team", "", "Team name")
 	cmd.Flags().StringVar(&name, "name", "", "Repo name")
-	cmd.Flags().StringVar(&approval, "approval", "manual", "Approval mode: auto or manual")
+	cmd.Flags().StringVar(&approval, "approval", "manual", "Approval policy: manual, auto or auto_if:<guards;...>")
+	return cmd
+}
+
+func newRepoCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Manage a repo's pre-merge checks",
+		Long:  "Checks run in the task's worktree before it merges; a failing required check blocks the merge. A repo without checks runs its test_cmd.",
+	}
+	cmd.AddCommand(newRepoCheckSetCmd())
+	cmd.AddCommand(newRepoCheckRemoveCmd())
+	cmd.AddCommand(newRepoCheckListCmd())
+	return cmd
+}
+
+// updateRepoChecks loads the repo's checks, lets edit change them and stores the result.
+func updateRepoChecks(cmd *cobra.Command, team, repo string, edit func([]store.RepoCheck) ([]store.RepoCheck, error)) error {
+	home := config.MustHomeFrom(cmd.Context())
+	st, err := store.Open(home)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = st.Close() }()
+	repos, err := st.ListRepos(cmd.Context(), team)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(repos, func(r store.Repo) bool { return r.Name == repo }) {
+		return fmt.Errorf("repo %q not found", repo)
+	}
+	list, err := st.ListRepoChecks(cmd.Context(), team, repo)
+	if err != nil {
+		return err
+	}
+	if list, err = edit(list); err != nil {
+		return err
+	}
+	if err := checks.Validate(list); err != nil {
+		return err
+	}
+	return st.SetRepoChecks(cmd.Context(), team, repo, list)
+}
+
+func newRepoCheckSetCmd() *cobra.Command {
+	var team, repo, name, command string
+	var timeout time.Duration
+	var env []string
+	var optional bool
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Add a check, or replace the one with the same name",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || repo == "" || name == "" || command == "" {
+				return errors.New("--team, --repo, --name and --cmd are required")
+			}
+			c := store.RepoCheck{Name: name, Command: command, Timeout: timeout, Env: env, Required: !optional}
+			err := updateRepoChecks(cmd, team, repo, func(list []store.RepoCheck) ([]store.RepoCheck, error) {
+				if i := slices.IndexFunc(list, func(x store.RepoCheck) bool { return x.Name == name }); i >= 0 {
+					list[i] = c
+					return list, nil
+				}
+				return append(list, c), nil
+			})
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Check %q set on repo %q\n", name, repo)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&repo, "repo", "", "Repo name")
+	cmd.Flags().StringVar(&name, "name", "", "Check name (e.g. lint, unit, build)")
+	cmd.Flags().StringVar(&command, "cmd", "", "Shell command run in the worktree")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Timeout (default "+checks.DefaultTimeout.String()+")")
+	cmd.Flags().StringArrayVar(&env, "env", nil, "Environment variable KEY=value (repeatable)")
+	cmd.Flags().BoolVar(&optional, "optional", false, "Report the check without blocking the merge")
+	return cmd
+}
+
+func newRepoCheckRemoveCmd() *cobra.Command {
+	var team, repo, name string
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a check",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || repo == "" || name == "" {
+				return errors.New("--team, --repo and --name are required")
+			}
+			err := updateRepoChecks(cmd, team, repo, func(list []store.RepoCheck) ([]store.RepoCheck, error) {
+				i := slices.IndexFunc(list, func(x store.RepoCheck) bool { return x.Name == name })
+				if i < 0 {
+					return nil, fmt.Errorf("repo %q has no check %q", repo, name)
+				}
+				return slices.Delete(list, i, i+1), nil
+			})
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Check %q removed from repo %q\n", name, repo)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&repo, "repo", "", "Repo name")
+	cmd.Flags().StringVar(&name, "name", "", "Check name")
+	return cmd
+}
+
+func newRepoCheckListCmd() *cobra.Command {
+	var team, repo string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List a repo's checks in the order they run",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || repo == "" {
+				return errors.New("--team and --repo are required")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+			list, err := st.ListRepoChecks(cmd.Context(), team, repo)
+			if err != nil {
+				return err
+			}
+			if len(list) == 0 {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No checks (the repo's test_cmd runs, if set).")
+				return nil
+			}
+			for _, c := range list {
+				line := fmt.Sprintf("- %s: %s", c.Name, c.Command)
+				if !c.Required {
+					line += " (optional)"
+				}
+				if c.Timeout > 0 {
+					line += " timeout=" + c.Timeout.String()
+				}
+				if len(c.Env) > 0 {
+					line += " env=" + strings.Join(c.Env, ",")
+				}
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), line)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&repo, "repo", "", "Repo name")
 	return cmd
 }
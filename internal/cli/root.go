@@ -36,6 +36,9 @@ func NewRootCmd(version string) *cobra.Command {
 	cmd.AddCommand(newAgentCmd())
 	cmd.AddCommand(newRepoCmd())
 	cmd.AddCommand(newWorkflowCmd())
+	cmd.AddCommand(newWorktreeCmd())
+	cmd.AddCommand(newScheduleCmd())
+	cmd.AddCommand(newSchedulerCmd())
 	cmd.AddCommand(newNetworkCmd())
 	cmd.AddCommand(newIdentityCmd())
 	cmd.AddCommand(newApikeyCmd())
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ankittk/agentary/internal/config"
+	"github.com/ankittk/agentary/internal/schedule"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func newScheduleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Manage recurring and scheduled tasks",
+	}
+	cmd.AddCommand(newScheduleAddCmd())
+	cmd.AddCommand(newScheduleListCmd())
+	cmd.AddCommand(newScheduleRmCmd())
+	return cmd
+}
+
+func newScheduleAddCmd() *cobra.Command {
+	var (
+		team            string
+		name            string
+		title           string
+		cronExpr        string
+		at              string
+		workflow        string
+		workflowVersion int
+		labels          string
+	)
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add a task schedule (cron or one-shot --at)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" {
+				return errors.New("--team is required")
+			}
+			if name == "" {
+				return errors.New("--name is required")
+			}
+			if title == "" {
+				return errors.New("--title is required")
+			}
+			if (cronExpr == "") == (at == "") {
+				return errors.New("exactly one of --cron or --at is required")
+			}
+			sch := store.TaskSchedule{
+				Name:            name,
+				TitleTemplate:   title,
+				CronExpr:        cronExpr,
+				WorkflowName:    workflow,
+				WorkflowVersion: workflowVersion,
+				Labels:          labels,
+			}
+			if at != "" {
+				t, err := time.Parse(time.RFC3339, at)
+				if err != nil {
+					return fmt.Errorf("--at must be RFC3339 (e.g. 2026-01-02T15:04:05Z): %w", err)
+				}
+				sch.RunAt = &t
+			}
+			if err := schedule.Prepare(&sch, time.Now().UTC()); err != nil {
+				return err
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+
+			if _, err := st.CreateTaskSchedule(cmd.Context(), team, sch); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Added schedule %q for %q (next run %s)\n", name, team, sch.NextRunAt.Format(time.RFC3339))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&name, "name", "", "Schedule name")
+	cmd.Flags().StringVar(&title, "title", "", "Task title template (fields: .Name .Date .Time .Week)")
+	cmd.Flags().StringVar(&cronExpr, "cron", "", "Cron expression (5 fields or @daily, @weekly, ...; UTC)")
+	cmd.Flags().StringVar(&at, "at", "", "One-shot run time (RFC3339)")
+	cmd.Flags().StringVar(&workflow, "workflow", "", "Workflow name (default: default)")
+	cmd.Flags().IntVar(&workflowVersion, "workflow-version", 1, "Workflow version")
+	cmd.Flags().StringVar(&labels, "labels", "", "Comma-separated labels for created tasks")
+	return cmd
+}
+
+func newScheduleListCmd() *cobra.Command {
+	var team string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List task schedules for a team",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" {
+				return errors.New("--team is required")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+
+			list, err := st.ListTaskSchedules(cmd.Context(), team)
+			if err != nil {
+				return err
+			}
+			if len(list) == 0 {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No schedules.")
+				return nil
+			}
+			for _, s := range list {
+				when := "cron=" + s.CronExpr
+				if s.CronExpr == "" && s.RunAt != nil {
+					when = "at=" + s.RunAt.Format(time.RFC3339)
+				}
+				line := fmt.Sprintf("- %s %s title=%q", s.Name, when, s.TitleTemplate)
+				if s.NextRunAt != nil && s.Enabled {
+					line += " next=" + s.NextRunAt.Format(time.RFC3339)
+				} else {
+					line += " (done)"
+				}
+				if s.LastTaskID != nil {
+					line += fmt.Sprintf(" last_task=%d", *s.LastTaskID)
+				}
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), line)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	return cmd
+}
+
+func newScheduleRmCmd() *cobra.Command {
+	var team, name string
+	cmd := &cobra.Command{
+		Use:   "rm",
+		Short: "Remove a task schedule",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || name == "" {
+				return errors.New("--team and --name are required")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+			if err := st.DeleteTaskSchedule(cmd.Context(), team, name); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Removed schedule %q\n", name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&name, "name", "", "Schedule name")
+	return cmd
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ankittk/agentary/internal/config"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func newSchedulerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scheduler",
+		Short: "Pause, resume, or inspect the global scheduler",
+	}
+	cmd.AddCommand(newSchedulerPauseCmd(true))
+	cmd.AddCommand(newSchedulerPauseCmd(false))
+	cmd.AddCommand(newSchedulerStatusCmd())
+	return cmd
+}
+
+// newSchedulerPauseCmd builds `scheduler pause` (pause=true) or `scheduler resume` (pause=false).
+func newSchedulerPauseCmd(pause bool) *cobra.Command {
+	var reason string
+	use, short := "resume", "Resume scheduling for all teams"
+	if pause {
+		use, short = "pause", "Pause scheduling for all teams (in-flight turns finish; nothing new is claimed)"
+	}
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+
+			if err := st.SetSchedulerPaused(cmd.Context(), pause, reason); err != nil {
+				return err
+			}
+			if pause {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Scheduler paused.")
+			} else {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Scheduler resumed.")
+			}
+			return nil
+		},
+	}
+	if pause {
+		cmd.Flags().StringVar(&reason, "reason", "", "Optional reason")
+	}
+	return cmd
+}
+
+func newSchedulerStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show global and per-team pause state",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+
+			state, err := st.GetSchedulerState(cmd.Context())
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Scheduler: "+describePause(state.Paused, state.PausedAt, state.Reason))
+			teams, err := st.ListTeams(cmd.Context())
+			if err != nil {
+				return err
+			}
+			for _, t := range teams {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "- %s: %s\n", t.Name, describePause(t.Paused, t.PausedAt, t.PauseReason))
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+func describePause(paused bool, at *time.Time, reason string) string {
+	if !paused {
+		return "running"
+	}
+	s := "paused"
+	if at != nil {
+		s += " since " + at.Format(time.RFC3339)
+	}
+	if reason != "" {
+		s += " (" + reason + ")"
+	}
+	return s
+}
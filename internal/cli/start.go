@@ -8,9 +8,11 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/ankittk/agentary/internal/config"
 	"github.com/ankittk/agentary/internal/daemon"
+	"github.com/ankittk/agentary/internal/worktrees"
 	"github.com/spf13/cobra"
 )
 
@@ -31,6 +33,8 @@ func newStartCmd() *cobra.Command {
 		dbDriver       string
 		dbURL          string
 		enableOtel     bool
+		mergeBatch     int
+		worktreeGrace  time.Duration
 	)
 
 	cmd := &cobra.Command{
@@ -59,6 +63,8 @@ func newStartCmd() *cobra.Command {
 				DBDriver:       dbDriver,
 				DBURL:          dbURL,
 				EnableOtel:     enableOtel,
+				MergeBatchSize: mergeBatch,
+				WorktreeGrace:  worktreeGrace,
 			}
 
 			ui := (&url.URL{Scheme: "http", Host: fmt.Sprintf("localhost:%d", port)}).String()
@@ -96,6 +102,8 @@ func newStartCmd() *cobra.Command {
 	cmd.Flags().StringVar(&dbDriver, "db-driver", "sqlite", "Store driver: sqlite or postgres")
 	cmd.Flags().StringVar(&dbURL, "db-url", "", "DB connection string (for postgres; or set DATABASE_URL)")
 	cmd.Flags().BoolVar(&enableOtel, "otel", true, "Enable OpenTelemetry metrics (Prometheus exporter, HTTP/SSE/task/agent instrumentation)")
+	cmd.Flags().IntVar(&mergeBatch, "merge-batch", 1, "Merge queue tasks tested together (bisected when a batch fails)")
+	cmd.Flags().DurationVar(&worktreeGrace, "worktree-grace", worktrees.DefaultGrace, "How long finished tasks' worktrees are kept before the worktree GC removes them")
 
 	return cmd
 }
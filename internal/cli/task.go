@@ -2,10 +2,17 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/ankittk/agentary/internal/checks"
 	"github.com/ankittk/agentary/internal/config"
 	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/merge"
 	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/internal/workflow"
+	"github.com/ankittk/agentary/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -14,6 +21,8 @@ func newTaskCmd() *cobra.Command {
 		Use:   "task",
 		Short: "Manage tasks",
 	}
+	cmd.AddCommand(newTaskCreateCmd())
+	cmd.AddCommand(newTaskListCmd())
 	cmd.AddCommand(newTaskRequeueCmd())
 	cmd.AddCommand(newTaskAssignCmd())
 	cmd.AddCommand(newTaskStatusCmd())
@@ -22,9 +31,136 @@ func newTaskCmd() *cobra.Command {
 	cmd.AddCommand(newTaskCompleteCmd())
 	cmd.AddCommand(newTaskForceTransitionCmd())
 	cmd.AddCommand(newTaskRewindCmd())
+	cmd.AddCommand(newTaskChecksCmd())
+	cmd.AddCommand(newTaskExportCmd())
 	return cmd
 }
 
+func newTaskCreateCmd() *cobra.Command {
+	var team, title, policy string
+	var parentID int64
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a task on the team's default workflow, optionally as a subtask of --parent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || title == "" {
+				return fmt.Errorf("--team and --title are required")
+			}
+			if policy != "" {
+				if _, err := workflow.ParseSubtaskPolicy(policy); err != nil {
+					return err
+				}
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+
+			ctx := cmd.Context()
+			if parentID > 0 {
+				if parent, err := st.GetTaskByIDAndTeam(ctx, team, parentID); err != nil {
+					return err
+				} else if parent == nil {
+					return fmt.Errorf("parent task %d not found in team %q", parentID, team)
+				}
+			}
+			var wfID *string
+			if w, _ := st.GetWorkflowIDByTeamAndName(ctx, team, "default", 1); w != "" {
+				wfID = &w
+			}
+			id, err := st.CreateTask(ctx, team, title, models.StatusTodo, wfID)
+			if err != nil {
+				return err
+			}
+			if policy != "" {
+				if err := st.SetTaskSubtaskPolicy(ctx, id, policy); err != nil {
+					return err
+				}
+			}
+			if parentID > 0 {
+				if err := st.SetTaskParent(ctx, team, id, &parentID); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Created task %d (subtask of %d)\n", id, parentID)
+				return nil
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Created task %d\n", id)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&title, "title", "", "Task title")
+	cmd.Flags().Int64Var(&parentID, "parent", 0, "Parent task ID (create a subtask)")
+	cmd.Flags().StringVar(&policy, "subtask-policy", "", "What a failed subtask does to this task: fail (default), ignore or wait")
+	return cmd
+}
+
+func newTaskListCmd() *cobra.Command {
+	var team string
+	var tree bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List tasks for a team (--tree nests subtasks under their parents)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" {
+				return fmt.Errorf("--team is required")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+
+			tasks, err := st.ListTasks(cmd.Context(), team, 0)
+			if err != nil {
+				return err
+			}
+			if len(tasks) == 0 {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No tasks.")
+				return nil
+			}
+			if !tree {
+				for _, t := range tasks {
+					_, _ = fmt.Fprintln(cmd.OutOrStdout(), taskLine(t, ""))
+				}
+				return nil
+			}
+			var walk func(nodes []workflow.TaskNode, indent string)
+			walk = func(nodes []workflow.TaskNode, indent string) {
+				for _, n := range nodes {
+					_, _ = fmt.Fprintln(cmd.OutOrStdout(), taskLine(n.Task, indent))
+					walk(n.Subtasks, indent+"  ")
+				}
+			}
+			walk(workflow.TaskTree(tasks), "")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().BoolVar(&tree, "tree", false, "Nest subtasks under their parents")
+	return cmd
+}
+
+func taskLine(t store.Task, indent string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s- #%d [%s] %s", indent, t.TaskID, t.Status, t.Title)
+	if t.CurrentStage != nil && *t.CurrentStage != "" {
+		fmt.Fprintf(&b, " stage=%s", *t.CurrentStage)
+	}
+	if t.Assignee != nil {
+		fmt.Fprintf(&b, " assignee=%s", *t.Assignee)
+	}
+	if t.ParentTaskID != nil && indent == "" {
+		fmt.Fprintf(&b, " parent=%d", *t.ParentTaskID)
+	}
+	return b.String()
+}
+
 func newTaskRequeueCmd() *cobra.Command {
 	var team string
 	var taskID int64
@@ -250,7 +386,8 @@ func newTaskForceTransitionCmd() *cobra.Command {
 			}
 			defer func() { _ = st.Close() }()
 
-			if err := st.UpdateTaskStage(cmd.Context(), taskID, stage); err != nil {
+			ctx := store.WithTransitionCause(cmd.Context(), store.TransitionCause{Actor: "cli", Note: "force-transition"})
+			if err := st.UpdateTaskStage(ctx, taskID, stage); err != nil {
 				return err
 			}
 			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Task %d stage set to %q\n", taskID, stage)
@@ -281,7 +418,8 @@ func newTaskRewindCmd() *cobra.Command {
 			}
 			defer func() { _ = st.Close() }()
 
-			if err := st.RewindTask(cmd.Context(), team, taskID); err != nil {
+			ctx := store.WithTransitionCause(cmd.Context(), store.TransitionCause{Actor: "cli", Note: "rewind"})
+			if err := st.RewindTask(ctx, team, taskID); err != nil {
 				return err
 			}
 			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Rewound task %d\n", taskID)
@@ -292,3 +430,128 @@ func newTaskRewindCmd() *cobra.Command {
 	cmd.Flags().Int64Var(&taskID, "id", 0, "Task ID")
 	return cmd
 }
+
+func newTaskChecksCmd() *cobra.Command {
+	var team string
+	var taskID int64
+	var attempt int
+	var logs bool
+	cmd := &cobra.Command{
+		Use:   "checks",
+		Short: "Show a task's pre-merge check attempts",
+		Long:  "Lists every attempt at the task's checks with each check's result. --log prints the output of the latest attempt (or --attempt N).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || taskID <= 0 {
+				return fmt.Errorf("--team and --id are required")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+			runs, err := st.ListTaskCheckRuns(cmd.Context(), team, taskID)
+			if err != nil {
+				return err
+			}
+			attempts := checks.Attempts(runs, logs)
+			if len(attempts) == 0 {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No check runs.")
+				return nil
+			}
+			if attempt == 0 && logs {
+				attempt = attempts[len(attempts)-1].Attempt
+			}
+			out := cmd.OutOrStdout()
+			found := false
+			for _, a := range attempts {
+				if attempt != 0 && a.Attempt != attempt {
+					continue
+				}
+				found = true
+				result := "passed"
+				if !a.Passed {
+					result = "failed"
+				}
+				_, _ = fmt.Fprintf(out, "Attempt %d on %.12s: %s\n", a.Attempt, a.HeadSHA, result)
+				for _, c := range a.Checks {
+					line := fmt.Sprintf("  %-8s %s", c.Status, c.Name)
+					if !c.Required {
+						line += " (optional)"
+					}
+					_, _ = fmt.Fprintf(out, "%s %s\n", line, time.Duration(c.DurationMS)*time.Millisecond)
+					if logs && c.Log != "" {
+						_, _ = fmt.Fprintf(out, "%s\n", indent(strings.TrimRight(c.Log, "\n"), "    | "))
+					}
+				}
+			}
+			if !found {
+				return fmt.Errorf("task %d has no check attempt %d", taskID, attempt)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().Int64Var(&taskID, "id", 0, "Task ID")
+	cmd.Flags().IntVar(&attempt, "attempt", 0, "Only this attempt")
+	cmd.Flags().BoolVar(&logs, "log", false, "Print check output")
+	return cmd
+}
+
+func newTaskExportCmd() *cobra.Command {
+	var team, format, output string
+	var taskID int64
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a task's commits as a patch series or git bundle",
+		Long: "Writes the commits of the task branch after its base as a git format-patch series (apply with git am) " +
+			"or a git bundle (git fetch <file> <branch>), so the work can land through another review system. " +
+			"Patches go to stdout and bundles to <repo>-T<id>.bundle unless --output is given (- = stdout).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || taskID <= 0 {
+				return fmt.Errorf("--team and --id are required")
+			}
+			if format != git.ExportPatch && format != git.ExportBundle {
+				return fmt.Errorf("--format must be patch or bundle")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+			task, err := st.GetTaskByIDAndTeam(cmd.Context(), team, taskID)
+			if err != nil {
+				return err
+			}
+			if task == nil {
+				return fmt.Errorf("task %d not found in team %q", taskID, team)
+			}
+			out, file, err := merge.ExportTaskBranch(cmd.Context(), st, home, team, task, format)
+			if err != nil {
+				return err
+			}
+			if output == "" && format == git.ExportBundle {
+				output = file
+			}
+			if output == "" || output == "-" {
+				_, err = cmd.OutOrStdout().Write(out)
+				return err
+			}
+			if err := os.WriteFile(output, out, 0o644); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %s (%s)\n", output, *task.BranchName)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().Int64Var(&taskID, "id", 0, "Task ID")
+	cmd.Flags().StringVar(&format, "format", git.ExportPatch, "Export format: patch or bundle")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output file (- = stdout)")
+	return cmd
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
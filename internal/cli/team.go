@@ -20,6 +20,8 @@ func newTeamCmd() *cobra.Command {
 	cmd.AddCommand(newTeamAddCmd())
 	cmd.AddCommand(newTeamListCmd())
 	cmd.AddCommand(newTeamRemoveCmd())
+	cmd.AddCommand(newTeamPauseCmd(true))
+	cmd.AddCommand(newTeamPauseCmd(false))
 	return cmd
 }
 
@@ -96,7 +98,11 @@ func newTeamListCmd() *cobra.Command {
 				return nil
 			}
 			for _, t := range teams {
-				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "- %s (agents=%d tasks=%d)\n", t.Name, t.AgentCount, t.TaskCount)
+				line := fmt.Sprintf("- %s (agents=%d tasks=%d)", t.Name, t.AgentCount, t.TaskCount)
+				if t.Paused {
+					line += " [paused]"
+				}
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), line)
 			}
 			return nil
 		},
@@ -147,3 +153,42 @@ func newTeamRemoveCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&yes, "yes", false, "Skip confirmation prompt")
 	return cmd
 }
+
+// newTeamPauseCmd builds `team pause` (pause=true) or `team resume` (pause=false).
+func newTeamPauseCmd(pause bool) *cobra.Command {
+	var name, reason string
+	use, short := "resume", "Resume scheduling for a team"
+	if pause {
+		use, short = "pause", "Pause scheduling for a team (in-flight turns finish; nothing new is claimed)"
+	}
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return errors.New("--name is required")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+
+			if err := st.SetTeamPaused(cmd.Context(), name, pause, reason); err != nil {
+				return err
+			}
+			if pause {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Paused team %q\n", name)
+			} else {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Resumed team %q\n", name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "Team name")
+	if pause {
+		cmd.Flags().StringVar(&reason, "reason", "", "Optional reason (shown in team list and API)")
+	}
+	return cmd
+}
@@ -3,9 +3,13 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/ankittk/agentary/internal/config"
 	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/internal/workflow"
 	"github.com/spf13/cobra"
 )
 
@@ -18,11 +22,18 @@ func newWorkflowCmd() *cobra.Command {
 	cmd.AddCommand(newWorkflowAddCmd())
 	cmd.AddCommand(newWorkflowListCmd())
 	cmd.AddCommand(newWorkflowShowCmd())
+	cmd.AddCommand(newWorkflowSetSLACmd())
+	cmd.AddCommand(newWorkflowLintCmd())
+	cmd.AddCommand(newWorkflowMigrateCmd())
+	cmd.AddCommand(newWorkflowSimulateCmd())
 	return cmd
 }
 
 func newWorkflowInitCmd() *cobra.Command {
-	var team string
+	var (
+		team string
+		plan bool
+	)
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize default workflow metadata for a team",
@@ -38,12 +49,17 @@ func newWorkflowInitCmd() *cobra.Command {
 			defer func() { _ = st.Close() }()
 
 			// Best-effort idempotent insert.
-			_, _ = st.CreateWorkflow(cmd.Context(), team, "default", 1, "builtin:default")
+			source := "builtin:default"
+			if plan {
+				source = store.DefaultPlanSource
+			}
+			_, _ = st.CreateWorkflow(cmd.Context(), team, "default", 1, source)
 			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Initialized workflow default v1 for %q\n", team)
 			return nil
 		},
 	}
 	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().BoolVar(&plan, "plan", false, "Start with a Planning stage: the agent writes a plan and a human approves it before coding")
 	return cmd
 }
 
@@ -56,20 +72,29 @@ func newWorkflowAddCmd() *cobra.Command {
 	)
 	cmd := &cobra.Command{
 		Use:   "add",
-		Short: "Register workflow metadata for a team",
+		Short: "Load a YAML workflow definition for a team",
+		Long: "Load a workflow from a YAML file or a builtin (builtin:default, builtin:plan, builtin:solo).\n" +
+			"The definition is validated and its stages and transitions are stored in one transaction.\n" +
+			"--name and --version override the name and version in the file.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if team == "" {
 				return errors.New("--team is required")
 			}
-			if name == "" {
-				return errors.New("--name is required")
-			}
-			if version <= 0 {
-				return errors.New("--version must be > 0")
-			}
 			if sourcePath == "" {
 				return errors.New("--source is required")
 			}
+			if version < 0 {
+				return errors.New("--version must not be negative")
+			}
+			def, err := workflow.LoadSource(sourcePath)
+			if err != nil {
+				return err
+			}
+			if !strings.HasPrefix(sourcePath, workflow.BuiltinPrefix) {
+				if abs, err := filepath.Abs(sourcePath); err == nil {
+					sourcePath = abs
+				}
+			}
 			home := config.MustHomeFrom(cmd.Context())
 			st, err := store.Open(home)
 			if err != nil {
@@ -77,17 +102,30 @@ func newWorkflowAddCmd() *cobra.Command {
 			}
 			defer func() { _ = st.Close() }()
 
-			if _, err := st.CreateWorkflow(cmd.Context(), team, name, version, sourcePath); err != nil {
+			if name == "" {
+				name = def.Name
+			}
+			if version == 0 {
+				version = def.Version
+			}
+			if version == 0 {
+				version = 1
+			}
+			_, diags, err := workflow.CreateFromDefinition(cmd.Context(), st, team, def, name, version, sourcePath)
+			if err != nil {
 				return err
 			}
-			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Registered workflow %s v%d for %q\n", name, version, team)
+			for _, d := range diags {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), d.Error())
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Loaded workflow %s v%d for %q (%d stages, %d transitions)\n", name, version, team, len(def.Stages), len(def.Transitions))
 			return nil
 		},
 	}
 	cmd.Flags().StringVar(&team, "team", "", "Team name")
-	cmd.Flags().StringVar(&name, "name", "", "Workflow name")
-	cmd.Flags().IntVar(&version, "version", 1, "Workflow version (>0)")
-	cmd.Flags().StringVar(&sourcePath, "source", "", "Source path (file path or builtin:...)")
+	cmd.Flags().StringVar(&name, "name", "", "Workflow name (default: name in the definition)")
+	cmd.Flags().IntVar(&version, "version", 0, "Workflow version (default: version in the definition, else 1)")
+	cmd.Flags().StringVar(&sourcePath, "source", "", "YAML file path or builtin:<name>")
 	return cmd
 }
 
@@ -127,12 +165,16 @@ func newWorkflowListCmd() *cobra.Command {
 
 func newWorkflowShowCmd() *cobra.Command {
 	var (
-		team string
-		name string
+		team    string
+		name    string
+		version int
+		graph   bool
+		format  string
+		counts  bool
 	)
 	cmd := &cobra.Command{
 		Use:   "show",
-		Short: "Show workflow entries for a name (all versions)",
+		Short: "Show workflow entries for a name (all versions), or a diagram of one version with --graph",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if team == "" {
 				return errors.New("--team is required")
@@ -147,6 +189,18 @@ func newWorkflowShowCmd() *cobra.Command {
 			}
 			defer func() { _ = st.Close() }()
 
+			if graph {
+				g, err := workflow.BuildGraph(cmd.Context(), st, team, fmt.Sprintf("%s@%d", name, version), counts)
+				if err != nil {
+					return err
+				}
+				out, err := g.Render(format)
+				if err != nil {
+					return err
+				}
+				_, _ = fmt.Fprint(cmd.OutOrStdout(), out)
+				return nil
+			}
 			wfs, err := st.ListWorkflows(cmd.Context(), team)
 			if err != nil {
 				return err
@@ -166,5 +220,315 @@ func newWorkflowShowCmd() *cobra.Command {
 	}
 	cmd.Flags().StringVar(&team, "team", "", "Team name")
 	cmd.Flags().StringVar(&name, "name", "", "Workflow name")
+	cmd.Flags().IntVar(&version, "version", 1, "Workflow version (with --graph)")
+	cmd.Flags().BoolVar(&graph, "graph", false, "Print a diagram of the workflow")
+	cmd.Flags().StringVar(&format, "format", workflow.GraphMermaid, "Diagram format: mermaid, dot or json")
+	cmd.Flags().BoolVar(&counts, "counts", false, "Include open task counts per stage")
 	return cmd
 }
+
+func newWorkflowSetSLACmd() *cobra.Command {
+	var (
+		team        string
+		name        string
+		version     int
+		stage       string
+		maxDuration time.Duration
+		onTimeout   string
+	)
+	cmd := &cobra.Command{
+		Use:   "set-sla",
+		Short: "Set a stage SLA (max duration and timeout action)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || name == "" || stage == "" {
+				return errors.New("--team, --name and --stage are required")
+			}
+			if maxDuration < 0 {
+				return errors.New("--max-duration must be >= 0")
+			}
+			action, arg, err := workflow.ParseOnTimeout(onTimeout)
+			if err != nil {
+				return err
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+
+			wfID, err := st.GetWorkflowIDByTeamAndName(cmd.Context(), team, name, version)
+			if err != nil {
+				return err
+			}
+			if wfID == "" {
+				return fmt.Errorf("workflow not found: %s v%d", name, version)
+			}
+			if err := st.SetWorkflowStageSLA(cmd.Context(), wfID, stage, maxDuration, onTimeout); err != nil {
+				return err
+			}
+			if action == workflow.OnTimeoutReassign && arg != "" {
+				// Report an unknown reassign agent the way lint reports candidate agents.
+				diags, _ := workflow.LintStored(cmd.Context(), st, team, wfID)
+				for _, d := range diags {
+					if d.Code == "unknown_agent" && d.Stage == stage {
+						_, _ = fmt.Fprintln(cmd.ErrOrStderr(), d.Error())
+					}
+				}
+			}
+			if maxDuration == 0 {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Cleared SLA for %s v%d stage %s\n", name, version, stage)
+				return nil
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Set SLA for %s v%d stage %s: max %s, on timeout %q\n", name, version, stage, maxDuration, onTimeout)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&name, "name", "default", "Workflow name")
+	cmd.Flags().IntVar(&version, "version", 1, "Workflow version")
+	cmd.Flags().StringVar(&stage, "stage", "", "Stage name (e.g. InApproval)")
+	cmd.Flags().DurationVar(&maxDuration, "max-duration", 0, "Maximum time in stage (e.g. 4h); 0 clears the SLA")
+	cmd.Flags().StringVar(&onTimeout, "on-timeout", "notify", "Action on breach: notify[:capability], reassign[:agent], transition:<outcome>, fail")
+	return cmd
+}
+
+func newWorkflowLintCmd() *cobra.Command {
+	var (
+		team       string
+		name       string
+		version    int
+		sourcePath string
+	)
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check a workflow definition or stored workflow for graph problems",
+		Long: "Lint a YAML definition (--source) or a stored workflow (--team --name --version).\n" +
+			"Reports unreachable stages, stages without exits, outcomes without transitions, transitions to\n" +
+			"missing stages, missing initial or terminal stages, and candidate agents not on the team\n" +
+			"(when --team is given). Exits non-zero if any errors are found.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if sourcePath == "" && (team == "" || name == "") {
+				return errors.New("--source, or --team and --name, is required")
+			}
+			var diags []workflow.Diagnostic
+			var st store.Store
+			if team != "" {
+				home := config.MustHomeFrom(cmd.Context())
+				var err error
+				st, err = store.Open(home)
+				if err != nil {
+					return err
+				}
+				defer func() { _ = st.Close() }()
+			}
+			if sourcePath != "" {
+				data, err := workflow.ReadSource(sourcePath)
+				if err != nil {
+					return err
+				}
+				file := sourcePath
+				if !strings.HasPrefix(file, workflow.BuiltinPrefix) {
+					file = filepath.Base(file)
+				}
+				var agents []string
+				if st != nil {
+					list, err := st.ListAgents(cmd.Context(), team)
+					if err != nil {
+						return err
+					}
+					agents = []string{}
+					for _, a := range list {
+						agents = append(agents, a.Name)
+					}
+				}
+				_, diags = workflow.LintDefinition(data, file, agents)
+			} else {
+				wfID, err := st.GetWorkflowIDByTeamAndName(cmd.Context(), team, name, version)
+				if err != nil {
+					return err
+				}
+				if wfID == "" {
+					return fmt.Errorf("workflow not found: %s v%d", name, version)
+				}
+				diags, err = workflow.LintStored(cmd.Context(), st, team, wfID)
+				if err != nil {
+					return err
+				}
+			}
+			for _, d := range diags {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), d.Error())
+			}
+			if err := workflow.DiagnosticsError(diags); err != nil {
+				return errors.New("workflow has errors")
+			}
+			if len(diags) == 0 {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "OK")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name (checks candidate agents; required with --name)")
+	cmd.Flags().StringVar(&name, "name", "", "Stored workflow name")
+	cmd.Flags().IntVar(&version, "version", 1, "Stored workflow version")
+	cmd.Flags().StringVar(&sourcePath, "source", "", "YAML file path or builtin:<name>")
+	return cmd
+}
+
+func newWorkflowMigrateCmd() *cobra.Command {
+	var (
+		team     string
+		from     string
+		to       string
+		stageMap string
+		dryRun   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Move open tasks from one workflow version to another",
+		Long: "Re-point every open task in --from to --to in one transaction and record an audit entry.\n" +
+			"Tasks keep their stage name unless --map maps it (e.g. --map InReview=Review,InApproval=Review).\n" +
+			"Refuses if a mapped stage does not exist in the target or a task's stage has nowhere to go.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || from == "" || to == "" {
+				return errors.New("--team, --from and --to are required")
+			}
+			mapping, err := workflow.ParseStageMap(stageMap)
+			if err != nil {
+				return err
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+
+			plan, err := workflow.PlanMigration(cmd.Context(), st, team, from, to, mapping)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(plan.Migration.Moves) == 0 {
+				_, _ = fmt.Fprintf(out, "No open tasks in %s.\n", from)
+				return nil
+			}
+			for _, mv := range plan.Migration.Moves {
+				_, _ = fmt.Fprintf(out, "- #%d %s -> %s\n", mv.TaskID, stageLabel(mv.FromStage), stageLabel(mv.ToStage))
+			}
+			if dryRun {
+				_, _ = fmt.Fprintf(out, "Dry run: %d task(s) would move from %s to %s.\n", len(plan.Migration.Moves), from, to)
+				return nil
+			}
+			id, err := workflow.ApplyMigration(cmd.Context(), st, team, plan)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(out, "Migrated %d task(s) from %s to %s (migration #%d).\n", len(plan.Migration.Moves), from, to, id)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&from, "from", "", "Source workflow as name@version (e.g. default@1)")
+	cmd.Flags().StringVar(&to, "to", "", "Target workflow as name@version (e.g. default@2)")
+	cmd.Flags().StringVar(&stageMap, "map", "", "Stage mapping From=To[,From=To...]")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report affected tasks without changing anything")
+	return cmd
+}
+
+func newWorkflowSimulateCmd() *cobra.Command {
+	var (
+		team         string
+		ref          string
+		outcomes     string
+		changedFiles string
+		failTests    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Walk a hypothetical task through a workflow without touching real tasks",
+		Long: "Apply --outcomes in order from the workflow's initial stage and print each stage, the assignee the\n" +
+			"scheduler would pick, and the guard results. Guards see simulated state: approvals from earlier\n" +
+			"approved outcomes and the --changed-files diff.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" || ref == "" {
+				return errors.New("--team and --workflow are required")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+
+			opts := workflow.SimOptions{Outcomes: splitFlagList(outcomes), FailTests: failTests}
+			if changedFiles != "" {
+				opts.ChangedFiles = splitFlagList(changedFiles)
+			}
+			sim, err := workflow.Simulate(cmd.Context(), st, team, ref, opts)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			_, _ = fmt.Fprintf(out, "Simulating %s\n", sim.Workflow)
+			for i, step := range sim.Steps {
+				line := fmt.Sprintf("%d. %s (%s)", i+1, step.Stage, step.StageType)
+				switch {
+				case len(step.Reviewers) > 0:
+					line += " reviewers=" + strings.Join(step.Reviewers, ",")
+				case step.Assignee != "":
+					line += " assignee=" + step.Assignee
+				}
+				if step.Outcome != "" {
+					line += " outcome=" + step.Outcome
+				}
+				_, _ = fmt.Fprintln(out, line)
+				for _, g := range step.Guards {
+					mark := "pass"
+					if !g.Passed {
+						mark = "FAIL"
+					}
+					_, _ = fmt.Fprintf(out, "   guard %s: %s %s\n", g.Guard, mark, g.Reason)
+				}
+				if step.Applied != "" {
+					_, _ = fmt.Fprintf(out, "   applying %s instead\n", step.Applied)
+				}
+				if step.ToStage != "" {
+					_, _ = fmt.Fprintf(out, "   -> %s\n", step.ToStage)
+				}
+				if step.Error != "" {
+					_, _ = fmt.Fprintf(out, "   stopped: %s\n", step.Error)
+				}
+			}
+			state := "not terminal"
+			if sim.Terminal {
+				state = "terminal"
+			}
+			_, _ = fmt.Fprintf(out, "Final stage: %s (%s)\n", sim.Final, state)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&ref, "workflow", "", "Workflow as name or name@version")
+	cmd.Flags().StringVar(&outcomes, "outcomes", "", "Comma-separated outcomes to apply in order")
+	cmd.Flags().StringVar(&changedFiles, "changed-files", "", "Comma-separated files the simulated branch changes (guards)")
+	cmd.Flags().BoolVar(&failTests, "fail-tests", false, "Make the tests_pass guard fail")
+	return cmd
+}
+
+func splitFlagList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func stageLabel(s string) string {
+	if s == "" {
+		return "(no stage)"
+	}
+	return s
+}
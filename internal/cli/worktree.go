@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ankittk/agentary/internal/config"
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/internal/worktrees"
+	"github.com/spf13/cobra"
+)
+
+func newWorktreeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "worktree",
+		Short: "Manage repo mirrors and task worktrees",
+	}
+	cmd.AddCommand(newWorktreeRepairCmd())
+	cmd.AddCommand(newWorktreeGCCmd())
+	cmd.AddCommand(newWorktreeQuotaCmd())
+	return cmd
+}
+
+func newWorktreeRepairCmd() *cobra.Command {
+	var (
+		team string
+		repo string
+	)
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Repair worktree metadata after a crash or a moved home directory",
+		Long: "Runs git worktree repair and prune on each repo mirror of the team, passing the task worktrees " +
+			"under protected/teams/<team>/worktrees so links broken by a crash or a move are rewritten.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" {
+				return errors.New("--team is required")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			mirrorsDir := filepath.Dir(git.MirrorPath(home, team, "x"))
+			entries, err := os.ReadDir(mirrorsDir)
+			if err != nil {
+				if os.IsNotExist(err) {
+					_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No mirrors.")
+					return nil
+				}
+				return err
+			}
+			out := cmd.OutOrStdout()
+			var errs []error
+			for _, e := range entries {
+				name, ok := strings.CutSuffix(e.Name(), ".git")
+				if !e.IsDir() || !ok || (repo != "" && name != strings.ReplaceAll(repo, " ", "_")) {
+					continue
+				}
+				worktrees := taskWorktrees(filepath.Dir(git.WorktreePath(home, team, name, 0)), name)
+				if err := git.RepairWorktrees(cmd.Context(), filepath.Join(mirrorsDir, e.Name()), worktrees...); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", name, err))
+					continue
+				}
+				_, _ = fmt.Fprintf(out, "Repaired %s (%d worktrees)\n", name, len(worktrees))
+			}
+			return errors.Join(errs...)
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&repo, "repo", "", "Only this repo (default: all repos with a mirror)")
+	return cmd
+}
+
+// taskWorktrees lists the <repo>-T<id> directories in dir.
+func taskWorktrees(dir, repo string) []string {
+	entries, _ := os.ReadDir(dir)
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), repo+"-T") {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths
+}
+
+func newWorktreeGCCmd() *cobra.Command {
+	var (
+		team   string
+		grace  time.Duration
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove the worktrees of finished or missing tasks",
+		Long: "Removes task worktrees whose task is done, failed, cancelled or gone once they have been idle for " +
+			"--grace, and earlier, oldest first, while a team is over its worktree quota. Worktrees of running tasks " +
+			"are never removed, and branches stay in the repo mirror. The daemon does this periodically.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+			gc := &worktrees.Collector{Store: st, Home: home, Grace: grace, DryRun: dryRun}
+			var reports []worktrees.Report
+			if team != "" {
+				var r worktrees.Report
+				r, err = gc.CollectTeam(cmd.Context(), team)
+				if r.Team != "" {
+					reports = append(reports, r)
+				}
+			} else {
+				reports, err = gc.RunOnce(cmd.Context())
+			}
+			out := cmd.OutOrStdout()
+			verb := "Removed"
+			if dryRun {
+				verb = "Would remove"
+			}
+			for _, r := range reports {
+				for _, rm := range r.Removed {
+					_, _ = fmt.Fprintf(out, "%s %s (%s): %s\n", verb, rm.Path, worktrees.FormatSize(rm.Bytes), rm.Reason)
+				}
+				_, _ = fmt.Fprintf(out, "%s: %d removed, %s freed; %s\n", r.Team, len(r.Removed), worktrees.FormatSize(r.Freed), usageLine(r.Usage))
+				if r.OverQuota > 0 {
+					_, _ = fmt.Fprintf(out, "%s: still %s over quota (the rest belongs to running tasks)\n", r.Team, worktrees.FormatSize(r.OverQuota))
+				}
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Only this team (default: all teams)")
+	cmd.Flags().DurationVar(&grace, "grace", worktrees.DefaultGrace, "Keep finished tasks' worktrees this long")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only report what would be removed")
+	return cmd
+}
+
+func newWorktreeQuotaCmd() *cobra.Command {
+	var (
+		team string
+		set  string
+	)
+	cmd := &cobra.Command{
+		Use:   "quota",
+		Short: "Show or set a team's worktree disk quota",
+		Long: "Shows the disk used by the team's task worktrees and its quota. --set changes the quota " +
+			"(e.g. 20GiB, 500MB; none removes it); the worktree GC enforces it.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if team == "" {
+				return errors.New("--team is required")
+			}
+			home := config.MustHomeFrom(cmd.Context())
+			st, err := store.Open(home)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = st.Close() }()
+			if cmd.Flags().Changed("set") {
+				quota, err := worktrees.ParseSize(set)
+				if err != nil {
+					return err
+				}
+				if err := st.SetTeamWorktreeQuota(cmd.Context(), team, quota); err != nil {
+					return err
+				}
+			}
+			usage, err := worktrees.Scan(cmd.Context(), st, home, team)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", team, usageLine(usage))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&team, "team", "", "Team name")
+	cmd.Flags().StringVar(&set, "set", "", "New quota (e.g. 20GiB; none removes it)")
+	return cmd
+}
+
+// usageLine describes a team's worktree disk usage: "1.2 GiB in 3 worktrees (1 collectable) of a 5.0 GiB quota".
+func usageLine(u worktrees.TeamUsage) string {
+	line := fmt.Sprintf("%s in %d worktrees (%d collectable)", worktrees.FormatSize(u.Bytes), len(u.Worktrees), u.Collectable())
+	if u.Quota > 0 {
+		return line + " of a " + worktrees.FormatSize(u.Quota) + " quota"
+	}
+	return line + ", no quota"
+}
@@ -19,7 +19,9 @@ import (
 	"github.com/ankittk/agentary/internal/manager"
 	"github.com/ankittk/agentary/internal/merge"
 	"github.com/ankittk/agentary/internal/otel"
+	"github.com/ankittk/agentary/internal/schedule"
 	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/internal/workflow"
 )
 
 var errNotRunning = errors.New("agentary is not running")
@@ -125,6 +127,14 @@ func StartForeground(ctx context.Context, opts StartOptions) error {
 			}
 			return todo, inProgress, done, failed
 		})
+		_ = otel.InitWorktreeMetrics(ctx, func() []otel.WorktreeUsage {
+			var out []otel.WorktreeUsage
+			for _, s := range app.Worktrees.Stats() {
+				out = append(out, otel.WorktreeUsage{Team: s.Team, Bytes: s.Bytes, Quota: s.Quota, Active: s.Active,
+					Collectable: s.Collectable, Removed: s.Removed, Freed: s.Freed})
+			}
+			return out
+		})
 	}
 
 	slog.Info("daemon starting", "addr", addr, "home", opts.Home)
@@ -132,8 +142,19 @@ func StartForeground(ctx context.Context, opts StartOptions) error {
 	go func() {
 		// Scheduler runs alongside the HTTP server and publishes SSE events.
 		go runScheduler(ctx, opts, app)
-		// Merge worker processes tasks in Merging stage (rebase, test, merge, clean).
-		go (&merge.Worker{Store: app.Store, RebaseBeforeMerge: opts.RebaseBeforeMerge}).Run(ctx)
+		// Merge worker runs the per-repo merge queues (rebase on the queue head, test, land, clean); conflicting
+		// tasks are sent back through the workflow.
+		mergeEngine := &workflow.Engine{Store: app.Store, Home: opts.Home, Capabilities: app.Capabilities}
+		go (&merge.Worker{Store: app.Store, Home: opts.Home, BatchSize: opts.MergeBatchSize, Publish: app.Hub.PublishJSON, ApplyOutcome: mergeEngine.ApplyOutcome}).Run(ctx)
+		// Schedule runner creates tasks from recurring (cron) and one-shot task schedules.
+		go (&schedule.Runner{Store: app.Store, Publish: app.Hub.PublishJSON}).Run(ctx)
+		// SLA monitor runs on_timeout actions for tasks that exceed their stage's max_duration.
+		go (&workflow.SLAMonitor{Store: app.Store, Home: opts.Home, Capabilities: app.Capabilities, Publish: app.Hub.PublishJSON}).Run(ctx)
+		// Subtask monitor rolls parent tasks up once all their subtasks have finished.
+		go (&workflow.SubtaskMonitor{Store: app.Store, Home: opts.Home, Capabilities: app.Capabilities, Publish: app.Hub.PublishJSON}).Run(ctx)
+		// Worktree GC removes the worktrees of finished or missing tasks and enforces team worktree quotas.
+		app.Worktrees.Grace = opts.WorktreeGrace
+		go app.Worktrees.Run(ctx)
 		// Manager: LLM-backed if AGENTARY_LLM_URL + OPENAI_API_KEY set, else rule-based.
 		if opts.ManagerLLMURL != "" && opts.ManagerLLMKey != "" {
 			go manager.RunLLM(ctx, app, manager.LLMOpts{
@@ -205,6 +226,12 @@ func StartBackground(ctx context.Context, opts StartOptions) (int, error) {
 	if opts.PprofAddr != "" {
 		args = append(args, "--pprof", opts.PprofAddr)
 	}
+	if opts.MergeBatchSize > 1 {
+		args = append(args, "--merge-batch", strconv.Itoa(opts.MergeBatchSize))
+	}
+	if opts.WorktreeGrace > 0 {
+		args = append(args, "--worktree-grace", opts.WorktreeGrace.String())
+	}
 
 	cmd := exec.Command(exe, args...)
 	cmd.Stdout = io.Discard
@@ -9,6 +9,7 @@ import (
 
 	"github.com/ankittk/agentary/internal/httpapi"
 	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/internal/workflow"
 	"github.com/ankittk/agentary/pkg/models"
 )
 
@@ -41,9 +42,9 @@ func TestPickAssignee_prefersManager(t *testing.T) {
 	task, _ := app.Store.GetTaskByIDAndTeam(ctx, "team1", taskID)
 	agents, _ := app.Store.ListAgents(ctx, "team1")
 
-	got := pickAssignee(ctx, app.Store, "team1", task, agents)
+	got := workflow.PickAssignee(ctx, app.Store, "team1", task, agents)
 	if got != "alice" {
-		t.Errorf("pickAssignee (manager first): got %q, want alice", got)
+		t.Errorf("PickAssignee (manager first): got %q, want alice", got)
 	}
 }
 
@@ -58,9 +59,9 @@ func TestPickAssignee_fallbackToFirstAgent(t *testing.T) {
 	task, _ := app.Store.GetTaskByIDAndTeam(ctx, "team1", taskID)
 	agents, _ := app.Store.ListAgents(ctx, "team1")
 
-	got := pickAssignee(ctx, app.Store, "team1", task, agents)
+	got := workflow.PickAssignee(ctx, app.Store, "team1", task, agents)
 	if got != "bob" {
-		t.Errorf("pickAssignee (no manager): got %q, want bob", got)
+		t.Errorf("PickAssignee (no manager): got %q, want bob", got)
 	}
 }
 
@@ -84,9 +85,9 @@ func TestPickAssignee_candidatePool_prefersManager(t *testing.T) {
 	task, _ := app.Store.GetTaskByIDAndTeam(ctx, "team1", taskID)
 	agents, _ := app.Store.ListAgents(ctx, "team1")
 
-	got := pickAssignee(ctx, app.Store, "team1", task, agents)
+	got := workflow.PickAssignee(ctx, app.Store, "team1", task, agents)
 	if got != "bob" {
-		t.Errorf("pickAssignee (candidate pool with manager): got %q, want bob", got)
+		t.Errorf("PickAssignee (candidate pool with manager): got %q, want bob", got)
 	}
 }
 
@@ -109,10 +110,10 @@ func TestPickAssignee_candidatePool_noManager_returnsFirstCandidate(t *testing.T
 	task, _ := app.Store.GetTaskByIDAndTeam(ctx, "team1", taskID)
 	agents, _ := app.Store.ListAgents(ctx, "team1")
 
-	got := pickAssignee(ctx, app.Store, "team1", task, agents)
+	got := workflow.PickAssignee(ctx, app.Store, "team1", task, agents)
 	// Code picks first agent in list that is in the candidate pool; agents order is alice, bob
 	if got != "alice" && got != "bob" {
-		t.Errorf("pickAssignee (candidate pool): got %q, want alice or bob", got)
+		t.Errorf("PickAssignee (candidate pool): got %q, want alice or bob", got)
 	}
 }
 
@@ -192,3 +193,45 @@ func TestRunScheduler_claimsTaskAndAssigns(t *testing.T) {
 	cancel()
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestRunScheduler_skipsPausedTeamAndGlobalPause(t *testing.T) {
+	app, ctx := testApp(t)
+	defer func() { _ = app.Store.Close() }()
+
+	app.Store.CreateTeam(ctx, "paused")
+	app.Store.CreateTeam(ctx, "active")
+	app.Store.CreateAgent(ctx, "paused", "alice", "engineer")
+	app.Store.CreateAgent(ctx, "active", "bob", "engineer")
+	pausedTask, _ := app.Store.CreateTask(ctx, "paused", "Task", models.StatusTodo, nil)
+	_ = app.Store.SetTeamPaused(ctx, "paused", true, "repo migration")
+	_ = app.Store.SetSchedulerPaused(ctx, true, "budget")
+	activeTask, _ := app.Store.CreateTask(ctx, "active", "Task", models.StatusTodo, nil)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+	}()
+	opts := StartOptions{Home: app.Home, IntervalSec: 0.01, MaxConcurrent: 2}
+	go runScheduler(runCtx, opts, app)
+
+	time.Sleep(100 * time.Millisecond)
+	if task, _ := app.Store.GetTaskByIDAndTeam(ctx, "active", activeTask); task == nil || task.Status != models.StatusTodo {
+		t.Fatalf("global pause: expected active team task untouched, got %+v", task)
+	}
+
+	_ = app.Store.SetSchedulerPaused(ctx, false, "")
+	for i := 0; i < 100; i++ {
+		task, _ := app.Store.GetTaskByIDAndTeam(ctx, "active", activeTask)
+		if task != nil && task.Status != models.StatusTodo {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if task, _ := app.Store.GetTaskByIDAndTeam(ctx, "active", activeTask); task == nil || task.Status == models.StatusTodo {
+		t.Fatalf("after global resume: expected active team task claimed, got %+v", task)
+	}
+	if task, _ := app.Store.GetTaskByIDAndTeam(ctx, "paused", pausedTask); task == nil || task.Status != models.StatusTodo || task.Assignee != nil {
+		t.Fatalf("paused team: expected task untouched, got %+v", task)
+	}
+}
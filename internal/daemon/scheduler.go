@@ -4,7 +4,6 @@ import (
 	"context"
 	"log/slog"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
@@ -51,6 +50,10 @@ func runScheduler(ctx context.Context, opts StartOptions, app *httpapi.App) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			// Global pause: claim nothing new; turns started in earlier ticks have already finished.
+			if state, err := app.Store.GetSchedulerState(ctx); err == nil && state.Paused {
+				continue
+			}
 			teams, err := app.Store.ListTeams(ctx)
 			if err != nil {
 				slog.Error("scheduler list teams failed", "err", err)
@@ -59,6 +62,9 @@ func runScheduler(ctx context.Context, opts StartOptions, app *httpapi.App) {
 
 			var wg sync.WaitGroup
 			for _, t := range teams {
+				if t.Paused {
+					continue
+				}
 				task, err := app.Store.NextRunnableTaskForTeam(ctx, t.Name)
 				if err != nil || task == nil {
 					continue
@@ -77,7 +83,7 @@ func runScheduler(ctx context.Context, opts StartOptions, app *httpapi.App) {
 				}
 
 				// Candidate pool: if task has workflow + current_stage with candidate_agents, pick assignee from that pool; else prefer manager, then first agent.
-				agentName := pickAssignee(ctx, app.Store, t.Name, task, agents)
+				agentName := workflow.PickAssignee(ctx, app.Store, t.Name, task, agents)
 
 				select {
 				case sem <- struct{}{}:
@@ -108,7 +114,7 @@ func runScheduler(ctx context.Context, opts StartOptions, app *httpapi.App) {
 					publishTaskUpdate(app, teamName, tid, "in_progress", &agent)
 
 					turnStart := time.Now()
-					eng := &workflow.Engine{Store: app.Store, Home: opts.Home}
+					eng := &workflow.Engine{Store: app.Store, Home: opts.Home, Capabilities: app.Capabilities}
 					handled, err := eng.RunTurn(ctx, teamName, tk, runtime, func(ev agentrt.Event) {
 						if ev.Timestamp.IsZero() {
 							ev.Timestamp = time.Now().UTC()
@@ -187,44 +193,6 @@ func runScheduler(ctx context.Context, opts StartOptions, app *httpapi.App) {
 	}
 }
 
-func pickAssignee(ctx context.Context, st store.Store, teamName string, task *store.Task, agents []store.Agent) string {
-	if task.WorkflowID != nil && *task.WorkflowID != "" && task.CurrentStage != nil && *task.CurrentStage != "" {
-		stages, err := st.GetWorkflowStages(ctx, *task.WorkflowID)
-		if err == nil {
-			for _, s := range stages {
-				if s.StageName == *task.CurrentStage && strings.TrimSpace(s.CandidateAgents) != "" {
-					pool := strings.Split(s.CandidateAgents, ",")
-					set := make(map[string]bool)
-					for _, p := range pool {
-						set[strings.TrimSpace(p)] = true
-					}
-					var candidates []store.Agent
-					for _, a := range agents {
-						if set[a.Name] {
-							candidates = append(candidates, a)
-						}
-					}
-					if len(candidates) > 0 {
-						for _, a := range candidates {
-							if a.Role == "manager" {
-								return a.Name
-							}
-						}
-						return candidates[0].Name
-					}
-				}
-			}
-		}
-	}
-	// Default: prefer manager, else first agent.
-	for _, a := range agents {
-		if a.Role == "manager" {
-			return a.Name
-		}
-	}
-	return agents[0].Name
-}
-
 func publishTaskUpdate(app *httpapi.App, team string, taskID int64, status string, assignee *string) {
 	payload := map[string]any{"type": "task_update", "team": team, "task_id": taskID, "status": status}
 	if assignee != nil {
@@ -1,5 +1,7 @@
 package daemon
 
+import "time"
+
 // StartOptions configures the daemon (home, port, scheduler interval, runtime, DB, manager LLM, etc.).
 type StartOptions struct {
 	Home           string
@@ -16,11 +18,14 @@ type StartOptions struct {
 	DBDriver       string   // "sqlite" (default) or "postgres"
 	DBURL          string   // for postgres: connection string (or DATABASE_URL env)
 	// Manager LLM: when both set, use LLM manager instead of rule-based.
-	ManagerLLMURL     string // e.g. https://api.openai.com
-	ManagerLLMKey     string // OPENAI_API_KEY
-	ManagerLLMModel   string // e.g. gpt-4o-mini
-	RebaseBeforeMerge bool   // merge worker rebases task branch onto main before merging
-	EnableOtel        bool   // enable OpenTelemetry metrics (Prometheus exporter + HTTP/SSE/task/agent instrumentation)
+	ManagerLLMURL   string // e.g. https://api.openai.com
+	ManagerLLMKey   string // OPENAI_API_KEY
+	ManagerLLMModel string // e.g. gpt-4o-mini
+	MergeBatchSize  int    // merge queue tasks tested together (0 or 1 = one at a time)
+	EnableOtel      bool   // enable OpenTelemetry metrics (Prometheus exporter + HTTP/SSE/task/agent instrumentation)
+
+	// WorktreeGrace is how long finished tasks' worktrees are kept before the worktree GC removes them (0 = 24h).
+	WorktreeGrace time.Duration
 }
 
 // StatusInfo is the result of Status (running or not, PID, listen addr).
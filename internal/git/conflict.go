@@ -0,0 +1,72 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ConflictError is returned when a rebase or merge stops on conflicting changes. The operation has been
+// aborted, so the branch and worktree are as they were before it.
+type ConflictError struct {
+	Op    string   // "rebase" or "merge"
+	Onto  string   // what the branch was rebased onto or merged with
+	Files []string // conflicted paths
+	Err   error
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s onto %s: conflicts in %s", e.Op, e.Onto, strings.Join(e.Files, ", "))
+}
+
+func (e *ConflictError) Unwrap() error { return e.Err }
+
+// ConflictedFiles lists the unmerged paths in the worktree, i.e. the files a stopped rebase or merge
+// left with conflict markers.
+func ConflictedFiles(ctx context.Context, worktreePath string) []string {
+	out, err := run(ctx, worktreePath, "diff", "--name-only", "--diff-filter=U")
+	if err != nil || out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+// conflictOr aborts the stopped operation and returns a *ConflictError when it left conflicted files,
+// else err as is. abort is the git command that undoes the operation.
+func conflictOr(ctx context.Context, worktreePath, op, onto string, err error, abort ...string) error {
+	files := ConflictedFiles(ctx, worktreePath)
+	_, _ = run(ctx, worktreePath, abort...)
+	if len(files) == 0 {
+		return err
+	}
+	return &ConflictError{Op: op, Onto: onto, Files: files, Err: err}
+}
+
+// StartResolution merges onto into branchName in the worktree without committing, so that conflicts can be
+// resolved by hand: the conflicted files keep their conflict markers and git commit concludes the merge.
+// It returns the conflicted files; when there are none the merge is committed with message right away.
+// Called again while conflicts remain, it only lists them.
+func StartResolution(ctx context.Context, worktreePath, branchName, onto, message string) ([]string, error) {
+	if files := ConflictedFiles(ctx, worktreePath); len(files) > 0 {
+		return files, nil // a resolution is already under way
+	}
+	if _, err := run(ctx, worktreePath, "checkout", branchName); err != nil {
+		return nil, fmt.Errorf("git checkout %s: %w", branchName, err)
+	}
+	if _, err := run(ctx, worktreePath, "merge-base", "--is-ancestor", onto, "HEAD"); err == nil {
+		return nil, nil // already contains onto
+	}
+	ids := identityArgs(ctx, worktreePath, MergeOptions{})
+	if _, err := run(ctx, worktreePath, append(ids, "merge", "--no-ff", "--no-commit", onto)...); err != nil {
+		files := ConflictedFiles(ctx, worktreePath)
+		if len(files) == 0 {
+			_, _ = run(ctx, worktreePath, "merge", "--abort")
+			return nil, fmt.Errorf("git merge %s: %w", onto, err)
+		}
+		return files, nil
+	}
+	if _, err := run(ctx, worktreePath, append(ids, "commit", "-m", message)...); err != nil {
+		return nil, fmt.Errorf("git commit: %w", err)
+	}
+	return nil, nil
+}
@@ -0,0 +1,74 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRebaseBranch_conflict(t *testing.T) {
+	ctx := context.Background()
+	src := sourceRepo(t, 1)
+	home := t.TempDir()
+	mirror := MirrorPath(home, "t", "repo")
+	wt := WorktreePath(home, "t", "repo", 1)
+	if _, err := CreateWorktree(ctx, mirror, wt, src, "agentary/t/T1"); err != nil {
+		t.Fatal(err)
+	}
+	commit(t, wt, "f.txt", "mine")
+	// Another task changes the same line and lands first.
+	other := WorktreePath(home, "t", "repo", 2)
+	if _, err := CreateWorktree(ctx, mirror, other, src, "agentary/t/T2"); err != nil {
+		t.Fatal(err)
+	}
+	commit(t, other, "f.txt", "theirs")
+	if err := MergeInWorktree(ctx, other, "agentary/t/T2"); err != nil {
+		t.Fatal(err)
+	}
+
+	tip, _ := RevParse(ctx, wt, "agentary/t/T1")
+	err := RebaseBranch(ctx, wt, "agentary/t/T1", "main")
+	var ce *ConflictError
+	if !errors.As(err, &ce) {
+		t.Fatalf("RebaseBranch = %v, want a ConflictError", err)
+	}
+	if !slices.Equal(ce.Files, []string{"f.txt"}) || ce.Op != "rebase" {
+		t.Errorf("conflict = %+v, want a rebase conflict in f.txt", ce)
+	}
+	if got, _ := RevParse(ctx, wt, "HEAD"); got != tip {
+		t.Errorf("HEAD = %s after the aborted rebase, want %s", got, tip)
+	}
+	if files := ConflictedFiles(ctx, wt); len(files) != 0 {
+		t.Errorf("conflicted files after abort = %v", files)
+	}
+
+	// Resolving: main is merged in with markers left for the agent, who edits and commits.
+	files, err := StartResolution(ctx, wt, "agentary/t/T1", "main", "Merge main")
+	if err != nil || !slices.Equal(files, []string{"f.txt"}) {
+		t.Fatalf("StartResolution = %v, %v", files, err)
+	}
+	b, _ := os.ReadFile(filepath.Join(wt, "f.txt"))
+	if !strings.Contains(string(b), "<<<<<<<") {
+		t.Errorf("f.txt = %q, want conflict markers", b)
+	}
+	if again, _ := StartResolution(ctx, wt, "agentary/t/T1", "main", "Merge main"); !slices.Equal(again, files) {
+		t.Errorf("StartResolution again = %v, want %v", again, files)
+	}
+	commit(t, wt, "f.txt", "both")
+
+	// The resolved branch already contains main: it is not rebased again and lands cleanly.
+	resolved, _ := RevParse(ctx, wt, "HEAD")
+	if err := RebaseBranch(ctx, wt, "agentary/t/T1", "main"); err != nil {
+		t.Fatalf("RebaseBranch after resolving: %v", err)
+	}
+	if got, _ := RevParse(ctx, wt, "HEAD"); got != resolved {
+		t.Errorf("resolved branch was rewritten: %s, want %s", got, resolved)
+	}
+	if _, err := Merge(ctx, wt, "agentary/t/T1", MergeOptions{Strategy: StrategyMerge}); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+}
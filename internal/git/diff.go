@@ -0,0 +1,339 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// File statuses in a FileDiff.
+const (
+	FileAdded    = "added"
+	FileModified = "modified"
+	FileDeleted  = "deleted"
+	FileRenamed  = "renamed"
+	FileCopied   = "copied"
+)
+
+// DiffOptions shape a structured diff.
+type DiffOptions struct {
+	Paths            []string // pathspecs to limit the diff to; empty = all files
+	IgnoreWhitespace bool     // git diff -w
+	Context          int      // lines of context around each change; < 0 = git's default
+	Stat             bool     // per-file counts only, without hunks (git diff --numstat)
+}
+
+// FileDiff is one changed file. OldPath is set for renames and copies; binary files have no line
+// counts or hunks.
+type FileDiff struct {
+	Path       string `json:"path"`
+	OldPath    string `json:"old_path,omitempty"`
+	Status     string `json:"status"`
+	Additions  int    `json:"additions"`
+	Deletions  int    `json:"deletions"`
+	Binary     bool   `json:"binary"`
+	Similarity int    `json:"similarity,omitempty"` // percent, for renames and copies
+	HunkCount  int    `json:"hunk_count"`
+	Hunks      []Hunk `json:"hunks,omitempty"`
+}
+
+// Hunk is one @@ section of a file diff. Lines keep their ' ', '+', '-' or '\' prefix.
+type Hunk struct {
+	Index    int      `json:"index"` // position among all hunks of the diff, from 0
+	Header   string   `json:"header"`
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Lines    []string `json:"lines"`
+}
+
+// DiffFiles returns the files changed between baseSHA and headRef in worktreePath, with line counts and,
+// unless opts.Stat is set, their hunks. Renames and copies are detected.
+func DiffFiles(ctx context.Context, worktreePath, baseSHA, headRef string, opts DiffOptions) ([]FileDiff, error) {
+	if worktreePath == "" {
+		return nil, nil
+	}
+	if headRef == "" {
+		headRef = "HEAD"
+	}
+	if baseSHA == "" {
+		baseSHA = "HEAD~1"
+	}
+	args := []string{"-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff", "-M"}
+	if opts.IgnoreWhitespace {
+		args = append(args, "-w")
+	}
+	if opts.Stat {
+		return diffStat(ctx, worktreePath, args, baseSHA+".."+headRef, opts.Paths)
+	}
+	if opts.Context >= 0 {
+		args = append(args, fmt.Sprintf("-U%d", opts.Context))
+	}
+	out, err := diffOutput(ctx, worktreePath, args, baseSHA+".."+headRef, opts.Paths)
+	if err != nil {
+		return nil, err
+	}
+	return parsePatch(out), nil
+}
+
+func diffOutput(ctx context.Context, worktreePath string, args []string, rev string, paths []string) (string, error) {
+	args = append(append(args, rev, "--"), paths...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = worktreePath
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git diff: %w", err)
+	}
+	return string(out), nil
+}
+
+// diffStat combines git diff --name-status and --numstat, which list files in the same order.
+func diffStat(ctx context.Context, worktreePath string, args []string, rev string, paths []string) ([]FileDiff, error) {
+	names, err := diffOutput(ctx, worktreePath, append(args[:len(args):len(args)], "--name-status", "-z"), rev, paths)
+	if err != nil {
+		return nil, err
+	}
+	nums, err := diffOutput(ctx, worktreePath, append(args[:len(args):len(args)], "--numstat", "-z"), rev, paths)
+	if err != nil {
+		return nil, err
+	}
+	var files []FileDiff
+	fields := strings.Split(names, "\x00")
+	for i := 0; i+1 < len(fields); {
+		code := fields[i]
+		if code == "" {
+			break
+		}
+		f := FileDiff{Path: fields[i+1], Status: statusName(code[0])}
+		i += 2
+		if code[0] == 'R' || code[0] == 'C' {
+			if i >= len(fields) {
+				break
+			}
+			f.OldPath, f.Path = f.Path, fields[i]
+			f.Similarity, _ = strconv.Atoi(code[1:])
+			i++
+		}
+		files = append(files, f)
+	}
+	// numstat -z: "added\tdeleted\tpath\0", or "added\tdeleted\t\0old\0new\0" for renames and copies.
+	fields = strings.Split(nums, "\x00")
+	for i, n := 0, 0; i < len(fields) && n < len(files); n++ {
+		counts := strings.SplitN(fields[i], "\t", 3)
+		if len(counts) < 3 {
+			break
+		}
+		i++
+		if counts[2] == "" {
+			i += 2
+		}
+		if counts[0] == "-" {
+			files[n].Binary = true
+			continue
+		}
+		files[n].Additions, _ = strconv.Atoi(counts[0])
+		files[n].Deletions, _ = strconv.Atoi(counts[1])
+	}
+	return files, nil
+}
+
+func statusName(code byte) string {
+	switch code {
+	case 'A':
+		return FileAdded
+	case 'D':
+		return FileDeleted
+	case 'R':
+		return FileRenamed
+	case 'C':
+		return FileCopied
+	}
+	return FileModified
+}
+
+// parsePatch parses git diff output into files and hunks. Hunks are numbered across the whole diff.
+func parsePatch(patch string) []FileDiff {
+	var (
+		files []FileDiff
+		f     *FileDiff
+		h     *Hunk
+		index int
+	)
+	flush := func() {
+		if h != nil {
+			f.Hunks = append(f.Hunks, *h)
+			h = nil
+		}
+	}
+	sc := bufio.NewScanner(strings.NewReader(patch))
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "diff --git ") {
+			if f != nil {
+				flush()
+			}
+			files = append(files, FileDiff{Status: FileModified})
+			f = &files[len(files)-1]
+			f.OldPath, f.Path = headerPaths(strings.TrimPrefix(line, "diff --git "))
+			continue
+		}
+		if f == nil {
+			continue
+		}
+		if h != nil {
+			if line != "" && strings.ContainsRune(" +-\\", rune(line[0])) {
+				h.Lines = append(h.Lines, line)
+				switch line[0] {
+				case '+':
+					f.Additions++
+				case '-':
+					f.Deletions++
+				}
+				continue
+			}
+			flush()
+		}
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			h = &Hunk{Index: index, Header: line}
+			index++
+			f.HunkCount++
+			_, _ = fmt.Sscanf(hunkRange(line, '-'), "%d,%d", &h.OldStart, &h.OldLines)
+			_, _ = fmt.Sscanf(hunkRange(line, '+'), "%d,%d", &h.NewStart, &h.NewLines)
+			if !strings.Contains(hunkRange(line, '-'), ",") {
+				h.OldLines = 1
+			}
+			if !strings.Contains(hunkRange(line, '+'), ",") {
+				h.NewLines = 1
+			}
+		case strings.HasPrefix(line, "new file mode"):
+			f.Status = FileAdded
+		case strings.HasPrefix(line, "deleted file mode"):
+			f.Status = FileDeleted
+		case strings.HasPrefix(line, "rename from "):
+			f.Status, f.OldPath = FileRenamed, unquotePath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			f.Path = unquotePath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "copy from "):
+			f.Status, f.OldPath = FileCopied, unquotePath(strings.TrimPrefix(line, "copy from "))
+		case strings.HasPrefix(line, "copy to "):
+			f.Path = unquotePath(strings.TrimPrefix(line, "copy to "))
+		case strings.HasPrefix(line, "similarity index "):
+			f.Similarity, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%"))
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			f.Binary = true
+		case strings.HasPrefix(line, "+++ ") && line != "+++ /dev/null":
+			f.Path = strings.TrimPrefix(unquotePath(strings.TrimRight(strings.TrimPrefix(line, "+++ "), "\t")), "b/")
+		case strings.HasPrefix(line, "--- ") && line != "--- /dev/null":
+			f.OldPath = strings.TrimPrefix(unquotePath(strings.TrimRight(strings.TrimPrefix(line, "--- "), "\t")), "a/")
+		}
+	}
+	if f != nil {
+		flush()
+	}
+	for i := range files {
+		if files[i].Status != FileRenamed && files[i].Status != FileCopied {
+			files[i].OldPath = ""
+		}
+	}
+	return files
+}
+
+// headerPaths splits the "a/old b/new" of a diff --git line. Paths with spaces are ambiguous there;
+// when old and new differ, the rename or ---/+++ lines that follow set them.
+func headerPaths(s string) (oldPath, newPath string) {
+	if strings.HasPrefix(s, `"`) {
+		if i := strings.Index(s, `" `); i > 0 {
+			return strings.TrimPrefix(unquotePath(s[:i+1]), "a/"), strings.TrimPrefix(unquotePath(s[i+2:]), "b/")
+		}
+	}
+	if len(s)%2 == 1 { // "a/P b/P": the same path twice
+		half := len(s) / 2
+		if a, b := s[:half], s[half+1:]; strings.HasPrefix(a, "a/") && strings.HasPrefix(b, "b/") && a[2:] == b[2:] {
+			return a[2:], b[2:]
+		}
+	}
+	if i := strings.Index(s, " b/"); i > 0 {
+		return strings.TrimPrefix(s[:i], "a/"), s[i+3:]
+	}
+	return s, s
+}
+
+// hunkRange returns the "start,count" after sign in a hunk header ("@@ -1,3 +1,4 @@").
+func hunkRange(header string, sign byte) string {
+	for _, field := range strings.Fields(header) {
+		if len(field) > 1 && field[0] == sign && field[1] >= '0' && field[1] <= '9' {
+			return field[1:]
+		}
+	}
+	return ""
+}
+
+func unquotePath(p string) string {
+	if strings.HasPrefix(p, `"`) {
+		if s, err := strconv.Unquote(p); err == nil {
+			return s
+		}
+	}
+	return p
+}
+
+// PageHunks keeps only the hunks with Index in [offset, offset+limit) across files, which keep their
+// counts. It returns the total number of hunks and the offset of the next page, or -1 after the last.
+func PageHunks(files []FileDiff, offset, limit int) (total, next int) {
+	for i := range files {
+		total += files[i].HunkCount
+		var kept []Hunk
+		for _, h := range files[i].Hunks {
+			if h.Index >= offset && h.Index < offset+limit {
+				kept = append(kept, h)
+			}
+		}
+		files[i].Hunks = kept
+	}
+	if offset+limit >= total {
+		return total, -1
+	}
+	return total, offset + limit
+}
+
+// MapLines follows lines start..end of path from commit fromSHA to toRef. It returns where they are in
+// toRef, or ok=false when the change touched them or removed the file.
+func MapLines(ctx context.Context, worktreePath, fromSHA, toRef, path string, start, end int) (newStart, newEnd int, ok bool, err error) {
+	files, err := DiffFiles(ctx, worktreePath, fromSHA, toRef, DiffOptions{Paths: []string{path}, Context: 0})
+	if err != nil {
+		return 0, 0, false, err
+	}
+	delta := 0
+	for _, f := range files {
+		if f.Path != path && f.OldPath != path {
+			continue
+		}
+		if f.Status == FileDeleted || f.Binary {
+			return 0, 0, false, nil
+		}
+		for _, h := range f.Hunks {
+			// A pure insertion (OldLines 0) goes after line OldStart; otherwise it replaces
+			// OldStart..OldStart+OldLines-1.
+			before := h.OldStart+h.OldLines-1 < start
+			after := h.OldStart > end
+			if h.OldLines == 0 {
+				before, after = h.OldStart < start, h.OldStart >= end
+			}
+			switch {
+			case before:
+				delta += h.NewLines - h.OldLines
+			case after:
+				return start + delta, end + delta, true, nil
+			default:
+				return 0, 0, false, nil
+			}
+		}
+	}
+	return start + delta, end + delta, true, nil
+}
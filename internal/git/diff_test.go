@@ -0,0 +1,122 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDiffFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := sourceRepo(t, 0)
+	long := strings.Repeat("line\n", 20)
+	commit(t, dir, "keep.txt", "a\nb\nc\n")
+	commit(t, dir, "gone.txt", "bye\n")
+	commit(t, dir, "old name.txt", long)
+	base, _ := RevParse(ctx, dir, "HEAD")
+
+	commit(t, dir, "keep.txt", "a\nB \nc\n")
+	commit(t, dir, "new.txt", "1\n2\n")
+	commit(t, dir, "blob.bin", "\x00\x01\x02")
+	for _, args := range [][]string{{"rm", "-q", "gone.txt"}, {"mv", "old name.txt", "new name.txt"}} {
+		if _, err := run(ctx, dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit(t, dir, "new name.txt", long+"tail\n")
+
+	byPath := func(files []FileDiff) map[string]FileDiff {
+		m := make(map[string]FileDiff)
+		for _, f := range files {
+			m[f.Path] = f
+		}
+		return m
+	}
+	for _, stat := range []bool{false, true} {
+		files, err := DiffFiles(ctx, dir, base, "HEAD", DiffOptions{Context: -1, Stat: stat})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := byPath(files)
+		if len(files) != 5 {
+			t.Fatalf("stat=%v: files = %+v", stat, files)
+		}
+		if f := got["keep.txt"]; f.Status != FileModified || f.Additions != 1 || f.Deletions != 1 {
+			t.Errorf("stat=%v: keep.txt = %+v", stat, f)
+		}
+		if f := got["new.txt"]; f.Status != FileAdded || f.Additions != 2 {
+			t.Errorf("stat=%v: new.txt = %+v", stat, f)
+		}
+		if f := got["gone.txt"]; f.Status != FileDeleted || f.Deletions != 1 {
+			t.Errorf("stat=%v: gone.txt = %+v", stat, f)
+		}
+		if f := got["blob.bin"]; !f.Binary || f.Status != FileAdded || len(f.Hunks) != 0 {
+			t.Errorf("stat=%v: blob.bin = %+v", stat, f)
+		}
+		if f := got["new name.txt"]; f.Status != FileRenamed || f.OldPath != "old name.txt" || f.Additions != 1 || f.Similarity == 0 {
+			t.Errorf("stat=%v: rename = %+v", stat, f)
+		}
+		if f := got["keep.txt"]; stat == (len(f.Hunks) == 1) {
+			t.Errorf("stat=%v: keep.txt hunks = %+v", stat, f.Hunks)
+		}
+	}
+
+	files, _ := DiffFiles(ctx, dir, base, "HEAD", DiffOptions{Paths: []string{"keep.txt"}, Context: 0})
+	if len(files) != 1 || len(files[0].Hunks) != 1 {
+		t.Fatalf("path filter: %+v", files)
+	}
+	if h := files[0].Hunks[0]; h.OldStart != 2 || h.OldLines != 1 || h.NewStart != 2 || h.NewLines != 1 || strings.Join(h.Lines, "|") != "-b|+B " {
+		t.Errorf("hunk with no context = %+v", h)
+	}
+	if files, _ := DiffFiles(ctx, dir, base, "HEAD", DiffOptions{Paths: []string{"keep.txt"}, IgnoreWhitespace: true, Context: -1}); len(files) != 1 || files[0].Additions != 1 {
+		t.Errorf("ignore whitespace: %+v", files)
+	}
+	commit(t, dir, "keep.txt", "a\nB\nc\n")
+	if files, _ := DiffFiles(ctx, dir, "HEAD~1", "HEAD", DiffOptions{IgnoreWhitespace: true, Context: -1}); len(files) > 0 && files[0].Additions != 0 {
+		t.Errorf("whitespace-only change with -w: %+v", files)
+	}
+}
+
+func TestPageHunks(t *testing.T) {
+	files := []FileDiff{
+		{Path: "a", HunkCount: 2, Hunks: []Hunk{{Index: 0}, {Index: 1}}},
+		{Path: "b", HunkCount: 1, Hunks: []Hunk{{Index: 2}}},
+	}
+	total, next := PageHunks(files, 1, 1)
+	if total != 3 || next != 2 || len(files[0].Hunks) != 1 || files[0].Hunks[0].Index != 1 || len(files[1].Hunks) != 0 {
+		t.Fatalf("PageHunks = %d, %d, %+v", total, next, files)
+	}
+	if _, next := PageHunks(files, 2, 5); next != -1 {
+		t.Fatalf("last page next = %d", next)
+	}
+}
+
+func TestMapLines(t *testing.T) {
+	ctx := context.Background()
+	dir := sourceRepo(t, 0)
+	commit(t, dir, "f.txt", "1\n2\n3\n4\n5\n6\n")
+	from, _ := RevParse(ctx, dir, "HEAD")
+	commit(t, dir, "f.txt", "0\n1\n2\n3\n4\nfive\n6\n")
+
+	for _, tc := range []struct {
+		start, end         int
+		wantStart, wantEnd int
+		ok                 bool
+	}{
+		{3, 4, 4, 5, true},  // shifted by the line inserted above
+		{4, 5, 0, 0, false}, // line 5 changed
+		{6, 6, 7, 7, true},
+	} {
+		start, end, ok, err := MapLines(ctx, dir, from, "HEAD", "f.txt", tc.start, tc.end)
+		if err != nil || ok != tc.ok || (ok && (start != tc.wantStart || end != tc.wantEnd)) {
+			t.Errorf("MapLines(%d-%d) = %d-%d, %v, %v", tc.start, tc.end, start, end, ok, err)
+		}
+	}
+	if _, err := run(ctx, dir, "rm", "-q", "f.txt"); err != nil {
+		t.Fatal(err)
+	}
+	commit(t, dir, "g.txt", "x\n")
+	if _, _, ok, err := MapLines(ctx, dir, from, "HEAD", "f.txt", 1, 1); ok || err != nil {
+		t.Errorf("deleted file: ok=%v err=%v", ok, err)
+	}
+}
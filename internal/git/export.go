@@ -0,0 +1,83 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Export formats (ExportTask).
+const (
+	ExportPatch  = "patch"  // git format-patch series in one mbox stream, for git am
+	ExportBundle = "bundle" // git bundle of the branch, for git fetch or git pull
+)
+
+// ErrNothingToExport is returned when the branch has no commits after the base.
+var ErrNothingToExport = errors.New("no commits to export")
+
+// ExportDir is where a task's branch can be read: its worktree, or the repo mirror once the worktree is gone
+// (the worktree GC keeps branches). Returns "" when neither exists.
+func ExportDir(worktreePath, mirrorPath string) string {
+	for _, dir := range []string{worktreePath, mirrorPath} {
+		if dir == "" {
+			continue
+		}
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// ExportTask returns the commits of branchName after baseSHA in dir as a patch series (ExportPatch) or a
+// git bundle (ExportBundle). A bundle holds the branch ref and needs baseSHA in the receiving repo.
+// baseSHA "" means the merge-base with upstream main.
+func ExportTask(ctx context.Context, dir, baseSHA, branchName, format string) ([]byte, error) {
+	if dir == "" || branchName == "" {
+		return nil, errors.New("dir and branch_name required")
+	}
+	if format != ExportPatch && format != ExportBundle {
+		return nil, fmt.Errorf("unknown export format %q (want patch or bundle)", format)
+	}
+	if baseSHA == "" {
+		base, err := run(ctx, dir, "merge-base", baseRef(ctx, dir), branchName)
+		if err != nil {
+			return nil, err
+		}
+		baseSHA = base
+	}
+	count, err := run(ctx, dir, "rev-list", "--count", baseSHA+".."+branchName)
+	if err != nil {
+		return nil, err
+	}
+	if count == "0" {
+		return nil, ErrNothingToExport
+	}
+	if format == ExportPatch {
+		cmd := exec.CommandContext(ctx, "git", "format-patch", "--stdout", "--no-color", "-M", baseSHA+".."+branchName)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("git format-patch: %w", err)
+		}
+		return out, nil
+	}
+	tmp, err := os.MkdirTemp("", "agentary-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+	file := filepath.Join(tmp, "task.bundle")
+	ref := branchName
+	if !strings.HasPrefix(ref, "refs/") {
+		ref = "refs/heads/" + ref
+	}
+	if _, err := run(ctx, dir, "bundle", "create", file, ref, "^"+baseSHA); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(file)
+}
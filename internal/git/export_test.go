@@ -0,0 +1,71 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportTask(t *testing.T) {
+	ctx := context.Background()
+	src := sourceRepo(t, 2)
+	home := t.TempDir()
+	mirror := MirrorPath(home, "t", "repo")
+	wt := WorktreePath(home, "t", "repo", 1)
+	base, err := CreateWorktree(ctx, mirror, wt, src, "agentary/t/T1")
+	if err != nil {
+		t.Fatalf("CreateWorktree: %v", err)
+	}
+	if _, err := ExportTask(ctx, wt, base, "agentary/t/T1", ExportPatch); !errors.Is(err, ErrNothingToExport) {
+		t.Fatalf("export without commits: %v, want ErrNothingToExport", err)
+	}
+	if _, err := ExportTask(ctx, wt, base, "agentary/t/T1", "tarball"); err == nil {
+		t.Fatal("expected error for an unknown format")
+	}
+	commit(t, wt, "a.txt", "one")
+	commit(t, wt, "b.txt", "two")
+
+	patch, err := ExportTask(ctx, wt, base, "agentary/t/T1", ExportPatch)
+	if err != nil {
+		t.Fatalf("ExportTask patch: %v", err)
+	}
+	if s := string(patch); !strings.Contains(s, "[PATCH 1/2] update a.txt") || !strings.Contains(s, "[PATCH 2/2] update b.txt") {
+		t.Fatalf("patch series:\n%s", s)
+	}
+	// The series applies on top of the base upstream.
+	patchFile := filepath.Join(t.TempDir(), "task.patch")
+	_ = os.WriteFile(patchFile, patch, 0o644)
+	if _, err := run(ctx, src, "-c", "user.name=t", "-c", "user.email=t@example.com", "am", "-q", patchFile); err != nil {
+		t.Fatalf("git am: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "b.txt")); err != nil {
+		t.Fatalf("patch not applied: %v", err)
+	}
+
+	// Once the worktree is gone the branch is read from the mirror.
+	if err := DeleteWorktree(ctx, wt); err != nil {
+		t.Fatal(err)
+	}
+	dir := ExportDir(wt, mirror)
+	if dir != mirror {
+		t.Fatalf("ExportDir = %q, want the mirror", dir)
+	}
+	bundle, err := ExportTask(ctx, dir, base, "agentary/t/T1", ExportBundle)
+	if err != nil {
+		t.Fatalf("ExportTask bundle: %v", err)
+	}
+	bundleFile := filepath.Join(t.TempDir(), "task.bundle")
+	_ = os.WriteFile(bundleFile, bundle, 0o644)
+	if _, err := run(ctx, src, "fetch", "-q", bundleFile, "agentary/t/T1:refs/heads/imported"); err != nil {
+		t.Fatalf("fetch from bundle: %v", err)
+	}
+	if n, _ := run(ctx, src, "rev-list", "--count", base+"..imported"); n != "2" {
+		t.Errorf("bundle carried %s commits, want 2", n)
+	}
+	if ExportDir(wt, "") != "" {
+		t.Error("ExportDir should be empty when nothing exists")
+	}
+}
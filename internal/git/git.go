@@ -3,7 +3,6 @@ package git
 import (
 	"context"
 	"fmt"
-	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
@@ -23,119 +22,31 @@ func WorktreePath(home, teamName, repoName string, taskID int64) string {
 	return filepath.Join(home, "protected", "teams", safeTeam, "worktrees", fmt.Sprintf("%s-T%d", safeRepo, taskID))
 }
 
-// CreateWorktree creates a worktree for the task: clones sourceURL into worktreePath and checks out branch branchName (creating it from main or HEAD).
-// Returns baseSHA (commit at branch creation). If the directory already exists, returns the current HEAD there without re-cloning.
-func CreateWorktree(ctx context.Context, worktreePath, sourceURL, branchName string) (baseSHA string, err error) {
-	if worktreePath == "" || sourceURL == "" || branchName == "" {
-		return "", fmt.Errorf("worktree_path, source_url, and branch_name required")
-	}
-	dir := filepath.Dir(worktreePath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return "", err
-	}
-
-	// If worktree already exists, just return HEAD (idempotent).
-	if _, err := os.Stat(worktreePath); err == nil {
-		cmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
-		cmd.Dir = worktreePath
-		out, runErr := cmd.Output()
-		if runErr != nil {
-			return "", runErr
-		}
-		return strings.TrimSpace(string(out)), nil
-	}
-
-	// Clone (shallow to save space).
-	cloneCmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", sourceURL, worktreePath)
-	if out, err := cloneCmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("git clone: %w: %s", err, string(out))
-	}
-
-	// Create and checkout branch.
-	checkoutCmd := exec.CommandContext(ctx, "git", "checkout", "-b", branchName)
-	checkoutCmd.Dir = worktreePath
-	if out, err := checkoutCmd.CombinedOutput(); err != nil {
-		_ = os.RemoveAll(worktreePath)
-		return "", fmt.Errorf("git checkout -b: %w: %s", err, string(out))
-	}
-
-	revCmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
-	revCmd.Dir = worktreePath
-	out, err := revCmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(string(out)), nil
-}
-
-// DeleteWorktree removes the worktree directory and optionally deletes the branch in the main repo.
-// If worktreePath is empty or the path doesn't exist, no-op (returns nil).
-func DeleteWorktree(ctx context.Context, worktreePath string) error {
-	if worktreePath == "" {
-		return nil
-	}
-	if _, err := os.Stat(worktreePath); os.IsNotExist(err) {
-		return nil
-	}
-	return os.RemoveAll(worktreePath)
-}
-
 // RebaseOntoMain checks out branchName, fetches origin, and rebases onto origin/main (or origin/master).
+// A rebase that stops on conflicts is aborted and reported as a *ConflictError.
 // No-op if worktreePath or branchName is empty.
 func RebaseOntoMain(ctx context.Context, worktreePath, branchName string) error {
 	if worktreePath == "" || branchName == "" {
 		return nil
 	}
-	co := exec.CommandContext(ctx, "git", "checkout", branchName)
-	co.Dir = worktreePath
-	if out, err := co.CombinedOutput(); err != nil {
-		return fmt.Errorf("git checkout %s: %w: %s", branchName, err, string(out))
-	}
-	fetch := exec.CommandContext(ctx, "git", "fetch", "origin")
-	fetch.Dir = worktreePath
-	if out, err := fetch.CombinedOutput(); err != nil {
-		return fmt.Errorf("git fetch origin: %w: %s", err, string(out))
-	}
-	rebase := exec.CommandContext(ctx, "git", "rebase", "origin/main")
-	rebase.Dir = worktreePath
-	if out, err := rebase.CombinedOutput(); err != nil {
-		rebase = exec.CommandContext(ctx, "git", "rebase", "origin/master")
-		rebase.Dir = worktreePath
-		if out2, err2 := rebase.CombinedOutput(); err2 != nil {
-			return fmt.Errorf("git rebase origin/main: %w: %s", err2, string(out2))
-		}
-		_ = out
-	} else {
-		_ = out
+	if _, err := run(ctx, worktreePath, "checkout", branchName); err != nil {
+		return fmt.Errorf("git checkout %s: %w", branchName, err)
 	}
-	return nil
+	if _, err := run(ctx, worktreePath, "fetch", "origin"); err != nil {
+		return fmt.Errorf("git fetch origin: %w", err)
+	}
+	upstream := "origin/main"
+	if _, err := run(ctx, worktreePath, "rev-parse", "--verify", "--quiet", upstream); err != nil {
+		upstream = "origin/master"
+	}
+	return RebaseBranch(ctx, worktreePath, branchName, upstream)
 }
 
-// MergeInWorktree runs git checkout main (or master) and git merge branchName in the worktree.
-// Used by the merge workflow stage to merge the task branch into main.
+// MergeInWorktree merges branchName into main (or master) from the worktree with a plain git merge (fast-forward
+// when possible) and moves the local main branch to the result. See Merge for the per-repo strategies.
 func MergeInWorktree(ctx context.Context, worktreePath, branchName string) error {
-	if worktreePath == "" || branchName == "" {
-		return nil
-	}
-	// Prefer main, fallback to master.
-	checkout := exec.CommandContext(ctx, "git", "checkout", "main")
-	checkout.Dir = worktreePath
-	if out, err := checkout.CombinedOutput(); err != nil {
-		checkout = exec.CommandContext(ctx, "git", "checkout", "master")
-		checkout.Dir = worktreePath
-		if out2, err2 := checkout.CombinedOutput(); err2 != nil {
-			return fmt.Errorf("git checkout main/master: %w: %s", err2, string(out2))
-		}
-		_ = out
-	} else {
-		_ = out
-	}
-	mergeCmd := exec.CommandContext(ctx, "git", "merge", branchName)
-	mergeCmd.Dir = worktreePath
-	if out, err := mergeCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git merge %s: %w: %s", branchName, err, string(out))
-	}
-	return nil
+	_, err := Merge(ctx, worktreePath, branchName, MergeOptions{})
+	return err
 }
 
 // RunTestCmd runs testCmd (e.g. from repo.test_cmd) in worktreePath. Uses sh -c for shell semantics.
@@ -171,3 +82,57 @@ func Diff(ctx context.Context, worktreePath, baseSHA, headRef string) (string, e
 	}
 	return string(out), nil
 }
+
+// ChangedFiles returns the paths changed between baseSHA and headRef in worktreePath (git diff --name-only).
+func ChangedFiles(ctx context.Context, worktreePath, baseSHA, headRef string) ([]string, error) {
+	if worktreePath == "" {
+		return nil, nil
+	}
+	if headRef == "" {
+		headRef = "HEAD"
+	}
+	if baseSHA == "" {
+		baseSHA = "HEAD~1"
+	}
+	cmd := exec.CommandContext(ctx, "git", "diff", "--name-only", baseSHA+".."+headRef)
+	cmd.Dir = worktreePath
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff --name-only: %w", err)
+	}
+	var files []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// DiffLines returns the number of lines added plus deleted between baseSHA and headRef in worktreePath
+// (git diff --numstat). Binary files count as no lines.
+func DiffLines(ctx context.Context, worktreePath, baseSHA, headRef string) (int, error) {
+	if worktreePath == "" {
+		return 0, nil
+	}
+	if headRef == "" {
+		headRef = "HEAD"
+	}
+	if baseSHA == "" {
+		baseSHA = "HEAD~1"
+	}
+	cmd := exec.CommandContext(ctx, "git", "diff", "--numstat", baseSHA+".."+headRef)
+	cmd.Dir = worktreePath
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("git diff --numstat: %w", err)
+	}
+	total := 0
+	for _, line := range strings.Split(string(out), "\n") {
+		var added, deleted int
+		if _, err := fmt.Sscanf(line, "%d\t%d", &added, &deleted); err == nil {
+			total += added + deleted
+		}
+	}
+	return total, nil
+}
@@ -75,14 +75,18 @@ func TestRebaseOntoMain_empty(t *testing.T) {
 
 func TestCreateWorktree_validation(t *testing.T) {
 	ctx := context.Background()
-	_, err := CreateWorktree(ctx, "", "http://x", "branch")
+	_, err := CreateWorktree(ctx, t.TempDir(), "", "http://x", "branch")
 	if err == nil {
 		t.Fatal("CreateWorktree empty path: expected error")
 	}
-	_, err = CreateWorktree(ctx, t.TempDir(), "", "branch")
+	_, err = CreateWorktree(ctx, t.TempDir(), t.TempDir(), "", "branch")
 	if err == nil {
 		t.Fatal("CreateWorktree empty sourceURL: expected error")
 	}
+	_, err = CreateWorktree(ctx, "", t.TempDir(), "http://x", "branch")
+	if err == nil {
+		t.Fatal("CreateWorktree empty mirror: expected error")
+	}
 }
 
 func TestDeleteWorktree_existingDir(t *testing.T) {
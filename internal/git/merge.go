@@ -0,0 +1,199 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Merge strategies (MergeOptions.Strategy).
+const (
+	StrategyMerge  = "merge"   // always a merge commit
+	StrategySquash = "squash"  // one commit with the branch's changes
+	StrategyRebase = "rebase"  // rebase the branch onto the target, then fast-forward
+	StrategyFFOnly = "ff-only" // fast-forward only; fails if the target has moved on
+)
+
+// MergeOptions says how Merge lands a branch.
+type MergeOptions struct {
+	Target   string // branch to land on; "" = main, or master when there is no main
+	Strategy string // one of the Strategy constants; "" = plain git merge (fast-forward when possible)
+	// Message is the commit message for merge and squash commits. Rebase and ff-only keep the branch's commits.
+	Message string
+	// CommitterName and CommitterEmail sign the commits Merge creates. When empty, git's configured identity
+	// is used, falling back to Agentary <agentary@localhost>.
+	CommitterName  string
+	CommitterEmail string
+}
+
+// Merge lands branchName on the target branch from the worktree and returns the target's new commit.
+// It starts from the local target, or from origin/<target> when upstream is ahead, and moves the local target
+// branch to the result; the worktree is left detached so tasks sharing a mirror never hold the target checked out.
+func Merge(ctx context.Context, worktreePath, branchName string, opts MergeOptions) (string, error) {
+	if worktreePath == "" || branchName == "" {
+		return "", nil
+	}
+	target := ResolveTarget(ctx, worktreePath, opts.Target)
+	start, err := mergeStart(ctx, worktreePath, target)
+	if err != nil {
+		return "", err
+	}
+	ids := identityArgs(ctx, worktreePath, opts)
+	git := func(args ...string) (string, error) {
+		return run(ctx, worktreePath, append(ids[:len(ids):len(ids)], args...)...)
+	}
+
+	if opts.Strategy == StrategyRebase {
+		if err := RebaseBranch(ctx, worktreePath, branchName, start); err != nil {
+			return "", err
+		}
+	}
+	if _, err := git("checkout", "--detach", start); err != nil {
+		return "", fmt.Errorf("git checkout %s: %w", target, err)
+	}
+	switch opts.Strategy {
+	case "":
+		if _, err := git("merge", "--no-edit", branchName); err != nil {
+			return "", conflictOr(ctx, worktreePath, "merge", target, fmt.Errorf("git merge %s: %w", branchName, err), "merge", "--abort")
+		}
+	case StrategyMerge:
+		if _, err := git(messageArgs([]string{"merge", "--no-ff"}, opts.Message, branchName)...); err != nil {
+			return "", conflictOr(ctx, worktreePath, "merge", target, fmt.Errorf("git merge %s: %w", branchName, err), "merge", "--abort")
+		}
+	case StrategySquash:
+		if _, err := git("merge", "--squash", branchName); err != nil {
+			return "", conflictOr(ctx, worktreePath, "merge", target, fmt.Errorf("git merge --squash %s: %w", branchName, err), "reset", "--hard", start)
+		}
+		// Nothing staged means the branch is already on the target: there is nothing to commit.
+		if _, err := git("diff", "--cached", "--quiet"); err != nil {
+			msg := opts.Message
+			if msg == "" {
+				msg = "Squashed " + branchName
+			}
+			if _, err := git("commit", "-m", msg); err != nil {
+				return "", fmt.Errorf("git commit: %w", err)
+			}
+		}
+	case StrategyRebase, StrategyFFOnly:
+		if _, err := git("merge", "--ff-only", branchName); err != nil {
+			return "", fmt.Errorf("git merge --ff-only %s: %w", branchName, err)
+		}
+	default:
+		return "", errors.New("unknown merge strategy " + opts.Strategy)
+	}
+	if _, err := git("branch", "-f", target, "HEAD"); err != nil {
+		return "", fmt.Errorf("git branch -f %s: %w", target, err)
+	}
+	return run(ctx, worktreePath, "rev-parse", "HEAD")
+}
+
+// ResolveTarget returns target, or when it is "" the default branch: main, or master when there is no main.
+func ResolveTarget(ctx context.Context, dir, target string) string {
+	if target != "" {
+		return target
+	}
+	if _, err := run(ctx, dir, "rev-parse", "--verify", "--quiet", "refs/heads/main"); err != nil {
+		return "master"
+	}
+	return "main"
+}
+
+// TargetHead returns the commit a merge onto target starts from: the local target, or origin/<target>
+// when upstream is ahead.
+func TargetHead(ctx context.Context, dir, target string) (string, error) {
+	start, err := mergeStart(ctx, dir, ResolveTarget(ctx, dir, target))
+	if err != nil {
+		return "", err
+	}
+	return run(ctx, dir, "rev-parse", start)
+}
+
+// RebaseBranch checks out branchName in the worktree and rebases it onto onto; a branch that already contains
+// onto (e.g. after a conflict resolution merge) is left alone. A failed rebase is aborted, leaving the branch
+// as it was; it returns a *ConflictError when the rebase stopped on conflicts.
+func RebaseBranch(ctx context.Context, worktreePath, branchName, onto string) error {
+	if _, err := run(ctx, worktreePath, "checkout", branchName); err != nil {
+		return fmt.Errorf("git checkout %s: %w", branchName, err)
+	}
+	if _, err := run(ctx, worktreePath, "merge-base", "--is-ancestor", onto, "HEAD"); err == nil {
+		return nil
+	}
+	if _, err := run(ctx, worktreePath, append(identityArgs(ctx, worktreePath, MergeOptions{}), "rebase", onto)...); err != nil {
+		return conflictOr(ctx, worktreePath, "rebase", onto, fmt.Errorf("git rebase %s: %w", onto, err), "rebase", "--abort")
+	}
+	return nil
+}
+
+// ResetBranch points branchName at sha and checks it out in the worktree, discarding uncommitted changes.
+func ResetBranch(ctx context.Context, worktreePath, branchName, sha string) error {
+	_, err := run(ctx, worktreePath, "checkout", "--force", "-B", branchName, sha)
+	return err
+}
+
+// SetBranch points branchName at sha; the branch must not be checked out in a worktree.
+func SetBranch(ctx context.Context, dir, branchName, sha string) error {
+	_, err := run(ctx, dir, "branch", "-f", branchName, sha)
+	return err
+}
+
+// AdvanceBranch fast-forwards branchName to sha. It fails when sha does not contain the branch's tip,
+// i.e. the branch moved on in the meantime.
+func AdvanceBranch(ctx context.Context, dir, branchName, sha string) error {
+	if _, err := run(ctx, dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branchName); err != nil {
+		return SetBranch(ctx, dir, branchName, sha) // first landing on a branch that only exists upstream
+	}
+	if _, err := run(ctx, dir, "merge-base", "--is-ancestor", "refs/heads/"+branchName, sha); err != nil {
+		return fmt.Errorf("%s is not a fast-forward of %s", sha, branchName)
+	}
+	return SetBranch(ctx, dir, branchName, sha)
+}
+
+// RevParse returns the commit ref names in dir.
+func RevParse(ctx context.Context, dir, ref string) (string, error) {
+	return run(ctx, dir, "rev-parse", "--verify", ref+"^{commit}")
+}
+
+// CommitExists reports whether sha names a commit in dir's repository.
+func CommitExists(ctx context.Context, dir, sha string) bool {
+	_, err := run(ctx, dir, "cat-file", "-e", sha+"^{commit}")
+	return err == nil
+}
+
+// mergeStart is where landing on target starts: the local target branch, which holds earlier merges, or
+// origin/<target> when upstream has moved ahead of it (or there is no local branch yet).
+func mergeStart(ctx context.Context, worktreePath, target string) (string, error) {
+	_, localErr := run(ctx, worktreePath, "rev-parse", "--verify", "--quiet", "refs/heads/"+target)
+	_, upstreamErr := run(ctx, worktreePath, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+target)
+	switch {
+	case localErr != nil && upstreamErr != nil:
+		return "", fmt.Errorf("target branch %q not found", target)
+	case localErr != nil:
+		return "origin/" + target, nil
+	case upstreamErr != nil:
+		return target, nil
+	}
+	if _, err := run(ctx, worktreePath, "merge-base", "--is-ancestor", target, "origin/"+target); err == nil {
+		return "origin/" + target, nil
+	}
+	return target, nil
+}
+
+// messageArgs appends -m message (or --no-edit when there is none) and the branch to args.
+func messageArgs(args []string, message, branchName string) []string {
+	if message == "" {
+		return append(args, "--no-edit", branchName)
+	}
+	return append(args, "-m", message, branchName)
+}
+
+// identityArgs are the -c options that set the committer for Merge's commits.
+func identityArgs(ctx context.Context, worktreePath string, opts MergeOptions) []string {
+	name, email := opts.CommitterName, opts.CommitterEmail
+	if name == "" || email == "" {
+		if _, err := run(ctx, worktreePath, "config", "user.email"); err == nil {
+			return nil
+		}
+		name, email = "Agentary", "agentary@localhost"
+	}
+	return []string{"-c", "user.name=" + name, "-c", "user.email=" + email}
+}
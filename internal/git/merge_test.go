@@ -0,0 +1,92 @@
+package git
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMerge_strategies(t *testing.T) {
+	ctx := context.Background()
+	src := sourceRepo(t, 1)
+	home := t.TempDir()
+	mirror := MirrorPath(home, "t", "repo")
+	if _, err := run(ctx, src, "branch", "develop"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		strategy string
+		target   string
+		// parents of the target's new tip and commits it gained
+		parents, commits int
+	}{
+		// Each case lands on main after the previous one, so the later branches must catch up.
+		{StrategyMerge, "", 2, 3},
+		{StrategySquash, "", 1, 1},
+		{StrategyRebase, "", 1, 2},
+		{StrategyFFOnly, "develop", 1, 2},
+	}
+	for i, c := range cases {
+		branch := "agentary/t/T" + c.strategy
+		wt := WorktreePath(home, "t", "repo", int64(i))
+		if _, err := CreateWorktree(ctx, mirror, wt, src, branch); err != nil {
+			t.Fatal(err)
+		}
+		commit(t, wt, c.strategy+"1.txt", "a")
+		commit(t, wt, c.strategy+"2.txt", "b")
+		target := c.target
+		if target == "" {
+			target = "main"
+		}
+		before, _ := run(ctx, mirror, "rev-parse", target)
+		msg := "Task " + c.strategy + "\n\nAgentary-Task: t/T1\n"
+		tip, err := Merge(ctx, wt, branch, MergeOptions{Target: c.target, Strategy: c.strategy, Message: msg, CommitterName: "A", CommitterEmail: "a@example.com"})
+		if err != nil {
+			t.Fatalf("%s: Merge: %v", c.strategy, err)
+		}
+		if got, _ := run(ctx, mirror, "rev-parse", target); got != tip {
+			t.Errorf("%s: %s = %s, want %s", c.strategy, target, got, tip)
+		}
+		parents, _ := run(ctx, mirror, "rev-list", "--parents", "-n", "1", tip)
+		if n := len(strings.Fields(parents)) - 1; n != c.parents {
+			t.Errorf("%s: tip has %d parents, want %d", c.strategy, n, c.parents)
+		}
+		if n, _ := run(ctx, mirror, "rev-list", "--count", before+".."+tip); n != strconv.Itoa(c.commits) {
+			t.Errorf("%s: %s gained %s commits, want %d", c.strategy, target, n, c.commits)
+		}
+		if c.strategy == StrategyMerge || c.strategy == StrategySquash {
+			if body, _ := run(ctx, mirror, "log", "-1", "--format=%B", tip); body != strings.TrimSpace(msg) {
+				t.Errorf("%s: message = %q", c.strategy, body)
+			}
+		}
+	}
+}
+
+func TestMerge_ffOnlyRejectsDivergedTarget(t *testing.T) {
+	ctx := context.Background()
+	src := sourceRepo(t, 1)
+	home := t.TempDir()
+	mirror := MirrorPath(home, "t", "repo")
+	wt := WorktreePath(home, "t", "repo", 1)
+	if _, err := CreateWorktree(ctx, mirror, wt, src, "agentary/t/T1"); err != nil {
+		t.Fatal(err)
+	}
+	commit(t, wt, "task.txt", "task")
+	// Another task lands first.
+	other := WorktreePath(home, "t", "repo", 2)
+	if _, err := CreateWorktree(ctx, mirror, other, src, "agentary/t/T2"); err != nil {
+		t.Fatal(err)
+	}
+	commit(t, other, "other.txt", "other")
+	if err := MergeInWorktree(ctx, other, "agentary/t/T2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Merge(ctx, wt, "agentary/t/T1", MergeOptions{Strategy: StrategyFFOnly}); err == nil {
+		t.Fatal("ff-only merge onto a moved target: expected error")
+	}
+	if _, err := Merge(ctx, wt, "agentary/t/T1", MergeOptions{Target: "nope", Strategy: StrategyMerge}); err == nil {
+		t.Fatal("unknown target: expected error")
+	}
+}
@@ -0,0 +1,205 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// MirrorPath returns the path of the shared bare mirror for a repo: <home>/protected/teams/<team>/mirrors/<repo>.git.
+// Task worktrees for the repo are linked worktrees of this mirror.
+func MirrorPath(home, teamName, repoName string) string {
+	safeTeam := strings.ReplaceAll(teamName, " ", "_")
+	safeRepo := strings.ReplaceAll(repoName, " ", "_")
+	return filepath.Join(home, "protected", "teams", safeTeam, "mirrors", safeRepo+".git")
+}
+
+// mirrorLocks serializes fetches and worktree add/remove per mirror within the process.
+var mirrorLocks sync.Map // mirror path -> *sync.Mutex
+
+func lockMirror(mirrorPath string) func() {
+	mu, _ := mirrorLocks.LoadOrStore(filepath.Clean(mirrorPath), &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// run runs git in dir and returns its trimmed combined output.
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", subcommand(args), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// subcommand is the git command in args, after any leading -c name=value options.
+func subcommand(args []string) string {
+	for len(args) > 2 && args[0] == "-c" {
+		args = args[2:]
+	}
+	return args[0]
+}
+
+// EnsureMirror makes a bare mirror of sourceURL at mirrorPath, or fetches into the existing one. Upstream
+// branches are kept as origin/* remote-tracking refs, so fetching never touches local task branches.
+func EnsureMirror(ctx context.Context, mirrorPath, sourceURL string) error {
+	if mirrorPath == "" || sourceURL == "" {
+		return fmt.Errorf("mirror_path and source_url required")
+	}
+	defer lockMirror(mirrorPath)()
+	return ensureMirror(ctx, mirrorPath, sourceURL)
+}
+
+func ensureMirror(ctx context.Context, mirrorPath, sourceURL string) error {
+	if _, err := os.Stat(filepath.Join(mirrorPath, "HEAD")); err == nil {
+		return fetchMirror(ctx, mirrorPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(mirrorPath), 0o755); err != nil {
+		return err
+	}
+	if _, err := run(ctx, "", "clone", "--bare", sourceURL, mirrorPath); err != nil {
+		_ = os.RemoveAll(mirrorPath)
+		return err
+	}
+	if _, err := run(ctx, mirrorPath, "config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+		return err
+	}
+	return fetchMirror(ctx, mirrorPath)
+}
+
+// FetchMirror fetches new upstream commits into the mirror (incremental; prunes deleted upstream branches).
+func FetchMirror(ctx context.Context, mirrorPath string) error {
+	defer lockMirror(mirrorPath)()
+	return fetchMirror(ctx, mirrorPath)
+}
+
+func fetchMirror(ctx context.Context, mirrorPath string) error {
+	_, err := run(ctx, mirrorPath, "fetch", "--prune", "origin")
+	return err
+}
+
+// baseRef is the upstream branch new task branches start from: origin/main, origin/master, else the mirror's HEAD.
+func baseRef(ctx context.Context, repoDir string) string {
+	for _, ref := range []string{"origin/main", "origin/master", "origin/HEAD"} {
+		if _, err := run(ctx, repoDir, "rev-parse", "--verify", "--quiet", ref); err == nil {
+			return ref
+		}
+	}
+	return "HEAD"
+}
+
+// CreateWorktree creates the task's worktree at worktreePath as a linked worktree of the mirror at mirrorPath
+// (cloned from sourceURL on first use, fetched otherwise) on branch branchName. A new branch starts from the
+// upstream main (or master); an existing branch is checked out as is. Returns baseSHA: the start commit, or
+// the merge-base with upstream for an existing branch.
+// If the worktree already exists it is reused; a directory left behind by a crash is repaired or recreated.
+func CreateWorktree(ctx context.Context, mirrorPath, worktreePath, sourceURL, branchName string) (baseSHA string, err error) {
+	if mirrorPath == "" || worktreePath == "" || sourceURL == "" || branchName == "" {
+		return "", fmt.Errorf("mirror_path, worktree_path, source_url, and branch_name required")
+	}
+	defer lockMirror(mirrorPath)()
+	if err := ensureMirror(ctx, mirrorPath, sourceURL); err != nil {
+		return "", err
+	}
+	base := baseRef(ctx, mirrorPath)
+
+	if _, err := os.Stat(worktreePath); err == nil {
+		if _, err := run(ctx, worktreePath, "rev-parse", "HEAD"); err != nil {
+			// Stale directory (e.g. the admin files were pruned or the mirror was recreated): try to reconnect it.
+			_, _ = run(ctx, mirrorPath, "worktree", "repair", worktreePath)
+		}
+		if _, err := run(ctx, worktreePath, "rev-parse", "HEAD"); err == nil {
+			return run(ctx, worktreePath, "merge-base", base, "HEAD")
+		}
+		if err := os.RemoveAll(worktreePath); err != nil {
+			return "", err
+		}
+	}
+	// Drop metadata for worktrees whose directories are gone, so the branch can be checked out again.
+	_, _ = run(ctx, mirrorPath, "worktree", "prune")
+	if err := os.MkdirAll(filepath.Dir(worktreePath), 0o755); err != nil {
+		return "", err
+	}
+	if _, err := run(ctx, mirrorPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+branchName); err == nil {
+		if _, err := run(ctx, mirrorPath, "worktree", "add", worktreePath, branchName); err != nil {
+			return "", err
+		}
+		return run(ctx, worktreePath, "merge-base", base, "HEAD")
+	}
+	if _, err := run(ctx, mirrorPath, "worktree", "add", "-b", branchName, worktreePath, base); err != nil {
+		return "", err
+	}
+	return run(ctx, worktreePath, "rev-parse", "HEAD")
+}
+
+// commonDir returns the repository a linked worktree belongs to, or "" if worktreePath is not a linked worktree.
+func commonDir(ctx context.Context, worktreePath string) string {
+	dir, err := run(ctx, worktreePath, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return ""
+	}
+	gitDir, err := run(ctx, worktreePath, "rev-parse", "--path-format=absolute", "--git-dir")
+	if err != nil || gitDir == dir {
+		return "" // a standalone clone
+	}
+	return dir
+}
+
+// DeleteWorktree removes the task worktree. Linked worktrees are removed with git worktree remove (the branch
+// stays in the mirror); a plain directory is deleted. If worktreePath is empty or the path doesn't exist,
+// no-op (returns nil).
+func DeleteWorktree(ctx context.Context, worktreePath string) error {
+	if worktreePath == "" {
+		return nil
+	}
+	if _, err := os.Stat(worktreePath); os.IsNotExist(err) {
+		return nil
+	}
+	mirror := commonDir(ctx, worktreePath)
+	if mirror == "" {
+		return os.RemoveAll(worktreePath)
+	}
+	defer lockMirror(mirror)()
+	if _, err := run(ctx, mirror, "worktree", "remove", "--force", worktreePath); err != nil {
+		if rmErr := os.RemoveAll(worktreePath); rmErr != nil {
+			return errors.Join(err, rmErr)
+		}
+		_, pruneErr := run(ctx, mirror, "worktree", "prune")
+		return pruneErr
+	}
+	return nil
+}
+
+// PruneWorktrees drops the mirror's metadata for worktrees whose directories no longer exist.
+func PruneWorktrees(ctx context.Context, mirrorPath string) error {
+	defer lockMirror(mirrorPath)()
+	_, err := run(ctx, mirrorPath, "worktree", "prune")
+	return err
+}
+
+// RepairWorktrees fixes the links between the mirror and its worktrees after a crash or a move: each of paths
+// (existing worktree directories) is reconnected to the mirror, then metadata for missing worktrees is pruned.
+// Paths that cannot be repaired are reported in the returned error; the rest are still repaired.
+func RepairWorktrees(ctx context.Context, mirrorPath string, paths ...string) error {
+	defer lockMirror(mirrorPath)()
+	var errs []error
+	if _, err := run(ctx, mirrorPath, "worktree", "repair"); err != nil {
+		errs = append(errs, err)
+	}
+	for _, p := range paths {
+		if _, err := run(ctx, mirrorPath, "worktree", "repair", p); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p, err))
+		}
+	}
+	if _, err := run(ctx, mirrorPath, "worktree", "prune"); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
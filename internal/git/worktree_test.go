@@ -0,0 +1,143 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sourceRepo makes a repo on main with n commits and returns its path.
+func sourceRepo(t *testing.T, n int) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := filepath.Join(t.TempDir(), "src")
+	ctx := context.Background()
+	if _, err := run(ctx, "", "init", "-q", "-b", "main", dir); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		commit(t, dir, "f.txt", strings.Repeat("x", i+1))
+	}
+	return dir
+}
+
+func commit(t *testing.T, dir, file, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := run(ctx, dir, "add", file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(ctx, dir, "-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "-m", "update "+file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateWorktree_sharedMirror(t *testing.T) {
+	ctx := context.Background()
+	src := sourceRepo(t, 3)
+	home := t.TempDir()
+	mirror := MirrorPath(home, "team one", "repo")
+	if want := filepath.Join(home, "protected", "teams", "team_one", "mirrors", "repo.git"); mirror != want {
+		t.Fatalf("MirrorPath = %q, want %q", mirror, want)
+	}
+	wt1, wt2 := WorktreePath(home, "team one", "repo", 1), WorktreePath(home, "team one", "repo", 2)
+
+	base1, err := CreateWorktree(ctx, mirror, wt1, src, "agentary/t/T1")
+	if err != nil {
+		t.Fatalf("CreateWorktree: %v", err)
+	}
+	// Full history, unlike a shallow clone.
+	if n, _ := run(ctx, wt1, "rev-list", "--count", "HEAD"); n != "3" {
+		t.Errorf("history = %s commits, want 3", n)
+	}
+	// Upstream moves on: the second worktree starts from the fetched tip.
+	commit(t, src, "g.txt", "new")
+	base2, err := CreateWorktree(ctx, mirror, wt2, src, "agentary/t/T2")
+	if err != nil {
+		t.Fatalf("CreateWorktree T2: %v", err)
+	}
+	if base2 == base1 {
+		t.Error("second worktree did not start from the fetched upstream tip")
+	}
+	if common := commonDir(ctx, wt2); common != mirror {
+		t.Errorf("worktree common dir = %q, want mirror %q", common, mirror)
+	}
+	// Reusing an existing worktree returns the same base.
+	if again, err := CreateWorktree(ctx, mirror, wt1, src, "agentary/t/T1"); err != nil || again != base1 {
+		t.Errorf("CreateWorktree again = %q, %v; want %q", again, err, base1)
+	}
+
+	// Merging leaves the worktree detached and moves main in the mirror.
+	commit(t, wt2, "h.txt", "task")
+	if err := MergeInWorktree(ctx, wt2, "agentary/t/T2"); err != nil {
+		t.Fatalf("MergeInWorktree: %v", err)
+	}
+	tip, _ := run(ctx, wt2, "rev-parse", "agentary/t/T2")
+	if main, _ := run(ctx, mirror, "rev-parse", "main"); main != tip {
+		t.Errorf("main = %s, want the task tip %s", main, tip)
+	}
+
+	if err := DeleteWorktree(ctx, wt2); err != nil {
+		t.Fatalf("DeleteWorktree: %v", err)
+	}
+	if _, err := os.Stat(wt2); !os.IsNotExist(err) {
+		t.Error("worktree directory still exists")
+	}
+	if list, _ := run(ctx, mirror, "worktree", "list"); strings.Contains(list, wt2) {
+		t.Errorf("worktree still registered:\n%s", list)
+	}
+	if _, err := run(ctx, mirror, "rev-parse", "--verify", "refs/heads/agentary/t/T2"); err != nil {
+		t.Error("DeleteWorktree removed the task branch")
+	}
+}
+
+func TestCreateWorktree_afterCrash(t *testing.T) {
+	ctx := context.Background()
+	src := sourceRepo(t, 2)
+	home := t.TempDir()
+	mirror := MirrorPath(home, "t", "repo")
+	wt := WorktreePath(home, "t", "repo", 7)
+	if _, err := CreateWorktree(ctx, mirror, wt, src, "agentary/t/T7"); err != nil {
+		t.Fatal(err)
+	}
+	commit(t, wt, "work.txt", "in progress")
+	tip, _ := run(ctx, wt, "rev-parse", "HEAD")
+
+	// The worktree directory vanishes without git worktree remove: its metadata is stale.
+	if err := os.RemoveAll(wt); err != nil {
+		t.Fatal(err)
+	}
+	if err := RepairWorktrees(ctx, mirror); err != nil {
+		t.Fatalf("RepairWorktrees: %v", err)
+	}
+	if list, _ := run(ctx, mirror, "worktree", "list"); strings.Contains(list, wt) {
+		t.Errorf("stale worktree not pruned:\n%s", list)
+	}
+	// Recreating checks out the existing branch with the task's commits.
+	if _, err := CreateWorktree(ctx, mirror, wt, src, "agentary/t/T7"); err != nil {
+		t.Fatalf("CreateWorktree after crash: %v", err)
+	}
+	if head, _ := run(ctx, wt, "rev-parse", "HEAD"); head != tip {
+		t.Errorf("HEAD = %s, want the branch tip %s", head, tip)
+	}
+
+	// A moved mirror is reconnected by repairing with the worktree paths.
+	moved := mirror + ".moved"
+	if err := os.Rename(mirror, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := RepairWorktrees(ctx, moved, wt); err != nil {
+		t.Fatalf("RepairWorktrees moved: %v", err)
+	}
+	if _, err := run(ctx, wt, "status", "--short"); err != nil {
+		t.Errorf("worktree broken after repair: %v", err)
+	}
+}
@@ -5,10 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/ankittk/agentary/internal/checks"
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/review"
 )
 
 // TestHandlers exercises many server routes to improve coverage of server.go.
@@ -101,15 +109,166 @@ func TestHandlers(t *testing.T) {
 	if wfResp != nil && wfResp.StatusCode != http.StatusOK {
 		t.Fatalf("GET workflows: %d", wfResp.StatusCode)
 	}
-	wfPostResp, _ := http.Post(ts.URL+"/teams/h1/workflows", "application/json", strings.NewReader(`{"name":"w1","version":1,"source":"builtin:w1"}`))
+	wfPostResp, _ := http.Post(ts.URL+"/teams/h1/workflows", "application/json", strings.NewReader(`{"name":"w1","version":1,"source":"builtin:solo"}`))
 	if wfPostResp != nil {
 		_ = wfPostResp.Body.Close()
+		if wfPostResp.StatusCode != http.StatusOK {
+			t.Fatalf("POST workflows builtin: %d", wfPostResp.StatusCode)
+		}
+	}
+	// File paths are never read by the API, for create or lint
+	for _, path := range []string{"/teams/h1/workflows", "/teams/h1/workflows/lint"} {
+		resp, _ := http.Post(ts.URL+path, "application/json", strings.NewReader(`{"source":"/etc/passwd"}`))
+		var e map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || !strings.Contains(fmt.Sprint(e["error"]), "builtin:") {
+			t.Fatalf("POST %s with a file source: %d %v", path, resp.StatusCode, e)
+		}
+	}
+	wfBadResp, _ := http.Post(ts.URL+"/teams/h1/workflows", "application/json", strings.NewReader(`{"definition":"name: bad\nstages:\n  - name: A\n    type: robot\n"}`))
+	if wfBadResp != nil {
+		var e map[string]any
+		_ = json.NewDecoder(wfBadResp.Body).Decode(&e)
+		_ = wfBadResp.Body.Close()
+		if wfBadResp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("POST invalid workflow definition: %d", wfBadResp.StatusCode)
+		}
+		if msg, _ := e["error"].(string); !strings.Contains(msg, "definition:3:") {
+			t.Fatalf("invalid definition error should carry line number: %v", e)
+		}
+	}
+	lintResp, _ := http.Post(ts.URL+"/teams/h1/workflows/lint", "application/json", strings.NewReader(`{"definition":"name: l\nstages:\n  - name: A\n    type: agent\n    outcomes: [go]\n  - name: Done\n    type: terminal\n"}`))
+	if lintResp != nil {
+		var lint struct {
+			OK          bool
+			Diagnostics []map[string]any
+		}
+		_ = json.NewDecoder(lintResp.Body).Decode(&lint)
+		_ = lintResp.Body.Close()
+		if lintResp.StatusCode != http.StatusOK || lint.OK || len(lint.Diagnostics) == 0 {
+			t.Fatalf("POST workflows/lint: %d %+v", lintResp.StatusCode, lint)
+		}
+		if lint.Diagnostics[0]["code"] != "no_exit" || lint.Diagnostics[0]["line"] != float64(3) {
+			t.Fatalf("lint diagnostic = %v", lint.Diagnostics[0])
+		}
+	}
+	storedLintResp, _ := http.Get(ts.URL + "/teams/h1/workflows/w1/lint?version=1")
+	if storedLintResp != nil {
+		var lint map[string]any
+		_ = json.NewDecoder(storedLintResp.Body).Decode(&lint)
+		_ = storedLintResp.Body.Close()
+		if storedLintResp.StatusCode != http.StatusOK || lint["ok"] != true {
+			t.Fatalf("GET workflows/w1/lint: %d %v", storedLintResp.StatusCode, lint)
+		}
 	}
 	wfInitResp, _ := http.Post(ts.URL+"/teams/h1/workflows/init", "application/json", nil)
 	if wfInitResp != nil {
 		_ = wfInitResp.Body.Close()
 	}
 
+	// Workflow migration: validation, dry run, apply and the audit log
+	_, _ = http.Post(ts.URL+"/teams", "application/json", strings.NewReader(`{"name":"mig"}`))
+	_, _ = http.Post(ts.URL+"/teams/mig/workflows", "application/json", strings.NewReader(`{"name":"flow","version":1,"source":"builtin:default"}`))
+	v2, _ := json.Marshal(map[string]any{"name": "flow", "version": 2, "definition": `stages:
+  - {name: Coding, type: agent, outcomes: [submit_for_review]}
+  - {name: Review, type: human, outcomes: [approved, changes_requested]}
+  - {name: Done, type: terminal}
+transitions:
+  - {from: Coding, outcome: submit_for_review, to: Review}
+  - {from: Review, outcome: approved, to: Done}
+  - {from: Review, outcome: changes_requested, to: Coding}
+`})
+	if resp, _ := http.Post(ts.URL+"/teams/mig/workflows", "application/json", bytes.NewReader(v2)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST workflow v2: %d", resp.StatusCode)
+	}
+	migWF, _ := app.Store.GetWorkflowIDByTeamAndName(context.Background(), "mig", "flow", 1)
+	migTask, _ := app.Store.CreateTask(context.Background(), "mig", "move me", "todo", &migWF)
+	_ = app.Store.SetTaskWorkflowAndStage(context.Background(), migTask, migWF, "InReview")
+	migrate := func(body string) (int, map[string]any) {
+		resp, _ := http.Post(ts.URL+"/teams/mig/workflows/migrate", "application/json", strings.NewReader(body))
+		var out map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		_ = resp.Body.Close()
+		return resp.StatusCode, out
+	}
+	for _, bad := range []string{`{`, `{"from":"flow@1"}`, `{"from":"flow@1","to":"flow@9"}`, `{"from":"flow@1","to":"flow@2"}`} {
+		if code, out := migrate(bad); code != http.StatusBadRequest {
+			t.Fatalf("migrate %s: %d %v, want 400", bad, code, out)
+		}
+	}
+	if resp, _ := http.Get(ts.URL + "/teams/mig/workflows/migrate"); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("GET migrate: %d", resp.StatusCode)
+	}
+	code, out := migrate(`{"from":"flow@1","to":"flow@2","stage_map":{"InReview":"Review"},"dry_run":true}`)
+	if moves, _ := out["moves"].([]any); code != http.StatusOK || out["dry_run"] != true || len(moves) != 1 || out["migration_id"] != nil {
+		t.Fatalf("dry-run migrate: %d %v", code, out)
+	}
+	if task, _ := app.Store.GetTaskByIDAndTeam(context.Background(), "mig", migTask); task.CurrentStage == nil || *task.CurrentStage != "InReview" {
+		t.Fatalf("dry run moved the task: %+v", task)
+	}
+	code, out = migrate(`{"from":"flow@1","to":"flow@2","stage_map":{"InReview":"Review"}}`)
+	if code != http.StatusOK || out["migration_id"] == nil {
+		t.Fatalf("migrate: %d %v", code, out)
+	}
+	if task, _ := app.Store.GetTaskByIDAndTeam(context.Background(), "mig", migTask); task.CurrentStage == nil || *task.CurrentStage != "Review" {
+		t.Fatalf("migrated task: %+v", task)
+	}
+	migrationsResp, _ := http.Get(ts.URL + "/teams/mig/workflows/migrations")
+	var migrations []map[string]any
+	_ = json.NewDecoder(migrationsResp.Body).Decode(&migrations)
+	_ = migrationsResp.Body.Close()
+	if len(migrations) != 1 {
+		t.Fatalf("GET migrations = %v", migrations)
+	}
+
+	// Team pause/resume and global scheduler pause
+	pauseResp, _ := http.Post(ts.URL+"/teams/h1/pause", "application/json", strings.NewReader(`{"reason":"migration"}`))
+	var pausedTeam map[string]any
+	_ = json.NewDecoder(pauseResp.Body).Decode(&pausedTeam)
+	if pauseResp.StatusCode != http.StatusOK || pausedTeam["Paused"] != true || pausedTeam["PauseReason"] != "migration" {
+		t.Fatalf("POST pause: %d %v", pauseResp.StatusCode, pausedTeam)
+	}
+	resumeResp, _ := http.Post(ts.URL+"/teams/h1/resume", "application/json", nil)
+	if resumeResp.StatusCode != http.StatusOK {
+		t.Fatalf("POST resume: %d", resumeResp.StatusCode)
+	}
+	if r, _ := http.Post(ts.URL+"/teams/nope/pause", "application/json", nil); r.StatusCode != http.StatusNotFound {
+		t.Fatalf("POST pause unknown team: %d", r.StatusCode)
+	}
+	schedPause, _ := http.Post(ts.URL+"/scheduler/pause", "application/json", nil)
+	if schedPause.StatusCode != http.StatusOK {
+		t.Fatalf("POST /scheduler/pause: %d", schedPause.StatusCode)
+	}
+	schedGet, _ := http.Get(ts.URL + "/scheduler")
+	var schedState map[string]any
+	_ = json.NewDecoder(schedGet.Body).Decode(&schedState)
+	if schedState["Paused"] != true {
+		t.Fatalf("GET /scheduler after pause: %v", schedState)
+	}
+	_, _ = http.Post(ts.URL+"/scheduler/resume", "application/json", nil)
+
+	// Schedules POST/GET/DELETE
+	schBad, _ := http.Post(ts.URL+"/teams/h1/schedules", "application/json", strings.NewReader(`{"name":"s1","title":"x","cron":"bogus"}`))
+	if schBad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("POST schedules invalid cron: %d", schBad.StatusCode)
+	}
+	schPost, _ := http.Post(ts.URL+"/teams/h1/schedules", "application/json", strings.NewReader(`{"name":"s1","title":"Weekly deps {{.Week}}","cron":"0 9 * * 1","labels":"chore"}`))
+	if schPost.StatusCode != http.StatusOK {
+		t.Fatalf("POST schedules: %d", schPost.StatusCode)
+	}
+	schList, _ := http.Get(ts.URL + "/teams/h1/schedules")
+	var schedules []map[string]any
+	_ = json.NewDecoder(schList.Body).Decode(&schedules)
+	if len(schedules) != 1 || schedules[0]["Name"] != "s1" {
+		t.Fatalf("GET schedules: %v", schedules)
+	}
+	schDel, _ := http.NewRequest(http.MethodDelete, ts.URL+"/teams/h1/schedules/s1", nil)
+	schDelResp, _ := http.DefaultClient.Do(schDel)
+	if schDelResp.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE schedule: %d", schDelResp.StatusCode)
+	}
+
 	// Task comments GET/POST
 	commentsResp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/comments", ts.URL, taskID))
 	if commentsResp.StatusCode != http.StatusOK {
@@ -197,6 +356,211 @@ func TestHandlers(t *testing.T) {
 		t.Fatalf("GET task: %d", getTask.StatusCode)
 	}
 
+	// Repo checks PUT/GET and a task's check attempts
+	repoResp, _ := http.Post(ts.URL+"/teams/h1/repos", "application/json", strings.NewReader(`{"name":"app","source":"/tmp/app"}`))
+	_ = repoResp.Body.Close()
+	badChecks, _ := http.NewRequest(http.MethodPut, ts.URL+"/teams/h1/repos/app/checks", strings.NewReader(`{"checks":[{"name":"unit","command":""}]}`))
+	if resp, _ := http.DefaultClient.Do(badChecks); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("PUT checks without a command: %d", resp.StatusCode)
+	}
+	putChecks, _ := http.NewRequest(http.MethodPut, ts.URL+"/teams/h1/repos/app/checks", strings.NewReader(`{"checks":[{"name":"lint","command":"make lint","required":false},{"name":"unit","command":"go test ./...","timeout_seconds":60,"env":{"CGO_ENABLED":"0"}}]}`))
+	checksResp, _ := http.DefaultClient.Do(putChecks)
+	var checksBody struct {
+		Checks []checks.Spec `json:"checks"`
+	}
+	_ = json.NewDecoder(checksResp.Body).Decode(&checksBody)
+	_ = checksResp.Body.Close()
+	if len(checksBody.Checks) != 2 || *checksBody.Checks[0].Required || !*checksBody.Checks[1].Required || checksBody.Checks[1].Env["CGO_ENABLED"] != "0" {
+		t.Fatalf("PUT checks = %+v", checksBody.Checks)
+	}
+	taskChecks, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/checks", ts.URL, taskID))
+	var taskChecksBody struct {
+		Attempts []checks.Attempt `json:"attempts"`
+	}
+	_ = json.NewDecoder(taskChecks.Body).Decode(&taskChecksBody)
+	_ = taskChecks.Body.Close()
+	if taskChecks.StatusCode != http.StatusOK || taskChecksBody.Attempts == nil {
+		t.Fatalf("GET task checks: %d %+v", taskChecks.StatusCode, taskChecksBody)
+	}
+	if resp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/checks?attempt=1", ts.URL, taskID)); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET missing check attempt: %d", resp.StatusCode)
+	}
+
+	// Structured diff: a task without a worktree has no files; unknown attempts and bad numbers are rejected
+	diffFiles, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/diff/files?stat=1", ts.URL, taskID))
+	var diffFilesBody struct {
+		Files  []json.RawMessage `json:"files"`
+		Totals map[string]int    `json:"totals"`
+	}
+	_ = json.NewDecoder(diffFiles.Body).Decode(&diffFilesBody)
+	_ = diffFiles.Body.Close()
+	if diffFiles.StatusCode != http.StatusOK || diffFilesBody.Files == nil || diffFilesBody.Totals["files"] != 0 {
+		t.Fatalf("GET diff/files: %d %+v", diffFiles.StatusCode, diffFilesBody)
+	}
+	if resp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/diff/files?from_attempt=3", ts.URL, taskID)); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET diff/files for a missing attempt: %d", resp.StatusCode)
+	}
+	if resp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/diff/files?limit=all", ts.URL, taskID)); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("GET diff/files with a bad limit: %d", resp.StatusCode)
+	}
+	if resp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/diff/files?limit=10abc", ts.URL, taskID)); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("GET diff/files with trailing junk in limit: %d", resp.StatusCode)
+	}
+
+	// Export: a task without a branch has nothing to export; a task branch downloads as patches or a bundle
+	if resp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/patch", ts.URL, taskID)); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET patch without a branch: %d", resp.StatusCode)
+	}
+	if resp, _ := http.Post(fmt.Sprintf("%s/teams/h1/tasks/%d/bundle", ts.URL, taskID), "application/json", nil); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("POST bundle: %d", resp.StatusCode)
+	}
+	exportWT := t.TempDir()
+	gitIn := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		cmd.Dir = exportWT
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	gitIn("init", "-q", "-b", "feature")
+	gitIn("commit", "-q", "--allow-empty", "-m", "base")
+	exportBase := gitIn("rev-parse", "HEAD")
+	_ = os.WriteFile(filepath.Join(exportWT, "new.go"), []byte("package x\n"), 0o644)
+	gitIn("add", "new.go")
+	gitIn("commit", "-q", "-m", "add new.go")
+	exportTask, _ := app.Store.CreateTask(context.Background(), "h1", "export me", "todo", nil)
+	exportBranch := "feature"
+	_ = app.Store.UpdateTaskGitFields(context.Background(), exportTask, &exportWT, &exportBranch, &exportBase, nil)
+	patchResp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/patch", ts.URL, exportTask))
+	patchText, _ := io.ReadAll(patchResp.Body)
+	_ = patchResp.Body.Close()
+	if patchResp.StatusCode != http.StatusOK || !strings.Contains(string(patchText), "Subject: [PATCH] add new.go") ||
+		patchResp.Header.Get("Content-Disposition") != fmt.Sprintf(`attachment; filename="app-T%d.patch"`, exportTask) {
+		t.Fatalf("GET patch: %d %v\n%s", patchResp.StatusCode, patchResp.Header, patchText)
+	}
+	bundleResp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/bundle", ts.URL, exportTask))
+	bundleData, _ := io.ReadAll(bundleResp.Body)
+	_ = bundleResp.Body.Close()
+	if bundleResp.StatusCode != http.StatusOK || !bytes.HasPrefix(bundleData, []byte("# v2 git bundle")) {
+		t.Fatalf("GET bundle: %d %q", bundleResp.StatusCode, bundleData[:min(len(bundleData), 40)])
+	}
+
+	// Repo approval policies are validated; a task's approval decisions start empty
+	badApproval, _ := http.NewRequest(http.MethodPatch, ts.URL+"/teams/h1/repos/app", strings.NewReader(`{"approval":"auto_if:vibes"}`))
+	if resp, _ := http.DefaultClient.Do(badApproval); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("PATCH repo with a bad approval policy: %d", resp.StatusCode)
+	}
+	setApproval, _ := http.NewRequest(http.MethodPatch, ts.URL+"/teams/h1/repos/app", strings.NewReader(`{"approval":"auto_if:max_diff_lines:200"}`))
+	if resp, _ := http.DefaultClient.Do(setApproval); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PATCH repo approval policy: %d", resp.StatusCode)
+	}
+	approvalResp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/approval", ts.URL, taskID))
+	var approvalBody struct {
+		Policy    string            `json:"policy"`
+		Decisions []json.RawMessage `json:"decisions"`
+	}
+	_ = json.NewDecoder(approvalResp.Body).Decode(&approvalBody)
+	_ = approvalResp.Body.Close()
+	if approvalResp.StatusCode != http.StatusOK || approvalBody.Policy != "auto_if:max_diff_lines:200" || approvalBody.Decisions == nil {
+		t.Fatalf("GET task approval: %d %+v", approvalResp.StatusCode, approvalBody)
+	}
+
+	// Inline review comments: start a thread, reply, resolve, and filter open threads
+	if resp, _ := http.Post(fmt.Sprintf("%s/teams/h1/tasks/%d/review-comments", ts.URL, taskID), "application/json", strings.NewReader(`{"path":"a.go","start_line":0,"body":"x"}`)); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("POST review comment with a bad range: %d", resp.StatusCode)
+	}
+	threadResp, _ := http.Post(fmt.Sprintf("%s/teams/h1/tasks/%d/review-comments", ts.URL, taskID), "application/json", strings.NewReader(`{"author":"carol","path":"a.go","start_line":3,"end_line":5,"commit_sha":"abc","body":"split this"}`))
+	var threadBody struct {
+		CommentID int64 `json:"comment_id"`
+	}
+	_ = json.NewDecoder(threadResp.Body).Decode(&threadBody)
+	_ = threadResp.Body.Close()
+	if threadResp.StatusCode != http.StatusOK || threadBody.CommentID == 0 {
+		t.Fatalf("POST review comment: %d %+v", threadResp.StatusCode, threadBody)
+	}
+	_, _ = http.Post(fmt.Sprintf("%s/teams/h1/tasks/%d/review-comments", ts.URL, taskID), "application/json", strings.NewReader(fmt.Sprintf(`{"body":"ok","in_reply_to":%d}`, threadBody.CommentID)))
+	threadsResp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/review-comments", ts.URL, taskID))
+	var threadsBody struct {
+		Threads []review.Thread `json:"threads"`
+	}
+	_ = json.NewDecoder(threadsResp.Body).Decode(&threadsBody)
+	_ = threadsResp.Body.Close()
+	if len(threadsBody.Threads) != 1 || len(threadsBody.Threads[0].Comments) != 2 || threadsBody.Threads[0].EndLine != 5 || threadsBody.Threads[0].Comments[1].Author != "api" {
+		t.Fatalf("GET review comments = %+v", threadsBody.Threads)
+	}
+	if resp, _ := http.Post(fmt.Sprintf("%s/teams/h1/tasks/%d/review-comments/%d/resolve", ts.URL, taskID, threadBody.CommentID), "application/json", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("resolve thread: %d", resp.StatusCode)
+	}
+	if resp, _ := http.Post(fmt.Sprintf("%s/teams/h1/tasks/%d/review-comments/999/reopen", ts.URL, taskID), "application/json", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("reopen a missing thread: %d", resp.StatusCode)
+	}
+	openResp, _ := http.Get(fmt.Sprintf("%s/teams/h1/tasks/%d/review-comments?open=1", ts.URL, taskID))
+	threadsBody.Threads = nil
+	_ = json.NewDecoder(openResp.Body).Decode(&threadsBody)
+	_ = openResp.Body.Close()
+	if threadsBody.Threads == nil || len(threadsBody.Threads) != 0 {
+		t.Fatalf("GET open review comments = %+v", threadsBody.Threads)
+	}
+
+	// worktrees: usage, quota, dry-run and real gc, /metrics
+	orphan := git.WorktreePath(home, "h1", "r1", 999)
+	if err := os.MkdirAll(orphan, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(orphan, "f"), []byte("stale"), 0o644)
+	req, _ := http.NewRequest(http.MethodPut, ts.URL+"/teams/h1/worktrees/quota", strings.NewReader(`{"quota":"lots"}`))
+	if resp, _ := http.DefaultClient.Do(req); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("PUT invalid quota: %d", resp.StatusCode)
+	}
+	req, _ = http.NewRequest(http.MethodPut, ts.URL+"/teams/h1/worktrees/quota", strings.NewReader(`{"quota":"1"}`))
+	if resp, _ := http.DefaultClient.Do(req); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT quota: %d", resp.StatusCode)
+	}
+	usageResp, _ := http.Get(ts.URL + "/teams/h1/worktrees")
+	var usage struct {
+		Bytes     int64 `json:"bytes"`
+		Quota     int64 `json:"quota"`
+		Worktrees []struct {
+			TaskID      int64 `json:"task_id"`
+			Collectable bool  `json:"collectable"`
+		} `json:"worktrees"`
+	}
+	_ = json.NewDecoder(usageResp.Body).Decode(&usage)
+	_ = usageResp.Body.Close()
+	if usage.Bytes != 5 || usage.Quota != 1 || len(usage.Worktrees) != 1 || !usage.Worktrees[0].Collectable {
+		t.Fatalf("GET worktrees = %+v", usage)
+	}
+	var gcBody struct {
+		DryRun bool `json:"dry_run"`
+		Report struct {
+			Removed []struct {
+				Reason string `json:"reason"`
+			} `json:"removed"`
+		} `json:"report"`
+	}
+	gcResp, _ := http.Post(ts.URL+"/teams/h1/worktrees/gc?dry_run=1", "application/json", nil)
+	_ = json.NewDecoder(gcResp.Body).Decode(&gcBody)
+	_ = gcResp.Body.Close()
+	if _, err := os.Stat(orphan); err != nil || !gcBody.DryRun || len(gcBody.Report.Removed) != 1 || gcBody.Report.Removed[0].Reason != "task missing, over quota" {
+		t.Fatalf("dry-run gc = %+v, stat %v", gcBody, err)
+	}
+	gcResp, _ = http.Post(ts.URL+"/teams/h1/worktrees/gc", "application/json", nil)
+	_ = gcResp.Body.Close()
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Fatalf("gc left %s: %v", orphan, err)
+	}
+	metricsBody, _ := http.Get(ts.URL + "/metrics")
+	metricsText, _ := io.ReadAll(metricsBody.Body)
+	_ = metricsBody.Body.Close()
+	if !strings.Contains(string(metricsText), `agentary_worktree_gc_freed_bytes_total{team="h1"} 5`) {
+		t.Fatalf("/metrics missing worktree gc totals:\n%s", metricsText)
+	}
+	if resp, _ := http.Get(ts.URL + "/teams/h1/worktrees/gc"); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("GET worktrees/gc: %d", resp.StatusCode)
+	}
+
 	// request-review and approve (workflow task via API: init default workflow then create task)
 	_, _ = http.Post(ts.URL+"/teams", "application/json", strings.NewReader(`{"name":"wfteam"}`))
 	_, _ = http.Post(ts.URL+"/teams/wfteam/workflows/init", "application/json", nil)
@@ -5,22 +5,31 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ankittk/agentary/internal/capabilities"
+	"github.com/ankittk/agentary/internal/checks"
 	"github.com/ankittk/agentary/internal/git"
 	"github.com/ankittk/agentary/internal/memory"
+	"github.com/ankittk/agentary/internal/merge"
 	"github.com/ankittk/agentary/internal/otel"
 	"github.com/ankittk/agentary/internal/review"
+	"github.com/ankittk/agentary/internal/schedule"
 	"github.com/ankittk/agentary/internal/store"
 	"github.com/ankittk/agentary/internal/store/postgres"
 	"github.com/ankittk/agentary/internal/ui"
+	"github.com/ankittk/agentary/internal/workflow"
+	"github.com/ankittk/agentary/internal/worktrees"
 	"github.com/ankittk/agentary/pkg/models"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
@@ -77,6 +86,7 @@ type App struct {
 	Store        store.Store
 	Capabilities *capabilities.Registry // optional; loaded from env (e.g. SLACK_WEBHOOK_URL)
 	Home         string                 // data directory; for team/agent dirs and charter
+	Worktrees    *worktrees.Collector   // task worktree GC; its latest figures are served on /metrics
 }
 
 // NewServer builds an HTTP server from options; kept for backward compatibility (prefer NewApp).
@@ -105,6 +115,19 @@ func NewApp(opts ServerOptions) (*App, error) {
 	}
 	_ = st.SeedDemo(context.Background())
 
+	reg := capabilities.NewRegistry()
+	if u := os.Getenv("SLACK_WEBHOOK_URL"); u != "" {
+		reg.Register("slack", capabilities.SlackWebhook{WebhookURL: u})
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		if repo := os.Getenv("GITHUB_OWNER_REPO"); repo != "" {
+			reg.Register("github", capabilities.GitHubNotifier{Token: token, OwnerRepo: repo})
+		}
+	}
+	gc := &worktrees.Collector{Store: st, Home: opts.Home, Publish: hub.PublishJSON}
+	// eng applies outcomes submitted over the API (approve, reviews) with guards and hooks.
+	eng := &workflow.Engine{Store: st, Home: opts.Home, Capabilities: reg}
+
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
@@ -137,6 +160,7 @@ func NewApp(opts ServerOptions) (*App, error) {
 			_, _ = fmt.Fprintf(w, "agentary_tasks_total{status=\"in_progress\"} %d\n", inProgress)
 			_, _ = fmt.Fprintf(w, "agentary_tasks_total{status=\"done\"} %d\n", done)
 			_, _ = fmt.Fprintf(w, "agentary_tasks_total{status=\"failed\"} %d\n", failed)
+			writeWorktreeMetrics(w, gc.Stats())
 		})
 	}
 
@@ -220,6 +244,35 @@ func NewApp(opts ServerOptions) (*App, error) {
 		}
 
 		switch parts[1] {
+		case "pause", "resume":
+			// POST /teams/{team}/pause (optional body {"reason"}) | /teams/{team}/resume
+			if r.Method != http.MethodPost {
+				writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			reason, ok := decodePauseReason(w, r)
+			if !ok {
+				return
+			}
+			paused := parts[1] == "pause"
+			if err := st.SetTeamPaused(r.Context(), team, paused, reason); err != nil {
+				writeJSONError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			t, err := st.GetTeamByName(r.Context(), team)
+			if err != nil {
+				writeJSONError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			hub.PublishJSON(map[string]any{"type": "scheduler_state", "team": team, "paused": t.Paused, "reason": t.PauseReason})
+			writeJSON(w, t)
+			return
+
+		case "worktrees":
+			// /teams/{team}/worktrees[/quota|/gc] — task worktree disk usage, quota and GC
+			handleWorktrees(w, r, st, gc, hub, opts.Home, team, parts[2:])
+			return
+
 		case "tasks":
 			// /teams/{team}/tasks/{id} or /teams/{team}/tasks/{id}/comments|attachments|dependencies
 			if len(parts) >= 3 && parts[2] != "" {
@@ -372,47 +425,47 @@ func NewApp(opts ServerOptions) (*App, error) {
 						writeJSONError(w, http.StatusBadRequest, "task has no workflow or current stage")
 						return
 					}
-					transitions, err := st.GetWorkflowTransitions(r.Context(), *task.WorkflowID)
+					ctx := store.WithTransitionCause(r.Context(), store.TransitionCause{Actor: "human"})
+					nextStage, err := eng.ApplyOutcome(ctx, team, task, body.Outcome)
+					var guardErr *workflow.GuardError
+					if errors.As(err, &guardErr) {
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(http.StatusConflict)
+						_ = json.NewEncoder(w).Encode(map[string]any{"error": guardErr.Error(), "guards": guardErr.Results})
+						return
+					}
 					if err != nil {
 						writeJSONError(w, http.StatusInternalServerError, err.Error())
 						return
 					}
-					var nextStage string
-					for _, tr := range transitions {
-						if tr.FromStage == *task.CurrentStage && tr.Outcome == body.Outcome {
-							nextStage = tr.ToStage
-							break
-						}
-					}
 					if nextStage == "" {
 						writeJSONError(w, http.StatusBadRequest, "no transition for stage "+*task.CurrentStage+" with outcome "+body.Outcome)
 						return
 					}
-					if err := st.UpdateTaskStage(r.Context(), taskID, nextStage); err != nil {
-						writeJSONError(w, http.StatusInternalServerError, err.Error())
-						return
-					}
-					stages, _ := st.GetWorkflowStages(r.Context(), *task.WorkflowID)
-					isTerminal := false
-					for _, s := range stages {
-						if s.StageName == nextStage && s.StageType == "terminal" {
-							isTerminal = true
-							break
-						}
-					}
-					if isTerminal {
-						_ = st.UpdateTask(r.Context(), taskID, models.StatusDone, nil)
-					}
 					hub.PublishJSON(map[string]any{"type": "task_update", "team": team, "task_id": taskID, "current_stage": nextStage})
 					writeJSON(w, map[string]any{"ok": true, "current_stage": nextStage})
 					return
 				}
-				// /teams/{team}/tasks/{id}/diff — GET diff (base_sha → branch tip) for review UI
+				// /teams/{team}/tasks/{id}/patch and /bundle — GET the task branch as a patch series or git bundle
+				if len(parts) == 4 && (parts[3] == git.ExportPatch || parts[3] == git.ExportBundle) {
+					if r.Method != http.MethodGet {
+						writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+						return
+					}
+					handleTaskExport(w, r, st, opts.Home, team, task, parts[3])
+					return
+				}
+				// /teams/{team}/tasks/{id}/diff — GET diff (base_sha → branch tip) for review UI;
+				// /diff/files — the same diff per file with stats and paged hunks (see handleTaskDiffFiles)
 				if len(parts) >= 4 && parts[3] == "diff" {
 					if r.Method != http.MethodGet {
 						writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 						return
 					}
+					if len(parts) >= 5 && parts[4] == "files" {
+						handleTaskDiffFiles(w, r, st, team, task)
+						return
+					}
 					worktreePath := ""
 					if task.WorktreePath != nil {
 						worktreePath = *task.WorktreePath
@@ -433,7 +486,190 @@ func NewApp(opts ServerOptions) (*App, error) {
 					writeJSON(w, map[string]any{"diff": diffOut})
 					return
 				}
-				// /teams/{team}/tasks/{id}/reviews — GET list of reviews
+				// /teams/{team}/tasks/{id}/checks — GET pre-merge check attempts with logs (?attempt=N for one,
+				// ?logs=0 to leave out the output)
+				if len(parts) >= 4 && parts[3] == "checks" {
+					if r.Method != http.MethodGet {
+						writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+						return
+					}
+					runs, err := st.ListTaskCheckRuns(r.Context(), team, taskID)
+					if err != nil {
+						writeJSONError(w, http.StatusInternalServerError, err.Error())
+						return
+					}
+					q := r.URL.Query()
+					attempts := checks.Attempts(runs, q.Get("logs") != "0" && q.Get("logs") != "false")
+					if a := q.Get("attempt"); a != "" {
+						var n int
+						if _, err := fmt.Sscanf(a, "%d", &n); err != nil {
+							writeJSONError(w, http.StatusBadRequest, "attempt must be a number")
+							return
+						}
+						i := slices.IndexFunc(attempts, func(at checks.Attempt) bool { return at.Attempt == n })
+						if i < 0 {
+							writeJSONError(w, http.StatusNotFound, "attempt not found")
+							return
+						}
+						writeJSON(w, attempts[i])
+						return
+					}
+					if attempts == nil {
+						attempts = []checks.Attempt{}
+					}
+					resp := map[string]any{"task_id": taskID, "attempts": attempts}
+					if len(attempts) > 0 {
+						resp["latest"] = attempts[len(attempts)-1]
+					}
+					writeJSON(w, resp)
+					return
+				}
+				// /teams/{team}/tasks/{id}/approval — GET the repo's approval policy and what it decided for the task
+				if len(parts) >= 4 && parts[3] == "approval" {
+					if r.Method != http.MethodGet {
+						writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+						return
+					}
+					decisions, err := st.ListTaskApprovalDecisions(r.Context(), team, taskID)
+					if err != nil {
+						writeJSONError(w, http.StatusInternalServerError, err.Error())
+						return
+					}
+					if decisions == nil {
+						decisions = []store.TaskApprovalDecision{}
+					}
+					policy := workflow.ApprovalManual
+					if repo := merge.TaskRepo(r.Context(), st, team, task); repo != nil && repo.Approval != "" {
+						policy = repo.Approval
+					}
+					resp := map[string]any{"task_id": taskID, "policy": policy, "decisions": decisions}
+					if len(decisions) > 0 {
+						resp["latest"] = decisions[len(decisions)-1]
+					}
+					writeJSON(w, resp)
+					return
+				}
+				// /teams/{team}/tasks/{id}/subtasks — GET direct subtasks (?tree=1 for all descendants, nested)
+				if len(parts) >= 4 && parts[3] == "subtasks" {
+					if r.Method != http.MethodGet {
+						writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+						return
+					}
+					if q := r.URL.Query().Get("tree"); q == "1" || q == "true" {
+						tree, err := workflow.SubtaskTree(r.Context(), st, team, taskID)
+						if err != nil {
+							writeJSONError(w, http.StatusInternalServerError, err.Error())
+							return
+						}
+						writeJSON(w, tree)
+						return
+					}
+					subtasks, err := st.ListSubtasks(r.Context(), team, taskID)
+					if err != nil {
+						writeJSONError(w, http.StatusInternalServerError, err.Error())
+						return
+					}
+					writeJSON(w, subtasks)
+					return
+				}
+				// /teams/{team}/tasks/{id}/timeline — GET transitions, comments, reviews, messages and turns, oldest first
+				if len(parts) >= 4 && parts[3] == "timeline" {
+					if r.Method != http.MethodGet {
+						writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+						return
+					}
+					timeline, err := workflow.BuildTimeline(r.Context(), st, team, taskID)
+					if err != nil {
+						writeJSONError(w, http.StatusInternalServerError, err.Error())
+						return
+					}
+					writeJSON(w, map[string]any{"timeline": timeline})
+					return
+				}
+				// /teams/{team}/tasks/{id}/plan — GET plan revisions, PUT edit the pending plan;
+				// POST .../plan/approve (optional edited body) or .../plan/reject (feedback) decides it
+				if len(parts) >= 4 && parts[3] == "plan" {
+					var body struct {
+						Body     string `json:"body"`
+						Feedback string `json:"feedback"`
+						By       string `json:"by"`
+					}
+					if r.Method == http.MethodPut || r.Method == http.MethodPost {
+						if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+							writeJSONError(w, http.StatusBadRequest, "invalid json")
+							return
+						}
+					}
+					if body.By == "" {
+						body.By = "human"
+					}
+					action := ""
+					if len(parts) >= 5 {
+						action = parts[4]
+					}
+					switch {
+					case action == "" && r.Method == http.MethodGet:
+						plans, err := st.ListTaskPlans(r.Context(), team, taskID)
+						if err != nil {
+							writeJSONError(w, http.StatusInternalServerError, err.Error())
+							return
+						}
+						var latest *store.TaskPlan
+						if len(plans) > 0 {
+							latest = &plans[len(plans)-1]
+						}
+						writeJSON(w, map[string]any{"plan": latest, "revisions": plans})
+						return
+					case action == "" && r.Method == http.MethodPut:
+						latest, err := workflow.LatestPlan(r.Context(), st, team, taskID)
+						if err != nil {
+							writeJSONError(w, http.StatusInternalServerError, err.Error())
+							return
+						}
+						if latest == nil || latest.Status != workflow.PlanPending {
+							writeJSONError(w, http.StatusConflict, workflow.ErrNoPendingPlan.Error())
+							return
+						}
+						plan, err := workflow.EditPlan(r.Context(), st, team, task, body.By, body.Body)
+						if err != nil {
+							writeJSONError(w, http.StatusBadRequest, err.Error())
+							return
+						}
+						hub.PublishJSON(map[string]any{"type": "plan_update", "team": team, "task_id": taskID, "revision": plan.Revision, "status": plan.Status})
+						writeJSON(w, map[string]any{"ok": true, "plan": plan})
+						return
+					case (action == "approve" || action == "reject") && r.Method == http.MethodPost:
+						var (
+							next string
+							err  error
+						)
+						if action == "approve" {
+							next, err = eng.ApprovePlan(r.Context(), team, task, body.By, body.Body)
+						} else {
+							next, err = eng.RejectPlan(r.Context(), team, task, body.By, body.Feedback)
+						}
+						var guardErr *workflow.GuardError
+						switch {
+						case errors.Is(err, workflow.ErrNoPendingPlan), errors.Is(err, workflow.ErrNotInPlanStage), errors.Is(err, workflow.ErrNoPlanTransition):
+							writeJSONError(w, http.StatusConflict, err.Error())
+							return
+						case errors.As(err, &guardErr):
+							w.Header().Set("Content-Type", "application/json")
+							w.WriteHeader(http.StatusConflict)
+							_ = json.NewEncoder(w).Encode(map[string]any{"error": guardErr.Error(), "guards": guardErr.Results})
+							return
+						case err != nil:
+							writeJSONError(w, http.StatusInternalServerError, err.Error())
+							return
+						}
+						hub.PublishJSON(map[string]any{"type": "task_update", "team": team, "task_id": taskID, "current_stage": next})
+						writeJSON(w, map[string]any{"ok": true, "current_stage": next})
+						return
+					}
+					writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+					return
+				}
+				// /teams/{team}/tasks/{id}/reviews — GET list of reviews (plus the quorum tally in a review stage)
 				if len(parts) >= 4 && parts[3] == "reviews" {
 					if r.Method != http.MethodGet {
 						writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -444,19 +680,31 @@ func NewApp(opts ServerOptions) (*App, error) {
 						writeJSONError(w, http.StatusInternalServerError, err.Error())
 						return
 					}
-					writeJSON(w, map[string]any{"reviews": reviews})
+					resp := map[string]any{"reviews": reviews}
+					if stage := review.QuorumStage(r.Context(), st, task); stage != nil {
+						if tally, err := review.TallyTask(r.Context(), st, team, task, stage); err == nil {
+							resp["quorum"] = tally
+						}
+					}
+					writeJSON(w, resp)
 					return
 				}
-				// /teams/{team}/tasks/{id}/submit-review — POST submit review (reviewer_agent, outcome, comments)
+				// /teams/{team}/tasks/{id}/review-comments[/{comment_id}/resolve|reopen] — inline review threads
+				if len(parts) >= 4 && parts[3] == "review-comments" {
+					handleReviewComments(w, r, st, hub, team, task, parts[4:])
+					return
+				}
+				// /teams/{team}/tasks/{id}/submit-review — POST submit review (reviewer_agent, outcome, comments, inline_comments)
 				if len(parts) >= 4 && parts[3] == "submit-review" {
 					if r.Method != http.MethodPost {
 						writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 						return
 					}
 					var body struct {
-						ReviewerAgent string `json:"reviewer_agent"`
-						Outcome       string `json:"outcome"`
-						Comments      string `json:"comments"`
+						ReviewerAgent  string                 `json:"reviewer_agent"`
+						Outcome        string                 `json:"outcome"`
+						Comments       string                 `json:"comments"`
+						InlineComments []review.InlineComment `json:"inline_comments"`
 					}
 					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 						writeJSONError(w, http.StatusBadRequest, "invalid json")
@@ -466,7 +714,7 @@ func NewApp(opts ServerOptions) (*App, error) {
 						writeJSONError(w, http.StatusBadRequest, "outcome required (e.g. approved, changes_requested)")
 						return
 					}
-					if err := review.SubmitReview(r.Context(), st, team, taskID, body.ReviewerAgent, body.Outcome, body.Comments); err != nil {
+					if err := review.SubmitReviewVia(r.Context(), st, eng, team, taskID, body.ReviewerAgent, body.Outcome, body.Comments, body.InlineComments...); err != nil {
 						writeJSONError(w, http.StatusBadRequest, err.Error())
 						return
 					}
@@ -507,12 +755,16 @@ func NewApp(opts ServerOptions) (*App, error) {
 						writeJSONError(w, http.StatusBadRequest, "no submit_for_review transition from current stage")
 						return
 					}
-					if err := st.SetTaskWorkflowAndStage(r.Context(), taskID, *task.WorkflowID, nextStage); err != nil {
+					ctx := store.WithTransitionCause(r.Context(), store.TransitionCause{Actor: "api", Outcome: "submit_for_review"})
+					if err := st.SetTaskWorkflowAndStage(ctx, taskID, *task.WorkflowID, nextStage); err != nil {
 						writeJSONError(w, http.StatusInternalServerError, err.Error())
 						return
 					}
 					agents, _ := st.ListAgents(r.Context(), team)
 					updated, _ := st.GetTaskByIDAndTeam(r.Context(), team, taskID)
+					if updated != nil && review.QuorumStage(r.Context(), st, updated) != nil {
+						_ = review.StartRound(r.Context(), st, team, updated)
+					}
 					if updated != nil && len(agents) > 0 && nextStage == "InReview" {
 						reviewer := review.PickReviewer(r.Context(), st, team, updated, agents)
 						if reviewer != "" {
@@ -536,13 +788,35 @@ func NewApp(opts ServerOptions) (*App, error) {
 					return
 				case http.MethodPatch:
 					var body struct {
-						Status   *string `json:"status"`
-						Assignee *string `json:"assignee"`
+						Status        *string `json:"status"`
+						Assignee      *string `json:"assignee"`
+						ParentTaskID  *int64  `json:"parent_task_id"` // 0 detaches
+						SubtaskPolicy *string `json:"subtask_policy"`
 					}
 					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 						writeJSONError(w, http.StatusBadRequest, "invalid json")
 						return
 					}
+					if body.SubtaskPolicy != nil {
+						if _, err := workflow.ParseSubtaskPolicy(*body.SubtaskPolicy); err != nil {
+							writeJSONError(w, http.StatusBadRequest, err.Error())
+							return
+						}
+						if err := st.SetTaskSubtaskPolicy(r.Context(), taskID, *body.SubtaskPolicy); err != nil {
+							writeJSONError(w, http.StatusInternalServerError, err.Error())
+							return
+						}
+					}
+					if body.ParentTaskID != nil {
+						parent := body.ParentTaskID
+						if *parent == 0 {
+							parent = nil
+						}
+						if err := st.SetTaskParent(r.Context(), team, taskID, parent); err != nil {
+							writeJSONError(w, http.StatusBadRequest, err.Error())
+							return
+						}
+					}
 					status := ""
 					if body.Status != nil {
 						status = *body.Status
@@ -593,12 +867,18 @@ func NewApp(opts ServerOptions) (*App, error) {
 					writeJSONError(w, http.StatusNotFound, err.Error())
 					return
 				}
+				if q := r.URL.Query().Get("tree"); q == "1" || q == "true" {
+					writeJSON(w, workflow.TaskTree(tasks))
+					return
+				}
 				writeJSON(w, tasks)
 				return
 			case http.MethodPost:
 				var body struct {
-					Title  string `json:"title"`
-					Status string `json:"status"`
+					Title         string `json:"title"`
+					Status        string `json:"status"`
+					ParentTaskID  *int64 `json:"parent_task_id"`
+					SubtaskPolicy string `json:"subtask_policy"`
 				}
 				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 					writeJSONError(w, http.StatusBadRequest, "invalid json")
@@ -612,6 +892,18 @@ func NewApp(opts ServerOptions) (*App, error) {
 					writeJSONError(w, http.StatusBadRequest, "status must be todo or in_progress")
 					return
 				}
+				if body.SubtaskPolicy != "" {
+					if _, err := workflow.ParseSubtaskPolicy(body.SubtaskPolicy); err != nil {
+						writeJSONError(w, http.StatusBadRequest, err.Error())
+						return
+					}
+				}
+				if body.ParentTaskID != nil {
+					if parent, _ := st.GetTaskByIDAndTeam(r.Context(), team, *body.ParentTaskID); parent == nil {
+						writeJSONError(w, http.StatusBadRequest, "parent task not found")
+						return
+					}
+				}
 				var wfID *string
 				if defaultWF, _ := st.GetWorkflowIDByTeamAndName(r.Context(), team, "default", 1); defaultWF != "" {
 					wfID = &defaultWF
@@ -621,6 +913,18 @@ func NewApp(opts ServerOptions) (*App, error) {
 					writeJSONError(w, http.StatusBadRequest, err.Error())
 					return
 				}
+				if body.SubtaskPolicy != "" {
+					if err := st.SetTaskSubtaskPolicy(r.Context(), id, body.SubtaskPolicy); err != nil {
+						writeJSONError(w, http.StatusInternalServerError, err.Error())
+						return
+					}
+				}
+				if body.ParentTaskID != nil {
+					if err := st.SetTaskParent(r.Context(), team, id, body.ParentTaskID); err != nil {
+						writeJSONError(w, http.StatusBadRequest, err.Error())
+						return
+					}
+				}
 				otel.RecordTaskOp(r.Context(), "create", team, body.Status)
 				hub.PublishJSON(map[string]any{"type": "task_update", "team": team, "task_id": id})
 				writeJSON(w, map[string]any{"task_id": id})
@@ -750,6 +1054,34 @@ func NewApp(opts ServerOptions) (*App, error) {
 			}
 
 		case "repos":
+			// /teams/{team}/repos/{name}/checks — GET the repo's pre-merge checks, PUT replaces them
+			if len(parts) >= 4 && parts[3] == "checks" {
+				handleRepoChecks(w, r, st, hub, team, parts[2])
+				return
+			}
+			// /teams/{team}/repos/{name}/merge-queue
+			if len(parts) >= 4 && parts[3] == "merge-queue" {
+				if r.Method != http.MethodGet {
+					writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+					return
+				}
+				if repos, err := st.ListRepos(r.Context(), team); err != nil || !slices.ContainsFunc(repos, func(rp store.Repo) bool { return rp.Name == parts[2] }) {
+					writeJSONError(w, http.StatusNotFound, "repo not found")
+					return
+				}
+				qs, err := merge.LoadQueueState(r.Context(), st, team, parts[2])
+				if err != nil {
+					writeJSONError(w, http.StatusNotFound, err.Error())
+					return
+				}
+				writeJSON(w, qs)
+				return
+			}
+			// /teams/{team}/repos/{name}
+			if len(parts) >= 3 && parts[2] != "" {
+				handleRepo(w, r, st, hub, team, parts[2])
+				return
+			}
 			switch r.Method {
 			case http.MethodGet:
 				repos, err := st.ListRepos(r.Context(), team)
@@ -761,23 +1093,39 @@ func NewApp(opts ServerOptions) (*App, error) {
 				return
 			case http.MethodPost:
 				var body struct {
-					Name     string  `json:"name"`
-					Source   string  `json:"source"`
-					Approval string  `json:"approval"`
-					TestCmd  *string `json:"test_cmd"`
+					Name           string  `json:"name"`
+					Source         string  `json:"source"`
+					Approval       string  `json:"approval"`
+					TestCmd        *string `json:"test_cmd"`
+					TargetBranch   string  `json:"target_branch"`
+					MergeStrategy  string  `json:"merge_strategy"`
+					CommitTemplate string  `json:"commit_template"`
 				}
 				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 					writeJSONError(w, http.StatusBadRequest, "invalid json")
 					return
 				}
-				if body.Approval != "" && body.Approval != "auto" && body.Approval != "manual" {
-					writeJSONError(w, http.StatusBadRequest, "approval must be auto or manual")
+				if body.Approval != "" {
+					if _, _, err := workflow.ParseApprovalPolicy(body.Approval); err != nil {
+						writeJSONError(w, http.StatusBadRequest, err.Error())
+						return
+					}
+				}
+				if body.MergeStrategy == "" {
+					body.MergeStrategy = "merge"
+				}
+				if msg := checkMergeSettings(body.MergeStrategy, body.CommitTemplate); msg != "" {
+					writeJSONError(w, http.StatusBadRequest, msg)
 					return
 				}
 				if err := st.CreateRepo(r.Context(), team, body.Name, body.Source, body.Approval, body.TestCmd); err != nil {
 					writeJSONError(w, http.StatusBadRequest, err.Error())
 					return
 				}
+				if err := st.SetRepoMergeSettings(r.Context(), team, body.Name, body.TargetBranch, body.MergeStrategy, body.CommitTemplate); err != nil {
+					writeJSONError(w, http.StatusInternalServerError, err.Error())
+					return
+				}
 				hub.PublishJSON(map[string]any{"type": "repo_update", "team": team, "repo": body.Name})
 				writeJSON(w, map[string]any{"ok": true})
 				return
@@ -793,64 +1141,320 @@ func NewApp(opts ServerOptions) (*App, error) {
 					writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
 					return
 				}
-				_, _ = st.CreateWorkflow(r.Context(), team, "default", 1, "builtin:default")
+				// Optional body {"plan": true} starts the default workflow with a Planning stage.
+				var body struct {
+					Plan bool `json:"plan"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+					writeJSONError(w, http.StatusBadRequest, "invalid json")
+					return
+				}
+				source := "builtin:default"
+				if body.Plan {
+					source = store.DefaultPlanSource
+				}
+				_, _ = st.CreateWorkflow(r.Context(), team, "default", 1, source)
 				hub.PublishJSON(map[string]any{"type": "workflow_update", "team": team, "workflow": "default"})
 				writeJSON(w, map[string]any{"ok": true})
 				return
 			}
-			switch r.Method {
-			case http.MethodGet:
-				wfs, err := st.ListWorkflows(r.Context(), team)
+			// POST /teams/{team}/workflows/lint lints a definition without storing it.
+			if len(parts) >= 3 && parts[2] == "lint" {
+				if r.Method != http.MethodPost {
+					writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+					return
+				}
+				_, data, file, err := decodeWorkflowSource(r)
 				if err != nil {
-					writeJSONError(w, http.StatusNotFound, err.Error())
+					writeJSONError(w, http.StatusBadRequest, err.Error())
 					return
 				}
-				writeJSON(w, wfs)
+				def, diags := workflow.LintDefinition(data, file, nil)
+				if def != nil {
+					agents, err := st.ListAgents(r.Context(), team)
+					if err != nil {
+						writeJSONError(w, http.StatusNotFound, err.Error())
+						return
+					}
+					names := make([]string, 0, len(agents))
+					for _, a := range agents {
+						names = append(names, a.Name)
+					}
+					diags = workflow.Lint(def, names)
+				}
+				writeJSON(w, map[string]any{"ok": workflow.DiagnosticsError(diags) == nil, "diagnostics": diags})
 				return
-			case http.MethodPost:
+			}
+			// POST /teams/{team}/workflows/migrate moves open tasks to another workflow version.
+			if len(parts) >= 3 && parts[2] == "migrate" {
+				if r.Method != http.MethodPost {
+					writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+					return
+				}
 				var body struct {
-					Name       string `json:"name"`
-					Version    int    `json:"version"`
-					SourcePath string `json:"source"`
+					From     string            `json:"from"`
+					To       string            `json:"to"`
+					StageMap map[string]string `json:"stage_map"`
+					DryRun   bool              `json:"dry_run"`
 				}
 				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 					writeJSONError(w, http.StatusBadRequest, "invalid json")
 					return
 				}
-				if _, err := st.CreateWorkflow(r.Context(), team, body.Name, body.Version, body.SourcePath); err != nil {
+				if body.From == "" || body.To == "" {
+					writeJSONError(w, http.StatusBadRequest, "from and to required")
+					return
+				}
+				plan, err := workflow.PlanMigration(r.Context(), st, team, body.From, body.To, body.StageMap)
+				if err != nil {
 					writeJSONError(w, http.StatusBadRequest, err.Error())
 					return
 				}
-				hub.PublishJSON(map[string]any{"type": "workflow_update", "team": team, "workflow": body.Name})
-				writeJSON(w, map[string]any{"ok": true})
-				return
-			default:
-				writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
-				return
-			}
-
-		case "messages":
-			// GET /teams/{team}/messages?recipient=X (inbox for X); POST send message
-			switch r.Method {
-			case http.MethodGet:
-				recipient := r.URL.Query().Get("recipient")
-				limit := 0
-				if l := r.URL.Query().Get("limit"); l != "" {
-					if n, _ := fmt.Sscanf(l, "%d", &limit); n == 1 && limit > 0 {
-						if limit > models.DefaultMessageListLimit {
-							limit = models.DefaultMessageListLimit
-						}
-					}
+				if body.DryRun {
+					writeJSON(w, map[string]any{"ok": true, "dry_run": true, "moves": plan.Migration.Moves})
+					return
 				}
-				msgs, err := st.ListMessages(r.Context(), team, recipient, limit)
+				id, err := workflow.ApplyMigration(r.Context(), st, team, plan)
 				if err != nil {
-					writeJSONError(w, http.StatusInternalServerError, err.Error())
+					writeJSONError(w, http.StatusConflict, err.Error())
 					return
 				}
-				writeJSON(w, msgs)
+				hub.PublishJSON(map[string]any{"type": "workflow_migration", "team": team, "from": body.From, "to": body.To, "migration_id": id, "tasks": len(plan.Migration.Moves)})
+				writeJSON(w, map[string]any{"ok": true, "migration_id": id, "moves": plan.Migration.Moves})
 				return
-			case http.MethodPost:
-				var body struct {
+			}
+			// POST /teams/{team}/workflows/simulate walks a hypothetical task through a workflow.
+			if len(parts) >= 3 && parts[2] == "simulate" {
+				if r.Method != http.MethodPost {
+					writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+					return
+				}
+				var body struct {
+					Workflow     string   `json:"workflow"`
+					Outcomes     []string `json:"outcomes"`
+					ChangedFiles []string `json:"changed_files"`
+					FailTests    bool     `json:"fail_tests"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					writeJSONError(w, http.StatusBadRequest, "invalid json")
+					return
+				}
+				if body.Workflow == "" {
+					writeJSONError(w, http.StatusBadRequest, "workflow required")
+					return
+				}
+				sim, err := workflow.Simulate(r.Context(), st, team, body.Workflow, workflow.SimOptions{Outcomes: body.Outcomes, ChangedFiles: body.ChangedFiles, FailTests: body.FailTests})
+				if err != nil {
+					writeJSONError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				writeJSON(w, sim)
+				return
+			}
+			// GET /teams/{team}/workflows/migrations lists the migration audit log.
+			if len(parts) >= 3 && parts[2] == "migrations" {
+				if r.Method != http.MethodGet {
+					writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+					return
+				}
+				list, err := st.ListWorkflowMigrations(r.Context(), team)
+				if err != nil {
+					writeJSONError(w, http.StatusNotFound, err.Error())
+					return
+				}
+				writeJSON(w, list)
+				return
+			}
+			// GET /teams/{team}/workflows/{name}/graph?version=N&format=mermaid|dot|json&counts=1 renders a stored workflow.
+			if len(parts) >= 4 && parts[3] == "graph" {
+				if r.Method != http.MethodGet {
+					writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+					return
+				}
+				version := 1
+				if v := r.URL.Query().Get("version"); v != "" {
+					var n int
+					if _, err := fmt.Sscanf(v, "%d", &n); err != nil || n <= 0 {
+						writeJSONError(w, http.StatusBadRequest, "invalid version")
+						return
+					}
+					version = n
+				}
+				q := r.URL.Query().Get("counts")
+				g, err := workflow.BuildGraph(r.Context(), st, team, fmt.Sprintf("%s@%d", parts[2], version), q == "1" || q == "true")
+				if err != nil {
+					writeJSONError(w, http.StatusNotFound, err.Error())
+					return
+				}
+				format := r.URL.Query().Get("format")
+				if format == workflow.GraphJSON {
+					writeJSON(w, g)
+					return
+				}
+				out, err := g.Render(format)
+				if err != nil {
+					writeJSONError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				_, _ = io.WriteString(w, out)
+				return
+			}
+			// GET /teams/{team}/workflows/{name}/lint?version=N lints a stored workflow.
+			if len(parts) >= 4 && parts[3] == "lint" {
+				if r.Method != http.MethodGet {
+					writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+					return
+				}
+				version := 1
+				if v := r.URL.Query().Get("version"); v != "" {
+					var n int
+					if _, err := fmt.Sscanf(v, "%d", &n); err != nil || n <= 0 {
+						writeJSONError(w, http.StatusBadRequest, "invalid version")
+						return
+					}
+					version = n
+				}
+				wfID, err := st.GetWorkflowIDByTeamAndName(r.Context(), team, parts[2], version)
+				if err != nil || wfID == "" {
+					writeJSONError(w, http.StatusNotFound, fmt.Sprintf("workflow not found: %s v%d", parts[2], version))
+					return
+				}
+				diags, err := workflow.LintStored(r.Context(), st, team, wfID)
+				if err != nil {
+					writeJSONError(w, http.StatusInternalServerError, err.Error())
+					return
+				}
+				writeJSON(w, map[string]any{"ok": workflow.DiagnosticsError(diags) == nil, "diagnostics": diags})
+				return
+			}
+			switch r.Method {
+			case http.MethodGet:
+				wfs, err := st.ListWorkflows(r.Context(), team)
+				if err != nil {
+					writeJSONError(w, http.StatusNotFound, err.Error())
+					return
+				}
+				writeJSON(w, wfs)
+				return
+			case http.MethodPost:
+				body, data, file, err := decodeWorkflowSource(r)
+				if err != nil {
+					writeJSONError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				def, diags := workflow.LintDefinition(data, file, nil)
+				if err := workflow.DiagnosticsError(diags); err != nil {
+					writeDiagnosticsError(w, err, diags)
+					return
+				}
+				wfID, diags, err := workflow.CreateFromDefinition(r.Context(), st, team, def, body.Name, body.Version, body.SourcePath)
+				if err != nil {
+					if len(diags) > 0 {
+						writeDiagnosticsError(w, err, diags)
+						return
+					}
+					writeJSONError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				name := body.Name
+				if name == "" {
+					name = def.Name
+				}
+				hub.PublishJSON(map[string]any{"type": "workflow_update", "team": team, "workflow": name})
+				writeJSON(w, map[string]any{"ok": true, "workflow_id": wfID, "name": name, "diagnostics": diags})
+				return
+			default:
+				writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+
+		case "schedules":
+			// DELETE /teams/{team}/schedules/{name}
+			if len(parts) >= 3 && parts[2] != "" {
+				if r.Method != http.MethodDelete {
+					writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+					return
+				}
+				if err := st.DeleteTaskSchedule(r.Context(), team, parts[2]); err != nil {
+					writeJSONError(w, http.StatusNotFound, err.Error())
+					return
+				}
+				hub.PublishJSON(map[string]any{"type": "schedule_update", "team": team, "schedule": parts[2]})
+				writeJSON(w, map[string]any{"ok": true})
+				return
+			}
+			switch r.Method {
+			case http.MethodGet:
+				list, err := st.ListTaskSchedules(r.Context(), team)
+				if err != nil {
+					writeJSONError(w, http.StatusNotFound, err.Error())
+					return
+				}
+				writeJSON(w, list)
+				return
+			case http.MethodPost:
+				var body struct {
+					Name            string     `json:"name"`
+					Title           string     `json:"title"`
+					Cron            string     `json:"cron"`
+					RunAt           *time.Time `json:"run_at"`
+					Workflow        string     `json:"workflow"`
+					WorkflowVersion int        `json:"workflow_version"`
+					Labels          string     `json:"labels"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					writeJSONError(w, http.StatusBadRequest, "invalid json")
+					return
+				}
+				sch := store.TaskSchedule{
+					Name:            body.Name,
+					TitleTemplate:   body.Title,
+					CronExpr:        body.Cron,
+					RunAt:           body.RunAt,
+					WorkflowName:    body.Workflow,
+					WorkflowVersion: body.WorkflowVersion,
+					Labels:          body.Labels,
+				}
+				if err := schedule.Prepare(&sch, time.Now().UTC()); err != nil {
+					writeJSONError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				id, err := st.CreateTaskSchedule(r.Context(), team, sch)
+				if err != nil {
+					writeJSONError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				hub.PublishJSON(map[string]any{"type": "schedule_update", "team": team, "schedule": body.Name})
+				writeJSON(w, map[string]any{"schedule_id": id, "next_run_at": sch.NextRunAt})
+				return
+			default:
+				writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+
+		case "messages":
+			// GET /teams/{team}/messages?recipient=X (inbox for X); POST send message
+			switch r.Method {
+			case http.MethodGet:
+				recipient := r.URL.Query().Get("recipient")
+				limit := 0
+				if l := r.URL.Query().Get("limit"); l != "" {
+					if n, _ := fmt.Sscanf(l, "%d", &limit); n == 1 && limit > 0 {
+						if limit > models.DefaultMessageListLimit {
+							limit = models.DefaultMessageListLimit
+						}
+					}
+				}
+				msgs, err := st.ListMessages(r.Context(), team, recipient, limit)
+				if err != nil {
+					writeJSONError(w, http.StatusInternalServerError, err.Error())
+					return
+				}
+				writeJSON(w, msgs)
+				return
+			case http.MethodPost:
+				var body struct {
 					Sender    string `json:"sender"`
 					Recipient string `json:"recipient"`
 					Content   string `json:"content"`
@@ -957,6 +1561,45 @@ func NewApp(opts ServerOptions) (*App, error) {
 		writeJSON(w, map[string]any{"ok": true})
 	})
 
+	// --- Scheduler (global pause) ---
+	mux.HandleFunc("/scheduler", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		state, err := st.GetSchedulerState(r.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, state)
+	})
+	schedulerPauseHandler := func(paused bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			reason, ok := decodePauseReason(w, r)
+			if !ok {
+				return
+			}
+			if err := st.SetSchedulerPaused(r.Context(), paused, reason); err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			state, err := st.GetSchedulerState(r.Context())
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			hub.PublishJSON(map[string]any{"type": "scheduler_state", "paused": state.Paused, "reason": state.Reason})
+			writeJSON(w, state)
+		}
+	}
+	mux.HandleFunc("/scheduler/pause", schedulerPauseHandler(true))
+	mux.HandleFunc("/scheduler/resume", schedulerPauseHandler(false))
+
 	// UI: embedded React SPA (web/dist via go:embed)
 	mux.Handle("/", ui.Handler())
 
@@ -984,16 +1627,7 @@ func NewApp(opts ServerOptions) (*App, error) {
 		_ = st.Close()
 	})
 
-	reg := capabilities.NewRegistry()
-	if u := os.Getenv("SLACK_WEBHOOK_URL"); u != "" {
-		reg.Register("slack", capabilities.SlackWebhook{WebhookURL: u})
-	}
-	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
-		if repo := os.Getenv("GITHUB_OWNER_REPO"); repo != "" {
-			reg.Register("github", capabilities.GitHubNotifier{Token: token, OwnerRepo: repo})
-		}
-	}
-	return &App{Server: srv, Hub: hub, Store: st, Capabilities: reg, Home: opts.Home}, nil
+	return &App{Server: srv, Hub: hub, Store: st, Capabilities: reg, Home: opts.Home, Worktrees: gc}, nil
 }
 
 // responseRecorder captures status code for logging and forwards Flusher if supported.
@@ -1091,6 +1725,7 @@ func handleBootstrap(w http.ResponseWriter, r *http.Request, st store.Store, hom
 	var repos any = []any{}
 	var workflows any = []any{}
 	allowlist, _ := st.ListAllowedDomains(r.Context())
+	scheduler, _ := st.GetSchedulerState(r.Context())
 	if initialTeam != "" {
 		if t, err := st.ListTasks(r.Context(), initialTeam, 0); err == nil {
 			tasks = t
@@ -1120,9 +1755,23 @@ func handleBootstrap(w http.ResponseWriter, r *http.Request, st store.Store, hom
 		"network": map[string]any{
 			"allowlist": allowlist,
 		},
+		"scheduler": scheduler,
 	})
 }
 
+// decodePauseReason reads the optional {"reason": "..."} body of a pause/resume request.
+// An empty body is allowed; on invalid JSON it writes a 400 and returns ok=false.
+func decodePauseReason(w http.ResponseWriter, r *http.Request) (reason string, ok bool) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeJSONError(w, http.StatusBadRequest, "invalid json")
+		return "", false
+	}
+	return body.Reason, true
+}
+
 func writeJSON(w http.ResponseWriter, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	enc := json.NewEncoder(w)
@@ -1130,6 +1779,49 @@ func writeJSON(w http.ResponseWriter, v any) {
 	_ = enc.Encode(v)
 }
 
+// workflowSourceBody is the request body for creating or linting a workflow. Definition is inline
+// YAML; otherwise Source must name a builtin (builtin:<name>). File paths are CLI-only: the API never
+// reads files on the daemon host.
+type workflowSourceBody struct {
+	Name       string `json:"name"`
+	Version    int    `json:"version"`
+	SourcePath string `json:"source"`
+	Definition string `json:"definition"`
+}
+
+// decodeWorkflowSource decodes a workflowSourceBody and returns the YAML and the file name used in diagnostics.
+func decodeWorkflowSource(r *http.Request) (workflowSourceBody, []byte, string, error) {
+	var body workflowSourceBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return body, nil, "", errors.New("invalid json")
+	}
+	switch {
+	case body.Definition != "":
+		if body.SourcePath == "" {
+			body.SourcePath = "api"
+		}
+		return body, []byte(body.Definition), "definition", nil
+	case body.SourcePath != "":
+		if !strings.HasPrefix(body.SourcePath, workflow.BuiltinPrefix) {
+			return body, nil, "", errors.New("source must be builtin:<name>; send a file's YAML as definition")
+		}
+		data, err := workflow.ReadSource(body.SourcePath)
+		if err != nil {
+			return body, nil, "", err
+		}
+		return body, data, body.SourcePath, nil
+	default:
+		return body, nil, "", errors.New("source or definition required")
+	}
+}
+
+// writeDiagnosticsError writes a 400 with the joined error and the structured diagnostics.
+func writeDiagnosticsError(w http.ResponseWriter, err error, diags []workflow.Diagnostic) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": err.Error(), "diagnostics": diags})
+}
+
 // writeJSONError sends a JSON body {"error": "message"} with the given status code.
 func writeJSONError(w http.ResponseWriter, code int, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -1143,3 +1835,416 @@ func nilIfEmpty(s string) any {
 	}
 	return s
 }
+
+// checkMergeSettings validates a repo merge strategy and commit template; it returns an error message or "".
+func checkMergeSettings(strategy, template string) string {
+	if !store.ValidMergeStrategy(strategy) {
+		return "merge_strategy must be one of " + strings.Join(store.MergeStrategies, ", ")
+	}
+	if _, err := merge.ParseCommitTemplate(template); err != nil {
+		return "commit_template: " + err.Error()
+	}
+	return ""
+}
+
+// handleRepo serves /teams/{team}/repos/{name}: GET returns the repo, PATCH changes the fields given
+// (approval, target_branch, merge_strategy, commit_template).
+func handleRepo(w http.ResponseWriter, r *http.Request, st store.Store, hub *SSEHub, team, name string) {
+	repos, err := st.ListRepos(r.Context(), team)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	var repo *store.Repo
+	for i := range repos {
+		if repos[i].Name == name {
+			repo = &repos[i]
+		}
+	}
+	if repo == nil {
+		writeJSONError(w, http.StatusNotFound, "repo not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, repo)
+	case http.MethodPatch:
+		var body struct {
+			Approval       *string `json:"approval"`
+			TargetBranch   *string `json:"target_branch"`
+			MergeStrategy  *string `json:"merge_strategy"`
+			CommitTemplate *string `json:"commit_template"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		if body.TargetBranch != nil {
+			repo.TargetBranch = *body.TargetBranch
+		}
+		if body.MergeStrategy != nil {
+			repo.MergeStrategy = *body.MergeStrategy
+		}
+		if body.CommitTemplate != nil {
+			repo.CommitTemplate = *body.CommitTemplate
+		}
+		if msg := checkMergeSettings(repo.MergeStrategy, repo.CommitTemplate); msg != "" {
+			writeJSONError(w, http.StatusBadRequest, msg)
+			return
+		}
+		if body.Approval != nil {
+			if _, _, err := workflow.ParseApprovalPolicy(*body.Approval); err != nil {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if err := st.SetRepoApproval(r.Context(), team, name, *body.Approval); err != nil {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			repo.Approval = *body.Approval
+		}
+		if err := st.SetRepoMergeSettings(r.Context(), team, name, repo.TargetBranch, repo.MergeStrategy, repo.CommitTemplate); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		hub.PublishJSON(map[string]any{"type": "repo_update", "team": team, "repo": name})
+		writeJSON(w, repo)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleRepoChecks serves GET and PUT /teams/{team}/repos/{name}/checks. PUT takes {"checks": [...]} and
+// replaces the list; an empty list goes back to the repo's test_cmd.
+func handleRepoChecks(w http.ResponseWriter, r *http.Request, st store.Store, hub *SSEHub, team, name string) {
+	if repos, err := st.ListRepos(r.Context(), team); err != nil || !slices.ContainsFunc(repos, func(rp store.Repo) bool { return rp.Name == name }) {
+		writeJSONError(w, http.StatusNotFound, "repo not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var body struct {
+			Checks []checks.Spec `json:"checks"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		list := make([]store.RepoCheck, len(body.Checks))
+		for i, c := range body.Checks {
+			list[i] = c.RepoCheck()
+		}
+		if err := checks.Validate(list); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := st.SetRepoChecks(r.Context(), team, name, list); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		hub.PublishJSON(map[string]any{"type": "repo_update", "team": team, "repo": name})
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	list, err := st.ListRepoChecks(r.Context(), team, name)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	specs := make([]checks.Spec, len(list))
+	for i, c := range list {
+		specs[i] = checks.SpecOf(c)
+	}
+	writeJSON(w, map[string]any{"checks": specs})
+}
+
+// Hunk page size for /diff/files.
+const (
+	defaultDiffHunks = 100
+	maxDiffHunks     = 1000
+)
+
+// handleTaskDiffFiles serves GET /teams/{team}/tasks/{id}/diff/files: the task's diff as per-file entries
+// with line counts and hunks, paged by ?offset= and ?limit= across the whole diff. ?path= (repeatable)
+// limits it to some paths, ?stat=1 leaves out the hunks, ?whitespace=ignore ignores whitespace changes and
+// ?context=N sets the context lines. ?from_attempt=N and ?to_attempt=M diff the commits that check
+// attempts ran on instead of the base and the branch tip.
+func handleTaskDiffFiles(w http.ResponseWriter, r *http.Request, st store.Store, team string, task *store.Task) {
+	q := r.URL.Query()
+	worktreePath := ""
+	if task.WorktreePath != nil {
+		worktreePath = *task.WorktreePath
+	}
+	base := "HEAD~1"
+	if task.BaseSHA != nil && *task.BaseSHA != "" {
+		base = *task.BaseSHA
+	}
+	head := "HEAD"
+	if task.BranchName != nil && *task.BranchName != "" {
+		head = *task.BranchName
+	}
+	number := func(name string, def int) (int, bool) {
+		v := q.Get(name)
+		if v == "" {
+			return def, true
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSONError(w, http.StatusBadRequest, name+" must be a non-negative number")
+			return 0, false
+		}
+		return n, true
+	}
+	if q.Get("from_attempt") != "" || q.Get("to_attempt") != "" {
+		runs, err := st.ListTaskCheckRuns(r.Context(), team, task.TaskID)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, side := range []struct {
+			param string
+			ref   *string
+		}{{"from_attempt", &base}, {"to_attempt", &head}} {
+			if q.Get(side.param) == "" {
+				continue
+			}
+			n, ok := number(side.param, 0)
+			if !ok {
+				return
+			}
+			i := slices.IndexFunc(runs, func(run store.TaskCheckRun) bool { return run.Attempt == n && run.HeadSHA != "" })
+			if i < 0 {
+				writeJSONError(w, http.StatusNotFound, fmt.Sprintf("attempt %d has no recorded commit", n))
+				return
+			}
+			*side.ref = runs[i].HeadSHA
+		}
+	}
+	opts := git.DiffOptions{
+		Paths:            q["path"],
+		IgnoreWhitespace: q.Get("whitespace") == "ignore",
+		Stat:             q.Get("stat") == "1" || q.Get("stat") == "true",
+		Context:          -1,
+	}
+	var ok bool
+	if q.Get("context") != "" {
+		if opts.Context, ok = number("context", -1); !ok {
+			return
+		}
+	}
+	offset, ok := number("offset", 0)
+	if !ok {
+		return
+	}
+	limit, ok := number("limit", defaultDiffHunks)
+	if !ok {
+		return
+	}
+	limit = min(max(limit, 1), maxDiffHunks)
+	files, err := git.DiffFiles(r.Context(), worktreePath, base, head, opts)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if files == nil {
+		files = []git.FileDiff{}
+	}
+	additions, deletions := 0, 0
+	for _, f := range files {
+		additions += f.Additions
+		deletions += f.Deletions
+	}
+	resp := map[string]any{
+		"task_id": task.TaskID,
+		"base":    base,
+		"head":    head,
+		"files":   files,
+		"totals":  map[string]int{"files": len(files), "additions": additions, "deletions": deletions},
+	}
+	if !opts.Stat {
+		total, next := git.PageHunks(files, offset, limit)
+		resp["total_hunks"], resp["offset"], resp["limit"] = total, offset, limit
+		if next >= 0 {
+			resp["next_offset"] = next
+		}
+	}
+	writeJSON(w, resp)
+}
+
+// handleReviewComments serves a task's inline review threads. GET /review-comments lists them, following
+// open threads to the branch tip first (?open=1 leaves out resolved ones). POST /review-comments starts a
+// thread on path lines start_line..end_line (of commit_sha, default the branch tip), or replies to a
+// thread with in_reply_to. POST /review-comments/{id}/resolve and /reopen change a thread's state.
+func handleReviewComments(w http.ResponseWriter, r *http.Request, st store.Store, hub *SSEHub, team string, task *store.Task, rest []string) {
+	if len(rest) == 2 && (rest[1] == "resolve" || rest[1] == "reopen") {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		var threadID int64
+		if _, err := fmt.Sscanf(rest[0], "%d", &threadID); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid comment id")
+			return
+		}
+		if err := st.SetReviewThreadResolved(r.Context(), team, task.TaskID, threadID, rest[1] == "resolve"); err != nil {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		hub.PublishJSON(map[string]any{"type": "review_comment", "team": team, "task_id": task.TaskID, "thread_id": threadID})
+		writeJSON(w, map[string]any{"ok": true})
+		return
+	}
+	if len(rest) > 0 {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		threads, err := review.TaskThreads(r.Context(), st, team, task)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if q := r.URL.Query().Get("open"); q == "1" || q == "true" {
+			threads = slices.DeleteFunc(threads, func(th review.Thread) bool { return th.Resolved })
+		}
+		if threads == nil {
+			threads = []review.Thread{}
+		}
+		writeJSON(w, map[string]any{"threads": threads})
+	case http.MethodPost:
+		var body struct {
+			Author    string `json:"author"`
+			Path      string `json:"path"`
+			StartLine int    `json:"start_line"`
+			EndLine   int    `json:"end_line"`
+			CommitSHA string `json:"commit_sha"`
+			Body      string `json:"body"`
+			InReplyTo int64  `json:"in_reply_to"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		if body.Author == "" {
+			body.Author = "api"
+		}
+		id, err := review.AddComment(r.Context(), st, team, task, store.ReviewComment{Author: body.Author, Path: body.Path, StartLine: body.StartLine,
+			EndLine: body.EndLine, CommitSHA: body.CommitSHA, Body: body.Body, InReplyTo: body.InReplyTo})
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		hub.PublishJSON(map[string]any{"type": "review_comment", "team": team, "task_id": task.TaskID, "comment_id": id})
+		writeJSON(w, map[string]any{"comment_id": id})
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// writeWorktreeMetrics writes the worktree GC's latest per-team figures in the Prometheus text format.
+func writeWorktreeMetrics(w io.Writer, stats []worktrees.Stats) {
+	if len(stats) == 0 {
+		return
+	}
+	metrics := []struct {
+		name, kind string
+		value      func(worktrees.Stats) int64
+	}{
+		{"agentary_worktree_bytes", "gauge", func(s worktrees.Stats) int64 { return s.Bytes }},
+		{"agentary_worktree_quota_bytes", "gauge", func(s worktrees.Stats) int64 { return s.Quota }},
+		{"agentary_worktree_gc_removed_total", "counter", func(s worktrees.Stats) int64 { return s.Removed }},
+		{"agentary_worktree_gc_freed_bytes_total", "counter", func(s worktrees.Stats) int64 { return s.Freed }},
+	}
+	for _, m := range metrics {
+		_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+		for _, s := range stats {
+			_, _ = fmt.Fprintf(w, "%s{team=%q} %d\n", m.name, s.Team, m.value(s))
+		}
+	}
+	_, _ = fmt.Fprintf(w, "# TYPE agentary_worktrees gauge\n")
+	for _, s := range stats {
+		_, _ = fmt.Fprintf(w, "agentary_worktrees{team=%q,state=\"active\"} %d\n", s.Team, s.Active)
+		_, _ = fmt.Fprintf(w, "agentary_worktrees{team=%q,state=\"collectable\"} %d\n", s.Team, s.Collectable)
+	}
+}
+
+// handleWorktrees serves a team's task worktrees. GET /worktrees measures them; PUT /worktrees/quota sets
+// the team's quota from {"quota": "10GiB"} ("none" removes it); POST /worktrees/gc collects them now,
+// or only reports what would go with ?dry_run=1.
+func handleWorktrees(w http.ResponseWriter, r *http.Request, st store.Store, gc *worktrees.Collector, hub *SSEHub, home, team string, rest []string) {
+	sub := ""
+	if len(rest) > 0 {
+		sub = rest[0]
+	}
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		usage, err := worktrees.Scan(r.Context(), st, home, team)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSON(w, usage)
+	case sub == "quota" && r.Method == http.MethodPut:
+		var body struct {
+			Quota string `json:"quota"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		quota, err := worktrees.ParseSize(body.Quota)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := st.SetTeamWorktreeQuota(r.Context(), team, quota); err != nil {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		hub.PublishJSON(map[string]any{"type": "team_update", "team": team})
+		writeJSON(w, map[string]any{"team": team, "quota": quota})
+	case sub == "gc" && r.Method == http.MethodPost:
+		collector := gc
+		if q := r.URL.Query().Get("dry_run"); q == "1" || q == "true" {
+			collector = &worktrees.Collector{Store: st, Home: home, Grace: gc.Grace, DryRun: true}
+		}
+		report, err := collector.CollectTeam(r.Context(), team)
+		if err != nil && report.Team == "" {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		resp := map[string]any{"report": report, "dry_run": collector.DryRun}
+		if err != nil {
+			resp["error"] = err.Error()
+		}
+		writeJSON(w, resp)
+	case sub == "" || sub == "quota" || sub == "gc":
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		writeJSONError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleTaskExport writes the commits of the task branch after its base as a download: a format-patch
+// series for git am, or a git bundle for git fetch (see merge.ExportTaskBranch).
+func handleTaskExport(w http.ResponseWriter, r *http.Request, st store.Store, home, team string, task *store.Task, format string) {
+	out, file, err := merge.ExportTaskBranch(r.Context(), st, home, team, task, format)
+	switch {
+	case errors.Is(err, merge.ErrNoTaskBranch), errors.Is(err, git.ErrNothingToExport):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	contentType := "text/x-patch; charset=utf-8"
+	if format == git.ExportBundle {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file))
+	_, _ = w.Write(out)
+}
@@ -110,3 +110,21 @@ func DetectAndSave(home, repoDir string) (*Human, error) {
 	}
 	return &h, nil
 }
+
+// DefaultHuman returns the first member identity under <home>/members (by file name), or nil when none has
+// been saved. Agentary is single-user, so this is the human who approves merges.
+func DefaultHuman(home string) (*Human, error) {
+	entries, err := os.ReadDir(MembersDir(home))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".yaml"); ok && !e.IsDir() {
+			return LoadHuman(home, name)
+		}
+	}
+	return nil, nil
+}
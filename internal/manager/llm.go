@@ -36,13 +36,14 @@ func RunLLM(ctx context.Context, app *httpapi.App, opts LLMOpts) {
 			"type": "function",
 			"function": map[string]any{
 				"name":        "create_task",
-				"description": "Create a new task in the team",
+				"description": "Create a new task in the team; set parent_task_id to break an epic into subtasks",
 				"parameters": map[string]any{
 					"type": "object",
 					"properties": map[string]any{
-						"team":   map[string]any{"type": "string", "description": "Team name"},
-						"title":  map[string]any{"type": "string", "description": "Task title"},
-						"status": map[string]any{"type": "string", "description": "todo or in_progress"},
+						"team":           map[string]any{"type": "string", "description": "Team name"},
+						"title":          map[string]any{"type": "string", "description": "Task title"},
+						"status":         map[string]any{"type": "string", "description": "todo or in_progress"},
+						"parent_task_id": map[string]any{"type": "integer", "description": "Optional parent task; it waits until its subtasks finish"},
 					},
 					"required": []string{"team", "title"},
 				},
@@ -205,6 +206,12 @@ func executeLLMTool(ctx context.Context, app *httpapi.App, defaultTeam, name, ar
 			status = "todo"
 		}
 		if id, err := CreateTaskForTeam(ctx, app.Store, team, title, status); err == nil {
+			if parentID, _ := args["parent_task_id"].(float64); parentID > 0 {
+				parent := int64(parentID)
+				if err := app.Store.SetTaskParent(ctx, team, id, &parent); err != nil {
+					slog.Warn("LLM manager link subtask failed", "team", team, "task_id", id, "parent", parent, "err", err)
+				}
+			}
 			slog.Info("LLM manager created task", "team", team, "task_id", id)
 			app.Hub.PublishJSON(map[string]any{"type": "task_update", "team": team, "task_id": id})
 		}
@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/ankittk/agentary/internal/store"
 	"github.com/ankittk/agentary/pkg/models"
@@ -10,6 +11,9 @@ import (
 // Store is the minimal store interface required by MCPToolkit. *store.Store implements it.
 type Store interface {
 	CreateTask(ctx context.Context, teamName, title, status string, workflowID *string) (int64, error)
+	GetTaskByIDAndTeam(ctx context.Context, teamName string, taskID int64) (*store.Task, error)
+	SetTaskParent(ctx context.Context, teamName string, taskID int64, parentID *int64) error
+	ListSubtasks(ctx context.Context, teamName string, parentID int64) ([]store.Task, error)
 	ListTasks(ctx context.Context, teamName string, limit int) ([]store.Task, error)
 	CreateMessage(ctx context.Context, teamName, sender, recipient, content string) (int64, error)
 	ListMessages(ctx context.Context, teamName string, recipient string, limit int) ([]store.Message, error)
@@ -30,6 +34,31 @@ func (t *MCPToolkit) CreateTask(ctx context.Context, title string) (int64, error
 	return t.Store.CreateTask(ctx, t.TeamName, title, models.StatusTodo, nil)
 }
 
+// CreateSubtask creates a task like CreateTask and links it under parentID, which must be a task in the
+// agent's team. The parent waits until its subtasks finish and is then rolled up.
+func (t *MCPToolkit) CreateSubtask(ctx context.Context, parentID int64, title string) (int64, error) {
+	parent, err := t.Store.GetTaskByIDAndTeam(ctx, t.TeamName, parentID)
+	if err != nil {
+		return 0, err
+	}
+	if parent == nil {
+		return 0, fmt.Errorf("parent task %d not found in team %q", parentID, t.TeamName)
+	}
+	id, err := t.CreateTask(ctx, title)
+	if err != nil {
+		return 0, err
+	}
+	if err := t.Store.SetTaskParent(ctx, t.TeamName, id, &parentID); err != nil {
+		return id, err
+	}
+	return id, nil
+}
+
+// ListSubtasks returns the direct subtasks of parentID.
+func (t *MCPToolkit) ListSubtasks(ctx context.Context, parentID int64) ([]store.Task, error) {
+	return t.Store.ListSubtasks(ctx, t.TeamName, parentID)
+}
+
 // SendMessage sends a message from this agent to the given recipient.
 func (t *MCPToolkit) SendMessage(ctx context.Context, recipient, content string) (int64, error) {
 	return t.Store.CreateMessage(ctx, t.TeamName, t.AgentName, recipient, content)
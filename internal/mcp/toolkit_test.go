@@ -103,3 +103,28 @@ func TestListMessages(t *testing.T) {
 		t.Fatalf("ListMessages: %+v", msgs)
 	}
 }
+
+func TestCreateSubtask(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "home")
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	st.CreateTeam(ctx, "team1")
+	tk := &MCPToolkit{Store: st, AgentName: "alice", TeamName: "team1"}
+	parent, _ := tk.CreateTask(ctx, "Epic")
+	id, err := tk.CreateSubtask(ctx, parent, "Part 1")
+	if err != nil {
+		t.Fatalf("CreateSubtask: %v", err)
+	}
+	subs, err := tk.ListSubtasks(ctx, parent)
+	if err != nil || len(subs) != 1 || subs[0].TaskID != id {
+		t.Fatalf("ListSubtasks: %+v, %v", subs, err)
+	}
+	if _, err := tk.CreateSubtask(ctx, 9999, "orphan"); err == nil {
+		t.Fatal("expected error for unknown parent")
+	}
+}
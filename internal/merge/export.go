@@ -0,0 +1,45 @@
+package merge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// ErrNoTaskBranch is returned by ExportTaskBranch when the task has no branch, or neither its worktree nor
+// its repo mirror exists.
+var ErrNoTaskBranch = errors.New("task branch not found")
+
+// ExportTaskBranch returns the commits of the task branch after its base as a patch series or git bundle
+// (git.ExportPatch, git.ExportBundle) and a file name for it, <repo>-T<id>.<format>. The branch is read from
+// the worktree, or from the repo mirror once the worktree has been removed; the repo is the task's, else
+// the team's first (TaskRepo).
+func ExportTaskBranch(ctx context.Context, st store.Store, home, teamName string, task *store.Task, format string) ([]byte, string, error) {
+	if task.BranchName == nil || *task.BranchName == "" {
+		return nil, "", fmt.Errorf("%w: task %d has no branch", ErrNoTaskBranch, task.TaskID)
+	}
+	worktreePath, mirrorPath, repoName := "", "", "task"
+	if task.WorktreePath != nil {
+		worktreePath = *task.WorktreePath
+	}
+	if repo := TaskRepo(ctx, st, teamName, task); repo != nil {
+		repoName = repo.Name
+		mirrorPath = git.MirrorPath(home, teamName, repo.Name)
+	}
+	dir := git.ExportDir(worktreePath, mirrorPath)
+	if dir == "" {
+		return nil, "", fmt.Errorf("%w: task %d has no worktree or repo mirror", ErrNoTaskBranch, task.TaskID)
+	}
+	base := ""
+	if task.BaseSHA != nil {
+		base = *task.BaseSHA
+	}
+	data, err := git.ExportTask(ctx, dir, base, *task.BranchName, format)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, fmt.Sprintf("%s-T%d.%s", repoName, task.TaskID, format), nil
+}
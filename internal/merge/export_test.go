@@ -0,0 +1,39 @@
+package merge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/git"
+)
+
+func TestExportTaskBranch_afterWorktreeGC(t *testing.T) {
+	ctx := context.Background()
+	st, mirror, ids := queueFixture(t, "true", "a.txt")
+	home := filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(mirror)))))
+	task, _ := st.GetTaskByIDAndTeam(ctx, "team1", ids[0])
+
+	// The worktree GC removed the worktree; the task has no repo name recorded, so the team's repo is used.
+	if err := git.DeleteWorktree(ctx, *task.WorktreePath); err != nil {
+		t.Fatal(err)
+	}
+	_ = st.UpdateTaskGitFields(ctx, task.TaskID, nil, task.BranchName, task.BaseSHA, nil)
+	task, _ = st.GetTaskByIDAndTeam(ctx, "team1", ids[0])
+	data, file, err := ExportTaskBranch(ctx, st, home, "team1", task, git.ExportPatch)
+	if err != nil {
+		t.Fatalf("ExportTaskBranch: %v", err)
+	}
+	if want := fmt.Sprintf("app-T%d.patch", task.TaskID); file != want || !strings.Contains(string(data), "Subject: [PATCH] add a.txt") {
+		t.Fatalf("ExportTaskBranch = %q, %q; want %s with the task commit", file, data, want)
+	}
+
+	_ = st.ClearTaskGitFields(ctx, task.TaskID)
+	task, _ = st.GetTaskByIDAndTeam(ctx, "team1", ids[0])
+	if _, _, err := ExportTaskBranch(ctx, st, home, "team1", task, git.ExportBundle); !errors.Is(err, ErrNoTaskBranch) {
+		t.Fatalf("export without a branch: %v, want ErrNoTaskBranch", err)
+	}
+}
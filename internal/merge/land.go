@@ -0,0 +1,142 @@
+package merge
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/identity"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// TaskRepo returns the task's repo, or the team's first repo when the task names none.
+func TaskRepo(ctx context.Context, st store.Store, teamName string, task *store.Task) *store.Repo {
+	repos, _ := st.ListRepos(ctx, teamName)
+	for i := range repos {
+		if task.RepoName != nil && repos[i].Name == *task.RepoName {
+			return &repos[i]
+		}
+	}
+	if len(repos) > 0 {
+		return &repos[0]
+	}
+	return nil
+}
+
+// Enqueue adds the task to its repo's merge queue, where the Worker lands it. It reports false, queueing
+// nothing, when the task has no worktree or branch: there is nothing to land and the caller can finish it.
+// It is shared by the workflow engine's merge stage and the Worker.
+func Enqueue(ctx context.Context, st store.Store, teamName string, task *store.Task) (bool, error) {
+	if task.WorktreePath == nil || *task.WorktreePath == "" || task.BranchName == nil || *task.BranchName == "" {
+		return false, nil
+	}
+	repoName := ""
+	if repo := TaskRepo(ctx, st, teamName, task); repo != nil {
+		repoName = repo.Name
+	}
+	stage := ""
+	if task.CurrentStage != nil {
+		stage = *task.CurrentStage
+	}
+	_, err := st.EnqueueMerge(ctx, teamName, repoName, task.TaskID, stage)
+	return err == nil, err
+}
+
+// mergeOptions are the repo's merge settings for the task. Without a repo the branch is merged into main
+// with a plain git merge, as before repos had settings.
+func mergeOptions(ctx context.Context, st store.Store, home, teamName string, task *store.Task, repo *store.Repo) (git.MergeOptions, error) {
+	if repo == nil {
+		return git.MergeOptions{}, nil
+	}
+	opts := git.MergeOptions{Target: repo.TargetBranch, Strategy: repo.MergeStrategy}
+	if opts.Strategy == git.StrategyMerge || opts.Strategy == git.StrategySquash {
+		msg, err := CommitMessage(ctx, st, home, teamName, task, repo)
+		if err != nil {
+			return opts, err
+		}
+		opts.Message = msg
+	}
+	if home != "" {
+		if h, _ := identity.DefaultHuman(home); h != nil {
+			opts.CommitterName, opts.CommitterEmail = h.Name, h.Email
+		}
+	}
+	return opts, nil
+}
+
+// finishTask moves a landed task along its stage's "done" outcome. With ApplyOutcome set the transition goes
+// through the workflow engine, so guards and hooks run and only a terminal stage marks the task done;
+// without it, or when the stage has no done transition, the task moves to the workflow's Done stage. A task
+// that finished has its worktree removed; one that moved on to another stage (e.g. Deploy) keeps it and is
+// set to todo so that stage gets a turn.
+func (w *Worker) finishTask(ctx context.Context, teamName string, task *store.Task) {
+	if task.WorkflowID == nil || *task.WorkflowID == "" {
+		_ = w.Store.UpdateTask(ctx, task.TaskID, models.StatusDone, nil)
+		w.cleanUp(ctx, teamName, task)
+		return
+	}
+	mctx := store.WithTransitionCause(ctx, store.TransitionCause{Actor: "merge-worker", Outcome: OutcomeDone})
+	next := ""
+	if w.ApplyOutcome != nil {
+		var err error
+		if next, err = w.ApplyOutcome(mctx, teamName, task, OutcomeDone); err != nil {
+			// The branch has landed; leave the task for a human to move on.
+			slog.Warn("merge worker done outcome failed", "task_id", task.TaskID, "err", err)
+			_, _ = w.Store.CreateTaskComment(ctx, teamName, task.TaskID, queueAuthor, fmt.Sprintf("Merge queue: merged, but the done transition failed: %v", err))
+			_ = w.Store.SetTaskFailed(ctx, task.TaskID)
+			return
+		}
+	}
+	if next == "" {
+		// No engine or no done transition: follow the transition directly, else go to the Done stage.
+		if next = doneTransition(ctx, w.Store, task); next == "" {
+			next = "Done"
+		}
+		_ = w.Store.SetTaskWorkflowAndStage(mctx, task.TaskID, *task.WorkflowID, next)
+		if isTerminal(ctx, w.Store, *task.WorkflowID, next) {
+			_ = w.Store.UpdateTask(ctx, task.TaskID, models.StatusDone, nil)
+		}
+	}
+	updated, _ := w.Store.GetTaskByIDAndTeam(ctx, teamName, task.TaskID)
+	if updated == nil || updated.Status == models.StatusDone {
+		w.cleanUp(ctx, teamName, task)
+		return
+	}
+	if updated.Status == models.StatusInProgress {
+		_ = w.Store.UpdateTask(ctx, task.TaskID, models.StatusTodo, nil)
+	}
+	slog.Info("merge worker landed task", "task_id", task.TaskID, "team", teamName, "stage", next)
+}
+
+// cleanUp forgets a finished task's worktree and branch and removes the worktree.
+func (w *Worker) cleanUp(ctx context.Context, teamName string, task *store.Task) {
+	_ = w.Store.ClearTaskGitFields(ctx, task.TaskID)
+	if task.WorktreePath != nil && *task.WorktreePath != "" {
+		_ = git.DeleteWorktree(ctx, *task.WorktreePath)
+	}
+	slog.Info("merge worker completed task", "task_id", task.TaskID, "team", teamName)
+}
+
+// doneTransition returns where the task's stage goes on "done", or "".
+func doneTransition(ctx context.Context, st store.Store, task *store.Task) string {
+	transitions, _ := st.GetWorkflowTransitions(ctx, *task.WorkflowID)
+	for _, tr := range transitions {
+		if task.CurrentStage != nil && tr.FromStage == *task.CurrentStage && tr.Outcome == OutcomeDone {
+			return tr.ToStage
+		}
+	}
+	return ""
+}
+
+// isTerminal reports whether stage is a terminal stage of the workflow.
+func isTerminal(ctx context.Context, st store.Store, workflowID, stage string) bool {
+	stages, _ := st.GetWorkflowStages(ctx, workflowID)
+	for _, s := range stages {
+		if s.StageName == stage && s.StageType == "terminal" {
+			return true
+		}
+	}
+	return false
+}
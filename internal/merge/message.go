@@ -0,0 +1,120 @@
+package merge
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/ankittk/agentary/internal/identity"
+	"github.com/ankittk/agentary/internal/review"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// DefaultCommitTemplate is the merge commit message used when a repo sets no commit_template.
+const DefaultCommitTemplate = "{{.Title}} (T{{.TaskID}})"
+
+// CommitData is what a repo's commit_template can use.
+type CommitData struct {
+	Team      string
+	TaskID    int64
+	Title     string
+	Branch    string
+	Reviewers []string // agents whose latest review approved the task
+	Approver  string   // "Name <email>" of the human who approved, "" when nobody did
+}
+
+// ParseCommitTemplate checks a commit_template; "" is valid and means DefaultCommitTemplate.
+func ParseCommitTemplate(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultCommitTemplate
+	}
+	return template.New("commit").Option("missingkey=error").Parse(text)
+}
+
+// CommitMessage renders the repo's commit_template for the task and appends the trailers
+// Agentary-Task: <team>/T<id> and one Reviewed-by: per approving agent and for the human approver.
+// The human is identity.DefaultHuman under home, counted as approver when "human" approved the task in its
+// approval stage.
+func CommitMessage(ctx context.Context, st store.Store, home, teamName string, task *store.Task, repo *store.Repo) (string, error) {
+	text := ""
+	if repo != nil {
+		text = repo.CommitTemplate
+	}
+	tmpl, err := ParseCommitTemplate(text)
+	if err != nil {
+		return "", fmt.Errorf("commit_template: %w", err)
+	}
+	data := CommitData{Team: teamName, TaskID: task.TaskID, Title: task.Title}
+	if task.BranchName != nil {
+		data.Branch = *task.BranchName
+	}
+	data.Reviewers = approvingReviewers(ctx, st, teamName, task.TaskID)
+	if humanApproved(ctx, st, teamName, task) && home != "" {
+		if h, _ := identity.DefaultHuman(home); h != nil && h.Name != "" {
+			data.Approver = h.Name
+			if h.Email != "" {
+				data.Approver += " <" + h.Email + ">"
+			}
+		}
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("commit_template: %w", err)
+	}
+	msg := strings.TrimSpace(b.String())
+	trailers := []string{fmt.Sprintf("Agentary-Task: %s/T%d", teamName, task.TaskID)}
+	for _, r := range data.Reviewers {
+		trailers = append(trailers, "Reviewed-by: "+r)
+	}
+	if data.Approver != "" {
+		trailers = append(trailers, "Reviewed-by: "+data.Approver)
+	}
+	return msg + "\n\n" + strings.Join(trailers, "\n") + "\n", nil
+}
+
+// approvingReviewers returns, in first-review order, the reviewers whose latest review approved.
+func approvingReviewers(ctx context.Context, st store.Store, teamName string, taskID int64) []string {
+	reviews, _ := st.ListTaskReviews(ctx, teamName, taskID)
+	sort.Slice(reviews, func(i, j int) bool { return reviews[i].ReviewID < reviews[j].ReviewID })
+	latest := make(map[string]string)
+	var order []string
+	for _, r := range reviews {
+		if _, seen := latest[r.ReviewerAgent]; !seen {
+			order = append(order, r.ReviewerAgent)
+		}
+		latest[r.ReviewerAgent] = r.Outcome
+	}
+	var out []string
+	for _, name := range order {
+		if latest[name] == review.Approved {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// humanApproved reports whether a human approved the task: the latest transition out of a human
+// (approval) stage was taken by "human" with the approved outcome. A later rejection, or an approval by
+// anyone else, does not count.
+func humanApproved(ctx context.Context, st store.Store, teamName string, task *store.Task) bool {
+	if task.WorkflowID == nil || *task.WorkflowID == "" {
+		return false
+	}
+	stages, _ := st.GetWorkflowStages(ctx, *task.WorkflowID)
+	approval := make(map[string]bool)
+	for _, s := range stages {
+		if s.StageType == "human" {
+			approval[s.StageName] = true
+		}
+	}
+	transitions, _ := st.ListTaskTransitions(ctx, teamName, task.TaskID)
+	for i := len(transitions) - 1; i >= 0; i-- {
+		if t := transitions[i]; approval[t.FromStage] {
+			return t.Outcome == review.Approved && t.Actor == "human"
+		}
+	}
+	return false
+}
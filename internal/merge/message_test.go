@@ -0,0 +1,104 @@
+package merge
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/identity"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func TestCommitMessage(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "home")
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "team1")
+	_ = st.CreateRepo(ctx, "team1", "r1", "/tmp", "manual", nil)
+	id, _ := st.CreateTask(ctx, "team1", "Add login", "todo", nil)
+	task, _ := st.GetTaskByIDAndTeam(ctx, "team1", id)
+	repos, _ := st.ListRepos(ctx, "team1")
+	repo := &repos[0]
+
+	msg, err := CommitMessage(ctx, st, home, "team1", task, repo)
+	if err != nil {
+		t.Fatalf("CommitMessage: %v", err)
+	}
+	want := "Add login (T" + strconv.FormatInt(id, 10) + ")\n\nAgentary-Task: team1/T" + strconv.FormatInt(id, 10) + "\n"
+	if msg != want {
+		t.Errorf("default message = %q, want %q", msg, want)
+	}
+
+	// bob approves after asking for changes; carol's latest review asks for changes.
+	_, _ = st.CreateTaskReview(ctx, "team1", id, "bob", "changes_requested", "")
+	_, _ = st.CreateTaskReview(ctx, "team1", id, "carol", "approved", "")
+	_, _ = st.CreateTaskReview(ctx, "team1", id, "bob", "approved", "")
+	_, _ = st.CreateTaskReview(ctx, "team1", id, "carol", "changes_requested", "")
+	if err := identity.SaveHuman(home, "ada", &identity.Human{Name: "Ada", Email: "ada@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = st.CreateWorkflow(ctx, "team1", "default", 1, "builtin:default")
+	wfID, _ := st.GetWorkflowIDByTeamAndName(ctx, "team1", "default", 1)
+	_ = st.SetTaskWorkflowAndStage(ctx, id, wfID, "InApproval")
+	hctx := store.WithTransitionCause(ctx, store.TransitionCause{Actor: "human", Outcome: "approved"})
+	_ = st.SetTaskWorkflowAndStage(hctx, id, wfID, "Merging")
+	task, _ = st.GetTaskByIDAndTeam(ctx, "team1", id)
+
+	repo.CommitTemplate = "T{{.TaskID}}: {{.Title}}\n\nReviewers: {{join .Reviewers \", \"}}"
+	if _, err := CommitMessage(ctx, st, home, "team1", task, repo); err == nil {
+		t.Error("template with an unknown function: expected error")
+	}
+	repo.CommitTemplate = "T{{.TaskID}}: {{.Title}}{{range .Reviewers}} +{{.}}{{end}} ({{.Approver}})"
+	msg, err = CommitMessage(ctx, st, home, "team1", task, repo)
+	if err != nil {
+		t.Fatalf("CommitMessage: %v", err)
+	}
+	want = "T" + strconv.FormatInt(id, 10) + ": Add login +bob (Ada <ada@example.com>)\n\nAgentary-Task: team1/T" + strconv.FormatInt(id, 10) +
+		"\nReviewed-by: bob\nReviewed-by: Ada <ada@example.com>\n"
+	if msg != want {
+		t.Errorf("message = %q, want %q", msg, want)
+	}
+	if _, err := ParseCommitTemplate("{{.Title"); err == nil || !strings.Contains(err.Error(), "commit") {
+		t.Errorf("ParseCommitTemplate bad template: %v", err)
+	}
+}
+
+func TestCommitMessage_humanRequestedChanges(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "home")
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "team1")
+	if err := identity.SaveHuman(home, "ada", &identity.Human{Name: "Ada", Email: "ada@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = st.CreateWorkflow(ctx, "team1", "default", 1, "builtin:default")
+	wfID, _ := st.GetWorkflowIDByTeamAndName(ctx, "team1", "default", 1)
+	id, _ := st.CreateTask(ctx, "team1", "Add login", "todo", &wfID)
+
+	// The human sent the task back and never approved it; it later reached Merging some other way.
+	_ = st.SetTaskWorkflowAndStage(ctx, id, wfID, "InApproval")
+	hctx := store.WithTransitionCause(ctx, store.TransitionCause{Actor: "human", Outcome: "changes_requested"})
+	_ = st.SetTaskWorkflowAndStage(hctx, id, wfID, "Coding")
+	cctx := store.WithTransitionCause(ctx, store.TransitionCause{Actor: "cli"})
+	_ = st.SetTaskWorkflowAndStage(cctx, id, wfID, "Merging")
+	task, _ := st.GetTaskByIDAndTeam(ctx, "team1", id)
+
+	msg, err := CommitMessage(ctx, st, home, "team1", task, nil)
+	if err != nil {
+		t.Fatalf("CommitMessage: %v", err)
+	}
+	if strings.Contains(msg, "Reviewed-by: Ada") {
+		t.Errorf("message = %q, want no human approver", msg)
+	}
+}
@@ -0,0 +1,379 @@
+package merge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ankittk/agentary/internal/checks"
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// Merge queue entry statuses (store.MergeQueueEntry.Status).
+const (
+	QueueQueued   = "queued"
+	QueueTesting  = "testing"
+	QueueMerged   = "merged"
+	QueueFailed   = "failed"
+	QueueRemoved  = "removed"
+	QueueConflict = "conflict"
+)
+
+// OutcomeConflict is the outcome a task leaves its merge stage with when its branch conflicts with the target.
+const OutcomeConflict = "conflict"
+
+// OutcomeDone is the outcome a task leaves its merge stage with once its branch has landed.
+const OutcomeDone = "done"
+
+// queueAuthor is the comment author for merge queue notes on tasks.
+const queueAuthor = "merge-queue"
+
+// recentQueueEntries is how many finished entries QueueState keeps.
+const recentQueueEntries = 20
+
+// QueueEntry is a merge queue entry as served by the API and SSE.
+type QueueEntry struct {
+	EntryID    int64     `json:"entry_id"`
+	Position   int       `json:"position,omitempty"` // 1-based landing order among open entries
+	TaskID     int64     `json:"task_id"`
+	Title      string    `json:"title"`
+	Status     string    `json:"status"`
+	Batch      int64     `json:"batch,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// QueueState is a repo's merge queue: open entries in landing order and the latest finished ones.
+type QueueState struct {
+	Team    string       `json:"team"`
+	Repo    string       `json:"repo"`
+	Entries []QueueEntry `json:"entries"`
+	Recent  []QueueEntry `json:"recent"` // newest first
+}
+
+// LoadQueueState returns the merge queue of the team's repo.
+func LoadQueueState(ctx context.Context, st store.Store, teamName, repoName string) (*QueueState, error) {
+	entries, err := st.ListMergeQueue(ctx, teamName, false)
+	if err != nil {
+		return nil, err
+	}
+	qs := &QueueState{Team: teamName, Repo: repoName, Entries: []QueueEntry{}, Recent: []QueueEntry{}}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.RepoName != repoName || isOpen(e.Status) || len(qs.Recent) >= recentQueueEntries {
+			continue
+		}
+		qs.Recent = append(qs.Recent, queueEntry(ctx, st, teamName, e))
+	}
+	for _, e := range entries {
+		if e.RepoName == repoName && isOpen(e.Status) {
+			qe := queueEntry(ctx, st, teamName, e)
+			qe.Position = len(qs.Entries) + 1
+			qs.Entries = append(qs.Entries, qe)
+		}
+	}
+	return qs, nil
+}
+
+func queueEntry(ctx context.Context, st store.Store, teamName string, e store.MergeQueueEntry) QueueEntry {
+	qe := QueueEntry{EntryID: e.EntryID, TaskID: e.TaskID, Status: e.Status, Batch: e.Batch, Note: e.Note, EnqueuedAt: e.EnqueuedAt, UpdatedAt: e.UpdatedAt}
+	if task, _ := st.GetTaskByIDAndTeam(ctx, teamName, e.TaskID); task != nil {
+		qe.Title = task.Title
+	}
+	return qe
+}
+
+func isOpen(status string) bool {
+	return status == QueueQueued || status == QueueTesting
+}
+
+// candidate is a queued task being landed; tip is its branch before the queue rebased it.
+type candidate struct {
+	entry store.MergeQueueEntry
+	task  *store.Task
+	tip   string
+}
+
+func (c *candidate) worktree() string { return *c.task.WorktreePath }
+func (c *candidate) branch() string   { return *c.task.BranchName }
+
+// processQueues lands the next batch of each of the team's repo queues.
+func (w *Worker) processQueues(ctx context.Context, teamName string) {
+	entries, err := w.Store.ListMergeQueue(ctx, teamName, true)
+	if err != nil {
+		slog.Error("merge queue list failed", "team", teamName, "err", err)
+		return
+	}
+	var repos []string
+	byRepo := make(map[string][]store.MergeQueueEntry)
+	for _, e := range entries {
+		if _, ok := byRepo[e.RepoName]; !ok {
+			repos = append(repos, e.RepoName)
+		}
+		byRepo[e.RepoName] = append(byRepo[e.RepoName], e)
+	}
+	for _, repoName := range repos {
+		w.processQueue(ctx, teamName, repoName, byRepo[repoName])
+	}
+}
+
+// processQueue drops entries whose task has moved on, then tests and lands the head of the queue: up to
+// BatchSize tasks rebased one after another on the target branch's head.
+func (w *Worker) processQueue(ctx context.Context, teamName, repoName string, entries []store.MergeQueueEntry) {
+	var repo *store.Repo
+	repos, _ := w.Store.ListRepos(ctx, teamName)
+	for i := range repos {
+		if repos[i].Name == repoName {
+			repo = &repos[i]
+		}
+	}
+	var live []*candidate
+	for _, e := range entries {
+		task, _ := w.Store.GetTaskByIDAndTeam(ctx, teamName, e.TaskID)
+		if reason := leftQueue(task, e); reason != "" {
+			_ = w.Store.UpdateMergeQueueEntry(ctx, e.EntryID, QueueRemoved, e.Batch, reason)
+			continue
+		}
+		live = append(live, &candidate{entry: e, task: task})
+	}
+	size := w.BatchSize
+	if size < 1 || (repo != nil && repo.MergeStrategy == git.StrategyFFOnly) {
+		size = 1 // ff-only cannot stack candidates on each other
+	}
+	if len(live) > size {
+		live = live[:size]
+	}
+	if len(live) > 0 {
+		w.landBatch(ctx, teamName, repo, live)
+	}
+	w.publish(ctx, teamName, repoName)
+}
+
+// leftQueue says why an entry's task no longer belongs in the queue, or "".
+func leftQueue(task *store.Task, e store.MergeQueueEntry) string {
+	switch {
+	case task == nil:
+		return "task deleted"
+	case task.Status == models.StatusDone || task.Status == models.StatusFailed || task.Status == models.StatusCancelled:
+		return "task is " + task.Status
+	case task.CurrentStage == nil || *task.CurrentStage != e.Stage:
+		return "task left " + e.Stage
+	case task.WorktreePath == nil || *task.WorktreePath == "" || task.BranchName == nil || *task.BranchName == "":
+		return "task has no branch"
+	}
+	return ""
+}
+
+// landBatch builds the batch on a staging branch started at the target's head: each candidate is rebased
+// onto the staging tip and merged into it with the repo's strategy. The repo's checks run once on the combined
+// result; if the required ones pass the target fast-forwards to it and every candidate is done. A failing
+// batch is bisected: its halves are landed in turn, down to the single task that breaks it.
+func (w *Worker) landBatch(ctx context.Context, teamName string, repo *store.Repo, batch []*candidate) {
+	batchID := batch[0].entry.EntryID
+	for _, c := range batch {
+		_ = w.Store.UpdateMergeQueueEntry(ctx, c.entry.EntryID, QueueTesting, batchID, "")
+	}
+	w.publish(ctx, teamName, batch[0].entry.RepoName)
+
+	dir := batch[0].worktree()
+	targetName := ""
+	if repo != nil {
+		targetName = repo.TargetBranch
+	}
+	target := git.ResolveTarget(ctx, dir, targetName)
+	head, err := git.TargetHead(ctx, dir, target)
+	if err != nil {
+		for _, c := range batch {
+			w.fail(ctx, teamName, c, batchID, err.Error())
+		}
+		return
+	}
+	staging := "agentary/queue/" + target
+	if err := git.SetBranch(ctx, dir, staging, head); err != nil {
+		for _, c := range batch {
+			w.fail(ctx, teamName, c, batchID, err.Error())
+		}
+		return
+	}
+
+	var staged []*candidate
+	for _, c := range batch {
+		if c.tip, err = git.RevParse(ctx, c.worktree(), c.branch()); err != nil {
+			w.fail(ctx, teamName, c, batchID, err.Error())
+			continue
+		}
+		if repo == nil || repo.MergeStrategy != git.StrategyFFOnly {
+			if err := git.RebaseBranch(ctx, c.worktree(), c.branch(), staging); err != nil {
+				var conflict *git.ConflictError
+				if errors.As(err, &conflict) {
+					w.conflict(ctx, teamName, c, batchID, target, conflict)
+					continue
+				}
+				w.fail(ctx, teamName, c, batchID, "rebase onto the queue head failed: "+err.Error())
+				continue
+			}
+		}
+		opts, err := mergeOptions(ctx, w.Store, w.Home, teamName, c.task, repo)
+		if err == nil {
+			opts.Target = staging
+			_, err = git.Merge(ctx, c.worktree(), c.branch(), opts)
+		}
+		if err != nil {
+			w.restore(ctx, c)
+			var conflict *git.ConflictError
+			if errors.As(err, &conflict) {
+				w.conflict(ctx, teamName, c, batchID, target, conflict)
+				continue
+			}
+			w.fail(ctx, teamName, c, batchID, "merge failed: "+err.Error())
+			continue
+		}
+		staged = append(staged, c)
+	}
+	if len(staged) == 0 {
+		return
+	}
+
+	// The last worktree to merge is at the staging tip: the target plus the whole batch. The checks' results
+	// are recorded on every task in the batch.
+	ids := make([]int64, len(staged))
+	for i, c := range staged {
+		ids[i] = c.task.TaskID
+	}
+	testErr := checks.Gate(ctx, w.Store, w.Home, teamName, repo, staged[len(staged)-1].worktree(), ids...)
+	if testErr == nil {
+		tip, err := git.RevParse(ctx, dir, staging)
+		if err == nil {
+			err = git.AdvanceBranch(ctx, dir, target, tip)
+		}
+		if err != nil {
+			// The target moved under us: put the batch back at the head of the queue for the next round.
+			for _, c := range staged {
+				w.restore(ctx, c)
+				_ = w.Store.UpdateMergeQueueEntry(ctx, c.entry.EntryID, QueueQueued, batchID, err.Error())
+			}
+			return
+		}
+		for _, c := range staged {
+			_ = w.Store.UpdateMergeQueueEntry(ctx, c.entry.EntryID, QueueMerged, batchID, "")
+			w.finishTask(ctx, teamName, c.task)
+		}
+		return
+	}
+	for _, c := range staged {
+		w.restore(ctx, c)
+	}
+	if len(staged) == 1 {
+		w.fail(ctx, teamName, staged[0], batchID, testErr.Error())
+		return
+	}
+	slog.Info("merge queue bisecting failed batch", "team", teamName, "batch", batchID, "size", len(staged))
+	mid := len(staged) / 2
+	w.landBatch(ctx, teamName, repo, staged[:mid])
+	w.landBatch(ctx, teamName, repo, staged[mid:])
+}
+
+// restore puts the candidate's branch back where it was before the queue rebased it and checks it out.
+func (w *Worker) restore(ctx context.Context, c *candidate) {
+	if c.tip == "" {
+		return
+	}
+	if err := git.ResetBranch(ctx, c.worktree(), c.branch(), c.tip); err != nil {
+		slog.Warn("merge queue restore branch failed", "task_id", c.task.TaskID, "err", err)
+	}
+}
+
+// fail takes the candidate out of the queue and fails its task, leaving the reason on the task.
+func (w *Worker) fail(ctx context.Context, teamName string, c *candidate, batchID int64, reason string) {
+	slog.Error("merge queue failed task", "task_id", c.task.TaskID, "team", teamName, "err", reason)
+	_ = w.Store.UpdateMergeQueueEntry(ctx, c.entry.EntryID, QueueFailed, batchID, reason)
+	_ = w.Store.SetTaskFailed(ctx, c.task.TaskID)
+	_, _ = w.Store.CreateTaskComment(ctx, teamName, c.task.TaskID, queueAuthor, fmt.Sprintf("Merge queue: %s", reason))
+}
+
+// conflict takes the candidate out of the queue and sends its task back to be resolved: it leaves a comment
+// listing the conflicted files and applies the conflict outcome (by default back to Coding), assigned to
+// the task's DRI. The next agent turn merges the target into the branch and resolves the conflicts.
+func (w *Worker) conflict(ctx context.Context, teamName string, c *candidate, batchID int64, target string, ce *git.ConflictError) {
+	task := c.task
+	slog.Info("merge queue conflict", "task_id", task.TaskID, "team", teamName, "files", ce.Files)
+	_ = w.Store.UpdateMergeQueueEntry(ctx, c.entry.EntryID, QueueConflict, batchID, "conflicts in "+strings.Join(ce.Files, ", "))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Merge queue: branch %s conflicts with %s.\n\nConflicted files:\n", c.branch(), target)
+	for _, f := range ce.Files {
+		fmt.Fprintf(&b, "- %s\n", f)
+	}
+	fmt.Fprintf(&b, "\nThe %s was aborted and the branch left unchanged; the task goes back to its DRI to resolve the conflicts.", ce.Op)
+	_, _ = w.Store.CreateTaskComment(ctx, teamName, task.TaskID, queueAuthor, b.String())
+
+	cctx := store.WithTransitionCause(ctx, store.TransitionCause{Actor: queueAuthor, Outcome: OutcomeConflict, Note: strings.Join(ce.Files, ", ")})
+	next := ""
+	if w.ApplyOutcome != nil {
+		var err error
+		if next, err = w.ApplyOutcome(cctx, teamName, task, OutcomeConflict); err != nil {
+			slog.Warn("merge queue conflict outcome failed", "task_id", task.TaskID, "err", err)
+		}
+	}
+	if next == "" {
+		// No conflict transition: fall back to the workflow's first agent stage.
+		if stage := firstAgentStage(ctx, w.Store, task); stage != "" {
+			_ = w.Store.SetTaskWorkflowAndStage(cctx, task.TaskID, *task.WorkflowID, stage)
+		}
+	}
+	if err := w.Store.UpdateTask(ctx, task.TaskID, models.StatusTodo, task.DRI); err != nil {
+		slog.Warn("merge queue conflict reassign failed", "task_id", task.TaskID, "err", err)
+	}
+}
+
+// firstAgentStage returns the first agent stage reachable from the task's workflow's initial stage, or "".
+func firstAgentStage(ctx context.Context, st store.Store, task *store.Task) string {
+	if task.WorkflowID == nil || *task.WorkflowID == "" {
+		return ""
+	}
+	stages, err := st.GetWorkflowStages(ctx, *task.WorkflowID)
+	if err != nil {
+		return ""
+	}
+	types := make(map[string]string, len(stages))
+	for _, s := range stages {
+		types[s.StageName] = s.StageType
+	}
+	transitions, _ := st.GetWorkflowTransitions(ctx, *task.WorkflowID)
+	initial, _ := st.GetWorkflowInitialStage(ctx, *task.WorkflowID)
+	seen := map[string]bool{}
+	for queue := []string{initial}; len(queue) > 0; queue = queue[1:] {
+		name := queue[0]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if types[name] == "agent" {
+			return name
+		}
+		for _, tr := range transitions {
+			if tr.FromStage == name {
+				queue = append(queue, tr.ToStage)
+			}
+		}
+	}
+	return ""
+}
+
+// publish sends the repo's queue state as a merge_queue event.
+func (w *Worker) publish(ctx context.Context, teamName, repoName string) {
+	if w.Publish == nil {
+		return
+	}
+	qs, err := LoadQueueState(ctx, w.Store, teamName, repoName)
+	if err != nil {
+		return
+	}
+	w.Publish(map[string]any{"type": "merge_queue", "team": teamName, "repo": repoName, "queue": qs})
+}
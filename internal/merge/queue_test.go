@@ -0,0 +1,265 @@
+package merge
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// gitRun runs git in dir with a fixed identity.
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return string(out)
+}
+
+// queueFixture is a team with a repo, the default workflow and a task per file, each on its own worktree
+// with one commit adding that file, all in the Merging stage.
+func queueFixture(t *testing.T, testCmd string, files ...string) (store.Store, string, []int64) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	home := filepath.Join(t.TempDir(), "home")
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	src := filepath.Join(t.TempDir(), "src")
+	gitRun(t, "", "init", "-q", "-b", "main", src)
+	_ = os.WriteFile(filepath.Join(src, "README"), []byte("hi"), 0o644)
+	gitRun(t, src, "add", ".")
+	gitRun(t, src, "commit", "-q", "-m", "init")
+
+	_, _ = st.CreateTeam(ctx, "team1")
+	if err := st.CreateRepo(ctx, "team1", "app", src, "manual", &testCmd); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = st.CreateWorkflow(ctx, "team1", "default", 1, "builtin:default")
+	wfID, _ := st.GetWorkflowIDByTeamAndName(ctx, "team1", "default", 1)
+	mirror := git.MirrorPath(home, "team1", "app")
+	var ids []int64
+	for _, f := range files {
+		id, _ := st.CreateTask(ctx, "team1", "add "+f, models.StatusInProgress, &wfID)
+		wt := git.WorktreePath(home, "team1", "app", id)
+		branch := git.BranchName("x", "team1", id)
+		base, err := git.CreateWorktree(ctx, mirror, wt, src, branch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = os.WriteFile(filepath.Join(wt, f), []byte(f), 0o644)
+		gitRun(t, wt, "add", f)
+		gitRun(t, wt, "commit", "-q", "-m", "add "+f)
+		repo := "app"
+		if err := st.UpdateTaskGitFields(ctx, id, &wt, &branch, &base, &repo); err != nil {
+			t.Fatal(err)
+		}
+		_ = st.SetTaskWorkflowAndStage(ctx, id, wfID, "Merging")
+		ids = append(ids, id)
+	}
+	return st, mirror, ids
+}
+
+func TestQueue_batchLands(t *testing.T) {
+	st, mirror, ids := queueFixture(t, "test -f a.txt && test -f b.txt", "a.txt", "b.txt")
+	ctx := context.Background()
+	var events int
+	w := &Worker{Store: st, Interval: time.Hour, BatchSize: 2, Publish: func(any) { events++ }}
+	w.runOnce(ctx)
+
+	// Both files are on main: the batch was tested as one tree.
+	gitRun(t, mirror, "cat-file", "-e", "main:a.txt")
+	gitRun(t, mirror, "cat-file", "-e", "main:b.txt")
+	for _, id := range ids {
+		task, _ := st.GetTaskByIDAndTeam(ctx, "team1", id)
+		if task.Status != models.StatusDone || *task.CurrentStage != "Done" {
+			t.Errorf("task %d = %s in %s, want done in Done", id, task.Status, *task.CurrentStage)
+		}
+	}
+	qs, err := LoadQueueState(ctx, st, "team1", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qs.Entries) != 0 || len(qs.Recent) != 2 {
+		t.Fatalf("queue = %+v, want 2 finished entries", qs)
+	}
+	for _, e := range qs.Recent {
+		if e.Status != QueueMerged || e.Batch != qs.Recent[1].EntryID {
+			t.Errorf("entry %+v, want merged in batch %d", e, qs.Recent[1].EntryID)
+		}
+	}
+	if events == 0 {
+		t.Error("no merge_queue events published")
+	}
+}
+
+func TestQueue_bisectsFailingBatch(t *testing.T) {
+	// Each task is green alone, but together they break the build.
+	st, mirror, ids := queueFixture(t, "! (test -f a.txt && test -f b.txt)", "a.txt", "b.txt", "c.txt")
+	ctx := context.Background()
+	w := &Worker{Store: st, Interval: time.Hour, BatchSize: 3}
+	w.runOnce(ctx)
+
+	gitRun(t, mirror, "cat-file", "-e", "main:a.txt")
+	gitRun(t, mirror, "cat-file", "-e", "main:c.txt")
+	if err := exec.Command("git", "-C", mirror, "cat-file", "-e", "main:b.txt").Run(); err == nil {
+		t.Error("b.txt landed on main")
+	}
+	want := []string{models.StatusDone, models.StatusFailed, models.StatusDone}
+	for i, id := range ids {
+		task, _ := st.GetTaskByIDAndTeam(ctx, "team1", id)
+		if task.Status != want[i] {
+			t.Errorf("task %d status = %s, want %s", id, task.Status, want[i])
+		}
+	}
+	// The failed task's branch is back to its own commit on the old base, checked out for a fix.
+	failed, _ := st.GetTaskByIDAndTeam(ctx, "team1", ids[1])
+	if n := gitRun(t, *failed.WorktreePath, "rev-list", "--count", "HEAD"); n != "2\n" {
+		t.Errorf("failed branch has %s commits, want 2 (init + its own)", n)
+	}
+	comments, _ := st.ListTaskComments(ctx, "team1", ids[1])
+	if len(comments) == 0 || comments[0].Author != queueAuthor {
+		t.Errorf("comments = %+v, want a merge queue note", comments)
+	}
+	qs, _ := LoadQueueState(ctx, st, "team1", "app")
+	if len(qs.Entries) != 0 || len(qs.Recent) != 3 {
+		t.Fatalf("queue = %+v", qs)
+	}
+}
+
+func TestQueue_dropsTasksThatMovedOn(t *testing.T) {
+	st, _, ids := queueFixture(t, "true", "a.txt")
+	ctx := context.Background()
+	task, _ := st.GetTaskByIDAndTeam(ctx, "team1", ids[0])
+	if queued, err := Enqueue(ctx, st, "team1", task); !queued || err != nil {
+		t.Fatalf("Enqueue = %v, %v", queued, err)
+	}
+	_ = st.SetTaskCancelled(ctx, "team1", ids[0])
+	w := &Worker{Store: st, Interval: time.Hour}
+	w.runOnce(ctx)
+	qs, _ := LoadQueueState(ctx, st, "team1", "app")
+	if len(qs.Recent) != 1 || qs.Recent[0].Status != QueueRemoved {
+		t.Fatalf("queue = %+v, want the entry removed", qs)
+	}
+}
+
+func TestQueue_conflictGoesBackToDRI(t *testing.T) {
+	st, mirror, ids := queueFixture(t, "true", "a.txt", "b.txt")
+	ctx := context.Background()
+	// The second task also adds a.txt, with other content.
+	second, _ := st.GetTaskByIDAndTeam(ctx, "team1", ids[1])
+	_ = os.WriteFile(filepath.Join(*second.WorktreePath, "a.txt"), []byte("other"), 0o644)
+	gitRun(t, *second.WorktreePath, "add", "a.txt")
+	gitRun(t, *second.WorktreePath, "commit", "-q", "-m", "add a.txt too")
+	_ = st.UpdateTask(ctx, ids[1], models.StatusTodo, nil)
+	if ok, err := st.ClaimTask(ctx, "team1", ids[1], "alice"); !ok || err != nil {
+		t.Fatalf("ClaimTask = %v, %v", ok, err)
+	}
+	_ = st.UpdateTask(ctx, ids[1], models.StatusInProgress, nil)
+
+	var outcomes []string
+	w := &Worker{Store: st, Interval: time.Hour, ApplyOutcome: func(_ context.Context, _ string, _ *store.Task, outcome string) (string, error) {
+		outcomes = append(outcomes, outcome)
+		return "", nil // no transition: the queue falls back to the first agent stage
+	}}
+	w.runOnce(ctx) // lands the first task
+	w.runOnce(ctx) // the second conflicts with it
+
+	gitRun(t, mirror, "cat-file", "-e", "main:a.txt")
+	if len(outcomes) != 2 || outcomes[0] != OutcomeDone || outcomes[1] != OutcomeConflict {
+		t.Errorf("outcomes = %v, want [done conflict]", outcomes)
+	}
+	task, _ := st.GetTaskByIDAndTeam(ctx, "team1", ids[1])
+	if task.Status != models.StatusTodo || *task.CurrentStage != "Coding" || task.Assignee == nil || *task.Assignee != "alice" {
+		t.Errorf("task = %s in %s assigned to %v, want todo in Coding for alice", task.Status, *task.CurrentStage, task.Assignee)
+	}
+	transitions, _ := st.ListTaskTransitions(ctx, "team1", ids[1])
+	if last := transitions[len(transitions)-1]; last.Outcome != OutcomeConflict || last.Actor != queueAuthor || last.Note != "a.txt" {
+		t.Errorf("last transition = %+v, want conflict by %s", last, queueAuthor)
+	}
+	comments, _ := st.ListTaskComments(ctx, "team1", ids[1])
+	if len(comments) == 0 || !strings.Contains(comments[0].Body, "Conflicted files:\n- a.txt") {
+		t.Errorf("comments = %+v, want the conflicted files", comments)
+	}
+	qs, _ := LoadQueueState(ctx, st, "team1", "app")
+	if len(qs.Entries) != 0 || qs.Recent[0].Status != QueueConflict {
+		t.Errorf("queue = %+v, want the entry closed as conflict", qs)
+	}
+}
+
+func TestQueue_requiredChecksGateMerge(t *testing.T) {
+	st, mirror, ids := queueFixture(t, "", "a.txt")
+	ctx := context.Background()
+	_ = st.SetRepoChecks(ctx, "team1", "app", []store.RepoCheck{
+		{Name: "lint", Command: "echo lint warning; exit 1"},
+		{Name: "unit", Command: "echo unit failed >&2; test -f missing.txt", Required: true},
+	})
+	w := &Worker{Store: st, Interval: time.Hour}
+	w.runOnce(ctx)
+
+	if err := exec.Command("git", "-C", mirror, "cat-file", "-e", "main:a.txt").Run(); err == nil {
+		t.Error("a.txt landed on main despite a failing required check")
+	}
+	task, _ := st.GetTaskByIDAndTeam(ctx, "team1", ids[0])
+	if task.Status != models.StatusFailed {
+		t.Errorf("task status = %s, want failed", task.Status)
+	}
+	comments, _ := st.ListTaskComments(ctx, "team1", ids[0])
+	if len(comments) == 0 || comments[0].Body != "Merge queue: required checks failed: unit failed (exit 1)" {
+		t.Errorf("comments = %+v", comments)
+	}
+	runs, _ := st.ListTaskCheckRuns(ctx, "team1", ids[0])
+	if len(runs) != 2 || runs[0].Log != "lint warning\n" || runs[1].Log != "unit failed\n" || runs[1].HeadSHA == "" {
+		t.Errorf("check runs = %+v, want both logs from one attempt", runs)
+	}
+}
+
+func TestQueue_doneMovesToNextStage(t *testing.T) {
+	st, mirror, ids := queueFixture(t, "true", "a.txt")
+	ctx := context.Background()
+	// Merging is followed by a Deploy stage rather than the terminal one.
+	wfID, err := st.CreateWorkflowWithStages(ctx, "team1", "deploy", 1, "builtin:deploy",
+		[]store.WorkflowStage{
+			{StageName: "Merging", StageType: "auto", Outcomes: "done"},
+			{StageName: "Deploy", StageType: "agent", Outcomes: "done"},
+			{StageName: "Done", StageType: "terminal"},
+		},
+		[]store.WorkflowTransition{
+			{FromStage: "Merging", Outcome: "done", ToStage: "Deploy"},
+			{FromStage: "Deploy", Outcome: "done", ToStage: "Done"},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = st.SetTaskWorkflowAndStage(ctx, ids[0], wfID, "Merging")
+	w := &Worker{Store: st, Interval: time.Hour}
+	w.runOnce(ctx)
+
+	gitRun(t, mirror, "cat-file", "-e", "main:a.txt")
+	task, _ := st.GetTaskByIDAndTeam(ctx, "team1", ids[0])
+	if task.Status != models.StatusTodo || *task.CurrentStage != "Deploy" {
+		t.Fatalf("task = %s in %s, want todo in Deploy", task.Status, *task.CurrentStage)
+	}
+	if task.WorktreePath == nil {
+		t.Fatal("worktree cleared for a task that is not done")
+	}
+	if _, err := os.Stat(*task.WorktreePath); err != nil {
+		t.Fatalf("worktree removed: %v", err)
+	}
+}
@@ -5,18 +5,26 @@ import (
 	"log/slog"
 	"time"
 
-	"github.com/ankittk/agentary/internal/git"
 	"github.com/ankittk/agentary/internal/store"
 	"github.com/ankittk/agentary/pkg/models"
 )
 
-// Worker polls for tasks in "Merging" stage, rebases onto main, runs test_cmd, merges, marks done, and cleans worktree.
+// Worker runs the merge queues: it queues tasks in the "Merging" stage (merge stages queue their own tasks),
+// then tests each repo's next batch rebased on the target branch's head and lands it (see landBatch).
 type Worker struct {
 	Store store.Store
+	// Home locates the human identity for merge commits (optional)
+	Home string
 	// Interval between poll rounds
 	Interval time.Duration
-	// RebaseBeforeMerge runs rebase onto origin/main before merge when true
-	RebaseBeforeMerge bool
+	// BatchSize is how many queued tasks are tested together; 0 or 1 lands them one at a time
+	BatchSize int
+	// Publish receives merge_queue events with a repo's queue state (optional; e.g. the SSE hub)
+	Publish func(v any)
+	// ApplyOutcome moves a task along its workflow, e.g. the workflow engine's ApplyOutcome; the queue uses it
+	// to move landed tasks on with "done" and to send conflicting tasks back with "conflict" (optional; without
+	// it landed tasks go to the Done stage and conflicting ones to the first agent stage)
+	ApplyOutcome func(ctx context.Context, teamName string, task *store.Task, outcome string) (string, error)
 }
 
 const defaultMergeInterval = 15 * time.Second
@@ -40,82 +48,37 @@ func (w *Worker) Run(ctx context.Context) {
 }
 
 func (w *Worker) runOnce(ctx context.Context) {
+	// Merges count as new work: respect the global and per-team scheduler pause.
+	if state, err := w.Store.GetSchedulerState(ctx); err == nil && state.Paused {
+		return
+	}
 	teams, err := w.Store.ListTeams(ctx)
 	if err != nil {
 		slog.Error("merge worker list teams failed", "err", err)
 		return
 	}
 	for _, t := range teams {
+		if t.Paused {
+			continue
+		}
 		tasks, err := w.Store.ListTasksInStage(ctx, t.Name, "Merging", 20)
 		if err != nil {
 			slog.Error("merge worker list tasks in stage failed", "team", t.Name, "err", err)
 			continue
 		}
 		for _, task := range tasks {
-			w.processTask(ctx, t.Name, &task)
-		}
-	}
-}
-
-func (w *Worker) processTask(ctx context.Context, teamName string, task *store.Task) {
-	worktreePath := ""
-	if task.WorktreePath != nil {
-		worktreePath = *task.WorktreePath
-	}
-	branchName := ""
-	if task.BranchName != nil {
-		branchName = *task.BranchName
-	}
-	wfID := ""
-	if task.WorkflowID != nil {
-		wfID = *task.WorkflowID
-	}
-	if wfID == "" {
-		return
-	}
-
-	if worktreePath != "" && branchName != "" && w.RebaseBeforeMerge {
-		if err := git.RebaseOntoMain(ctx, worktreePath, branchName); err != nil {
-			slog.Error("merge worker rebase failed", "task_id", task.TaskID, "err", err)
-			_ = w.Store.SetTaskFailed(ctx, task.TaskID)
-			return
-		}
-	}
-
-	if worktreePath != "" {
-		repos, _ := w.Store.ListRepos(ctx, teamName)
-		var repo *store.Repo
-		for i := range repos {
-			if task.RepoName != nil && repos[i].Name == *task.RepoName {
-				repo = &repos[i]
-				break
+			if task.Status == models.StatusDone || task.Status == models.StatusFailed || task.Status == models.StatusCancelled {
+				continue
 			}
-		}
-		if repo == nil && len(repos) > 0 {
-			repo = &repos[0]
-		}
-		if repo != nil && repo.TestCmd != nil && *repo.TestCmd != "" {
-			if err := git.RunTestCmd(ctx, worktreePath, *repo.TestCmd); err != nil {
-				slog.Error("merge worker test failed", "task_id", task.TaskID, "err", err)
-				_ = w.Store.SetTaskFailed(ctx, task.TaskID)
-				return
+			queued, err := Enqueue(ctx, w.Store, t.Name, &task)
+			if err != nil {
+				slog.Error("merge worker enqueue failed", "task_id", task.TaskID, "err", err)
+				continue
 			}
-		}
-
-		if branchName != "" {
-			if err := git.MergeInWorktree(ctx, worktreePath, branchName); err != nil {
-				slog.Error("merge worker merge failed", "task_id", task.TaskID, "err", err)
-				_ = w.Store.SetTaskFailed(ctx, task.TaskID)
-				return
+			if !queued && task.WorkflowID != nil && *task.WorkflowID != "" {
+				w.finishTask(ctx, t.Name, &task) // nothing to land
 			}
 		}
+		w.processQueues(ctx, t.Name)
 	}
-
-	_ = w.Store.SetTaskWorkflowAndStage(ctx, task.TaskID, wfID, "Done")
-	_ = w.Store.UpdateTask(ctx, task.TaskID, models.StatusDone, nil)
-	_ = w.Store.ClearTaskGitFields(ctx, task.TaskID)
-	if worktreePath != "" {
-		_ = git.DeleteWorktree(ctx, worktreePath)
-	}
-	slog.Info("merge worker completed task", "task_id", task.TaskID, "team", teamName)
 }
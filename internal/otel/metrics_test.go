@@ -2,6 +2,9 @@ package otel
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -55,3 +58,22 @@ func TestInitMetricsWithTaskCount_nilFunc(t *testing.T) {
 		t.Fatalf("InitMetricsWithTaskCount(nil): %v", err)
 	}
 }
+
+func TestInitWorktreeMetrics(t *testing.T) {
+	ctx := context.Background()
+	h, err := InitMeterProvider(ctx, "worktree-test")
+	if err != nil {
+		t.Fatalf("InitMeterProvider: %v", err)
+	}
+	err = InitWorktreeMetrics(ctx, func() []WorktreeUsage {
+		return []WorktreeUsage{{Team: "t1", Bytes: 1 << 20, Quota: 1 << 30, Active: 1, Collectable: 2}}
+	})
+	if err != nil {
+		t.Fatalf("InitWorktreeMetrics: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `agentary_worktree_bytes{`) || !strings.Contains(body, `state="collectable"`) {
+		t.Fatalf("metrics missing worktree usage:\n%s", body)
+	}
+}
@@ -0,0 +1,64 @@
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// WorktreeUsage is one team's task worktree disk usage, as last seen by the worktree GC.
+type WorktreeUsage struct {
+	Team        string
+	Bytes       int64
+	Quota       int64 // 0 = no quota
+	Active      int   // worktrees of tasks that may still run
+	Collectable int   // worktrees of finished or missing tasks, not yet removed
+	Removed     int64 // worktrees removed since start
+	Freed       int64 // bytes freed since start
+}
+
+// WorktreeUsageFunc returns the current usage of every team.
+type WorktreeUsageFunc func() []WorktreeUsage
+
+// InitWorktreeMetrics registers the agentary_worktree_* instruments, observed through usage.
+// Call after InitMeterProvider.
+func InitWorktreeMetrics(ctx context.Context, usage WorktreeUsageFunc) error {
+	if usage == nil {
+		return nil
+	}
+	m := Meter()
+	bytesGauge, err := m.Int64ObservableGauge("agentary_worktree_bytes", metric.WithDescription("Disk used by task worktrees per team"), metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	quotaGauge, err := m.Int64ObservableGauge("agentary_worktree_quota_bytes", metric.WithDescription("Worktree disk quota per team (0 = none)"), metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	countGauge, err := m.Int64ObservableGauge("agentary_worktrees", metric.WithDescription("Task worktrees per team by state (active or collectable)"))
+	if err != nil {
+		return err
+	}
+	removedCounter, err := m.Int64ObservableCounter("agentary_worktree_gc_removed_total", metric.WithDescription("Task worktrees removed by the worktree GC"))
+	if err != nil {
+		return err
+	}
+	freedCounter, err := m.Int64ObservableCounter("agentary_worktree_gc_freed_bytes_total", metric.WithDescription("Disk freed by the worktree GC"), metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	_, err = m.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		for _, u := range usage() {
+			team := metric.WithAttributes(AttrTeam.String(u.Team))
+			o.ObserveInt64(bytesGauge, u.Bytes, team)
+			o.ObserveInt64(quotaGauge, u.Quota, team)
+			o.ObserveInt64(countGauge, int64(u.Active), metric.WithAttributes(AttrTeam.String(u.Team), attribute.String("state", "active")))
+			o.ObserveInt64(countGauge, int64(u.Collectable), metric.WithAttributes(AttrTeam.String(u.Team), attribute.String("state", "collectable")))
+			o.ObserveInt64(removedCounter, u.Removed, team)
+			o.ObserveInt64(freedCounter, u.Freed, team)
+		}
+		return nil
+	}, bytesGauge, quotaGauge, countGauge, removedCounter, freedCounter)
+	return err
+}
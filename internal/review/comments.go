@@ -0,0 +1,200 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// Thread is an inline review thread: where it is anchored, its state, and its comments, first one first.
+type Thread struct {
+	ID        int64           `json:"id"`
+	ReviewID  int64           `json:"review_id,omitempty"`
+	Path      string          `json:"path"`
+	StartLine int             `json:"start_line"`
+	EndLine   int             `json:"end_line"`
+	CommitSHA string          `json:"commit_sha"`
+	Resolved  bool            `json:"resolved"`
+	Outdated  bool            `json:"outdated"`
+	Comments  []ThreadComment `json:"comments"`
+}
+
+// ThreadComment is one comment in a Thread.
+type ThreadComment struct {
+	ID        int64     `json:"id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InlineComment starts a thread on lines StartLine..EndLine of Path as of CommitSHA ("" = the task
+// branch tip). It is how reviews carry line comments.
+type InlineComment struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	CommitSHA string `json:"commit_sha"`
+	Body      string `json:"body"`
+}
+
+// comment returns c as a new thread on the task by author.
+func (c InlineComment) comment(taskID int64, author string) store.ReviewComment {
+	return store.ReviewComment{TaskID: taskID, Author: author, Path: c.Path, StartLine: c.StartLine, EndLine: c.EndLine, CommitSHA: c.CommitSHA, Body: c.Body}
+}
+
+// checkInline validates inline comments before anything is stored.
+func checkInline(inline []InlineComment) error {
+	for _, c := range inline {
+		rc := c.comment(0, "")
+		if strings.TrimSpace(c.Body) == "" {
+			return fmt.Errorf("inline comment on %s: body required", c.Path)
+		}
+		if err := store.CheckReviewCommentAnchor(&rc); err != nil {
+			return fmt.Errorf("inline comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// Threads groups a task's comments into threads, oldest first.
+func Threads(comments []store.ReviewComment) []Thread {
+	var threads []Thread
+	index := make(map[int64]int)
+	for _, c := range comments {
+		tc := ThreadComment{ID: c.CommentID, Author: c.Author, Body: c.Body, CreatedAt: c.CreatedAt}
+		if c.InReplyTo == 0 {
+			index[c.CommentID] = len(threads)
+			threads = append(threads, Thread{ID: c.CommentID, ReviewID: c.ReviewID, Path: c.Path, StartLine: c.StartLine, EndLine: c.EndLine,
+				CommitSHA: c.CommitSHA, Resolved: c.Resolved, Outdated: c.Outdated, Comments: []ThreadComment{tc}})
+			continue
+		}
+		if i, ok := index[c.InReplyTo]; ok {
+			threads[i].Comments = append(threads[i].Comments, tc)
+		}
+	}
+	return threads
+}
+
+// branchTip returns the commit the task branch points at, or "" when the task has no worktree.
+func branchTip(ctx context.Context, task *store.Task) string {
+	if task.WorktreePath == nil || *task.WorktreePath == "" {
+		return ""
+	}
+	ref := "HEAD"
+	if task.BranchName != nil && *task.BranchName != "" {
+		ref = *task.BranchName
+	}
+	sha, _ := git.RevParse(ctx, *task.WorktreePath, ref)
+	return sha
+}
+
+// AddComment stores a new thread or, with InReplyTo set, a reply. A new thread without a commit is
+// anchored to the task branch tip; one with a commit must name a commit in the task's worktree.
+func AddComment(ctx context.Context, st store.Store, teamName string, task *store.Task, c store.ReviewComment) (int64, error) {
+	c.TaskID = task.TaskID
+	if c.InReplyTo == 0 && c.CommitSHA == "" {
+		c.CommitSHA = branchTip(ctx, task)
+	} else if c.InReplyTo == 0 && task.WorktreePath != nil && *task.WorktreePath != "" && !git.CommitExists(ctx, *task.WorktreePath, c.CommitSHA) {
+		return 0, fmt.Errorf("commit_sha %s is not a commit in the task's repository", c.CommitSHA)
+	}
+	return st.CreateReviewComment(ctx, teamName, c)
+}
+
+// AddInlineComments starts a thread for each inline comment of review reviewID by author.
+func AddInlineComments(ctx context.Context, st store.Store, teamName string, task *store.Task, reviewID int64, author string, inline []InlineComment) error {
+	for _, c := range inline {
+		rc := c.comment(task.TaskID, author)
+		rc.ReviewID = reviewID
+		if _, err := AddComment(ctx, st, teamName, task, rc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemapComments follows the task branch: each open thread anchored to an older commit moves to the
+// branch tip, or is marked outdated when the branch changed its lines or removed its file, or its lines
+// cannot be mapped at all (e.g. its commit is gone after a force-push).
+func RemapComments(ctx context.Context, st store.Store, teamName string, task *store.Task) error {
+	tip := branchTip(ctx, task)
+	if tip == "" {
+		return nil
+	}
+	comments, err := st.ListReviewComments(ctx, teamName, task.TaskID)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if c.InReplyTo != 0 || c.Resolved || c.Outdated || c.CommitSHA == "" || c.CommitSHA == tip {
+			continue
+		}
+		start, end, ok, err := git.MapLines(ctx, *task.WorktreePath, c.CommitSHA, tip, c.Path, c.StartLine, c.EndLine)
+		if err != nil {
+			slog.Warn("review comment remap failed", "task_id", task.TaskID, "comment_id", c.CommentID, "err", err)
+			ok = false
+		}
+		sha := tip
+		if !ok { // keep the original anchor for context
+			start, end, sha = c.StartLine, c.EndLine, c.CommitSHA
+		}
+		if err := st.UpdateReviewCommentAnchor(ctx, c.CommentID, start, end, sha, !ok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TaskThreads remaps the task's threads to its branch tip and returns them.
+func TaskThreads(ctx context.Context, st store.Store, teamName string, task *store.Task) ([]Thread, error) {
+	if err := RemapComments(ctx, st, teamName, task); err != nil {
+		return nil, err
+	}
+	comments, err := st.ListReviewComments(ctx, teamName, task.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	return Threads(comments), nil
+}
+
+var errNoAnchor = errors.New("not an inline comment")
+
+// inlinePattern matches "path:line: text" and "path:start-end: text".
+var inlinePattern = regexp.MustCompile(`^(\S+?):(\d+)(?:-(\d+))?:\s+(\S.*)$`)
+
+// ParseInlineComments picks the lines of a review reply written as "path:line: text" or
+// "path:start-end: text", which is how agent reviewers comment on code.
+func ParseInlineComments(text string) []InlineComment {
+	var out []InlineComment
+	for _, line := range strings.Split(text, "\n") {
+		c, err := parseInline(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*")))
+		if err == nil {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func parseInline(line string) (InlineComment, error) {
+	m := inlinePattern.FindStringSubmatch(line)
+	if m == nil {
+		return InlineComment{}, errNoAnchor
+	}
+	c := InlineComment{Path: m[1], Body: m[4]}
+	c.StartLine, _ = strconv.Atoi(m[2])
+	c.EndLine = c.StartLine
+	if m[3] != "" {
+		c.EndLine, _ = strconv.Atoi(m[3])
+	}
+	if c.StartLine < 1 || c.EndLine < c.StartLine {
+		return InlineComment{}, errNoAnchor
+	}
+	return c, nil
+}
@@ -0,0 +1,126 @@
+package review
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func TestParseInlineComments(t *testing.T) {
+	t.Parallel()
+	got := ParseInlineComments("changes needed\n- main.go:12: handle the error\n* pkg/x.go:3-7:  too clever\nsee http://x: y\nbad.go:0: zero\nrange.go:5-2: backwards")
+	if len(got) != 2 {
+		t.Fatalf("ParseInlineComments = %+v", got)
+	}
+	if c := got[0]; c.Path != "main.go" || c.StartLine != 12 || c.EndLine != 12 || c.Body != "handle the error" {
+		t.Errorf("first = %+v", c)
+	}
+	if c := got[1]; c.Path != "pkg/x.go" || c.StartLine != 3 || c.EndLine != 7 || c.Body != "too clever" {
+		t.Errorf("second = %+v", c)
+	}
+}
+
+func TestThreads(t *testing.T) {
+	t.Parallel()
+	threads := Threads([]store.ReviewComment{
+		{CommentID: 1, Path: "a.go", StartLine: 1, EndLine: 2, Body: "one"},
+		{CommentID: 2, Path: "b.go", StartLine: 5, EndLine: 5, Body: "two", Resolved: true},
+		{CommentID: 3, InReplyTo: 1, Author: "alice", Body: "fixed"},
+	})
+	if len(threads) != 2 || len(threads[0].Comments) != 2 || threads[0].Comments[1].Author != "alice" || !threads[1].Resolved {
+		t.Fatalf("Threads = %+v", threads)
+	}
+}
+
+func TestInlineCommentsFollowBranch(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	wt := t.TempDir()
+	gitCommit := func(content string) {
+		if err := os.WriteFile(filepath.Join(wt, "f.txt"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "f.txt"}, {"commit", "-q", "-m", "change"}} {
+			cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+			cmd.Dir = wt
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v: %s", args, err, out)
+			}
+		}
+	}
+	if out, err := exec.Command("git", "init", "-q", "-b", "feature", wt).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	gitCommit("a\nb\nc\nd\n")
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	wfID, _ := st.CreateWorkflow(ctx, "t1", "wf", 1, "builtin:wf")
+	taskID, _ := st.CreateTask(ctx, "t1", "task", "todo", &wfID)
+	branch := "feature"
+	_ = st.UpdateTaskGitFields(ctx, taskID, &wt, &branch, nil, nil)
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+
+	if err := SubmitReview(ctx, st, "t1", taskID, "bob", ChangesRequested, "see inline", InlineComment{Path: "f.txt", StartLine: 0, Body: "x"}); err == nil {
+		t.Fatal("expected an invalid inline comment to be rejected")
+	}
+	if err := SubmitReview(ctx, st, "t1", taskID, "bob", ChangesRequested, "see inline",
+		InlineComment{Path: "f.txt", StartLine: 3, Body: "rename c"},
+		InlineComment{Path: "f.txt", StartLine: 2, Body: "drop b"}); err != nil {
+		t.Fatal(err)
+	}
+	reviews, _ := st.ListTaskReviews(ctx, "t1", taskID)
+	threads, err := TaskThreads(ctx, st, "t1", task)
+	if err != nil || len(threads) != 2 {
+		t.Fatalf("TaskThreads = %+v, %v", threads, err)
+	}
+	if threads[0].ReviewID != reviews[0].ReviewID || threads[0].CommitSHA == "" || threads[0].Comments[0].Author != "bob" {
+		t.Fatalf("thread = %+v", threads[0])
+	}
+
+	// A line inserted at the top moves the first thread down; the second thread's line is rewritten.
+	gitCommit("top\na\nB\nc\nd\n")
+	threads, _ = TaskThreads(ctx, st, "t1", task)
+	if th := threads[0]; th.StartLine != 4 || th.Outdated {
+		t.Errorf("moved thread = %+v", th)
+	}
+	if th := threads[1]; th.StartLine != 2 || !th.Outdated {
+		t.Errorf("outdated thread = %+v", th)
+	}
+	if threads[0].CommitSHA == threads[1].CommitSHA {
+		t.Errorf("outdated thread should keep its original commit, got %s for both", threads[0].CommitSHA)
+	}
+
+	// A thread on a commit that is not in the repository is rejected; one already stored (e.g. before a
+	// force-push) is marked outdated without stopping the others from following the branch.
+	if _, err := AddComment(ctx, st, "t1", task, store.ReviewComment{Author: "bob", Path: "f.txt", StartLine: 1, EndLine: 1, CommitSHA: "0123456789abcdef0123456789abcdef01234567", Body: "x"}); err == nil {
+		t.Fatal("expected a comment on an unknown commit to be rejected")
+	}
+	if _, err := st.CreateReviewComment(ctx, "t1", store.ReviewComment{TaskID: taskID, Author: "bob", Path: "f.txt", StartLine: 1, EndLine: 1, CommitSHA: "0123456789abcdef0123456789abcdef01234567", Body: "lost"}); err != nil {
+		t.Fatal(err)
+	}
+	gitCommit("top\ntop2\na\nB\nc\nd\n")
+	threads, err = TaskThreads(ctx, st, "t1", task)
+	if err != nil || len(threads) != 3 {
+		t.Fatalf("TaskThreads after an unmappable thread = %+v, %v", threads, err)
+	}
+	if th := threads[0]; th.StartLine != 5 || th.Outdated {
+		t.Errorf("moved thread = %+v", th)
+	}
+	if th := threads[2]; !th.Outdated || th.StartLine != 1 {
+		t.Errorf("unmappable thread = %+v, want outdated at its original lines", th)
+	}
+}
@@ -0,0 +1,161 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// Review outcomes.
+const (
+	Approved         = "approved"
+	ChangesRequested = "changes_requested"
+)
+
+// PickReviewers chooses up to n reviewers for the task, never the DRI. If pool is set (a review stage's
+// candidate_agents) reviewers come from it in order; names that are not team agents are human reviewers
+// who submit through the API. Otherwise any non-DRI agent.
+func PickReviewers(task *store.Task, agents []store.Agent, pool []string, n int) []string {
+	if n <= 0 {
+		n = 1
+	}
+	dri := ""
+	if task.DRI != nil {
+		dri = *task.DRI
+	}
+	var candidates []string
+	if len(pool) > 0 {
+		candidates = pool
+	} else {
+		for _, a := range agents {
+			candidates = append(candidates, a.Name)
+		}
+	}
+	var out []string
+	seen := make(map[string]bool)
+	for _, name := range candidates {
+		name = strings.TrimSpace(name)
+		if name == "" || name == dri || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+		if len(out) == n {
+			break
+		}
+	}
+	return out
+}
+
+// Tally aggregates the current review round of a quorum review stage.
+type Tally struct {
+	Reviewers        []string `json:"reviewers"`
+	Quorum           int      `json:"quorum"`
+	Approved         []string `json:"approved"`
+	ChangesRequested []string `json:"changes_requested"`
+	Pending          []string `json:"pending"`
+}
+
+// Aggregate tallies reviews (newest first, as ListTaskReviews returns them) in the current round, i.e. with
+// a ReviewID above round, keeping each reviewer's latest. Only members of reviewers count. quorum is the
+// approvals needed; 0 means every reviewer, and it is capped at len(reviewers) so a small reviewer pool can
+// still approve.
+func Aggregate(reviews []store.TaskReview, reviewers []string, round int64, quorum int) Tally {
+	if quorum <= 0 || quorum > len(reviewers) {
+		quorum = len(reviewers)
+	}
+	t := Tally{Reviewers: reviewers, Quorum: quorum}
+	latest := make(map[string]string)
+	for _, r := range reviews {
+		if r.ReviewID <= round {
+			continue
+		}
+		if _, seen := latest[r.ReviewerAgent]; !seen {
+			latest[r.ReviewerAgent] = r.Outcome
+		}
+	}
+	for _, name := range reviewers {
+		switch latest[name] {
+		case Approved:
+			t.Approved = append(t.Approved, name)
+		case ChangesRequested:
+			t.ChangesRequested = append(t.ChangesRequested, name)
+		default:
+			t.Pending = append(t.Pending, name)
+		}
+	}
+	return t
+}
+
+// Outcome is the stage outcome the tally has reached: changes_requested as soon as any reviewer asks
+// for changes, approved once the quorum of approvals is met, or "" while still waiting.
+func (t Tally) Outcome() string {
+	if len(t.ChangesRequested) > 0 {
+		return ChangesRequested
+	}
+	if t.Quorum > 0 && len(t.Approved) >= t.Quorum {
+		return Approved
+	}
+	return ""
+}
+
+func (t Tally) String() string {
+	return fmt.Sprintf("%d of %d approvals (reviewers: %s)", len(t.Approved), t.Quorum, strings.Join(t.Reviewers, ", "))
+}
+
+// QuorumStage returns the task's current stage if it is a quorum review stage (type review), else nil.
+func QuorumStage(ctx context.Context, st store.Store, task *store.Task) *store.WorkflowStage {
+	if task.WorkflowID == nil || *task.WorkflowID == "" || task.CurrentStage == nil {
+		return nil
+	}
+	stages, err := st.GetWorkflowStages(ctx, *task.WorkflowID)
+	if err != nil {
+		return nil
+	}
+	for i := range stages {
+		if stages[i].StageName == *task.CurrentStage && stages[i].StageType == "review" {
+			return &stages[i]
+		}
+	}
+	return nil
+}
+
+// TallyTask aggregates the current round for a task in a quorum review stage.
+func TallyTask(ctx context.Context, st store.Store, teamName string, task *store.Task, stage *store.WorkflowStage) (Tally, error) {
+	reviews, err := st.ListTaskReviews(ctx, teamName, task.TaskID)
+	if err != nil {
+		return Tally{}, err
+	}
+	return Aggregate(reviews, SplitReviewers(task.Reviewers), task.ReviewRound, stage.Quorum), nil
+}
+
+// StartRound begins a new review round for a task entering a quorum review stage: earlier reviews stop
+// counting, and the reviewer set is kept so the same reviewers look again.
+func StartRound(ctx context.Context, st store.Store, teamName string, task *store.Task) error {
+	reviews, err := st.ListTaskReviews(ctx, teamName, task.TaskID)
+	if err != nil {
+		return err
+	}
+	var round int64
+	for _, r := range reviews {
+		round = max(round, r.ReviewID)
+	}
+	if err := st.SetTaskReviewers(ctx, task.TaskID, SplitReviewers(task.Reviewers), round); err != nil {
+		return err
+	}
+	task.ReviewRound = round
+	return nil
+}
+
+// SplitReviewers splits a task's comma-separated reviewer set.
+func SplitReviewers(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -2,6 +2,7 @@ package review
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/ankittk/agentary/internal/store"
@@ -50,17 +51,75 @@ func PickReviewer(ctx context.Context, st store.Store, teamName string, task *st
 	return ""
 }
 
+// Advancer applies a workflow outcome to a task; *workflow.Engine implements it (with guards and hooks).
+type Advancer interface {
+	ApplyOutcome(ctx context.Context, teamName string, task *store.Task, outcome string) (string, error)
+}
+
 // SubmitReview records a review (approve/changes_requested) and applies the workflow transition.
 // If outcome is changes_requested, assignee is set back to the DRI so the task returns to the author.
-func SubmitReview(ctx context.Context, st store.Store, teamName string, taskID int64, reviewerAgent, outcome, comments string) error {
-	_, err := st.CreateTaskReview(ctx, teamName, taskID, reviewerAgent, outcome, comments)
+// Inline comments start threads on the task's code, attached to the review.
+func SubmitReview(ctx context.Context, st store.Store, teamName string, taskID int64, reviewerAgent, outcome, comments string, inline ...InlineComment) error {
+	return SubmitReviewVia(ctx, st, nil, teamName, taskID, reviewerAgent, outcome, comments, inline...)
+}
+
+// SubmitReviewVia is SubmitReview with the transition applied by adv. If adv is nil the transition
+// is applied directly (no guards or hooks).
+// In a quorum review stage the reviewer must be in the task's reviewer set, and the stage only moves
+// once the round's tally reaches an outcome (see Tally.Outcome).
+func SubmitReviewVia(ctx context.Context, st store.Store, adv Advancer, teamName string, taskID int64, reviewerAgent, outcome, comments string, inline ...InlineComment) error {
+	task, err := st.GetTaskByIDAndTeam(ctx, teamName, taskID)
 	if err != nil {
 		return err
 	}
-	task, err := st.GetTaskByIDAndTeam(ctx, teamName, taskID)
-	if err != nil || task == nil || task.WorkflowID == nil || *task.WorkflowID == "" {
+	var quorum *store.WorkflowStage
+	if task != nil {
+		quorum = QuorumStage(ctx, st, task)
+	}
+	if quorum != nil {
+		if !containsName(SplitReviewers(task.Reviewers), reviewerAgent) {
+			return fmt.Errorf("%q is not a reviewer of task %d (reviewers: %s)", reviewerAgent, taskID, task.Reviewers)
+		}
+		if outcome != Approved && outcome != ChangesRequested {
+			return fmt.Errorf("outcome must be %s or %s in a quorum review", Approved, ChangesRequested)
+		}
+	}
+	if err := checkInline(inline); err != nil {
 		return err
 	}
+	if len(inline) > 0 && task == nil {
+		return fmt.Errorf("task %d not found", taskID)
+	}
+	reviewID, err := st.CreateTaskReview(ctx, teamName, taskID, reviewerAgent, outcome, comments)
+	if err != nil {
+		return err
+	}
+	if err := AddInlineComments(ctx, st, teamName, task, reviewID, reviewerAgent, inline); err != nil {
+		return err
+	}
+	ctx = store.WithTransitionCause(ctx, store.TransitionCause{Actor: reviewerAgent})
+	if task == nil || task.WorkflowID == nil || *task.WorkflowID == "" {
+		return nil
+	}
+	if quorum != nil {
+		tally, err := TallyTask(ctx, st, teamName, task, quorum)
+		if err != nil {
+			return err
+		}
+		if outcome = tally.Outcome(); outcome == "" {
+			return nil // still waiting for the quorum
+		}
+	}
+	if adv != nil {
+		toStage, err := adv.ApplyOutcome(ctx, teamName, task, outcome)
+		if err != nil || toStage == "" {
+			return err
+		}
+		if outcome == "changes_requested" && task.DRI != nil && *task.DRI != "" {
+			_ = st.UpdateTask(ctx, taskID, "in_progress", task.DRI)
+		}
+		return nil
+	}
 	wfID := *task.WorkflowID
 	stageName := ""
 	if task.CurrentStage != nil {
@@ -80,6 +139,7 @@ func SubmitReview(ctx context.Context, st store.Store, teamName string, taskID i
 	if toStage == "" {
 		return nil
 	}
+	ctx = store.WithTransitionCause(ctx, store.TransitionCause{Outcome: outcome})
 	if err := st.SetTaskWorkflowAndStage(ctx, taskID, wfID, toStage); err != nil {
 		return err
 	}
@@ -97,3 +157,12 @@ func SubmitReview(ctx context.Context, st store.Store, teamName string, taskID i
 	}
 	return nil
 }
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
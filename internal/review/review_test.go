@@ -134,3 +134,44 @@ func TestPickReviewer_singleAgent(t *testing.T) {
 		t.Errorf("PickReviewer single agent: got %q", got)
 	}
 }
+
+func TestPickReviewersAndAggregate(t *testing.T) {
+	t.Parallel()
+	dri := "alice"
+	task := &store.Task{DRI: &dri}
+	agents := []store.Agent{{Name: "alice"}, {Name: "bob"}, {Name: "carol"}}
+	if got := PickReviewers(task, agents, nil, 2); len(got) != 2 || got[0] != "bob" || got[1] != "carol" {
+		t.Errorf("PickReviewers(agents) = %v", got)
+	}
+	if got := PickReviewers(task, agents, []string{"alice", "dana", "bob"}, 3); len(got) != 2 || got[0] != "dana" {
+		t.Errorf("PickReviewers(pool) = %v, want dana (human) and bob without the DRI", got)
+	}
+
+	const round = 10               // reviews 1..10 belong to earlier rounds
+	reviews := []store.TaskReview{ // newest first
+		{ReviewID: 14, ReviewerAgent: "bob", Outcome: Approved},
+		{ReviewID: 13, ReviewerAgent: "bob", Outcome: ChangesRequested},
+		{ReviewID: 12, ReviewerAgent: "mallory", Outcome: Approved},
+		{ReviewID: 9, ReviewerAgent: "carol", Outcome: Approved},
+	}
+	tally := Aggregate(reviews, []string{"bob", "carol", "dana"}, round, 2)
+	if len(tally.Approved) != 1 || len(tally.Pending) != 2 || tally.Outcome() != "" {
+		t.Fatalf("tally = %+v (outcome %q)", tally, tally.Outcome())
+	}
+	reviews = append([]store.TaskReview{{ReviewID: 15, ReviewerAgent: "dana", Outcome: Approved}}, reviews...)
+	if got := Aggregate(reviews, []string{"bob", "carol", "dana"}, round, 2).Outcome(); got != Approved {
+		t.Errorf("two approvals: outcome = %q", got)
+	}
+	reviews = append([]store.TaskReview{{ReviewID: 16, ReviewerAgent: "carol", Outcome: ChangesRequested}}, reviews...)
+	if got := Aggregate(reviews, []string{"bob", "carol", "dana"}, round, 2).Outcome(); got != ChangesRequested {
+		t.Errorf("changes_requested should block: outcome = %q", got)
+	}
+	if got := Aggregate(nil, []string{"bob", "carol"}, round, 0); got.Quorum != 2 {
+		t.Errorf("quorum 0 should mean all reviewers, got %d", got.Quorum)
+	}
+	// Only two reviewers could be picked for a quorum of 3: both approving is enough.
+	approvals := []store.TaskReview{{ReviewID: 12, ReviewerAgent: "bob", Outcome: Approved}, {ReviewID: 11, ReviewerAgent: "dana", Outcome: Approved}}
+	if got := Aggregate(approvals, []string{"bob", "dana"}, round, 3); got.Quorum != 2 || got.Outcome() != Approved {
+		t.Errorf("quorum above the reviewer count = %+v (outcome %q), want capped at 2 and approved", got, got.Outcome())
+	}
+}
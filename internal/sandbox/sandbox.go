@@ -5,8 +5,21 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
+// HomeFor returns home when worktree is under it, so commands run in worktree get the sandbox, and ""
+// otherwise (no home, or a worktree elsewhere that the sandbox could not bind).
+func HomeFor(home, worktree string) string {
+	if home == "" {
+		return ""
+	}
+	if rel, err := filepath.Rel(home, worktree); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		return home
+	}
+	return ""
+}
+
 // WrapCommand returns an *exec.Cmd that runs binary with args. If home is non-empty and
 // bubblewrap (bwrap) is available on Linux, the command runs inside a minimal bubblewrap
 // sandbox. If teamDir is non-empty, only teamDir is writable and home is read-only (so
@@ -0,0 +1,24 @@
+package sandbox
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHomeFor(t *testing.T) {
+	home := filepath.Join(string(filepath.Separator), "srv", "agentary")
+	cases := map[string]string{
+		filepath.Join(home, "teams", "t1", "worktrees", "T1"): home,
+		home:                               "",
+		filepath.Join(home, "..", "other"): "",
+		filepath.Join(string(filepath.Separator), "tmp", "wt"): "",
+	}
+	for worktree, want := range cases {
+		if got := HomeFor(home, worktree); got != want {
+			t.Errorf("HomeFor(%q) = %q, want %q", worktree, got, want)
+		}
+	}
+	if got := HomeFor("", filepath.Join(home, "teams")); got != "" {
+		t.Errorf("HomeFor without home = %q, want empty", got)
+	}
+}
@@ -0,0 +1,145 @@
+// Package schedule implements recurring and one-shot task schedules: a small
+// cron expression parser and a runner that creates tasks when schedules fall due.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed 5-field cron expression (minute hour day-of-month month day-of-week).
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domStar / dowStar record whether the field was "*"; when both day fields are
+	// restricted, a time matches if either matches (standard cron semantics).
+	domStar, dowStar bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard 5-field cron expression or one of the macros
+// @yearly, @monthly, @weekly, @daily, @hourly. Fields accept *, lists (1,2),
+// ranges (1-5) and steps (*/15, 0-30/10). Day-of-week accepts 0-7 (0 and 7 are Sunday).
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields, got %d", len(fields))
+	}
+	c := &Cron{}
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron day-of-month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron month: %w", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron day-of-week: %w", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = fields[2] == "*"
+	c.dowStar = fields[4] == "*"
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			a, b, _ := strings.Cut(part, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q", a)
+			}
+			if hi, err = strconv.Atoi(b); err != nil {
+				return 0, fmt.Errorf("invalid value %q", b)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value out of range %d-%d", min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time strictly after t (truncated to the minute) that matches the expression.
+// It returns the zero time if no match is found within five years.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
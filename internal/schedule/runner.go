@@ -0,0 +1,197 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// Prepare validates a schedule before it is stored and fills in NextRunAt.
+// Exactly one of CronExpr and RunAt must be set; the title template must parse.
+func Prepare(sch *store.TaskSchedule, now time.Time) error {
+	if sch.Name == "" {
+		return errors.New("schedule name required")
+	}
+	if sch.TitleTemplate == "" {
+		return errors.New("schedule title required")
+	}
+	if _, err := template.New("title").Parse(sch.TitleTemplate); err != nil {
+		return fmt.Errorf("invalid title template: %w", err)
+	}
+	switch {
+	case sch.CronExpr != "" && sch.RunAt != nil:
+		return errors.New("cron and run_at are mutually exclusive")
+	case sch.CronExpr != "":
+		c, err := ParseCron(sch.CronExpr)
+		if err != nil {
+			return err
+		}
+		next := c.Next(now)
+		if next.IsZero() {
+			return errors.New("cron expression never fires")
+		}
+		sch.NextRunAt = &next
+	case sch.RunAt != nil:
+		at := sch.RunAt.UTC()
+		sch.RunAt = &at
+		sch.NextRunAt = &at
+	default:
+		return errors.New("cron or run_at required")
+	}
+	return nil
+}
+
+// RenderTitle expands a schedule's title template. Available fields:
+// .Name (schedule name), .Date (2006-01-02), .Time (15:04), .Week (ISO week, e.g. 2026-W07) and .Now (time.Time).
+func RenderTitle(sch store.TaskSchedule, now time.Time) (string, error) {
+	tmpl, err := template.New("title").Parse(sch.TitleTemplate)
+	if err != nil {
+		return "", err
+	}
+	year, week := now.ISOWeek()
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]any{
+		"Name": sch.Name,
+		"Date": now.Format("2006-01-02"),
+		"Time": now.Format("15:04"),
+		"Week": fmt.Sprintf("%d-W%02d", year, week),
+		"Now":  now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Runner polls for due task schedules and creates a task for each one.
+// A recurring schedule whose previous task is still open is skipped for that run.
+type Runner struct {
+	Store store.Store
+	// Interval between poll rounds
+	Interval time.Duration
+	// Publish, when set, receives task_update events for created tasks (e.g. SSEHub.PublishJSON).
+	Publish func(v any)
+	// Now overrides the clock (tests).
+	Now func() time.Time
+}
+
+const defaultScheduleInterval = 30 * time.Second
+
+// Run runs the schedule runner until ctx is cancelled.
+func (r *Runner) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = defaultScheduleInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.RunOnce(ctx)
+		}
+	}
+}
+
+func (r *Runner) now() time.Time {
+	if r.Now != nil {
+		return r.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// RunOnce fires every schedule that is due at the current time.
+func (r *Runner) RunOnce(ctx context.Context) {
+	now := r.now()
+	due, err := r.Store.ListDueTaskSchedules(ctx, now)
+	if err != nil {
+		slog.Error("schedule runner list due failed", "err", err)
+		return
+	}
+	for _, sch := range due {
+		r.fire(ctx, sch, now)
+	}
+}
+
+func (r *Runner) fire(ctx context.Context, sch store.TaskSchedule, now time.Time) {
+	next := r.nextRun(sch, now)
+	if r.previousOpen(ctx, sch) {
+		slog.Info("schedule skipped: previous task still open", "team", sch.TeamName, "schedule", sch.Name, "task_id", *sch.LastTaskID)
+		if err := r.Store.RecordTaskScheduleRun(ctx, sch.ScheduleID, now, nil, next); err != nil {
+			slog.Error("schedule record run failed", "schedule", sch.Name, "err", err)
+		}
+		return
+	}
+	title, err := RenderTitle(sch, now)
+	if err != nil || title == "" {
+		title = sch.Name
+	}
+	var wfID *string
+	wfName, wfVersion := sch.WorkflowName, sch.WorkflowVersion
+	if wfName == "" {
+		wfName, wfVersion = "default", 1
+	}
+	if id, _ := r.Store.GetWorkflowIDByTeamAndName(ctx, sch.TeamName, wfName, wfVersion); id != "" {
+		wfID = &id
+	} else if sch.WorkflowName != "" {
+		slog.Warn("schedule workflow not found; creating task without workflow", "schedule", sch.Name, "workflow", wfName, "version", wfVersion)
+	}
+	taskID, err := r.Store.CreateTask(ctx, sch.TeamName, title, models.StatusTodo, wfID)
+	if err != nil {
+		slog.Error("schedule create task failed", "team", sch.TeamName, "schedule", sch.Name, "err", err)
+		return
+	}
+	if sch.Labels != "" {
+		if err := r.Store.SetTaskLabels(ctx, taskID, sch.Labels); err != nil {
+			slog.Error("schedule set labels failed", "task_id", taskID, "err", err)
+		}
+	}
+	if err := r.Store.RecordTaskScheduleRun(ctx, sch.ScheduleID, now, &taskID, next); err != nil {
+		slog.Error("schedule record run failed", "schedule", sch.Name, "err", err)
+	}
+	if r.Publish != nil {
+		r.Publish(map[string]any{"type": "task_update", "team": sch.TeamName, "task_id": taskID, "schedule": sch.Name})
+	}
+}
+
+// nextRun returns the next fire time for a recurring schedule, or nil for one-shot (run_at) schedules.
+func (r *Runner) nextRun(sch store.TaskSchedule, now time.Time) *time.Time {
+	if sch.CronExpr == "" {
+		return nil
+	}
+	c, err := ParseCron(sch.CronExpr)
+	if err != nil {
+		slog.Error("schedule has invalid cron; disabling", "schedule", sch.Name, "err", err)
+		return nil
+	}
+	next := c.Next(now)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+func (r *Runner) previousOpen(ctx context.Context, sch store.TaskSchedule) bool {
+	if sch.LastTaskID == nil {
+		return false
+	}
+	t, err := r.Store.GetTaskByIDAndTeam(ctx, sch.TeamName, *sch.LastTaskID)
+	if err != nil || t == nil {
+		return false
+	}
+	switch t.Status {
+	case models.StatusDone, models.StatusFailed, models.StatusCancelled:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package schedule
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+func TestParseCron_next(t *testing.T) {
+	t.Parallel()
+	base := time.Date(2026, 3, 4, 10, 7, 30, 0, time.UTC) // Wednesday
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 3, 4, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"30 8 1 * *", time.Date(2026, 4, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		c, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tc.expr, err)
+		}
+		if got := c.Next(base); !got.Equal(tc.want) {
+			t.Errorf("Next(%q) = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParseCron_invalid(t *testing.T) {
+	t.Parallel()
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q): expected error", expr)
+		}
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 4, 10, 7, 0, 0, time.UTC)
+	sch := store.TaskSchedule{Name: "deps", TitleTemplate: "Bump deps {{.Week}}", CronExpr: "@daily"}
+	if err := Prepare(&sch, now); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	if sch.NextRunAt == nil || !sch.NextRunAt.Equal(time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("NextRunAt = %v", sch.NextRunAt)
+	}
+	bad := store.TaskSchedule{Name: "x", TitleTemplate: "t", CronExpr: "@daily", RunAt: &now}
+	if err := Prepare(&bad, now); err == nil {
+		t.Fatal("expected error when both cron and run_at set")
+	}
+	title, err := RenderTitle(sch, now)
+	if err != nil || title != "Bump deps 2026-W10" {
+		t.Fatalf("RenderTitle = %q, %v", title, err)
+	}
+}
+
+func TestRunner_createsTaskAndSkipsWhileOpen(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+
+	now := time.Date(2026, 3, 4, 10, 7, 0, 0, time.UTC)
+	sch := store.TaskSchedule{Name: "nightly", TitleTemplate: "Nightly {{.Date}}", CronExpr: "0 * * * *", Labels: "chore,nightly"}
+	if err := Prepare(&sch, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.CreateTaskSchedule(ctx, "t1", sch); err != nil {
+		t.Fatalf("CreateTaskSchedule: %v", err)
+	}
+
+	var events int
+	clock := now.Add(time.Hour)
+	r := &Runner{Store: st, Now: func() time.Time { return clock }, Publish: func(any) { events++ }}
+	r.RunOnce(ctx)
+	tasks, _ := st.ListTasks(ctx, "t1", 10)
+	if len(tasks) != 1 || tasks[0].Title != "Nightly 2026-03-04" || tasks[0].Labels != "chore,nightly" {
+		t.Fatalf("tasks after first run = %+v", tasks)
+	}
+	if events != 1 {
+		t.Fatalf("events = %d", events)
+	}
+
+	// Previous task still open: next run is skipped but the schedule advances.
+	clock = clock.Add(time.Hour)
+	r.RunOnce(ctx)
+	if tasks, _ = st.ListTasks(ctx, "t1", 10); len(tasks) != 1 {
+		t.Fatalf("expected skip while previous task open, got %d tasks", len(tasks))
+	}
+
+	_ = st.UpdateTask(ctx, tasks[0].TaskID, models.StatusDone, nil)
+	clock = clock.Add(time.Hour)
+	r.RunOnce(ctx)
+	if tasks, _ = st.ListTasks(ctx, "t1", 10); len(tasks) != 2 {
+		t.Fatalf("expected new task after previous done, got %d tasks", len(tasks))
+	}
+}
+
+func TestRunner_oneShotDisables(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+
+	at := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	sch := store.TaskSchedule{Name: "once", TitleTemplate: "Release prep", RunAt: &at}
+	if err := Prepare(&sch, at.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.CreateTaskSchedule(ctx, "t1", sch); err != nil {
+		t.Fatal(err)
+	}
+	r := &Runner{Store: st, Now: func() time.Time { return at.Add(time.Minute) }}
+	r.RunOnce(ctx)
+	r.RunOnce(ctx)
+	tasks, _ := st.ListTasks(ctx, "t1", 10)
+	if len(tasks) != 1 {
+		t.Fatalf("expected exactly one task, got %d", len(tasks))
+	}
+	list, _ := st.ListTaskSchedules(ctx, "t1")
+	if len(list) != 1 || list[0].Enabled || list[0].LastTaskID == nil {
+		t.Fatalf("schedule after one-shot = %+v", list)
+	}
+}
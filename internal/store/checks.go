@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// ListRepoChecks returns the repo's checks in the order they run.
+func (s *sqliteStore) ListRepoChecks(ctx context.Context, teamName, repoName string) ([]RepoCheck, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT name, command, timeout_seconds, env, required FROM repo_checks WHERE team_id=? AND repo_name=? ORDER BY position ASC, name ASC`, team.TeamID, repoName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []RepoCheck
+	for rows.Next() {
+		var c RepoCheck
+		var timeout int64
+		var env string
+		var required int
+		if err := rows.Scan(&c.Name, &c.Command, &timeout, &env, &required); err != nil {
+			return nil, err
+		}
+		c.Timeout = time.Duration(timeout) * time.Second
+		c.Env = SplitCheckEnv(env)
+		c.Required = required != 0
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// SetRepoChecks replaces the repo's checks; they run in the given order.
+func (s *sqliteStore) SetRepoChecks(ctx context.Context, teamName, repoName string, checks []RepoCheck) error {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM repo_checks WHERE team_id=? AND repo_name=?`, team.TeamID, repoName); err != nil {
+		return err
+	}
+	for i, c := range checks {
+		required := 0
+		if c.Required {
+			required = 1
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO repo_checks(team_id, repo_name, name, position, command, timeout_seconds, env, required) VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+			team.TeamID, repoName, c.Name, i, c.Command, int64(c.Timeout/time.Second), strings.Join(c.Env, "\n"), required); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CreateTaskCheckRuns stores runs as the task's next check attempt and returns the attempt number.
+func (s *sqliteStore) CreateTaskCheckRuns(ctx context.Context, teamName string, taskID int64, runs []TaskCheckRun) (int, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	var attempt int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(attempt),0)+1 FROM task_check_runs WHERE task_id=?`, taskID).Scan(&attempt); err != nil {
+		return 0, err
+	}
+	for _, r := range runs {
+		required := 0
+		if r.Required {
+			required = 1
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO task_check_runs(team_id, task_id, attempt, name, command, required, status, exit_code, head_sha, log, started_at, finished_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			team.TeamID, taskID, attempt, r.Name, r.Command, required, r.Status, r.ExitCode, r.HeadSHA, r.Log, r.StartedAt.UTC().UnixMilli(), r.FinishedAt.UTC().UnixMilli()); err != nil {
+			return 0, err
+		}
+	}
+	return attempt, tx.Commit()
+}
+
+// ListTaskCheckRuns returns the task's check results by attempt, in the order the checks ran.
+func (s *sqliteStore) ListTaskCheckRuns(ctx context.Context, teamName string, taskID int64) ([]TaskCheckRun, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT run_id, task_id, attempt, name, command, required, status, exit_code, head_sha, log, started_at, finished_at FROM task_check_runs WHERE task_id=? AND team_id=? ORDER BY attempt ASC, run_id ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []TaskCheckRun
+	for rows.Next() {
+		var r TaskCheckRun
+		var required int
+		var started, finished int64
+		if err := rows.Scan(&r.RunID, &r.TaskID, &r.Attempt, &r.Name, &r.Command, &required, &r.Status, &r.ExitCode, &r.HeadSHA, &r.Log, &started, &finished); err != nil {
+			return nil, err
+		}
+		r.Required = required != 0
+		r.StartedAt = time.UnixMilli(started).UTC()
+		r.FinishedAt = time.UnixMilli(finished).UTC()
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// SplitCheckEnv splits a stored check environment (one KEY=value per line).
+func SplitCheckEnv(env string) []string {
+	if env == "" {
+		return nil
+	}
+	return strings.Split(env, "\n")
+}
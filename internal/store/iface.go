@@ -1,6 +1,9 @@
 package store
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Store is the persistence interface for teams, tasks, workflows, messages, and network allowlist.
 // Implementations: *sqlite.Store (SQLite) and *postgres.Store (PostgreSQL).
@@ -10,6 +13,12 @@ type Store interface {
 	GetTeamByName(ctx context.Context, name string) (Team, error)
 	CreateTeam(ctx context.Context, name string) (Team, error)
 	DeleteTeam(ctx context.Context, name string) error
+	SetTeamPaused(ctx context.Context, teamName string, paused bool, reason string) error
+	SetTeamWorktreeQuota(ctx context.Context, teamName string, bytes int64) error
+
+	// Scheduler (global pause switch)
+	GetSchedulerState(ctx context.Context) (SchedulerState, error)
+	SetSchedulerPaused(ctx context.Context, paused bool, reason string) error
 
 	// Agents
 	ListAgents(ctx context.Context, teamName string) ([]Agent, error)
@@ -26,6 +35,7 @@ type Store interface {
 	SetTaskCancelled(ctx context.Context, teamName string, taskID int64) error
 	ClearTaskGitFields(ctx context.Context, taskID int64) error
 	UpdateTaskGitFields(ctx context.Context, taskID int64, worktreePath, branchName, baseSHA, repoName *string) error
+	SetTaskReviewers(ctx context.Context, taskID int64, reviewers []string, round int64) error
 	RewindTask(ctx context.Context, teamName string, taskID int64) error
 	CreateTaskComment(ctx context.Context, teamName string, taskID int64, author, body string) (int64, error)
 	ListTaskComments(ctx context.Context, teamName string, taskID int64) ([]TaskComment, error)
@@ -38,15 +48,58 @@ type Store interface {
 	GetTaskByIDAndTeam(ctx context.Context, teamName string, taskID int64) (*Task, error)
 	UpdateTaskStage(ctx context.Context, taskID int64, stage string) error
 	SetTaskWorkflowAndStage(ctx context.Context, taskID int64, workflowID, stage string) error
+	SetTaskLabels(ctx context.Context, taskID int64, labels string) error
+
+	// Subtasks. SetTaskParent(nil) detaches a task; adding a subtask re-arms the parent's roll-up.
+	SetTaskParent(ctx context.Context, teamName string, taskID int64, parentID *int64) error
+	SetTaskSubtaskPolicy(ctx context.Context, taskID int64, policy string) error
+	ListSubtasks(ctx context.Context, teamName string, parentID int64) ([]Task, error)
+	ListSubtaskRollups(ctx context.Context) ([]SubtaskRollup, error)
+	MarkSubtasksRolledUp(ctx context.Context, taskID int64, at time.Time) error
+
+	// Task history: stage changes (recorded by UpdateTaskStage, SetTaskWorkflowAndStage, RewindTask and
+	// MigrateWorkflowTasks, attributed via WithTransitionCause) and agent turns
+	ListTaskTransitions(ctx context.Context, teamName string, taskID int64) ([]TaskTransition, error)
+	CreateTaskTurn(ctx context.Context, teamName string, turn TaskTurn) (int64, error)
+	ListTaskTurns(ctx context.Context, teamName string, taskID int64) ([]TaskTurn, error)
+
+	// Task plans (plan stages); CreateTaskPlan numbers the revision and supersedes pending ones
+	CreateTaskPlan(ctx context.Context, teamName string, plan TaskPlan) (int64, error)
+	ListTaskPlans(ctx context.Context, teamName string, taskID int64) ([]TaskPlan, error)
+	DecideTaskPlan(ctx context.Context, planID int64, status, decidedBy, feedback string, at time.Time) error
+
+	// Approval policy decisions, one per entry into a human approval stage
+	CreateTaskApprovalDecision(ctx context.Context, teamName string, d TaskApprovalDecision) (int64, error)
+	ListTaskApprovalDecisions(ctx context.Context, teamName string, taskID int64) ([]TaskApprovalDecision, error)
 
 	// Task reviews (agent-to-agent or human)
 	CreateTaskReview(ctx context.Context, teamName string, taskID int64, reviewerAgent, outcome, comments string) (int64, error)
 	ListTaskReviews(ctx context.Context, teamName string, taskID int64) ([]TaskReview, error)
 
+	// Inline review comments; replies are attached to the thread's first comment, which holds the anchor
+	// and the resolved flag
+	CreateReviewComment(ctx context.Context, teamName string, c ReviewComment) (int64, error)
+	ListReviewComments(ctx context.Context, teamName string, taskID int64) ([]ReviewComment, error)
+	SetReviewThreadResolved(ctx context.Context, teamName string, taskID, threadID int64, resolved bool) error
+	UpdateReviewCommentAnchor(ctx context.Context, commentID int64, startLine, endLine int, commitSHA string, outdated bool) error
+
 	// Repos
 	ListRepos(ctx context.Context, teamName string) ([]Repo, error)
 	CreateRepo(ctx context.Context, teamName, name, source, approval string, testCmd *string) error
 	SetRepoApproval(ctx context.Context, teamName, repoName, approval string) error
+	SetRepoMergeSettings(ctx context.Context, teamName, repoName, targetBranch, strategy, commitTemplate string) error
+
+	// Pre-merge checks; SetRepoChecks replaces the repo's checks, CreateTaskCheckRuns records them as the
+	// task's next attempt and returns its number
+	ListRepoChecks(ctx context.Context, teamName, repoName string) ([]RepoCheck, error)
+	SetRepoChecks(ctx context.Context, teamName, repoName string, checks []RepoCheck) error
+	CreateTaskCheckRuns(ctx context.Context, teamName string, taskID int64, runs []TaskCheckRun) (int, error)
+	ListTaskCheckRuns(ctx context.Context, teamName string, taskID int64) ([]TaskCheckRun, error)
+
+	// Merge queue; EnqueueMerge returns the task's open entry when it is already queued
+	EnqueueMerge(ctx context.Context, teamName, repoName string, taskID int64, stage string) (int64, error)
+	ListMergeQueue(ctx context.Context, teamName string, openOnly bool) ([]MergeQueueEntry, error)
+	UpdateMergeQueueEntry(ctx context.Context, entryID int64, status string, batch int64, note string) error
 
 	// Workflows
 	ListWorkflows(ctx context.Context, teamName string) ([]Workflow, error)
@@ -56,6 +109,23 @@ type Store interface {
 	GetWorkflowTransitions(ctx context.Context, workflowID string) ([]WorkflowTransition, error)
 	GetWorkflowIDByTeamAndName(ctx context.Context, teamName, name string, version int) (string, error)
 	GetWorkflowInitialStage(ctx context.Context, workflowID string) (string, error)
+	SetWorkflowStageSLA(ctx context.Context, workflowID, stageName string, maxDuration time.Duration, onTimeout string) error
+
+	// Workflow migrations (re-point open tasks to another workflow version)
+	ListOpenTasksInWorkflow(ctx context.Context, workflowID string) ([]Task, error)
+	MigrateWorkflowTasks(ctx context.Context, teamName string, m WorkflowMigration) (int64, error)
+	ListWorkflowMigrations(ctx context.Context, teamName string) ([]WorkflowMigration, error)
+
+	// Stage SLAs
+	ListStageSLABreaches(ctx context.Context, now time.Time) ([]StageSLABreach, error)
+	MarkTaskSLABreached(ctx context.Context, taskID int64, at time.Time) error
+
+	// Task schedules (recurring / one-shot task creation)
+	ListTaskSchedules(ctx context.Context, teamName string) ([]TaskSchedule, error)
+	CreateTaskSchedule(ctx context.Context, teamName string, sch TaskSchedule) (int64, error)
+	DeleteTaskSchedule(ctx context.Context, teamName, name string) error
+	ListDueTaskSchedules(ctx context.Context, now time.Time) ([]TaskSchedule, error)
+	RecordTaskScheduleRun(ctx context.Context, scheduleID int64, ranAt time.Time, taskID *int64, nextRunAt *time.Time) error
 
 	// Network allowlist
 	ListAllowedDomains(ctx context.Context) ([]string, error)
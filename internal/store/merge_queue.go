@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EnqueueMerge adds the task to the end of the repo's merge queue, or returns its open (queued or testing) entry.
+func (s *sqliteStore) EnqueueMerge(ctx context.Context, teamName, repoName string, taskID int64, stage string) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT entry_id FROM merge_queue WHERE task_id=? AND status IN ('queued','testing')`, taskID).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	now := time.Now().UTC().Unix()
+	res, err := tx.ExecContext(ctx, `INSERT INTO merge_queue(team_id, repo_name, task_id, stage, status, enqueued_at, updated_at) VALUES(?, ?, ?, ?, 'queued', ?, ?)`,
+		team.TeamID, repoName, taskID, stage, now, now)
+	if err != nil {
+		return 0, err
+	}
+	if id, err = res.LastInsertId(); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// ListMergeQueue returns the team's merge queue entries for all repos in queue order; openOnly keeps
+// queued and testing entries.
+func (s *sqliteStore) ListMergeQueue(ctx context.Context, teamName string, openOnly bool) ([]MergeQueueEntry, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	q := `SELECT entry_id, task_id, repo_name, stage, status, batch, note, enqueued_at, updated_at FROM merge_queue WHERE team_id=?`
+	if openOnly {
+		q += ` AND status IN ('queued','testing')`
+	}
+	rows, err := s.DB.QueryContext(ctx, q+` ORDER BY entry_id ASC`, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []MergeQueueEntry
+	for rows.Next() {
+		var e MergeQueueEntry
+		var enqueued, updated int64
+		if err := rows.Scan(&e.EntryID, &e.TaskID, &e.RepoName, &e.Stage, &e.Status, &e.Batch, &e.Note, &enqueued, &updated); err != nil {
+			return nil, err
+		}
+		e.EnqueuedAt = time.Unix(enqueued, 0).UTC()
+		e.UpdatedAt = time.Unix(updated, 0).UTC()
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// UpdateMergeQueueEntry sets an entry's status, batch and note.
+func (s *sqliteStore) UpdateMergeQueueEntry(ctx context.Context, entryID int64, status string, batch int64, note string) error {
+	res, err := s.DB.ExecContext(ctx, `UPDATE merge_queue SET status=?, batch=?, note=?, updated_at=? WHERE entry_id=?`,
+		status, batch, note, time.Now().UTC().Unix(), entryID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("merge queue entry %d not found", entryID)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+-- 009_task_schedules.sql
+-- Recurring and one-shot task schedules; labels on tasks.
+
+ALTER TABLE tasks ADD COLUMN labels TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS task_schedules (
+  schedule_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  team_id TEXT NOT NULL,
+  name TEXT NOT NULL,
+  title_template TEXT NOT NULL,
+  workflow_name TEXT NOT NULL DEFAULT '',
+  workflow_version INTEGER NOT NULL DEFAULT 1,
+  labels TEXT NOT NULL DEFAULT '',
+  cron_expr TEXT NOT NULL DEFAULT '',
+  run_at INTEGER,
+  next_run_at INTEGER,
+  last_run_at INTEGER,
+  last_task_id INTEGER,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  created_at INTEGER NOT NULL,
+  UNIQUE(team_id, name),
+  FOREIGN KEY (team_id) REFERENCES teams(team_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_schedules_next_run ON task_schedules(enabled, next_run_at);
@@ -0,0 +1,10 @@
+-- 010_stage_sla.sql
+-- Stage SLAs: per-stage max duration and timeout action; tasks record when they entered their current stage.
+
+ALTER TABLE tasks ADD COLUMN stage_entered_at INTEGER;
+ALTER TABLE tasks ADD COLUMN sla_breached_at INTEGER;
+
+UPDATE tasks SET stage_entered_at = updated_at WHERE current_stage IS NOT NULL AND stage_entered_at IS NULL;
+
+ALTER TABLE workflow_stages ADD COLUMN max_duration_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE workflow_stages ADD COLUMN on_timeout TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+-- 011_scheduler_pause.sql
+-- Pause/resume scheduling per team and globally (persisted across daemon restarts).
+
+ALTER TABLE teams ADD COLUMN paused INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE teams ADD COLUMN paused_at INTEGER;
+ALTER TABLE teams ADD COLUMN pause_reason TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS scheduler_state (
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  paused INTEGER NOT NULL DEFAULT 0,
+  paused_at INTEGER,
+  reason TEXT NOT NULL DEFAULT ''
+);
+
+INSERT OR IGNORE INTO scheduler_state(id, paused) VALUES(1, 0);
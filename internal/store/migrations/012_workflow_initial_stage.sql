@@ -0,0 +1,8 @@
+-- 012_workflow_initial_stage.sql
+-- Explicit initial stage per workflow (set from YAML definitions; the default workflow starts in Coding).
+
+ALTER TABLE workflows ADD COLUMN initial_stage TEXT NOT NULL DEFAULT '';
+
+UPDATE workflows SET initial_stage = 'Coding'
+WHERE name = 'default' AND version = 1
+  AND EXISTS (SELECT 1 FROM workflow_stages s WHERE s.workflow_id = workflows.workflow_id AND s.stage_name = 'Coding');
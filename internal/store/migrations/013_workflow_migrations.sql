@@ -0,0 +1,15 @@
+-- 013_workflow_migrations.sql
+-- Audit log of open tasks moved from one workflow version to another.
+
+CREATE TABLE IF NOT EXISTS workflow_migrations (
+  migration_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  team_id TEXT NOT NULL,
+  from_workflow_id TEXT NOT NULL,
+  to_workflow_id TEXT NOT NULL,
+  stage_map TEXT NOT NULL DEFAULT '{}',
+  moves TEXT NOT NULL DEFAULT '[]',
+  created_at INTEGER NOT NULL,
+  FOREIGN KEY (team_id) REFERENCES teams(team_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_migrations_team ON workflow_migrations(team_id, created_at);
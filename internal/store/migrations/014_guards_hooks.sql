@@ -0,0 +1,7 @@
+-- 014_guards_hooks.sql
+-- Transition guards (with a fallback outcome) and stage on_enter/on_exit hooks. Lists are newline-separated specs.
+
+ALTER TABLE workflow_stages ADD COLUMN on_enter TEXT NOT NULL DEFAULT '';
+ALTER TABLE workflow_stages ADD COLUMN on_exit TEXT NOT NULL DEFAULT '';
+ALTER TABLE workflow_transitions ADD COLUMN guards TEXT NOT NULL DEFAULT '';
+ALTER TABLE workflow_transitions ADD COLUMN on_guard_fail TEXT NOT NULL DEFAULT '';
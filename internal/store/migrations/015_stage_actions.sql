@@ -0,0 +1,5 @@
+-- 015_stage_actions.sql
+-- Actions run by auto stages and the mapping from exit code to outcome ("0=done,*=failed").
+
+ALTER TABLE workflow_stages ADD COLUMN action TEXT NOT NULL DEFAULT '';
+ALTER TABLE workflow_stages ADD COLUMN action_outcomes TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+-- 016_review_quorum.sql
+-- Review stages fan out to N reviewers and advance on a quorum of approvals; the reviewer set and the
+-- current round (reviews with a larger review_id) live on the task.
+
+ALTER TABLE workflow_stages ADD COLUMN reviewers INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE workflow_stages ADD COLUMN quorum INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN reviewers TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN review_round INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,35 @@
+-- 017_task_transitions.sql
+-- History of task stage changes (who or what moved the task, why) and agent turns, for the task timeline.
+
+CREATE TABLE IF NOT EXISTS task_transitions (
+  transition_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  task_id INTEGER NOT NULL,
+  team_id TEXT NOT NULL,
+  from_stage TEXT NOT NULL DEFAULT '',
+  to_stage TEXT NOT NULL DEFAULT '',
+  outcome TEXT NOT NULL DEFAULT '',
+  actor TEXT NOT NULL DEFAULT '',
+  note TEXT NOT NULL DEFAULT '',
+  from_entered_at INTEGER,
+  created_at INTEGER NOT NULL,
+  FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+  FOREIGN KEY (team_id) REFERENCES teams(team_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_transitions_task ON task_transitions(task_id, transition_id);
+
+CREATE TABLE IF NOT EXISTS task_turns (
+  turn_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  task_id INTEGER NOT NULL,
+  team_id TEXT NOT NULL,
+  agent TEXT NOT NULL DEFAULT '',
+  stage TEXT NOT NULL DEFAULT '',
+  outcome TEXT NOT NULL DEFAULT '',
+  error TEXT NOT NULL DEFAULT '',
+  started_at INTEGER NOT NULL,
+  finished_at INTEGER NOT NULL,
+  FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+  FOREIGN KEY (team_id) REFERENCES teams(team_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_turns_task ON task_turns(task_id, turn_id);
@@ -0,0 +1,9 @@
+-- 018_subtasks.sql
+-- Subtasks: a task can have a parent. The parent waits while children are open and is rolled up once they
+-- all finish; subtask_policy says what a failed child does, subtasks_rolled_up_at marks the roll-up as done.
+
+ALTER TABLE tasks ADD COLUMN parent_task_id INTEGER REFERENCES tasks(task_id) ON DELETE SET NULL;
+ALTER TABLE tasks ADD COLUMN subtask_policy TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN subtasks_rolled_up_at INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_parent ON tasks(parent_task_id);
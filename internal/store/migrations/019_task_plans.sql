@@ -0,0 +1,21 @@
+-- 019_task_plans.sql
+-- Implementation plans written in plan stages: one row per revision (agent draft or human edit), with the
+-- human decision on it.
+
+CREATE TABLE IF NOT EXISTS task_plans (
+  plan_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  task_id INTEGER NOT NULL,
+  team_id TEXT NOT NULL,
+  revision INTEGER NOT NULL,
+  author TEXT NOT NULL DEFAULT '',
+  body TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'pending',
+  feedback TEXT NOT NULL DEFAULT '',
+  decided_by TEXT NOT NULL DEFAULT '',
+  decided_at INTEGER,
+  created_at INTEGER NOT NULL,
+  FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+  FOREIGN KEY (team_id) REFERENCES teams(team_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_plans_task ON task_plans(task_id, revision);
@@ -0,0 +1,7 @@
+-- 020_repo_merge_settings.sql
+-- Per-repo merge settings: the branch tasks land on ('' = main, else master), how they land
+-- (merge, squash, rebase or ff-only) and a text/template for the merge or squash commit message.
+
+ALTER TABLE repos ADD COLUMN target_branch TEXT NOT NULL DEFAULT '';
+ALTER TABLE repos ADD COLUMN merge_strategy TEXT NOT NULL DEFAULT 'merge';
+ALTER TABLE repos ADD COLUMN commit_template TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,20 @@
+-- 021_merge_queue.sql
+-- Merge queue: tasks waiting to land on a repo's target branch, in order. Entries are tested together
+-- (batch is the first entry of the batch they were last tested in) and end merged, failed or removed.
+
+CREATE TABLE IF NOT EXISTS merge_queue (
+  entry_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  team_id TEXT NOT NULL,
+  repo_name TEXT NOT NULL DEFAULT '',
+  task_id INTEGER NOT NULL,
+  stage TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'queued',
+  batch INTEGER NOT NULL DEFAULT 0,
+  note TEXT NOT NULL DEFAULT '',
+  enqueued_at INTEGER NOT NULL,
+  updated_at INTEGER NOT NULL,
+  FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+  FOREIGN KEY (team_id) REFERENCES teams(team_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_merge_queue_team ON merge_queue(team_id, status, entry_id);
@@ -0,0 +1,36 @@
+-- 022_checks.sql
+-- Named pre-merge checks per repo (lint, unit, build, ...) and their results: every run of a repo's checks
+-- for a task is a numbered attempt, with each check's status, exit code and log.
+
+CREATE TABLE IF NOT EXISTS repo_checks (
+  team_id TEXT NOT NULL,
+  repo_name TEXT NOT NULL,
+  name TEXT NOT NULL,
+  position INTEGER NOT NULL DEFAULT 0,
+  command TEXT NOT NULL,
+  timeout_seconds INTEGER NOT NULL DEFAULT 0,
+  env TEXT NOT NULL DEFAULT '',
+  required INTEGER NOT NULL DEFAULT 1,
+  PRIMARY KEY (team_id, repo_name, name),
+  FOREIGN KEY (team_id) REFERENCES teams(team_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS task_check_runs (
+  run_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  team_id TEXT NOT NULL,
+  task_id INTEGER NOT NULL,
+  attempt INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  command TEXT NOT NULL DEFAULT '',
+  required INTEGER NOT NULL DEFAULT 1,
+  status TEXT NOT NULL,
+  exit_code INTEGER NOT NULL DEFAULT 0,
+  head_sha TEXT NOT NULL DEFAULT '',
+  log TEXT NOT NULL DEFAULT '',
+  started_at INTEGER NOT NULL,
+  finished_at INTEGER NOT NULL,
+  FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+  FOREIGN KEY (team_id) REFERENCES teams(team_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_check_runs_task ON task_check_runs(task_id, attempt);
@@ -0,0 +1,18 @@
+-- 023_approval_decisions.sql
+-- What the repo's approval policy decided each time a task entered a human approval stage: approved
+-- automatically or left for a human, and why.
+
+CREATE TABLE IF NOT EXISTS task_approval_decisions (
+  decision_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  team_id TEXT NOT NULL,
+  task_id INTEGER NOT NULL,
+  stage TEXT NOT NULL,
+  policy TEXT NOT NULL,
+  approved INTEGER NOT NULL DEFAULT 0,
+  reason TEXT NOT NULL DEFAULT '',
+  created_at INTEGER NOT NULL,
+  FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+  FOREIGN KEY (team_id) REFERENCES teams(team_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_approval_decisions_task ON task_approval_decisions(task_id, decision_id);
@@ -0,0 +1,25 @@
+-- 024_review_comments.sql
+-- Inline review comments anchored to a file, a line range and the commit they were made on. A comment
+-- with in_reply_to = 0 starts a thread (and carries its anchor and resolved flag); replies point at it.
+
+CREATE TABLE IF NOT EXISTS review_comments (
+  comment_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  team_id TEXT NOT NULL,
+  task_id INTEGER NOT NULL,
+  review_id INTEGER NOT NULL DEFAULT 0,
+  in_reply_to INTEGER NOT NULL DEFAULT 0,
+  author TEXT NOT NULL,
+  path TEXT NOT NULL DEFAULT '',
+  start_line INTEGER NOT NULL DEFAULT 0,
+  end_line INTEGER NOT NULL DEFAULT 0,
+  commit_sha TEXT NOT NULL DEFAULT '',
+  body TEXT NOT NULL,
+  resolved INTEGER NOT NULL DEFAULT 0,
+  outdated INTEGER NOT NULL DEFAULT 0,
+  created_at INTEGER NOT NULL,
+  updated_at INTEGER NOT NULL,
+  FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+  FOREIGN KEY (team_id) REFERENCES teams(team_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_comments_task ON review_comments(task_id, comment_id);
@@ -0,0 +1,4 @@
+-- 025_worktree_quota.sql
+-- Per-team cap on the disk used by task worktrees, enforced by the worktree GC (0 = no quota).
+
+ALTER TABLE teams ADD COLUMN worktree_quota INTEGER NOT NULL DEFAULT 0;
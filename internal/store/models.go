@@ -10,6 +10,19 @@ type Team struct {
 	CreatedAt  time.Time
 	AgentCount int
 	TaskCount  int
+	// Paused stops the scheduler from claiming new tasks for this team; in-flight turns finish.
+	Paused      bool
+	PausedAt    *time.Time
+	PauseReason string
+	// WorktreeQuota caps the bytes the team's task worktrees may use; the worktree GC enforces it. 0 = no quota.
+	WorktreeQuota int64
+}
+
+// SchedulerState is the global scheduler pause switch (applies to all teams).
+type SchedulerState struct {
+	Paused   bool
+	PausedAt *time.Time
+	Reason   string
 }
 
 // Agent is a team member (e.g. manager or engineer) that can be assigned tasks.
@@ -33,8 +46,18 @@ type Task struct {
 	BranchName   *string // agentary/<team_id>/<team>/T<NNNN>
 	BaseSHA      *string // Base commit when branch was created
 	RepoName     *string // Optional repo name for this task
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	Labels       string  // comma-separated labels (e.g. set by a task schedule)
+	Reviewers    string  // comma-separated reviewer set picked by a quorum review stage
+	ReviewRound  int64   // reviews with a larger ReviewID belong to the current review round
+	ParentTaskID *int64  // set on subtasks
+	// SubtaskPolicy is what a failed subtask does to this (parent) task: "fail" (default), "ignore" or "wait".
+	SubtaskPolicy string
+	// StageEnteredAt is when the task entered CurrentStage (reset on every stage change).
+	StageEnteredAt *time.Time
+	// SLABreachedAt is set once the current stage's max_duration has been exceeded and its on_timeout action ran.
+	SLABreachedAt *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 // TaskComment is a comment on a task (author and body).
@@ -55,21 +78,26 @@ type TaskAttachment struct {
 	CreatedAt    time.Time
 }
 
-// Repo is a git repository linked to a team (source path, approval mode, optional test command).
+// Repo is a git repository linked to a team (source path, approval mode, optional test command)
+// and how tasks land on it.
 type Repo struct {
-	Name      string
-	Source    string
-	Approval  string
-	TestCmd   *string
-	CreatedAt time.Time
+	Name           string
+	Source         string
+	Approval       string
+	TestCmd        *string
+	TargetBranch   string // branch tasks merge into; "" = main, or master when there is no main
+	MergeStrategy  string // merge, squash, rebase or ff-only (see MergeStrategies)
+	CommitTemplate string // text/template for merge and squash commit messages; "" = default
+	CreatedAt      time.Time
 }
 
 // Workflow is a named workflow definition (version and source path or builtin).
 type Workflow struct {
-	Name       string
-	Version    int
-	SourcePath string
-	CreatedAt  time.Time
+	Name         string
+	Version      int
+	SourcePath   string
+	InitialStage string // stage new tasks start in; empty = first stage with no inbound transitions
+	CreatedAt    time.Time
 }
 
 // WorkflowStage is a stage in a workflow (agent, human, auto, terminal).
@@ -79,6 +107,23 @@ type WorkflowStage struct {
 	StageType       string // agent, human, auto, terminal
 	Outcomes        string // comma-separated outcomes, empty for terminal
 	CandidateAgents string // comma-separated agent names; if set, scheduler picks assignee from this pool
+	// MaxDuration is the stage SLA; 0 means no limit.
+	MaxDuration time.Duration
+	// OnTimeout is the action taken when MaxDuration is exceeded: "notify[:capability]",
+	// "reassign[:agent]", "transition:<outcome>" or "fail". Empty defaults to notify.
+	OnTimeout string
+	// OnEnter and OnExit are newline-separated hooks run when a task enters or leaves the stage:
+	// "run:<command>", "notify[:capability]:<message>" or "comment:<text>".
+	OnEnter string
+	OnExit  string
+	// Action is what an auto stage runs: "run:<command>", "lint[:<command>]", "format[:<command>]",
+	// "webhook:<url>" or "go:<name>". ActionOutcomes maps exit codes to outcomes ("0=done,*=failed").
+	Action         string
+	ActionOutcomes string
+	// Reviewers is how many reviewers a review stage fans out to; Quorum is how many approvals it
+	// needs (0 means all of them). Any changes_requested blocks.
+	Reviewers int
+	Quorum    int
 }
 
 // WorkflowTransition is (from_stage, outcome) -> to_stage.
@@ -87,6 +132,11 @@ type WorkflowTransition struct {
 	FromStage  string
 	Outcome    string
 	ToStage    string
+	// Guards are newline-separated checks that must pass before the transition is taken
+	// (e.g. "diff_not_empty", "tests_pass", "min_approvals:2", "no_protected_paths:infra/").
+	Guards string
+	// OnGuardFail is the outcome applied instead when a guard fails; empty leaves the task in its stage.
+	OnGuardFail string
 }
 
 // TaskReview is an agent-to-agent or human review submission for a task.
@@ -100,6 +150,181 @@ type TaskReview struct {
 	CreatedAt     time.Time
 }
 
+// ReviewComment is an inline review comment. A comment with InReplyTo 0 starts a thread: it is anchored
+// to lines StartLine..EndLine of Path as of CommitSHA and carries the thread's Resolved flag. Replies
+// point at the thread's first comment and have no anchor. Outdated is set when the branch changed the
+// anchored lines; otherwise the anchor is moved along with the branch.
+type ReviewComment struct {
+	CommentID int64
+	TaskID    int64
+	ReviewID  int64 // review it was submitted with; 0 = standalone
+	InReplyTo int64
+	Author    string
+	Path      string
+	StartLine int
+	EndLine   int
+	CommitSHA string
+	Body      string
+	Resolved  bool
+	Outdated  bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TaskTransition records one stage change of a task: who or what moved it, with which outcome, and when.
+type TaskTransition struct {
+	TransitionID int64
+	TaskID       int64
+	FromStage    string // "" when the task had no stage yet
+	ToStage      string
+	Outcome      string // workflow outcome; "" for manual moves (force-transition, rewind)
+	Actor        string // agent, reviewer, "human", "sla", "cli", ...; "system" when unattributed
+	Note         string
+	// FromEnteredAt is when the task entered FromStage, so CreatedAt-FromEnteredAt is the time spent there.
+	FromEnteredAt *time.Time
+	CreatedAt     time.Time
+}
+
+// TransitionCause attributes the stage changes a store call makes; attach it with WithTransitionCause.
+type TransitionCause struct {
+	Actor   string
+	Outcome string
+	Note    string
+}
+
+// TaskTurn records one agent runtime turn on a task.
+type TaskTurn struct {
+	TurnID     int64
+	TaskID     int64
+	Agent      string
+	Stage      string
+	Outcome    string
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// TaskPlan is one revision of a task's implementation plan, written by the agent in a plan stage or
+// edited by a human. Status is "pending" until a human approves or rejects it; a newer revision
+// supersedes a pending one.
+type TaskPlan struct {
+	PlanID    int64
+	TaskID    int64
+	Revision  int
+	Author    string
+	Body      string
+	Status    string // pending, approved, rejected, superseded
+	Feedback  string // why it was rejected
+	DecidedBy string
+	DecidedAt *time.Time
+	CreatedAt time.Time
+}
+
+// TaskApprovalDecision records what the repo's approval policy decided when the task entered a human
+// approval stage: approved automatically, or left for a human.
+type TaskApprovalDecision struct {
+	DecisionID int64
+	TaskID     int64
+	Stage      string
+	Policy     string // the repo's approval setting, e.g. manual, auto or auto_if:max_diff_lines:200
+	Approved   bool
+	Reason     string
+	CreatedAt  time.Time
+}
+
+// MergeQueueEntry is a task waiting in (or done with) its repo's merge queue. Entries land in EntryID order.
+type MergeQueueEntry struct {
+	EntryID    int64
+	TaskID     int64
+	RepoName   string
+	Stage      string // stage the task was queued from; it leaves the queue if it moves on
+	Status     string // queued, testing, merged, failed, removed
+	Batch      int64  // EntryID of the first entry of the batch it was last tested in; 0 = not tested yet
+	Note       string // why it failed or was removed
+	EnqueuedAt time.Time
+	UpdatedAt  time.Time
+}
+
+// RepoCheck is a named pre-merge check of a repo (e.g. lint, unit, build): a shell command run in the
+// worktree. A failing required check blocks the merge; an optional one is only reported.
+type RepoCheck struct {
+	Name     string
+	Command  string
+	Timeout  time.Duration // 0 = default
+	Env      []string      // KEY=value pairs added to the environment
+	Required bool
+}
+
+// TaskCheckRun is one check's result in one attempt at a task's checks. Attempts are numbered per task;
+// all checks run in an attempt share its number.
+type TaskCheckRun struct {
+	RunID      int64
+	TaskID     int64
+	Attempt    int
+	Name       string
+	Command    string
+	Required   bool
+	Status     string // passed, failed, timed_out, error
+	ExitCode   int
+	HeadSHA    string // commit the checks ran on
+	Log        string // combined stdout and stderr
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// TaskSchedule materializes tasks on a cron expression or once at RunAt.
+type TaskSchedule struct {
+	ScheduleID      int64
+	TeamID          string
+	TeamName        string
+	Name            string
+	TitleTemplate   string // text/template rendered at run time (e.g. "Dependency audit {{.Date}}")
+	WorkflowName    string // empty = team default workflow
+	WorkflowVersion int
+	Labels          string // comma-separated labels copied to each created task
+	CronExpr        string // 5-field cron expression or @daily/@weekly/...; empty when RunAt is set
+	RunAt           *time.Time
+	NextRunAt       *time.Time // nil when the schedule has nothing left to run
+	LastRunAt       *time.Time
+	LastTaskID      *int64 // most recent task created by this schedule
+	Enabled         bool
+	CreatedAt       time.Time
+}
+
+// StageSLABreach is an open task that has been in its current stage longer than the stage's MaxDuration.
+type StageSLABreach struct {
+	TeamName string
+	Task     Task
+	Stage    WorkflowStage
+}
+
+// SubtaskRollup is an open parent task whose subtasks have all finished (done, failed or cancelled)
+// and that has not been rolled up since.
+type SubtaskRollup struct {
+	TeamName  string
+	Parent    Task
+	Done      int
+	Failed    int
+	Cancelled int
+}
+
+// TaskStageMove is one task re-pointed by a workflow migration.
+type TaskStageMove struct {
+	TaskID    int64
+	FromStage string
+	ToStage   string
+}
+
+// WorkflowMigration moves open tasks from one workflow (version) to another; stored as an audit record.
+type WorkflowMigration struct {
+	MigrationID    int64
+	FromWorkflowID string
+	ToWorkflowID   string
+	StageMap       map[string]string // from stage -> to stage, as requested
+	Moves          []TaskStageMove
+	CreatedAt      time.Time
+}
+
 // Message is used for agent↔agent or human↔manager communication (mailbox).
 type Message struct {
 	MessageID   int64
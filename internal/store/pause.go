@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SetTeamPaused pauses or resumes scheduling for a team. Reason is cleared on resume.
+func (s *sqliteStore) SetTeamPaused(ctx context.Context, teamName string, paused bool, reason string) error {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	var pausedAt any
+	if paused {
+		pausedAt = time.Now().UTC().Unix()
+	} else {
+		reason = ""
+	}
+	_, err = s.DB.ExecContext(ctx, `UPDATE teams SET paused=?, paused_at=?, pause_reason=? WHERE team_id=?`, paused, pausedAt, reason, team.TeamID)
+	return err
+}
+
+// SetTeamWorktreeQuota sets the disk quota for the team's task worktrees in bytes (0 = no quota).
+func (s *sqliteStore) SetTeamWorktreeQuota(ctx context.Context, teamName string, bytes int64) error {
+	if bytes < 0 {
+		return errors.New("worktree quota must not be negative")
+	}
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx, `UPDATE teams SET worktree_quota=? WHERE team_id=?`, bytes, team.TeamID)
+	return err
+}
+
+// GetSchedulerState returns the global scheduler pause state.
+func (s *sqliteStore) GetSchedulerState(ctx context.Context) (SchedulerState, error) {
+	var st SchedulerState
+	var pausedAt sql.NullInt64
+	err := s.DB.QueryRowContext(ctx, `SELECT paused, paused_at, reason FROM scheduler_state WHERE id = 1`).Scan(&st.Paused, &pausedAt, &st.Reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return SchedulerState{}, nil
+	}
+	if err != nil {
+		return SchedulerState{}, err
+	}
+	st.PausedAt = timeOrNil(pausedAt)
+	return st, nil
+}
+
+// SetSchedulerPaused pauses or resumes scheduling for all teams. Reason is cleared on resume.
+func (s *sqliteStore) SetSchedulerPaused(ctx context.Context, paused bool, reason string) error {
+	var pausedAt any
+	if paused {
+		pausedAt = time.Now().UTC().Unix()
+	} else {
+		reason = ""
+	}
+	_, err := s.DB.ExecContext(ctx, `INSERT INTO scheduler_state(id, paused, paused_at, reason) VALUES(1, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET paused=excluded.paused, paused_at=excluded.paused_at, reason=excluded.reason`, paused, pausedAt, reason)
+	return err
+}
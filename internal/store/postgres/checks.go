@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func (s *Store) ListRepoChecks(ctx context.Context, teamName, repoName string) ([]store.RepoCheck, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT name, command, timeout_seconds, env, required FROM repo_checks WHERE team_id=$1 AND repo_name=$2 ORDER BY position ASC, name ASC`, team.TeamID, repoName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.RepoCheck
+	for rows.Next() {
+		var c store.RepoCheck
+		var timeout int64
+		var env string
+		if err := rows.Scan(&c.Name, &c.Command, &timeout, &env, &c.Required); err != nil {
+			return nil, err
+		}
+		c.Timeout = time.Duration(timeout) * time.Second
+		c.Env = store.SplitCheckEnv(env)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) SetRepoChecks(ctx context.Context, teamName, repoName string, checks []store.RepoCheck) error {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if _, err := tx.Exec(ctx, `DELETE FROM repo_checks WHERE team_id=$1 AND repo_name=$2`, team.TeamID, repoName); err != nil {
+		return err
+	}
+	for i, c := range checks {
+		if _, err := tx.Exec(ctx, `INSERT INTO repo_checks(team_id, repo_name, name, position, command, timeout_seconds, env, required) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`,
+			team.TeamID, repoName, c.Name, i, c.Command, int64(c.Timeout/time.Second), strings.Join(c.Env, "\n"), c.Required); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) CreateTaskCheckRuns(ctx context.Context, teamName string, taskID int64, runs []store.TaskCheckRun) (int, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	var attempt int
+	if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(attempt),0)+1 FROM task_check_runs WHERE task_id=$1`, taskID).Scan(&attempt); err != nil {
+		return 0, err
+	}
+	for _, r := range runs {
+		if _, err := tx.Exec(ctx, `INSERT INTO task_check_runs(team_id, task_id, attempt, name, command, required, status, exit_code, head_sha, log, started_at, finished_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			team.TeamID, taskID, attempt, r.Name, r.Command, r.Required, r.Status, r.ExitCode, r.HeadSHA, r.Log, r.StartedAt.UTC().UnixMilli(), r.FinishedAt.UTC().UnixMilli()); err != nil {
+			return 0, err
+		}
+	}
+	return attempt, tx.Commit(ctx)
+}
+
+func (s *Store) ListTaskCheckRuns(ctx context.Context, teamName string, taskID int64) ([]store.TaskCheckRun, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT run_id, task_id, attempt, name, command, required, status, exit_code, head_sha, log, started_at, finished_at FROM task_check_runs WHERE task_id=$1 AND team_id=$2 ORDER BY attempt ASC, run_id ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.TaskCheckRun
+	for rows.Next() {
+		var r store.TaskCheckRun
+		var started, finished int64
+		if err := rows.Scan(&r.RunID, &r.TaskID, &r.Attempt, &r.Name, &r.Command, &r.Required, &r.Status, &r.ExitCode, &r.HeadSHA, &r.Log, &started, &finished); err != nil {
+			return nil, err
+		}
+		r.StartedAt = time.UnixMilli(started).UTC()
+		r.FinishedAt = time.UnixMilli(finished).UTC()
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) EnqueueMerge(ctx context.Context, teamName, repoName string, taskID int64, stage string) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	var id int64
+	err = tx.QueryRow(ctx, `SELECT entry_id FROM merge_queue WHERE task_id=$1 AND status IN ('queued','testing')`, taskID).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+	now := time.Now().UTC().Unix()
+	err = tx.QueryRow(ctx, `INSERT INTO merge_queue(team_id, repo_name, task_id, stage, status, enqueued_at, updated_at) VALUES($1, $2, $3, $4, 'queued', $5, $6) RETURNING entry_id`,
+		team.TeamID, repoName, taskID, stage, now, now).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
+}
+
+func (s *Store) ListMergeQueue(ctx context.Context, teamName string, openOnly bool) ([]store.MergeQueueEntry, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	q := `SELECT entry_id, task_id, repo_name, stage, status, batch, note, enqueued_at, updated_at FROM merge_queue WHERE team_id=$1`
+	if openOnly {
+		q += ` AND status IN ('queued','testing')`
+	}
+	rows, err := s.Pool.Query(ctx, q+` ORDER BY entry_id ASC`, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.MergeQueueEntry
+	for rows.Next() {
+		var e store.MergeQueueEntry
+		var enqueued, updated int64
+		if err := rows.Scan(&e.EntryID, &e.TaskID, &e.RepoName, &e.Stage, &e.Status, &e.Batch, &e.Note, &enqueued, &updated); err != nil {
+			return nil, err
+		}
+		e.EnqueuedAt = time.Unix(enqueued, 0).UTC()
+		e.UpdatedAt = time.Unix(updated, 0).UTC()
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) UpdateMergeQueueEntry(ctx context.Context, entryID int64, status string, batch int64, note string) error {
+	res, err := s.Pool.Exec(ctx, `UPDATE merge_queue SET status=$1, batch=$2, note=$3, updated_at=$4 WHERE entry_id=$5`,
+		status, batch, note, time.Now().UTC().Unix(), entryID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("merge queue entry %d not found", entryID)
+	}
+	return nil
+}
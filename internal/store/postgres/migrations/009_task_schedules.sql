@@ -0,0 +1,21 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS labels TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS task_schedules (
+  schedule_id BIGSERIAL PRIMARY KEY,
+  team_id TEXT NOT NULL REFERENCES teams(team_id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  title_template TEXT NOT NULL,
+  workflow_name TEXT NOT NULL DEFAULT '',
+  workflow_version INTEGER NOT NULL DEFAULT 1,
+  labels TEXT NOT NULL DEFAULT '',
+  cron_expr TEXT NOT NULL DEFAULT '',
+  run_at BIGINT,
+  next_run_at BIGINT,
+  last_run_at BIGINT,
+  last_task_id BIGINT,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at BIGINT NOT NULL,
+  UNIQUE(team_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_schedules_next_run ON task_schedules(enabled, next_run_at);
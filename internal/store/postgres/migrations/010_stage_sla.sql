@@ -0,0 +1,7 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS stage_entered_at BIGINT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sla_breached_at BIGINT;
+
+UPDATE tasks SET stage_entered_at = updated_at WHERE current_stage IS NOT NULL AND stage_entered_at IS NULL;
+
+ALTER TABLE workflow_stages ADD COLUMN IF NOT EXISTS max_duration_seconds BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE workflow_stages ADD COLUMN IF NOT EXISTS on_timeout TEXT NOT NULL DEFAULT '';
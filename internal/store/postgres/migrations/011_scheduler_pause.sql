@@ -0,0 +1,12 @@
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS paused_at BIGINT;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS pause_reason TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS scheduler_state (
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  paused BOOLEAN NOT NULL DEFAULT FALSE,
+  paused_at BIGINT,
+  reason TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO scheduler_state(id, paused) VALUES(1, FALSE) ON CONFLICT DO NOTHING;
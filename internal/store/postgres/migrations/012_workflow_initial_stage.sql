@@ -0,0 +1,5 @@
+ALTER TABLE workflows ADD COLUMN IF NOT EXISTS initial_stage TEXT NOT NULL DEFAULT '';
+
+UPDATE workflows SET initial_stage = 'Coding'
+WHERE name = 'default' AND version = 1
+  AND EXISTS (SELECT 1 FROM workflow_stages s WHERE s.workflow_id = workflows.workflow_id AND s.stage_name = 'Coding');
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS workflow_migrations (
+  migration_id BIGSERIAL PRIMARY KEY,
+  team_id TEXT NOT NULL REFERENCES teams(team_id) ON DELETE CASCADE,
+  from_workflow_id TEXT NOT NULL,
+  to_workflow_id TEXT NOT NULL,
+  stage_map TEXT NOT NULL DEFAULT '{}',
+  moves TEXT NOT NULL DEFAULT '[]',
+  created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_migrations_team ON workflow_migrations(team_id, created_at);
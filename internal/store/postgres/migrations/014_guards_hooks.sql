@@ -0,0 +1,4 @@
+ALTER TABLE workflow_stages ADD COLUMN IF NOT EXISTS on_enter TEXT NOT NULL DEFAULT '';
+ALTER TABLE workflow_stages ADD COLUMN IF NOT EXISTS on_exit TEXT NOT NULL DEFAULT '';
+ALTER TABLE workflow_transitions ADD COLUMN IF NOT EXISTS guards TEXT NOT NULL DEFAULT '';
+ALTER TABLE workflow_transitions ADD COLUMN IF NOT EXISTS on_guard_fail TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE workflow_stages ADD COLUMN IF NOT EXISTS action TEXT NOT NULL DEFAULT '';
+ALTER TABLE workflow_stages ADD COLUMN IF NOT EXISTS action_outcomes TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE workflow_stages ADD COLUMN IF NOT EXISTS reviewers INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE workflow_stages ADD COLUMN IF NOT EXISTS quorum INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS reviewers TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS review_round BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS task_transitions (
+  transition_id BIGSERIAL PRIMARY KEY,
+  task_id BIGINT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+  team_id TEXT NOT NULL REFERENCES teams(team_id) ON DELETE CASCADE,
+  from_stage TEXT NOT NULL DEFAULT '',
+  to_stage TEXT NOT NULL DEFAULT '',
+  outcome TEXT NOT NULL DEFAULT '',
+  actor TEXT NOT NULL DEFAULT '',
+  note TEXT NOT NULL DEFAULT '',
+  from_entered_at BIGINT,
+  created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_transitions_task ON task_transitions(task_id, transition_id);
+
+CREATE TABLE IF NOT EXISTS task_turns (
+  turn_id BIGSERIAL PRIMARY KEY,
+  task_id BIGINT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+  team_id TEXT NOT NULL REFERENCES teams(team_id) ON DELETE CASCADE,
+  agent TEXT NOT NULL DEFAULT '',
+  stage TEXT NOT NULL DEFAULT '',
+  outcome TEXT NOT NULL DEFAULT '',
+  error TEXT NOT NULL DEFAULT '',
+  started_at BIGINT NOT NULL,
+  finished_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_turns_task ON task_turns(task_id, turn_id);
@@ -0,0 +1,5 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_task_id BIGINT REFERENCES tasks(task_id) ON DELETE SET NULL;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS subtask_policy TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS subtasks_rolled_up_at BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_parent ON tasks(parent_task_id);
@@ -0,0 +1,3 @@
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS target_branch TEXT NOT NULL DEFAULT '';
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS merge_strategy TEXT NOT NULL DEFAULT 'merge';
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS commit_template TEXT NOT NULL DEFAULT '';
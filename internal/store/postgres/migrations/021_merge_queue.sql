@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS merge_queue (
+  entry_id BIGSERIAL PRIMARY KEY,
+  team_id TEXT NOT NULL REFERENCES teams(team_id) ON DELETE CASCADE,
+  repo_name TEXT NOT NULL DEFAULT '',
+  task_id BIGINT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+  stage TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'queued',
+  batch BIGINT NOT NULL DEFAULT 0,
+  note TEXT NOT NULL DEFAULT '',
+  enqueued_at BIGINT NOT NULL,
+  updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_merge_queue_team ON merge_queue(team_id, status, entry_id);
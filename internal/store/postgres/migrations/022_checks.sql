@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS repo_checks (
+  team_id TEXT NOT NULL REFERENCES teams(team_id) ON DELETE CASCADE,
+  repo_name TEXT NOT NULL,
+  name TEXT NOT NULL,
+  position INTEGER NOT NULL DEFAULT 0,
+  command TEXT NOT NULL,
+  timeout_seconds BIGINT NOT NULL DEFAULT 0,
+  env TEXT NOT NULL DEFAULT '',
+  required BOOLEAN NOT NULL DEFAULT TRUE,
+  PRIMARY KEY (team_id, repo_name, name)
+);
+
+CREATE TABLE IF NOT EXISTS task_check_runs (
+  run_id BIGSERIAL PRIMARY KEY,
+  team_id TEXT NOT NULL REFERENCES teams(team_id) ON DELETE CASCADE,
+  task_id BIGINT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+  attempt INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  command TEXT NOT NULL DEFAULT '',
+  required BOOLEAN NOT NULL DEFAULT TRUE,
+  status TEXT NOT NULL,
+  exit_code INTEGER NOT NULL DEFAULT 0,
+  head_sha TEXT NOT NULL DEFAULT '',
+  log TEXT NOT NULL DEFAULT '',
+  started_at BIGINT NOT NULL,
+  finished_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_check_runs_task ON task_check_runs(task_id, attempt);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS task_approval_decisions (
+  decision_id BIGSERIAL PRIMARY KEY,
+  team_id TEXT NOT NULL REFERENCES teams(team_id) ON DELETE CASCADE,
+  task_id BIGINT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+  stage TEXT NOT NULL,
+  policy TEXT NOT NULL,
+  approved BOOLEAN NOT NULL DEFAULT FALSE,
+  reason TEXT NOT NULL DEFAULT '',
+  created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_approval_decisions_task ON task_approval_decisions(task_id, decision_id);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS review_comments (
+  comment_id BIGSERIAL PRIMARY KEY,
+  team_id TEXT NOT NULL REFERENCES teams(team_id) ON DELETE CASCADE,
+  task_id BIGINT NOT NULL REFERENCES tasks(task_id) ON DELETE CASCADE,
+  review_id BIGINT NOT NULL DEFAULT 0,
+  in_reply_to BIGINT NOT NULL DEFAULT 0,
+  author TEXT NOT NULL,
+  path TEXT NOT NULL DEFAULT '',
+  start_line INTEGER NOT NULL DEFAULT 0,
+  end_line INTEGER NOT NULL DEFAULT 0,
+  commit_sha TEXT NOT NULL DEFAULT '',
+  body TEXT NOT NULL,
+  resolved BOOLEAN NOT NULL DEFAULT FALSE,
+  outdated BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at BIGINT NOT NULL,
+  updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_comments_task ON review_comments(task_id, comment_id);
@@ -0,0 +1 @@
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS worktree_quota BIGINT NOT NULL DEFAULT 0;
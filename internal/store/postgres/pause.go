@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) SetTeamPaused(ctx context.Context, teamName string, paused bool, reason string) error {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	var pausedAt *int64
+	if paused {
+		now := time.Now().UTC().Unix()
+		pausedAt = &now
+	} else {
+		reason = ""
+	}
+	_, err = s.Pool.Exec(ctx, `UPDATE teams SET paused=$1, paused_at=$2, pause_reason=$3 WHERE team_id=$4`, paused, pausedAt, reason, team.TeamID)
+	return err
+}
+
+func (s *Store) SetTeamWorktreeQuota(ctx context.Context, teamName string, bytes int64) error {
+	if bytes < 0 {
+		return errors.New("worktree quota must not be negative")
+	}
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	_, err = s.Pool.Exec(ctx, `UPDATE teams SET worktree_quota=$1 WHERE team_id=$2`, bytes, team.TeamID)
+	return err
+}
+
+func (s *Store) GetSchedulerState(ctx context.Context) (store.SchedulerState, error) {
+	var st store.SchedulerState
+	var pausedAt *int64
+	err := s.Pool.QueryRow(ctx, `SELECT paused, paused_at, reason FROM scheduler_state WHERE id = 1`).Scan(&st.Paused, &pausedAt, &st.Reason)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return store.SchedulerState{}, nil
+	}
+	if err != nil {
+		return store.SchedulerState{}, err
+	}
+	st.PausedAt = timeOrNil(pausedAt)
+	return st, nil
+}
+
+func (s *Store) SetSchedulerPaused(ctx context.Context, paused bool, reason string) error {
+	var pausedAt *int64
+	if paused {
+		now := time.Now().UTC().Unix()
+		pausedAt = &now
+	} else {
+		reason = ""
+	}
+	_, err := s.Pool.Exec(ctx, `INSERT INTO scheduler_state(id, paused, paused_at, reason) VALUES(1, $1, $2, $3)
+ON CONFLICT(id) DO UPDATE SET paused=EXCLUDED.paused, paused_at=EXCLUDED.paused_at, reason=EXCLUDED.reason`, paused, pausedAt, reason)
+	return err
+}
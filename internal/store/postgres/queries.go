@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ankittk/agentary/internal/store"
@@ -61,7 +62,8 @@ func (s *Store) ListTeams(ctx context.Context) ([]store.Team, error) {
 	rows, err := s.Pool.Query(ctx, `
 SELECT t.name, t.team_id, t.created_at,
   (SELECT COUNT(*) FROM agents a WHERE a.team_id = t.team_id) AS agent_count,
-  (SELECT COUNT(*) FROM tasks k WHERE k.team_id = t.team_id) AS task_count
+  (SELECT COUNT(*) FROM tasks k WHERE k.team_id = t.team_id) AS task_count,
+  t.paused, t.paused_at, t.pause_reason, t.worktree_quota
 FROM teams t ORDER BY t.created_at ASC`)
 	if err != nil {
 		return nil, err
@@ -72,13 +74,19 @@ FROM teams t ORDER BY t.created_at ASC`)
 		var name, teamID string
 		var createdAt int64
 		var agentCnt, taskCnt int
-		if err := rows.Scan(&name, &teamID, &createdAt, &agentCnt, &taskCnt); err != nil {
+		var paused bool
+		var pausedAt *int64
+		var reason string
+		var quota int64
+		if err := rows.Scan(&name, &teamID, &createdAt, &agentCnt, &taskCnt, &paused, &pausedAt, &reason, &quota); err != nil {
 			return nil, err
 		}
 		out = append(out, store.Team{
 			Name: name, TeamID: teamID,
 			CreatedAt: time.Unix(createdAt, 0).UTC(),
 			AgentCount: agentCnt, TaskCount: taskCnt,
+			Paused: paused, PausedAt: timeOrNil(pausedAt), PauseReason: reason,
+			WorktreeQuota: quota,
 		})
 	}
 	return out, rows.Err()
@@ -87,8 +95,9 @@ FROM teams t ORDER BY t.created_at ASC`)
 func (s *Store) GetTeamByName(ctx context.Context, name string) (store.Team, error) {
 	var t store.Team
 	var createdAt int64
-	err := s.Pool.QueryRow(ctx, `SELECT name, team_id, created_at FROM teams WHERE name = $1`, name).
-		Scan(&t.Name, &t.TeamID, &createdAt)
+	var pausedAt *int64
+	err := s.Pool.QueryRow(ctx, `SELECT name, team_id, created_at, paused, paused_at, pause_reason, worktree_quota FROM teams WHERE name = $1`, name).
+		Scan(&t.Name, &t.TeamID, &createdAt, &t.Paused, &pausedAt, &t.PauseReason, &t.WorktreeQuota)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return store.Team{}, fmt.Errorf("team not found: %s", name)
@@ -96,6 +105,7 @@ func (s *Store) GetTeamByName(ctx context.Context, name string) (store.Team, err
 		return store.Team{}, err
 	}
 	t.CreatedAt = time.Unix(createdAt, 0).UTC()
+	t.PausedAt = timeOrNil(pausedAt)
 	return t, nil
 }
 
@@ -159,6 +169,9 @@ func (s *Store) CreateAgent(ctx context.Context, teamName, name, role string) er
 	return err
 }
 
+// taskColumns is the column list scanned by scanTaskRow, in order.
+const taskColumns = `task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at, COALESCE(labels,''), stage_entered_at, sla_breached_at, COALESCE(reviewers,''), COALESCE(review_round,0), parent_task_id, COALESCE(subtask_policy,'')`
+
 // scanTaskRow scans a row with task columns into *store.Task (used by NextRunnableTaskForTeam, GetTaskByIDAndTeam).
 func scanTaskRow(row interface{ Scan(dest ...any) error }) (*store.Task, error) {
 	var id int64
@@ -166,7 +179,12 @@ func scanTaskRow(row interface{ Scan(dest ...any) error }) (*store.Task, error)
 	var assignee, dri, workflowID, currentStage, worktreePath, branchName, baseSHA, repoName *string
 	var attemptCount int
 	var createdAt, updatedAt int64
-	err := row.Scan(&id, &title, &status, &assignee, &dri, &attemptCount, &workflowID, &currentStage, &worktreePath, &branchName, &baseSHA, &repoName, &createdAt, &updatedAt)
+	var labels, reviewers string
+	var reviewRound int64
+	var parentID *int64
+	var policy string
+	var stageEntered, slaBreached *int64
+	err := row.Scan(&id, &title, &status, &assignee, &dri, &attemptCount, &workflowID, &currentStage, &worktreePath, &branchName, &baseSHA, &repoName, &createdAt, &updatedAt, &labels, &stageEntered, &slaBreached, &reviewers, &reviewRound, &parentID, &policy)
 	if err != nil {
 		return nil, err
 	}
@@ -174,6 +192,12 @@ func scanTaskRow(row interface{ Scan(dest ...any) error }) (*store.Task, error)
 		TaskID: id, Title: title, Status: status, Assignee: assignee, DRI: dri,
 		AttemptCount: attemptCount, WorkflowID: workflowID, CurrentStage: currentStage,
 		WorktreePath: worktreePath, BranchName: branchName, BaseSHA: baseSHA, RepoName: repoName,
+		Labels:         labels,
+		Reviewers:      reviewers,
+		ReviewRound:    reviewRound,
+		ParentTaskID:   parentID,
+		SubtaskPolicy:  policy,
+		StageEnteredAt: timeOrNil(stageEntered), SLABreachedAt: timeOrNil(slaBreached),
 		CreatedAt: time.Unix(createdAt, 0).UTC(), UpdatedAt: time.Unix(updatedAt, 0).UTC(),
 	}, nil
 }
@@ -190,7 +214,7 @@ func (s *Store) ListTasks(ctx context.Context, teamName string, limit int) ([]st
 	if err != nil {
 		return nil, err
 	}
-	q := `SELECT task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at FROM tasks WHERE team_id = $1 ORDER BY created_at DESC`
+	q := `SELECT ` + taskColumns + ` FROM tasks WHERE team_id = $1 ORDER BY created_at DESC`
 	args := []any{team.TeamID}
 	if limit > 0 {
 		q += ` LIMIT $2`
@@ -217,7 +241,7 @@ func (s *Store) ListTasksInStage(ctx context.Context, teamName, stage string, li
 	if err != nil {
 		return nil, err
 	}
-	q := `SELECT task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at FROM tasks WHERE team_id = $1 AND current_stage = $2 ORDER BY updated_at ASC`
+	q := `SELECT ` + taskColumns + ` FROM tasks WHERE team_id = $1 AND current_stage = $2 ORDER BY updated_at ASC`
 	args := []any{team.TeamID, stage}
 	if limit > 0 {
 		q += ` LIMIT $3`
@@ -260,7 +284,7 @@ func (s *Store) CreateTask(ctx context.Context, teamName, title, status string,
 	if workflowID != nil && *workflowID != "" {
 		initial, err := s.GetWorkflowInitialStage(ctx, *workflowID)
 		if err == nil {
-			_, _ = s.Pool.Exec(ctx, `UPDATE tasks SET workflow_id=$1, current_stage=$2, updated_at=$3 WHERE task_id=$4`, *workflowID, initial, now, id)
+			_, _ = s.Pool.Exec(ctx, `UPDATE tasks SET workflow_id=$1, current_stage=$2, stage_entered_at=$3, updated_at=$3 WHERE task_id=$4`, *workflowID, initial, now, id)
 		}
 	}
 	return id, nil
@@ -296,6 +320,12 @@ func (s *Store) SetTaskFailed(ctx context.Context, taskID int64) error {
 	return err
 }
 
+func (s *Store) SetTaskReviewers(ctx context.Context, taskID int64, reviewers []string, round int64) error {
+	now := time.Now().UTC().Unix()
+	_, err := s.Pool.Exec(ctx, `UPDATE tasks SET reviewers=$1, review_round=$2, updated_at=$3 WHERE task_id=$4`, strings.Join(reviewers, ","), round, now, taskID)
+	return err
+}
+
 func (s *Store) RequeueTask(ctx context.Context, teamName string, taskID int64) error {
 	team, err := s.GetTeamByName(ctx, teamName)
 	if err != nil {
@@ -341,17 +371,27 @@ func (s *Store) RewindTask(ctx context.Context, teamName string, taskID int64) e
 	if err != nil {
 		return err
 	}
-	now := time.Now().UTC().Unix()
+	stage := ""
 	if task.WorkflowID != nil && *task.WorkflowID != "" {
-		initial, err := s.GetWorkflowInitialStage(ctx, *task.WorkflowID)
-		if err != nil {
+		if stage, err = s.GetWorkflowInitialStage(ctx, *task.WorkflowID); err != nil {
 			return err
 		}
-		_, err = s.Pool.Exec(ctx, `UPDATE tasks SET status='todo', assignee=NULL, current_stage=$1, updated_at=$2 WHERE task_id=$3 AND team_id=$4`, initial, now, taskID, team.TeamID)
+	}
+	now := time.Now().UTC().Unix()
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
 		return err
 	}
-	_, err = s.Pool.Exec(ctx, `UPDATE tasks SET status='todo', assignee=NULL, current_stage=NULL, updated_at=$1 WHERE task_id=$2 AND team_id=$3`, now, taskID, team.TeamID)
-	return err
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err := recordTransition(ctx, tx, taskID, stage, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET status='todo', assignee=NULL, current_stage=NULLIF($1::text, ''),
+  stage_entered_at=CASE WHEN $1::text = '' THEN NULL ELSE $2::bigint END, sla_breached_at=NULL, updated_at=$2 WHERE task_id=$3 AND team_id=$4`,
+		stage, now, taskID, team.TeamID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (s *Store) CreateTaskComment(ctx context.Context, teamName string, taskID int64, author, body string) (int64, error) {
@@ -468,8 +508,9 @@ func (s *Store) NextRunnableTaskForTeam(ctx context.Context, teamName string) (*
 		return nil, err
 	}
 	row := s.Pool.QueryRow(ctx, `
-SELECT task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at
-FROM tasks WHERE team_id = $1 AND status IN ('todo','in_progress') AND (current_stage IS NULL OR current_stage != 'Merging') ORDER BY updated_at ASC LIMIT 1`, team.TeamID)
+SELECT `+taskColumns+`
+FROM tasks WHERE team_id = $1 AND status IN ('todo','in_progress') AND (current_stage IS NULL OR current_stage != 'Merging')
+  AND NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_task_id = tasks.task_id AND c.status NOT IN ('done','failed','cancelled')) ORDER BY updated_at ASC LIMIT 1`, team.TeamID)
 	task, err := scanTaskRow(row)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -486,7 +527,7 @@ func (s *Store) GetTaskByIDAndTeam(ctx context.Context, teamName string, taskID
 		return nil, err
 	}
 	row := s.Pool.QueryRow(ctx, `
-SELECT task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at
+SELECT `+taskColumns+`
 FROM tasks WHERE task_id = $1 AND team_id = $2`, taskID, team.TeamID)
 	task, err := scanTaskRow(row)
 	if err != nil {
@@ -500,14 +541,34 @@ FROM tasks WHERE task_id = $1 AND team_id = $2`, taskID, team.TeamID)
 
 func (s *Store) UpdateTaskStage(ctx context.Context, taskID int64, stage string) error {
 	now := time.Now().UTC().Unix()
-	_, err := s.Pool.Exec(ctx, `UPDATE tasks SET current_stage=$1, updated_at=$2 WHERE task_id=$3`, stage, now, taskID)
-	return err
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err := recordTransition(ctx, tx, taskID, stage, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET current_stage=$1, stage_entered_at=$2, sla_breached_at=NULL, updated_at=$2 WHERE task_id=$3`, stage, now, taskID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (s *Store) SetTaskWorkflowAndStage(ctx context.Context, taskID int64, workflowID, stage string) error {
 	now := time.Now().UTC().Unix()
-	_, err := s.Pool.Exec(ctx, `UPDATE tasks SET workflow_id=$1, current_stage=$2, updated_at=$3 WHERE task_id=$4`, workflowID, stage, now, taskID)
-	return err
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err := recordTransition(ctx, tx, taskID, stage, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET workflow_id=$1, current_stage=$2, stage_entered_at=$3, sla_breached_at=NULL, updated_at=$3 WHERE task_id=$4`, workflowID, stage, now, taskID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (s *Store) CreateTaskReview(ctx context.Context, teamName string, taskID int64, reviewerAgent, outcome, comments string) (int64, error) {
@@ -549,20 +610,21 @@ func (s *Store) ListRepos(ctx context.Context, teamName string) ([]store.Repo, e
 	if err != nil {
 		return nil, err
 	}
-	rows, err := s.Pool.Query(ctx, `SELECT name, source, approval, test_cmd, created_at FROM repos WHERE team_id = $1 ORDER BY created_at ASC`, team.TeamID)
+	rows, err := s.Pool.Query(ctx, `SELECT name, source, approval, test_cmd, target_branch, merge_strategy, commit_template, created_at FROM repos WHERE team_id = $1 ORDER BY created_at ASC`, team.TeamID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var out []store.Repo
 	for rows.Next() {
-		var name, source, approval string
+		var name, source, approval, target, strategy, template string
 		var testCmd *string
 		var createdAt int64
-		if err := rows.Scan(&name, &source, &approval, &testCmd, &createdAt); err != nil {
+		if err := rows.Scan(&name, &source, &approval, &testCmd, &target, &strategy, &template, &createdAt); err != nil {
 			return nil, err
 		}
-		out = append(out, store.Repo{Name: name, Source: source, Approval: approval, TestCmd: testCmd, CreatedAt: time.Unix(createdAt, 0).UTC()})
+		out = append(out, store.Repo{Name: name, Source: source, Approval: approval, TestCmd: testCmd,
+			TargetBranch: target, MergeStrategy: strategy, CommitTemplate: template, CreatedAt: time.Unix(createdAt, 0).UTC()})
 	}
 	return out, rows.Err()
 }
@@ -587,8 +649,8 @@ func (s *Store) CreateRepo(ctx context.Context, teamName, name, source, approval
 }
 
 func (s *Store) SetRepoApproval(ctx context.Context, teamName, repoName, approval string) error {
-	if approval != "auto" && approval != "manual" {
-		return errors.New("approval must be auto or manual")
+	if strings.TrimSpace(approval) == "" {
+		return errors.New("approval policy required (e.g. manual or auto)")
 	}
 	team, err := s.GetTeamByName(ctx, teamName)
 	if err != nil {
@@ -604,12 +666,31 @@ func (s *Store) SetRepoApproval(ctx context.Context, teamName, repoName, approva
 	return nil
 }
 
+func (s *Store) SetRepoMergeSettings(ctx context.Context, teamName, repoName, targetBranch, strategy, commitTemplate string) error {
+	if !store.ValidMergeStrategy(strategy) {
+		return fmt.Errorf("merge strategy must be one of %s", strings.Join(store.MergeStrategies, ", "))
+	}
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	res, err := s.Pool.Exec(ctx, `UPDATE repos SET target_branch=$1, merge_strategy=$2, commit_template=$3 WHERE team_id=$4 AND name=$5`,
+		targetBranch, strategy, commitTemplate, team.TeamID, repoName)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return errors.New("repo not found")
+	}
+	return nil
+}
+
 func (s *Store) ListWorkflows(ctx context.Context, teamName string) ([]store.Workflow, error) {
 	team, err := s.GetTeamByName(ctx, teamName)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := s.Pool.Query(ctx, `SELECT name, version, source_path, created_at FROM workflows WHERE team_id = $1 ORDER BY name ASC, version DESC`, team.TeamID)
+	rows, err := s.Pool.Query(ctx, `SELECT name, version, source_path, initial_stage, created_at FROM workflows WHERE team_id = $1 ORDER BY name ASC, version DESC`, team.TeamID)
 	if err != nil {
 		return nil, err
 	}
@@ -618,7 +699,7 @@ func (s *Store) ListWorkflows(ctx context.Context, teamName string) ([]store.Wor
 	for rows.Next() {
 		var w store.Workflow
 		var createdAt int64
-		if err := rows.Scan(&w.Name, &w.Version, &w.SourcePath, &createdAt); err != nil {
+		if err := rows.Scan(&w.Name, &w.Version, &w.SourcePath, &w.InitialStage, &createdAt); err != nil {
 			return nil, err
 		}
 		w.CreatedAt = time.Unix(createdAt, 0).UTC()
@@ -648,35 +729,72 @@ func (s *Store) CreateWorkflow(ctx context.Context, teamName, name string, versi
 		return "", err
 	}
 	if name == "default" && version == 1 {
-		_ = s.seedDefaultWorkflowStages(ctx, wfID)
+		_ = s.seedDefaultWorkflowStages(ctx, wfID, sourcePath == store.DefaultPlanSource)
 	}
 	return wfID, nil
 }
 
 func (s *Store) CreateWorkflowWithStages(ctx context.Context, teamName, name string, version int, sourcePath string, stages []store.WorkflowStage, transitions []store.WorkflowTransition) (string, error) {
-	wfID, err := s.CreateWorkflow(ctx, teamName, name, version, sourcePath)
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", errors.New("workflow name required")
+	}
+	if version <= 0 {
+		return "", errors.New("workflow version must be > 0")
+	}
+	if sourcePath == "" {
+		return "", errors.New("workflow source path required")
+	}
+	initial := ""
+	if len(stages) > 0 {
+		initial = stages[0].StageName
+	}
+	tx, err := s.Pool.Begin(ctx)
 	if err != nil {
 		return "", err
 	}
-	_, _ = s.Pool.Exec(ctx, `DELETE FROM workflow_stages WHERE workflow_id = $1`, wfID)
-	_, _ = s.Pool.Exec(ctx, `DELETE FROM workflow_transitions WHERE workflow_id = $1`, wfID)
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	wfID := randomID()
+	if _, err := tx.Exec(ctx, `INSERT INTO workflows(workflow_id, team_id, name, version, source_path, initial_stage, created_at) VALUES($1, $2, $3, $4, $5, $6, $7)`,
+		wfID, team.TeamID, name, version, sourcePath, initial, time.Now().UTC().Unix()); err != nil {
+		return "", err
+	}
 	for _, st := range stages {
-		_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes, candidate_agents) VALUES($1, $2, $3, $4, $5)`,
-			wfID, st.StageName, st.StageType, st.Outcomes, st.CandidateAgents)
+		if _, err := tx.Exec(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes, candidate_agents, max_duration_seconds, on_timeout, on_enter, on_exit, action, action_outcomes, reviewers, quorum) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+			wfID, st.StageName, st.StageType, st.Outcomes, st.CandidateAgents, int64(st.MaxDuration/time.Second), st.OnTimeout, st.OnEnter, st.OnExit, st.Action, st.ActionOutcomes, st.Reviewers, st.Quorum); err != nil {
+			return "", fmt.Errorf("stage %q: %w", st.StageName, err)
+		}
 	}
 	for _, tr := range transitions {
-		_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES($1, $2, $3, $4)`,
-			wfID, tr.FromStage, tr.Outcome, tr.ToStage)
+		if _, err := tx.Exec(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage, guards, on_guard_fail) VALUES($1, $2, $3, $4, $5, $6)`,
+			wfID, tr.FromStage, tr.Outcome, tr.ToStage, tr.Guards, tr.OnGuardFail); err != nil {
+			return "", fmt.Errorf("transition %s --%s--> %s: %w", tr.FromStage, tr.Outcome, tr.ToStage, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
 	}
 	return wfID, nil
 }
 
-func (s *Store) seedDefaultWorkflowStages(ctx context.Context, workflowID string) error {
+func (s *Store) seedDefaultWorkflowStages(ctx context.Context, workflowID string, plan bool) error {
 	// Enhanced default: Coding -> InReview -> InApproval -> Merging -> Done
+	// With plan: Planning (the agent writes a plan, a human approves it) -> Coding -> ...
+	initial := "Coding"
+	if plan {
+		initial = "Planning"
+		_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES($1, 'Planning', 'plan', 'approved,changes_requested') ON CONFLICT DO NOTHING`, workflowID)
+		_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES($1, 'Planning', 'approved', 'Coding') ON CONFLICT DO NOTHING`, workflowID)
+		_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES($1, 'Planning', 'changes_requested', 'Planning') ON CONFLICT DO NOTHING`, workflowID)
+	}
 	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES($1, 'Coding', 'agent', 'submit_for_review,done') ON CONFLICT DO NOTHING`, workflowID)
 	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES($1, 'InReview', 'agent', 'approved,changes_requested') ON CONFLICT DO NOTHING`, workflowID)
 	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES($1, 'InApproval', 'human', 'approved,changes_requested') ON CONFLICT DO NOTHING`, workflowID)
-	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES($1, 'Merging', 'merge', 'done') ON CONFLICT DO NOTHING`, workflowID)
+	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES($1, 'Merging', 'merge', 'done,conflict') ON CONFLICT DO NOTHING`, workflowID)
 	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES($1, 'Done', 'terminal', '') ON CONFLICT DO NOTHING`, workflowID)
 	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES($1, 'Coding', 'submit_for_review', 'InReview') ON CONFLICT DO NOTHING`, workflowID)
 	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES($1, 'Coding', 'done', 'Done') ON CONFLICT DO NOTHING`, workflowID)
@@ -685,11 +803,13 @@ func (s *Store) seedDefaultWorkflowStages(ctx context.Context, workflowID string
 	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES($1, 'InApproval', 'approved', 'Merging') ON CONFLICT DO NOTHING`, workflowID)
 	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES($1, 'InApproval', 'changes_requested', 'Coding') ON CONFLICT DO NOTHING`, workflowID)
 	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES($1, 'Merging', 'done', 'Done') ON CONFLICT DO NOTHING`, workflowID)
+	_, _ = s.Pool.Exec(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES($1, 'Merging', 'conflict', 'Coding') ON CONFLICT DO NOTHING`, workflowID)
+	_, _ = s.Pool.Exec(ctx, `UPDATE workflows SET initial_stage=$1 WHERE workflow_id=$2`, initial, workflowID)
 	return nil
 }
 
 func (s *Store) GetWorkflowStages(ctx context.Context, workflowID string) ([]store.WorkflowStage, error) {
-	rows, err := s.Pool.Query(ctx, `SELECT workflow_id, stage_name, stage_type, outcomes, COALESCE(candidate_agents,'') FROM workflow_stages WHERE workflow_id = $1 ORDER BY stage_name`, workflowID)
+	rows, err := s.Pool.Query(ctx, `SELECT workflow_id, stage_name, stage_type, outcomes, COALESCE(candidate_agents,''), max_duration_seconds, on_timeout, on_enter, on_exit, action, action_outcomes, reviewers, quorum FROM workflow_stages WHERE workflow_id = $1 ORDER BY stage_name`, workflowID)
 	if err != nil {
 		return nil, err
 	}
@@ -697,16 +817,18 @@ func (s *Store) GetWorkflowStages(ctx context.Context, workflowID string) ([]sto
 	var out []store.WorkflowStage
 	for rows.Next() {
 		var w store.WorkflowStage
-		if err := rows.Scan(&w.WorkflowID, &w.StageName, &w.StageType, &w.Outcomes, &w.CandidateAgents); err != nil {
+		var maxSeconds int64
+		if err := rows.Scan(&w.WorkflowID, &w.StageName, &w.StageType, &w.Outcomes, &w.CandidateAgents, &maxSeconds, &w.OnTimeout, &w.OnEnter, &w.OnExit, &w.Action, &w.ActionOutcomes, &w.Reviewers, &w.Quorum); err != nil {
 			return nil, err
 		}
+		w.MaxDuration = time.Duration(maxSeconds) * time.Second
 		out = append(out, w)
 	}
 	return out, rows.Err()
 }
 
 func (s *Store) GetWorkflowTransitions(ctx context.Context, workflowID string) ([]store.WorkflowTransition, error) {
-	rows, err := s.Pool.Query(ctx, `SELECT workflow_id, from_stage, outcome, to_stage FROM workflow_transitions WHERE workflow_id = $1`, workflowID)
+	rows, err := s.Pool.Query(ctx, `SELECT workflow_id, from_stage, outcome, to_stage, guards, on_guard_fail FROM workflow_transitions WHERE workflow_id = $1`, workflowID)
 	if err != nil {
 		return nil, err
 	}
@@ -714,7 +836,7 @@ func (s *Store) GetWorkflowTransitions(ctx context.Context, workflowID string) (
 	var out []store.WorkflowTransition
 	for rows.Next() {
 		var t store.WorkflowTransition
-		if err := rows.Scan(&t.WorkflowID, &t.FromStage, &t.Outcome, &t.ToStage); err != nil {
+		if err := rows.Scan(&t.WorkflowID, &t.FromStage, &t.Outcome, &t.ToStage, &t.Guards, &t.OnGuardFail); err != nil {
 			return nil, err
 		}
 		out = append(out, t)
@@ -739,6 +861,11 @@ func (s *Store) GetWorkflowIDByTeamAndName(ctx context.Context, teamName, name s
 }
 
 func (s *Store) GetWorkflowInitialStage(ctx context.Context, workflowID string) (string, error) {
+	var explicit string
+	_ = s.Pool.QueryRow(ctx, `SELECT initial_stage FROM workflows WHERE workflow_id = $1`, workflowID).Scan(&explicit)
+	if explicit != "" {
+		return explicit, nil
+	}
 	toStages, err := s.Pool.Query(ctx, `SELECT DISTINCT to_stage FROM workflow_transitions WHERE workflow_id = $1`, workflowID)
 	if err != nil {
 		return "", err
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) CreateReviewComment(ctx context.Context, teamName string, c store.ReviewComment) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	if c.Body == "" {
+		return 0, errors.New("comment body required")
+	}
+	if c.InReplyTo != 0 {
+		var root int64
+		err := s.Pool.QueryRow(ctx, `SELECT CASE WHEN in_reply_to=0 THEN comment_id ELSE in_reply_to END FROM review_comments WHERE comment_id=$1 AND task_id=$2 AND team_id=$3`,
+			c.InReplyTo, c.TaskID, team.TeamID).Scan(&root)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("review comment %d not found on task %d", c.InReplyTo, c.TaskID)
+		}
+		if err != nil {
+			return 0, err
+		}
+		c.InReplyTo, c.Path, c.StartLine, c.EndLine, c.CommitSHA = root, "", 0, 0, ""
+	} else if err := store.CheckReviewCommentAnchor(&c); err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC().Unix()
+	var id int64
+	err = s.Pool.QueryRow(ctx, `INSERT INTO review_comments(team_id, task_id, review_id, in_reply_to, author, path, start_line, end_line, commit_sha, body, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING comment_id`,
+		team.TeamID, c.TaskID, c.ReviewID, c.InReplyTo, c.Author, c.Path, c.StartLine, c.EndLine, c.CommitSHA, c.Body, now, now).Scan(&id)
+	return id, err
+}
+
+func (s *Store) ListReviewComments(ctx context.Context, teamName string, taskID int64) ([]store.ReviewComment, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT comment_id, task_id, review_id, in_reply_to, author, path, start_line, end_line, commit_sha, body, resolved, outdated, created_at, updated_at FROM review_comments WHERE task_id=$1 AND team_id=$2 ORDER BY comment_id ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.ReviewComment
+	for rows.Next() {
+		var c store.ReviewComment
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&c.CommentID, &c.TaskID, &c.ReviewID, &c.InReplyTo, &c.Author, &c.Path, &c.StartLine, &c.EndLine, &c.CommitSHA, &c.Body, &c.Resolved, &c.Outdated, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		c.CreatedAt = time.Unix(createdAt, 0).UTC()
+		c.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) SetReviewThreadResolved(ctx context.Context, teamName string, taskID, threadID int64, resolved bool) error {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	tag, err := s.Pool.Exec(ctx, `UPDATE review_comments SET resolved=$1, updated_at=$2 WHERE comment_id=$3 AND task_id=$4 AND team_id=$5 AND in_reply_to=0`,
+		resolved, time.Now().UTC().Unix(), threadID, taskID, team.TeamID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("review thread %d not found on task %d", threadID, taskID)
+	}
+	return nil
+}
+
+func (s *Store) UpdateReviewCommentAnchor(ctx context.Context, commentID int64, startLine, endLine int, commitSHA string, outdated bool) error {
+	_, err := s.Pool.Exec(ctx, `UPDATE review_comments SET start_line=$1, end_line=$2, commit_sha=$3, outdated=$4, updated_at=$5 WHERE comment_id=$6`,
+		startLine, endLine, commitSHA, outdated, time.Now().UTC().Unix(), commentID)
+	return err
+}
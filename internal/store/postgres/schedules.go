@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+const taskScheduleColumns = `s.schedule_id, s.team_id, t.name, s.name, s.title_template, s.workflow_name, s.workflow_version, s.labels, s.cron_expr, s.run_at, s.next_run_at, s.last_run_at, s.last_task_id, s.enabled, s.created_at`
+
+func (s *Store) SetTaskLabels(ctx context.Context, taskID int64, labels string) error {
+	now := time.Now().UTC().Unix()
+	_, err := s.Pool.Exec(ctx, `UPDATE tasks SET labels=$1, updated_at=$2 WHERE task_id=$3`, labels, now, taskID)
+	return err
+}
+
+func (s *Store) ListTaskSchedules(ctx context.Context, teamName string) ([]store.TaskSchedule, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT `+taskScheduleColumns+` FROM task_schedules s JOIN teams t ON t.team_id = s.team_id WHERE s.team_id = $1 ORDER BY s.name ASC`, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.TaskSchedule
+	for rows.Next() {
+		sch, err := scanTaskScheduleRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *sch)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) CreateTaskSchedule(ctx context.Context, teamName string, sch store.TaskSchedule) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	if sch.Name == "" {
+		return 0, errors.New("schedule name required")
+	}
+	if sch.TitleTemplate == "" {
+		return 0, errors.New("schedule title required")
+	}
+	if (sch.CronExpr == "") == (sch.RunAt == nil) {
+		return 0, errors.New("exactly one of cron expression or run_at required")
+	}
+	if sch.WorkflowVersion <= 0 {
+		sch.WorkflowVersion = 1
+	}
+	var id int64
+	err = s.Pool.QueryRow(ctx, `
+INSERT INTO task_schedules(team_id, name, title_template, workflow_name, workflow_version, labels, cron_expr, run_at, next_run_at, enabled, created_at)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, TRUE, $10) RETURNING schedule_id`,
+		team.TeamID, sch.Name, sch.TitleTemplate, sch.WorkflowName, sch.WorkflowVersion, sch.Labels, sch.CronExpr,
+		unixOrNil(sch.RunAt), unixOrNil(sch.NextRunAt), time.Now().UTC().Unix()).Scan(&id)
+	return id, err
+}
+
+func (s *Store) DeleteTaskSchedule(ctx context.Context, teamName, name string) error {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	res, err := s.Pool.Exec(ctx, `DELETE FROM task_schedules WHERE team_id=$1 AND name=$2`, team.TeamID, name)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return errors.New("schedule not found")
+	}
+	return nil
+}
+
+func (s *Store) ListDueTaskSchedules(ctx context.Context, now time.Time) ([]store.TaskSchedule, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT `+taskScheduleColumns+` FROM task_schedules s JOIN teams t ON t.team_id = s.team_id WHERE s.enabled AND s.next_run_at IS NOT NULL AND s.next_run_at <= $1 ORDER BY s.next_run_at ASC`, now.UTC().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.TaskSchedule
+	for rows.Next() {
+		sch, err := scanTaskScheduleRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *sch)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) RecordTaskScheduleRun(ctx context.Context, scheduleID int64, ranAt time.Time, taskID *int64, nextRunAt *time.Time) error {
+	_, err := s.Pool.Exec(ctx, `UPDATE task_schedules SET last_run_at=$1, last_task_id=COALESCE($2, last_task_id), next_run_at=$3, enabled=$4 WHERE schedule_id=$5`,
+		ranAt.UTC().Unix(), taskID, unixOrNil(nextRunAt), nextRunAt != nil, scheduleID)
+	return err
+}
+
+func scanTaskScheduleRow(row interface{ Scan(dest ...any) error }) (*store.TaskSchedule, error) {
+	var sch store.TaskSchedule
+	var runAt, nextRunAt, lastRunAt *int64
+	var createdAt int64
+	err := row.Scan(&sch.ScheduleID, &sch.TeamID, &sch.TeamName, &sch.Name, &sch.TitleTemplate, &sch.WorkflowName, &sch.WorkflowVersion,
+		&sch.Labels, &sch.CronExpr, &runAt, &nextRunAt, &lastRunAt, &sch.LastTaskID, &sch.Enabled, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	sch.RunAt = timeOrNil(runAt)
+	sch.NextRunAt = timeOrNil(nextRunAt)
+	sch.LastRunAt = timeOrNil(lastRunAt)
+	sch.CreatedAt = time.Unix(createdAt, 0).UTC()
+	return &sch, nil
+}
+
+func unixOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Unix()
+}
+
+func timeOrNil(v *int64) *time.Time {
+	if v == nil {
+		return nil
+	}
+	t := time.Unix(*v, 0).UTC()
+	return &t
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func (s *Store) SetWorkflowStageSLA(ctx context.Context, workflowID, stageName string, maxDuration time.Duration, onTimeout string) error {
+	res, err := s.Pool.Exec(ctx, `UPDATE workflow_stages SET max_duration_seconds=$1, on_timeout=$2 WHERE workflow_id=$3 AND stage_name=$4`,
+		int64(maxDuration/time.Second), onTimeout, workflowID, stageName)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return errors.New("stage not found")
+	}
+	return nil
+}
+
+func (s *Store) ListStageSLABreaches(ctx context.Context, now time.Time) ([]store.StageSLABreach, error) {
+	rows, err := s.Pool.Query(ctx, `
+SELECT t.task_id, tm.name, t.workflow_id, t.current_stage
+FROM tasks t
+JOIN teams tm ON tm.team_id = t.team_id
+JOIN workflow_stages ws ON ws.workflow_id = t.workflow_id AND ws.stage_name = t.current_stage
+WHERE ws.max_duration_seconds > 0
+  AND t.stage_entered_at IS NOT NULL
+  AND t.sla_breached_at IS NULL
+  AND t.status NOT IN ('done','failed','cancelled')
+  AND t.stage_entered_at + ws.max_duration_seconds <= $1
+ORDER BY t.stage_entered_at ASC`, now.UTC().Unix())
+	if err != nil {
+		return nil, err
+	}
+	type ref struct {
+		taskID           int64
+		team, wfID, name string
+	}
+	var refs []ref
+	for rows.Next() {
+		var r ref
+		if err := rows.Scan(&r.taskID, &r.team, &r.wfID, &r.name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		refs = append(refs, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var out []store.StageSLABreach
+	for _, r := range refs {
+		task, err := s.GetTaskByIDAndTeam(ctx, r.team, r.taskID)
+		if err != nil || task == nil {
+			continue
+		}
+		stages, err := s.GetWorkflowStages(ctx, r.wfID)
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range stages {
+			if st.StageName == r.name {
+				out = append(out, store.StageSLABreach{TeamName: r.team, Task: *task, Stage: st})
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) MarkTaskSLABreached(ctx context.Context, taskID int64, at time.Time) error {
+	_, err := s.Pool.Exec(ctx, `UPDATE tasks SET sla_breached_at=$1 WHERE task_id=$2`, at.UTC().Unix(), taskID)
+	return err
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) SetTaskParent(ctx context.Context, teamName string, taskID int64, parentID *int64) error {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Unix()
+	if parentID == nil {
+		_, err := s.Pool.Exec(ctx, `UPDATE tasks SET parent_task_id=NULL, updated_at=$1 WHERE task_id=$2 AND team_id=$3`, now, taskID, team.TeamID)
+		return err
+	}
+	for id := *parentID; ; {
+		if id == taskID {
+			return fmt.Errorf("task %d cannot be a subtask of its own subtask %d", taskID, *parentID)
+		}
+		var next *int64
+		err := s.Pool.QueryRow(ctx, `SELECT parent_task_id FROM tasks WHERE task_id=$1 AND team_id=$2`, id, team.TeamID).Scan(&next)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("task %d not found in team %q", id, teamName)
+		}
+		if err != nil {
+			return err
+		}
+		if next == nil {
+			break
+		}
+		id = *next
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	tag, err := tx.Exec(ctx, `UPDATE tasks SET parent_task_id=$1, updated_at=$2 WHERE task_id=$3 AND team_id=$4`, *parentID, now, taskID, team.TeamID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("task %d not found in team %q", taskID, teamName)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET subtasks_rolled_up_at=NULL WHERE task_id=$1`, *parentID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) SetTaskSubtaskPolicy(ctx context.Context, taskID int64, policy string) error {
+	_, err := s.Pool.Exec(ctx, `UPDATE tasks SET subtask_policy=$1, updated_at=$2 WHERE task_id=$3`, policy, time.Now().UTC().Unix(), taskID)
+	return err
+}
+
+func (s *Store) ListSubtasks(ctx context.Context, teamName string, parentID int64) ([]store.Task, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT `+taskColumns+` FROM tasks WHERE team_id = $1 AND parent_task_id = $2 ORDER BY task_id ASC`, team.TeamID, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.Task
+	for rows.Next() {
+		task, err := scanTaskRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *task)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) ListSubtaskRollups(ctx context.Context) ([]store.SubtaskRollup, error) {
+	rows, err := s.Pool.Query(ctx, `
+SELECT p.task_id, tm.name,
+  SUM(CASE WHEN c.status = 'done' THEN 1 ELSE 0 END),
+  SUM(CASE WHEN c.status = 'failed' THEN 1 ELSE 0 END),
+  SUM(CASE WHEN c.status = 'cancelled' THEN 1 ELSE 0 END)
+FROM tasks p
+JOIN teams tm ON tm.team_id = p.team_id
+JOIN tasks c ON c.parent_task_id = p.task_id
+WHERE p.status NOT IN ('done','failed','cancelled') AND p.subtasks_rolled_up_at IS NULL
+GROUP BY p.task_id, tm.name
+HAVING SUM(CASE WHEN c.status IN ('done','failed','cancelled') THEN 0 ELSE 1 END) = 0
+ORDER BY p.task_id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	var out []store.SubtaskRollup
+	var ids []int64
+	for rows.Next() {
+		var r store.SubtaskRollup
+		var id, done, failed, cancelled int64
+		if err := rows.Scan(&id, &r.TeamName, &done, &failed, &cancelled); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.Done, r.Failed, r.Cancelled = int(done), int(failed), int(cancelled)
+		out = append(out, r)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		task, err := s.GetTaskByIDAndTeam(ctx, out[i].TeamName, ids[i])
+		if err != nil {
+			return nil, err
+		}
+		if task != nil {
+			out[i].Parent = *task
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) MarkSubtasksRolledUp(ctx context.Context, taskID int64, at time.Time) error {
+	_, err := s.Pool.Exec(ctx, `UPDATE tasks SET subtasks_rolled_up_at=$1 WHERE task_id=$2`, at.UTC().Unix(), taskID)
+	return err
+}
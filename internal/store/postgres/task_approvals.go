@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func (s *Store) CreateTaskApprovalDecision(ctx context.Context, teamName string, d store.TaskApprovalDecision) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = s.Pool.QueryRow(ctx, `INSERT INTO task_approval_decisions(team_id, task_id, stage, policy, approved, reason, created_at) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING decision_id`,
+		team.TeamID, d.TaskID, d.Stage, d.Policy, d.Approved, d.Reason, time.Now().UTC().Unix()).Scan(&id)
+	return id, err
+}
+
+func (s *Store) ListTaskApprovalDecisions(ctx context.Context, teamName string, taskID int64) ([]store.TaskApprovalDecision, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT decision_id, task_id, stage, policy, approved, reason, created_at FROM task_approval_decisions WHERE task_id=$1 AND team_id=$2 ORDER BY decision_id ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.TaskApprovalDecision
+	for rows.Next() {
+		var d store.TaskApprovalDecision
+		var createdAt int64
+		if err := rows.Scan(&d.DecisionID, &d.TaskID, &d.Stage, &d.Policy, &d.Approved, &d.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		d.CreatedAt = time.Unix(createdAt, 0).UTC()
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/jackc/pgx/v5"
+)
+
+// recordTransition records the move of taskID to toStage, reading the stage it is leaving. It must run
+// before the UPDATE and inserts nothing when the stage does not change.
+func recordTransition(ctx context.Context, tx pgx.Tx, taskID int64, toStage string, now int64) error {
+	c := store.TransitionCauseFrom(ctx)
+	_, err := tx.Exec(ctx, `INSERT INTO task_transitions(task_id, team_id, from_stage, to_stage, outcome, actor, note, from_entered_at, created_at)
+SELECT task_id, team_id, COALESCE(current_stage,''), $1::text, $2, $3, $4, stage_entered_at, $5 FROM tasks WHERE task_id=$6 AND COALESCE(current_stage,'') != $1::text`,
+		toStage, c.Outcome, c.Actor, c.Note, now, taskID)
+	return err
+}
+
+func (s *Store) ListTaskTransitions(ctx context.Context, teamName string, taskID int64) ([]store.TaskTransition, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT transition_id, task_id, from_stage, to_stage, outcome, actor, note, from_entered_at, created_at FROM task_transitions WHERE task_id=$1 AND team_id=$2 ORDER BY transition_id ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.TaskTransition
+	for rows.Next() {
+		var t store.TaskTransition
+		var entered *int64
+		var createdAt int64
+		if err := rows.Scan(&t.TransitionID, &t.TaskID, &t.FromStage, &t.ToStage, &t.Outcome, &t.Actor, &t.Note, &entered, &createdAt); err != nil {
+			return nil, err
+		}
+		t.FromEnteredAt = timeOrNil(entered)
+		t.CreatedAt = time.Unix(createdAt, 0).UTC()
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) CreateTaskTurn(ctx context.Context, teamName string, turn store.TaskTurn) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = s.Pool.QueryRow(ctx, `INSERT INTO task_turns(task_id, team_id, agent, stage, outcome, error, started_at, finished_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING turn_id`,
+		turn.TaskID, team.TeamID, turn.Agent, turn.Stage, turn.Outcome, turn.Error, turn.StartedAt.UTC().Unix(), turn.FinishedAt.UTC().Unix()).Scan(&id)
+	return id, err
+}
+
+func (s *Store) ListTaskTurns(ctx context.Context, teamName string, taskID int64) ([]store.TaskTurn, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT turn_id, task_id, agent, stage, outcome, error, started_at, finished_at FROM task_turns WHERE task_id=$1 AND team_id=$2 ORDER BY turn_id ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.TaskTurn
+	for rows.Next() {
+		var t store.TaskTurn
+		var started, finished int64
+		if err := rows.Scan(&t.TurnID, &t.TaskID, &t.Agent, &t.Stage, &t.Outcome, &t.Error, &started, &finished); err != nil {
+			return nil, err
+		}
+		t.StartedAt = time.Unix(started, 0).UTC()
+		t.FinishedAt = time.Unix(finished, 0).UTC()
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
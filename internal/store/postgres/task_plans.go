@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func (s *Store) CreateTaskPlan(ctx context.Context, teamName string, plan store.TaskPlan) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	var revision int
+	if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(revision),0)+1 FROM task_plans WHERE task_id=$1`, plan.TaskID).Scan(&revision); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE task_plans SET status='superseded' WHERE task_id=$1 AND status='pending'`, plan.TaskID); err != nil {
+		return 0, err
+	}
+	var id int64
+	err = tx.QueryRow(ctx, `INSERT INTO task_plans(task_id, team_id, revision, author, body, status, created_at) VALUES($1, $2, $3, $4, $5, 'pending', $6) RETURNING plan_id`,
+		plan.TaskID, team.TeamID, revision, plan.Author, plan.Body, time.Now().UTC().Unix()).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
+}
+
+func (s *Store) ListTaskPlans(ctx context.Context, teamName string, taskID int64) ([]store.TaskPlan, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT plan_id, task_id, revision, author, body, status, feedback, decided_by, decided_at, created_at FROM task_plans WHERE task_id=$1 AND team_id=$2 ORDER BY revision ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.TaskPlan
+	for rows.Next() {
+		var p store.TaskPlan
+		var decided *int64
+		var createdAt int64
+		if err := rows.Scan(&p.PlanID, &p.TaskID, &p.Revision, &p.Author, &p.Body, &p.Status, &p.Feedback, &p.DecidedBy, &decided, &createdAt); err != nil {
+			return nil, err
+		}
+		p.DecidedAt = timeOrNil(decided)
+		p.CreatedAt = time.Unix(createdAt, 0).UTC()
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) DecideTaskPlan(ctx context.Context, planID int64, status, decidedBy, feedback string, at time.Time) error {
+	tag, err := s.Pool.Exec(ctx, `UPDATE task_plans SET status=$1, decided_by=$2, feedback=$3, decided_at=$4 WHERE plan_id=$5 AND status='pending'`,
+		status, decidedBy, feedback, at.UTC().Unix(), planID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("plan %d is not pending", planID)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func (s *Store) ListOpenTasksInWorkflow(ctx context.Context, workflowID string) ([]store.Task, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT `+taskColumns+` FROM tasks WHERE workflow_id = $1 AND status NOT IN ('done','failed','cancelled') ORDER BY task_id ASC`, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.Task
+	for rows.Next() {
+		task, err := scanTaskRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *task)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) MigrateWorkflowTasks(ctx context.Context, teamName string, m store.WorkflowMigration) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	stageMap, err := json.Marshal(m.StageMap)
+	if err != nil {
+		return 0, err
+	}
+	moves, err := json.Marshal(m.Moves)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	now := time.Now().UTC().Unix()
+	for _, mv := range m.Moves {
+		if err := recordTransition(ctx, tx, mv.TaskID, mv.ToStage, now); err != nil {
+			return 0, err
+		}
+		tag, err := tx.Exec(ctx, `UPDATE tasks SET workflow_id=$1, current_stage=NULLIF($2::text, ''),
+  stage_entered_at=CASE WHEN COALESCE(current_stage,'') = $2::text THEN stage_entered_at ELSE $3::bigint END,
+  sla_breached_at=CASE WHEN COALESCE(current_stage,'') = $2::text THEN sla_breached_at ELSE NULL END,
+  updated_at=$3
+WHERE task_id=$4 AND team_id=$5 AND workflow_id=$6 AND COALESCE(current_stage,'')=$7 AND status NOT IN ('done','failed','cancelled')`,
+			m.ToWorkflowID, mv.ToStage, now, mv.TaskID, team.TeamID, m.FromWorkflowID, mv.FromStage)
+		if err != nil {
+			return 0, err
+		}
+		if tag.RowsAffected() != 1 {
+			return 0, fmt.Errorf("task %d changed since the migration was planned", mv.TaskID)
+		}
+	}
+	var id int64
+	if err := tx.QueryRow(ctx, `INSERT INTO workflow_migrations(team_id, from_workflow_id, to_workflow_id, stage_map, moves, created_at) VALUES($1, $2, $3, $4, $5, $6) RETURNING migration_id`,
+		team.TeamID, m.FromWorkflowID, m.ToWorkflowID, string(stageMap), string(moves), now).Scan(&id); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (s *Store) ListWorkflowMigrations(ctx context.Context, teamName string) ([]store.WorkflowMigration, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Pool.Query(ctx, `SELECT migration_id, from_workflow_id, to_workflow_id, stage_map, moves, created_at FROM workflow_migrations WHERE team_id = $1 ORDER BY migration_id DESC`, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.WorkflowMigration
+	for rows.Next() {
+		var m store.WorkflowMigration
+		var stageMap, moves string
+		var createdAt int64
+		if err := rows.Scan(&m.MigrationID, &m.FromWorkflowID, &m.ToWorkflowID, &stageMap, &moves, &createdAt); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(stageMap), &m.StageMap)
+		_ = json.Unmarshal([]byte(moves), &m.Moves)
+		m.CreatedAt = time.Unix(createdAt, 0).UTC()
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -15,7 +16,8 @@ func (s *sqliteStore) ListTeams(ctx context.Context) ([]Team, error) {
 SELECT
   t.name, t.team_id, t.created_at,
   (SELECT COUNT(*) FROM agents a WHERE a.team_id = t.team_id) AS agent_count,
-  (SELECT COUNT(*) FROM tasks k WHERE k.team_id = t.team_id) AS task_count
+  (SELECT COUNT(*) FROM tasks k WHERE k.team_id = t.team_id) AS task_count,
+  t.paused, t.paused_at, t.pause_reason, t.worktree_quota
 FROM teams t
 ORDER BY t.created_at ASC`)
 	if err != nil {
@@ -31,16 +33,24 @@ ORDER BY t.created_at ASC`)
 			createdAt int64
 			agentCnt  int
 			taskCnt   int
+			paused    bool
+			pausedAt  sql.NullInt64
+			reason    string
+			quota     int64
 		)
-		if err := rows.Scan(&name, &teamID, &createdAt, &agentCnt, &taskCnt); err != nil {
+		if err := rows.Scan(&name, &teamID, &createdAt, &agentCnt, &taskCnt, &paused, &pausedAt, &reason, &quota); err != nil {
 			return nil, err
 		}
 		out = append(out, Team{
-			Name:       name,
-			TeamID:     teamID,
-			CreatedAt:  time.Unix(createdAt, 0).UTC(),
-			AgentCount: agentCnt,
-			TaskCount:  taskCnt,
+			Name:          name,
+			TeamID:        teamID,
+			CreatedAt:     time.Unix(createdAt, 0).UTC(),
+			AgentCount:    agentCnt,
+			TaskCount:     taskCnt,
+			Paused:        paused,
+			PausedAt:      timeOrNil(pausedAt),
+			PauseReason:   reason,
+			WorktreeQuota: quota,
 		})
 	}
 	return out, rows.Err()
@@ -49,7 +59,8 @@ ORDER BY t.created_at ASC`)
 func (s *sqliteStore) GetTeamByName(ctx context.Context, name string) (Team, error) {
 	var t Team
 	var createdAt int64
-	err := s.stmtGetTeamByName.QueryRowContext(ctx, name).Scan(&t.Name, &t.TeamID, &createdAt)
+	var pausedAt sql.NullInt64
+	err := s.stmtGetTeamByName.QueryRowContext(ctx, name).Scan(&t.Name, &t.TeamID, &createdAt, &t.Paused, &pausedAt, &t.PauseReason, &t.WorktreeQuota)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return Team{}, fmt.Errorf("team not found: %s", name)
@@ -57,6 +68,7 @@ func (s *sqliteStore) GetTeamByName(ctx context.Context, name string) (Team, err
 		return Team{}, err
 	}
 	t.CreatedAt = time.Unix(createdAt, 0).UTC()
+	t.PausedAt = timeOrNil(pausedAt)
 	return t, nil
 }
 
@@ -146,7 +158,7 @@ func (s *sqliteStore) ListTasks(ctx context.Context, teamName string, limit int)
 		}
 		return out, rows.Err()
 	}
-	q := `SELECT task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at FROM tasks WHERE team_id = ? ORDER BY created_at DESC LIMIT ?`
+	q := `SELECT ` + taskColumns + ` FROM tasks WHERE team_id = ? ORDER BY created_at DESC LIMIT ?`
 	rows, err := s.DB.QueryContext(ctx, q, team.TeamID, limit)
 	if err != nil {
 		return nil, err
@@ -168,7 +180,7 @@ func (s *sqliteStore) ListTasksInStage(ctx context.Context, teamName, stage stri
 	if err != nil {
 		return nil, err
 	}
-	q := `SELECT task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at FROM tasks WHERE team_id = ? AND current_stage = ? ORDER BY updated_at ASC`
+	q := `SELECT ` + taskColumns + ` FROM tasks WHERE team_id = ? AND current_stage = ? ORDER BY updated_at ASC`
 	args := []any{team.TeamID, stage}
 	if limit > 0 {
 		q += ` LIMIT ?`
@@ -190,7 +202,10 @@ func (s *sqliteStore) ListTasksInStage(ctx context.Context, teamName, stage stri
 	return out, rows.Err()
 }
 
-// scanTaskRow scans the current row of rows (must have task columns in order: task_id, title, status, assignee, dri, attempt_count, workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at).
+// taskColumns is the column list scanned by scanTaskRow, in order.
+const taskColumns = `task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at, COALESCE(labels,''), stage_entered_at, sla_breached_at, COALESCE(reviewers,''), COALESCE(review_round,0), parent_task_id, COALESCE(subtask_policy,'')`
+
+// scanTaskRow scans the current row of rows (must have taskColumns in order).
 func scanTaskRow(rows interface{ Scan(dest ...any) error }) (*Task, error) {
 	var (
 		id           int64
@@ -207,11 +222,22 @@ func scanTaskRow(rows interface{ Scan(dest ...any) error }) (*Task, error) {
 		repoName     sql.NullString
 		createdAt    int64
 		updatedAt    int64
+		labels       string
+		reviewers    string
+		reviewRound  int64
+		parentID     sql.NullInt64
+		policy       string
+		stageEntered sql.NullInt64
+		slaBreached  sql.NullInt64
 	)
-	err := rows.Scan(&id, &title, &status, &assignee, &dri, &attemptCount, &workflowID, &currentStage, &worktreePath, &branchName, &baseSHA, &repoName, &createdAt, &updatedAt)
+	err := rows.Scan(&id, &title, &status, &assignee, &dri, &attemptCount, &workflowID, &currentStage, &worktreePath, &branchName, &baseSHA, &repoName, &createdAt, &updatedAt, &labels, &stageEntered, &slaBreached, &reviewers, &reviewRound, &parentID, &policy)
 	if err != nil {
 		return nil, err
 	}
+	var parent *int64
+	if parentID.Valid {
+		parent = &parentID.Int64
+	}
 	var a, d, wfID, curStage, wtPath, brName, bSHA, rName *string
 	if assignee.Valid {
 		a = &assignee.String
@@ -238,20 +264,27 @@ func scanTaskRow(rows interface{ Scan(dest ...any) error }) (*Task, error) {
 		rName = &repoName.String
 	}
 	return &Task{
-		TaskID:       id,
-		Title:        title,
-		Status:       status,
-		Assignee:     a,
-		DRI:          d,
-		AttemptCount: attemptCount,
-		WorkflowID:   wfID,
-		CurrentStage: curStage,
-		WorktreePath: wtPath,
-		BranchName:   brName,
-		BaseSHA:      bSHA,
-		RepoName:     rName,
-		CreatedAt:    time.Unix(createdAt, 0).UTC(),
-		UpdatedAt:    time.Unix(updatedAt, 0).UTC(),
+		TaskID:         id,
+		Title:          title,
+		Status:         status,
+		Assignee:       a,
+		DRI:            d,
+		AttemptCount:   attemptCount,
+		WorkflowID:     wfID,
+		CurrentStage:   curStage,
+		WorktreePath:   wtPath,
+		BranchName:     brName,
+		BaseSHA:        bSHA,
+		RepoName:       rName,
+		Labels:         labels,
+		Reviewers:      reviewers,
+		ReviewRound:    reviewRound,
+		ParentTaskID:   parent,
+		SubtaskPolicy:  policy,
+		StageEnteredAt: timeOrNil(stageEntered),
+		SLABreachedAt:  timeOrNil(slaBreached),
+		CreatedAt:      time.Unix(createdAt, 0).UTC(),
+		UpdatedAt:      time.Unix(updatedAt, 0).UTC(),
 	}, nil
 }
 
@@ -278,7 +311,7 @@ func (s *sqliteStore) CreateTask(ctx context.Context, teamName, title, status st
 	if workflowID != nil && *workflowID != "" {
 		initial, err := s.GetWorkflowInitialStage(ctx, *workflowID)
 		if err == nil {
-			_, _ = s.DB.ExecContext(ctx, `UPDATE tasks SET workflow_id=?, current_stage=?, updated_at=? WHERE task_id=?`, *workflowID, initial, now, id)
+			_, _ = s.DB.ExecContext(ctx, `UPDATE tasks SET workflow_id=?, current_stage=?, stage_entered_at=?, updated_at=? WHERE task_id=?`, *workflowID, initial, now, now, id)
 		}
 	}
 	return id, nil
@@ -321,6 +354,14 @@ func (s *sqliteStore) SetTaskFailed(ctx context.Context, taskID int64) error {
 	return err
 }
 
+// SetTaskReviewers records the reviewer set of a quorum review stage and the review round: reviews with
+// a larger review_id count toward the current round.
+func (s *sqliteStore) SetTaskReviewers(ctx context.Context, taskID int64, reviewers []string, round int64) error {
+	now := time.Now().UTC().Unix()
+	_, err := s.DB.ExecContext(ctx, `UPDATE tasks SET reviewers=?, review_round=?, updated_at=? WHERE task_id=?`, strings.Join(reviewers, ","), round, now, taskID)
+	return err
+}
+
 // RequeueTask sets status to todo and clears assignee.
 func (s *sqliteStore) RequeueTask(ctx context.Context, teamName string, taskID int64) error {
 	team, err := s.GetTeamByName(ctx, teamName)
@@ -378,17 +419,27 @@ func (s *sqliteStore) RewindTask(ctx context.Context, teamName string, taskID in
 	if err != nil {
 		return err
 	}
-	now := time.Now().UTC().Unix()
+	stage := ""
 	if task.WorkflowID != nil && *task.WorkflowID != "" {
-		initial, err := s.GetWorkflowInitialStage(ctx, *task.WorkflowID)
-		if err != nil {
+		if stage, err = s.GetWorkflowInitialStage(ctx, *task.WorkflowID); err != nil {
 			return err
 		}
-		_, err = s.DB.ExecContext(ctx, `UPDATE tasks SET status='todo', assignee=NULL, current_stage=?, updated_at=? WHERE task_id=? AND team_id=?`, initial, now, taskID, team.TeamID)
+	}
+	now := time.Now().UTC().Unix()
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
 		return err
 	}
-	_, err = s.DB.ExecContext(ctx, `UPDATE tasks SET status='todo', assignee=NULL, current_stage=NULL, updated_at=? WHERE task_id=? AND team_id=?`, now, taskID, team.TeamID)
-	return err
+	defer func() { _ = tx.Rollback() }()
+	if err := recordTransition(ctx, tx, taskID, stage, now); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE tasks SET status='todo', assignee=NULL, current_stage=NULLIF(?, ''),
+  stage_entered_at=CASE WHEN ? = '' THEN NULL ELSE ? END, sla_breached_at=NULL, updated_at=? WHERE task_id=? AND team_id=?`,
+		stage, stage, now, now, taskID, team.TeamID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // CreateTaskComment adds a comment to a task.
@@ -510,6 +561,7 @@ func (s *sqliteStore) ListTaskDependencies(ctx context.Context, teamName string,
 }
 
 // NextRunnableTaskForTeam returns one task with status todo or in_progress for the team (oldest updated first), or nil if none.
+// Parent tasks wait while any of their subtasks is open.
 func (s *sqliteStore) NextRunnableTaskForTeam(ctx context.Context, teamName string) (*Task, error) {
 	team, err := s.GetTeamByName(ctx, teamName)
 	if err != nil {
@@ -549,7 +601,7 @@ func (s *sqliteStore) ListRepos(ctx context.Context, teamName string) ([]Repo, e
 		return nil, err
 	}
 	rows, err := s.DB.QueryContext(ctx, `
-SELECT name, source, approval, test_cmd, created_at
+SELECT name, source, approval, test_cmd, target_branch, merge_strategy, commit_template, created_at
 FROM repos
 WHERE team_id = ?
 ORDER BY created_at ASC`, team.TeamID)
@@ -565,9 +617,12 @@ ORDER BY created_at ASC`, team.TeamID)
 			source    string
 			approval  string
 			testCmd   sql.NullString
+			target    string
+			strategy  string
+			template  string
 			createdAt int64
 		)
-		if err := rows.Scan(&name, &source, &approval, &testCmd, &createdAt); err != nil {
+		if err := rows.Scan(&name, &source, &approval, &testCmd, &target, &strategy, &template, &createdAt); err != nil {
 			return nil, err
 		}
 		var tc *string
@@ -575,11 +630,14 @@ ORDER BY created_at ASC`, team.TeamID)
 			tc = &testCmd.String
 		}
 		out = append(out, Repo{
-			Name:      name,
-			Source:    source,
-			Approval:  approval,
-			TestCmd:   tc,
-			CreatedAt: time.Unix(createdAt, 0).UTC(),
+			Name:           name,
+			Source:         source,
+			Approval:       approval,
+			TestCmd:        tc,
+			TargetBranch:   target,
+			MergeStrategy:  strategy,
+			CommitTemplate: template,
+			CreatedAt:      time.Unix(createdAt, 0).UTC(),
 		})
 	}
 	return out, rows.Err()
@@ -607,10 +665,10 @@ VALUES(?, ?, ?, ?, ?, ?, ?)`,
 	return err
 }
 
-// SetRepoApproval sets the approval mode (auto or manual) for a repo.
+// SetRepoApproval sets a repo's approval policy (manual, auto or a policy spec; see workflow.ParseApprovalPolicy).
 func (s *sqliteStore) SetRepoApproval(ctx context.Context, teamName, repoName, approval string) error {
-	if approval != "auto" && approval != "manual" {
-		return errors.New("approval must be auto or manual")
+	if strings.TrimSpace(approval) == "" {
+		return errors.New("approval policy required (e.g. manual or auto)")
 	}
 	team, err := s.GetTeamByName(ctx, teamName)
 	if err != nil {
@@ -627,13 +685,48 @@ func (s *sqliteStore) SetRepoApproval(ctx context.Context, teamName, repoName, a
 	return nil
 }
 
+// MergeStrategies are the accepted Repo.MergeStrategy values: a merge commit, one squashed commit,
+// the branch rebased onto the target and fast-forwarded, or a fast-forward only (fails if the target moved).
+var MergeStrategies = []string{"merge", "squash", "rebase", "ff-only"}
+
+// ValidMergeStrategy reports whether s is one of MergeStrategies.
+func ValidMergeStrategy(s string) bool {
+	for _, m := range MergeStrategies {
+		if s == m {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRepoMergeSettings sets the target branch, merge strategy and commit message template for a repo.
+func (s *sqliteStore) SetRepoMergeSettings(ctx context.Context, teamName, repoName, targetBranch, strategy, commitTemplate string) error {
+	if !ValidMergeStrategy(strategy) {
+		return fmt.Errorf("merge strategy must be one of %s", strings.Join(MergeStrategies, ", "))
+	}
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	res, err := s.DB.ExecContext(ctx, `UPDATE repos SET target_branch=?, merge_strategy=?, commit_template=? WHERE team_id=? AND name=?`,
+		targetBranch, strategy, commitTemplate, team.TeamID, repoName)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return errors.New("repo not found")
+	}
+	return nil
+}
+
 func (s *sqliteStore) ListWorkflows(ctx context.Context, teamName string) ([]Workflow, error) {
 	team, err := s.GetTeamByName(ctx, teamName)
 	if err != nil {
 		return nil, err
 	}
 	rows, err := s.DB.QueryContext(ctx, `
-SELECT name, version, source_path, created_at
+SELECT name, version, source_path, initial_stage, created_at
 FROM workflows
 WHERE team_id = ?
 ORDER BY name ASC, version DESC`, team.TeamID)
@@ -648,16 +741,18 @@ ORDER BY name ASC, version DESC`, team.TeamID)
 			name       string
 			version    int
 			sourcePath string
+			initial    string
 			createdAt  int64
 		)
-		if err := rows.Scan(&name, &version, &sourcePath, &createdAt); err != nil {
+		if err := rows.Scan(&name, &version, &sourcePath, &initial, &createdAt); err != nil {
 			return nil, err
 		}
 		out = append(out, Workflow{
-			Name:       name,
-			Version:    version,
-			SourcePath: sourcePath,
-			CreatedAt:  time.Unix(createdAt, 0).UTC(),
+			Name:         name,
+			Version:      version,
+			SourcePath:   sourcePath,
+			InitialStage: initial,
+			CreatedAt:    time.Unix(createdAt, 0).UTC(),
 		})
 	}
 	return out, rows.Err()
@@ -686,39 +781,80 @@ VALUES(?, ?, ?, ?, ?, ?)`,
 		return "", err
 	}
 	if name == "default" && version == 1 {
-		_ = s.seedDefaultWorkflowStages(ctx, wfID)
+		_ = s.seedDefaultWorkflowStages(ctx, wfID, sourcePath == DefaultPlanSource)
 	}
 	return wfID, nil
 }
 
-// CreateWorkflowWithStages creates a workflow and inserts the given stages and transitions (e.g. from Python script output).
+// CreateWorkflowWithStages creates a workflow with the given stages and transitions in one transaction.
+// The first stage is recorded as the workflow's initial stage.
 func (s *sqliteStore) CreateWorkflowWithStages(ctx context.Context, teamName, name string, version int, sourcePath string, stages []WorkflowStage, transitions []WorkflowTransition) (string, error) {
-	wfID, err := s.CreateWorkflow(ctx, teamName, name, version, sourcePath)
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", errors.New("workflow name required")
+	}
+	if version <= 0 {
+		return "", errors.New("workflow version must be > 0")
+	}
+	if sourcePath == "" {
+		return "", errors.New("workflow source path required")
+	}
+	initial := ""
+	if len(stages) > 0 {
+		initial = stages[0].StageName
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return "", err
 	}
-	// Replace any existing stages/transitions (e.g. default seed) with the script output.
-	_, _ = s.DB.ExecContext(ctx, `DELETE FROM workflow_stages WHERE workflow_id = ?`, wfID)
-	_, _ = s.DB.ExecContext(ctx, `DELETE FROM workflow_transitions WHERE workflow_id = ?`, wfID)
+	defer func() { _ = tx.Rollback() }()
+
+	wfID := randomID()
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO workflows(workflow_id, team_id, name, version, source_path, initial_stage, created_at)
+VALUES(?, ?, ?, ?, ?, ?, ?)`,
+		wfID, team.TeamID, name, version, sourcePath, initial, time.Now().UTC().Unix()); err != nil {
+		return "", err
+	}
 	for _, st := range stages {
-		st.WorkflowID = wfID
-		_, _ = s.DB.ExecContext(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes, candidate_agents) VALUES(?, ?, ?, ?, ?)`,
-			wfID, st.StageName, st.StageType, st.Outcomes, st.CandidateAgents)
+		if _, err := tx.ExecContext(ctx, `INSERT INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes, candidate_agents, max_duration_seconds, on_timeout, on_enter, on_exit, action, action_outcomes, reviewers, quorum) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			wfID, st.StageName, st.StageType, st.Outcomes, st.CandidateAgents, int64(st.MaxDuration/time.Second), st.OnTimeout, st.OnEnter, st.OnExit, st.Action, st.ActionOutcomes, st.Reviewers, st.Quorum); err != nil {
+			return "", fmt.Errorf("stage %q: %w", st.StageName, err)
+		}
 	}
 	for _, tr := range transitions {
-		tr.WorkflowID = wfID
-		_, _ = s.DB.ExecContext(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES(?, ?, ?, ?)`,
-			wfID, tr.FromStage, tr.Outcome, tr.ToStage)
+		if _, err := tx.ExecContext(ctx, `INSERT INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage, guards, on_guard_fail) VALUES(?, ?, ?, ?, ?, ?)`,
+			wfID, tr.FromStage, tr.Outcome, tr.ToStage, tr.Guards, tr.OnGuardFail); err != nil {
+			return "", fmt.Errorf("transition %s --%s--> %s: %w", tr.FromStage, tr.Outcome, tr.ToStage, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
 	}
 	return wfID, nil
 }
 
-func (s *sqliteStore) seedDefaultWorkflowStages(ctx context.Context, workflowID string) error {
+// DefaultPlanSource is the source path that makes CreateWorkflow seed default v1 with a Planning stage
+// first (see builtin:plan in the workflow package).
+const DefaultPlanSource = "builtin:plan"
+
+func (s *sqliteStore) seedDefaultWorkflowStages(ctx context.Context, workflowID string, plan bool) error {
 	// Enhanced default: Coding -> InReview -> InApproval -> Merging -> Done
+	// With plan: Planning (the agent writes a plan, a human approves it) -> Coding -> ...
+	initial := "Coding"
+	if plan {
+		initial = "Planning"
+		_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES(?, 'Planning', 'plan', 'approved,changes_requested')`, workflowID)
+		_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES(?, 'Planning', 'approved', 'Coding')`, workflowID)
+		_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES(?, 'Planning', 'changes_requested', 'Planning')`, workflowID)
+	}
 	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES(?, 'Coding', 'agent', 'submit_for_review,done')`, workflowID)
 	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES(?, 'InReview', 'agent', 'approved,changes_requested')`, workflowID)
 	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES(?, 'InApproval', 'human', 'approved,changes_requested')`, workflowID)
-	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES(?, 'Merging', 'merge', 'done')`, workflowID)
+	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES(?, 'Merging', 'merge', 'done,conflict')`, workflowID)
 	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_stages(workflow_id, stage_name, stage_type, outcomes) VALUES(?, 'Done', 'terminal', '')`, workflowID)
 	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES(?, 'Coding', 'submit_for_review', 'InReview')`, workflowID)
 	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES(?, 'Coding', 'done', 'Done')`, workflowID)
@@ -727,11 +863,13 @@ func (s *sqliteStore) seedDefaultWorkflowStages(ctx context.Context, workflowID
 	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES(?, 'InApproval', 'approved', 'Merging')`, workflowID)
 	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES(?, 'InApproval', 'changes_requested', 'Coding')`, workflowID)
 	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES(?, 'Merging', 'done', 'Done')`, workflowID)
+	_, _ = s.DB.ExecContext(ctx, `INSERT OR IGNORE INTO workflow_transitions(workflow_id, from_stage, outcome, to_stage) VALUES(?, 'Merging', 'conflict', 'Coding')`, workflowID)
+	_, _ = s.DB.ExecContext(ctx, `UPDATE workflows SET initial_stage=? WHERE workflow_id=?`, initial, workflowID)
 	return nil
 }
 
 func (s *sqliteStore) GetWorkflowStages(ctx context.Context, workflowID string) ([]WorkflowStage, error) {
-	rows, err := s.DB.QueryContext(ctx, `SELECT workflow_id, stage_name, stage_type, outcomes, COALESCE(candidate_agents,'') FROM workflow_stages WHERE workflow_id = ? ORDER BY stage_name`, workflowID)
+	rows, err := s.DB.QueryContext(ctx, `SELECT workflow_id, stage_name, stage_type, outcomes, COALESCE(candidate_agents,''), max_duration_seconds, on_timeout, on_enter, on_exit, action, action_outcomes, reviewers, quorum FROM workflow_stages WHERE workflow_id = ? ORDER BY stage_name`, workflowID)
 	if err != nil {
 		return nil, err
 	}
@@ -739,16 +877,18 @@ func (s *sqliteStore) GetWorkflowStages(ctx context.Context, workflowID string)
 	var out []WorkflowStage
 	for rows.Next() {
 		var w WorkflowStage
-		if err := rows.Scan(&w.WorkflowID, &w.StageName, &w.StageType, &w.Outcomes, &w.CandidateAgents); err != nil {
+		var maxSeconds int64
+		if err := rows.Scan(&w.WorkflowID, &w.StageName, &w.StageType, &w.Outcomes, &w.CandidateAgents, &maxSeconds, &w.OnTimeout, &w.OnEnter, &w.OnExit, &w.Action, &w.ActionOutcomes, &w.Reviewers, &w.Quorum); err != nil {
 			return nil, err
 		}
+		w.MaxDuration = time.Duration(maxSeconds) * time.Second
 		out = append(out, w)
 	}
 	return out, rows.Err()
 }
 
 func (s *sqliteStore) GetWorkflowTransitions(ctx context.Context, workflowID string) ([]WorkflowTransition, error) {
-	rows, err := s.DB.QueryContext(ctx, `SELECT workflow_id, from_stage, outcome, to_stage FROM workflow_transitions WHERE workflow_id = ?`, workflowID)
+	rows, err := s.DB.QueryContext(ctx, `SELECT workflow_id, from_stage, outcome, to_stage, guards, on_guard_fail FROM workflow_transitions WHERE workflow_id = ?`, workflowID)
 	if err != nil {
 		return nil, err
 	}
@@ -756,7 +896,7 @@ func (s *sqliteStore) GetWorkflowTransitions(ctx context.Context, workflowID str
 	var out []WorkflowTransition
 	for rows.Next() {
 		var t WorkflowTransition
-		if err := rows.Scan(&t.WorkflowID, &t.FromStage, &t.Outcome, &t.ToStage); err != nil {
+		if err := rows.Scan(&t.WorkflowID, &t.FromStage, &t.Outcome, &t.ToStage, &t.Guards, &t.OnGuardFail); err != nil {
 			return nil, err
 		}
 		out = append(out, t)
@@ -780,7 +920,13 @@ func (s *sqliteStore) GetWorkflowIDByTeamAndName(ctx context.Context, teamName,
 	return wfID, nil
 }
 
+// GetWorkflowInitialStage returns the workflow's explicit initial stage, or else the first stage (by name) with no inbound transitions.
 func (s *sqliteStore) GetWorkflowInitialStage(ctx context.Context, workflowID string) (string, error) {
+	var explicit string
+	_ = s.DB.QueryRowContext(ctx, `SELECT initial_stage FROM workflows WHERE workflow_id = ?`, workflowID).Scan(&explicit)
+	if explicit != "" {
+		return explicit, nil
+	}
 	toStages, err := s.DB.QueryContext(ctx, `SELECT DISTINCT to_stage FROM workflow_transitions WHERE workflow_id = ?`, workflowID)
 	if err != nil {
 		return "", err
@@ -814,16 +960,38 @@ func (s *sqliteStore) GetWorkflowInitialStage(ctx context.Context, workflowID st
 	return "", errors.New("workflow has no initial stage")
 }
 
+// UpdateTaskStage moves the task to stage and records the transition (see WithTransitionCause).
 func (s *sqliteStore) UpdateTaskStage(ctx context.Context, taskID int64, stage string) error {
 	now := time.Now().UTC().Unix()
-	_, err := s.DB.ExecContext(ctx, `UPDATE tasks SET current_stage=?, updated_at=? WHERE task_id=?`, stage, now, taskID)
-	return err
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := recordTransition(ctx, tx, taskID, stage, now); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE tasks SET current_stage=?, stage_entered_at=?, sla_breached_at=NULL, updated_at=? WHERE task_id=?`, stage, now, now, taskID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// SetTaskWorkflowAndStage pins the task to workflowID at stage and records the transition.
 func (s *sqliteStore) SetTaskWorkflowAndStage(ctx context.Context, taskID int64, workflowID, stage string) error {
 	now := time.Now().UTC().Unix()
-	_, err := s.DB.ExecContext(ctx, `UPDATE tasks SET workflow_id=?, current_stage=?, updated_at=? WHERE task_id=?`, workflowID, stage, now, taskID)
-	return err
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := recordTransition(ctx, tx, taskID, stage, now); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE tasks SET workflow_id=?, current_stage=?, stage_entered_at=?, sla_breached_at=NULL, updated_at=? WHERE task_id=?`, workflowID, stage, now, now, taskID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *sqliteStore) CreateTaskReview(ctx context.Context, teamName string, taskID int64, reviewerAgent, outcome, comments string) (int64, error) {
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreateReviewComment stores an inline review comment. A reply (InReplyTo set) is attached to the first
+// comment of its thread and drops any anchor; a new thread needs a path, a line range and a body.
+func (s *sqliteStore) CreateReviewComment(ctx context.Context, teamName string, c ReviewComment) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	if c.Body == "" {
+		return 0, errors.New("comment body required")
+	}
+	if c.InReplyTo != 0 {
+		var root int64
+		err := s.DB.QueryRowContext(ctx, `SELECT CASE WHEN in_reply_to=0 THEN comment_id ELSE in_reply_to END FROM review_comments WHERE comment_id=? AND task_id=? AND team_id=?`,
+			c.InReplyTo, c.TaskID, team.TeamID).Scan(&root)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("review comment %d not found on task %d", c.InReplyTo, c.TaskID)
+		}
+		if err != nil {
+			return 0, err
+		}
+		c.InReplyTo, c.Path, c.StartLine, c.EndLine, c.CommitSHA = root, "", 0, 0, ""
+	} else if err := CheckReviewCommentAnchor(&c); err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC().Unix()
+	res, err := s.DB.ExecContext(ctx, `INSERT INTO review_comments(team_id, task_id, review_id, in_reply_to, author, path, start_line, end_line, commit_sha, body, created_at, updated_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		team.TeamID, c.TaskID, c.ReviewID, c.InReplyTo, c.Author, c.Path, c.StartLine, c.EndLine, c.CommitSHA, c.Body, now, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// CheckReviewCommentAnchor validates a new thread's anchor; an end line of 0 means a single line.
+func CheckReviewCommentAnchor(c *ReviewComment) error {
+	if c.Path == "" {
+		return errors.New("comment path required")
+	}
+	if c.EndLine == 0 {
+		c.EndLine = c.StartLine
+	}
+	if c.StartLine < 1 || c.EndLine < c.StartLine {
+		return fmt.Errorf("invalid line range %d-%d", c.StartLine, c.EndLine)
+	}
+	return nil
+}
+
+// ListReviewComments returns the task's inline review comments, oldest first.
+func (s *sqliteStore) ListReviewComments(ctx context.Context, teamName string, taskID int64) ([]ReviewComment, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT comment_id, task_id, review_id, in_reply_to, author, path, start_line, end_line, commit_sha, body, resolved, outdated, created_at, updated_at FROM review_comments WHERE task_id=? AND team_id=? ORDER BY comment_id ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []ReviewComment
+	for rows.Next() {
+		var c ReviewComment
+		var resolved, outdated int
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&c.CommentID, &c.TaskID, &c.ReviewID, &c.InReplyTo, &c.Author, &c.Path, &c.StartLine, &c.EndLine, &c.CommitSHA, &c.Body, &resolved, &outdated, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		c.Resolved, c.Outdated = resolved != 0, outdated != 0
+		c.CreatedAt = time.Unix(createdAt, 0).UTC()
+		c.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// SetReviewThreadResolved resolves or reopens the thread started by threadID.
+func (s *sqliteStore) SetReviewThreadResolved(ctx context.Context, teamName string, taskID, threadID int64, resolved bool) error {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	v := 0
+	if resolved {
+		v = 1
+	}
+	res, err := s.DB.ExecContext(ctx, `UPDATE review_comments SET resolved=?, updated_at=? WHERE comment_id=? AND task_id=? AND team_id=? AND in_reply_to=0`,
+		v, time.Now().UTC().Unix(), threadID, taskID, team.TeamID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("review thread %d not found on task %d", threadID, taskID)
+	}
+	return nil
+}
+
+// UpdateReviewCommentAnchor moves a thread's anchor to another commit, or marks it outdated.
+func (s *sqliteStore) UpdateReviewCommentAnchor(ctx context.Context, commentID int64, startLine, endLine int, commitSHA string, outdated bool) error {
+	v := 0
+	if outdated {
+		v = 1
+	}
+	_, err := s.DB.ExecContext(ctx, `UPDATE review_comments SET start_line=?, end_line=?, commit_sha=?, outdated=?, updated_at=? WHERE comment_id=?`,
+		startLine, endLine, commitSHA, v, time.Now().UTC().Unix(), commentID)
+	return err
+}
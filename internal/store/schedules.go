@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const taskScheduleColumns = `s.schedule_id, s.team_id, t.name, s.name, s.title_template, s.workflow_name, s.workflow_version, s.labels, s.cron_expr, s.run_at, s.next_run_at, s.last_run_at, s.last_task_id, s.enabled, s.created_at`
+
+// SetTaskLabels replaces the comma-separated labels on a task.
+func (s *sqliteStore) SetTaskLabels(ctx context.Context, taskID int64, labels string) error {
+	now := time.Now().UTC().Unix()
+	_, err := s.DB.ExecContext(ctx, `UPDATE tasks SET labels=?, updated_at=? WHERE task_id=?`, labels, now, taskID)
+	return err
+}
+
+// ListTaskSchedules returns all schedules for a team ordered by name.
+func (s *sqliteStore) ListTaskSchedules(ctx context.Context, teamName string) ([]TaskSchedule, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+taskScheduleColumns+` FROM task_schedules s JOIN teams t ON t.team_id = s.team_id WHERE s.team_id = ? ORDER BY s.name ASC`, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []TaskSchedule
+	for rows.Next() {
+		sch, err := scanTaskScheduleRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *sch)
+	}
+	return out, rows.Err()
+}
+
+// CreateTaskSchedule inserts a schedule. The caller computes NextRunAt (see internal/schedule).
+func (s *sqliteStore) CreateTaskSchedule(ctx context.Context, teamName string, sch TaskSchedule) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	if sch.Name == "" {
+		return 0, errors.New("schedule name required")
+	}
+	if sch.TitleTemplate == "" {
+		return 0, errors.New("schedule title required")
+	}
+	if (sch.CronExpr == "") == (sch.RunAt == nil) {
+		return 0, errors.New("exactly one of cron expression or run_at required")
+	}
+	if sch.WorkflowVersion <= 0 {
+		sch.WorkflowVersion = 1
+	}
+	res, err := s.DB.ExecContext(ctx, `
+INSERT INTO task_schedules(team_id, name, title_template, workflow_name, workflow_version, labels, cron_expr, run_at, next_run_at, enabled, created_at)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?)`,
+		team.TeamID, sch.Name, sch.TitleTemplate, sch.WorkflowName, sch.WorkflowVersion, sch.Labels, sch.CronExpr,
+		unixOrNil(sch.RunAt), unixOrNil(sch.NextRunAt), time.Now().UTC().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// DeleteTaskSchedule removes a schedule by name. Tasks it already created are left alone.
+func (s *sqliteStore) DeleteTaskSchedule(ctx context.Context, teamName, name string) error {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM task_schedules WHERE team_id=? AND name=?`, team.TeamID, name)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return errors.New("schedule not found")
+	}
+	return nil
+}
+
+// ListDueTaskSchedules returns enabled schedules (all teams) whose next_run_at is at or before now.
+func (s *sqliteStore) ListDueTaskSchedules(ctx context.Context, now time.Time) ([]TaskSchedule, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+taskScheduleColumns+` FROM task_schedules s JOIN teams t ON t.team_id = s.team_id WHERE s.enabled = 1 AND s.next_run_at IS NOT NULL AND s.next_run_at <= ? ORDER BY s.next_run_at ASC`, now.UTC().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []TaskSchedule
+	for rows.Next() {
+		sch, err := scanTaskScheduleRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *sch)
+	}
+	return out, rows.Err()
+}
+
+// RecordTaskScheduleRun stores the outcome of a due run. taskID nil means the run was skipped (last_task_id is kept).
+// A nil nextRunAt disables the schedule (one-shot schedules after they fire).
+func (s *sqliteStore) RecordTaskScheduleRun(ctx context.Context, scheduleID int64, ranAt time.Time, taskID *int64, nextRunAt *time.Time) error {
+	var tid any
+	if taskID != nil {
+		tid = *taskID
+	}
+	enabled := 0
+	if nextRunAt != nil {
+		enabled = 1
+	}
+	_, err := s.DB.ExecContext(ctx, `UPDATE task_schedules SET last_run_at=?, last_task_id=COALESCE(?, last_task_id), next_run_at=?, enabled=? WHERE schedule_id=?`,
+		ranAt.UTC().Unix(), tid, unixOrNil(nextRunAt), enabled, scheduleID)
+	return err
+}
+
+func scanTaskScheduleRow(rows interface{ Scan(dest ...any) error }) (*TaskSchedule, error) {
+	var (
+		sch                         TaskSchedule
+		runAt, nextRunAt, lastRunAt sql.NullInt64
+		lastTaskID                  sql.NullInt64
+		enabled                     int
+		createdAt                   int64
+	)
+	err := rows.Scan(&sch.ScheduleID, &sch.TeamID, &sch.TeamName, &sch.Name, &sch.TitleTemplate, &sch.WorkflowName, &sch.WorkflowVersion,
+		&sch.Labels, &sch.CronExpr, &runAt, &nextRunAt, &lastRunAt, &lastTaskID, &enabled, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	sch.RunAt = timeOrNil(runAt)
+	sch.NextRunAt = timeOrNil(nextRunAt)
+	sch.LastRunAt = timeOrNil(lastRunAt)
+	if lastTaskID.Valid {
+		id := lastTaskID.Int64
+		sch.LastTaskID = &id
+	}
+	sch.Enabled = enabled != 0
+	sch.CreatedAt = time.Unix(createdAt, 0).UTC()
+	return &sch, nil
+}
+
+func unixOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Unix()
+}
+
+func timeOrNil(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(v.Int64, 0).UTC()
+	return &t
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SetWorkflowStageSLA sets max_duration and on_timeout for one stage of a workflow. A zero maxDuration clears the SLA.
+func (s *sqliteStore) SetWorkflowStageSLA(ctx context.Context, workflowID, stageName string, maxDuration time.Duration, onTimeout string) error {
+	res, err := s.DB.ExecContext(ctx, `UPDATE workflow_stages SET max_duration_seconds=?, on_timeout=? WHERE workflow_id=? AND stage_name=?`,
+		int64(maxDuration/time.Second), onTimeout, workflowID, stageName)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return errors.New("stage not found")
+	}
+	return nil
+}
+
+// ListStageSLABreaches returns open tasks whose time in their current stage exceeds the stage's max_duration
+// and whose breach has not been handled yet (sla_breached_at is NULL).
+func (s *sqliteStore) ListStageSLABreaches(ctx context.Context, now time.Time) ([]StageSLABreach, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+SELECT t.task_id, tm.name, t.workflow_id, t.current_stage
+FROM tasks t
+JOIN teams tm ON tm.team_id = t.team_id
+JOIN workflow_stages ws ON ws.workflow_id = t.workflow_id AND ws.stage_name = t.current_stage
+WHERE ws.max_duration_seconds > 0
+  AND t.stage_entered_at IS NOT NULL
+  AND t.sla_breached_at IS NULL
+  AND t.status NOT IN ('done','failed','cancelled')
+  AND t.stage_entered_at + ws.max_duration_seconds <= ?
+ORDER BY t.stage_entered_at ASC`, now.UTC().Unix())
+	if err != nil {
+		return nil, err
+	}
+	type ref struct {
+		taskID           int64
+		team, wfID, name string
+	}
+	var refs []ref
+	for rows.Next() {
+		var r ref
+		if err := rows.Scan(&r.taskID, &r.team, &r.wfID, &r.name); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		refs = append(refs, r)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var out []StageSLABreach
+	for _, r := range refs {
+		task, err := s.GetTaskByIDAndTeam(ctx, r.team, r.taskID)
+		if err != nil || task == nil {
+			continue
+		}
+		stages, err := s.GetWorkflowStages(ctx, r.wfID)
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range stages {
+			if st.StageName == r.name {
+				out = append(out, StageSLABreach{TeamName: r.team, Task: *task, Stage: st})
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// MarkTaskSLABreached records that the SLA for the task's current stage has been handled.
+func (s *sqliteStore) MarkTaskSLABreached(ctx context.Context, taskID int64, at time.Time) error {
+	_, err := s.DB.ExecContext(ctx, `UPDATE tasks SET sla_breached_at=? WHERE task_id=?`, at.UTC().Unix(), taskID)
+	return err
+}
@@ -123,11 +123,11 @@ func (s *sqliteStore) prepareStatements(ctx context.Context) error {
 		dest **sql.Stmt
 		q    string
 	}{
-		{&s.stmtGetTeamByName, `SELECT name, team_id, created_at FROM teams WHERE name = ?`},
-		{&s.stmtListTasks100, `SELECT task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at FROM tasks WHERE team_id = ? ORDER BY created_at DESC LIMIT 100`},
+		{&s.stmtGetTeamByName, `SELECT name, team_id, created_at, paused, paused_at, pause_reason, worktree_quota FROM teams WHERE name = ?`},
+		{&s.stmtListTasks100, `SELECT ` + taskColumns + ` FROM tasks WHERE team_id = ? ORDER BY created_at DESC LIMIT 100`},
 		{&s.stmtCreateTask, `INSERT INTO tasks(team_id, title, status, assignee, created_at, updated_at) VALUES(?, ?, ?, NULL, ?, ?)`},
-		{&s.stmtGetTaskByID, `SELECT task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at FROM tasks WHERE task_id = ? AND team_id = ?`},
-		{&s.stmtNextRunnable, `SELECT task_id, title, status, assignee, dri, COALESCE(attempt_count,0), workflow_id, current_stage, worktree_path, branch_name, base_sha, repo_name, created_at, updated_at FROM tasks WHERE team_id = ? AND status IN ('todo','in_progress') AND (current_stage IS NULL OR current_stage != 'Merging') ORDER BY updated_at ASC LIMIT 1`},
+		{&s.stmtGetTaskByID, `SELECT ` + taskColumns + ` FROM tasks WHERE task_id = ? AND team_id = ?`},
+		{&s.stmtNextRunnable, `SELECT ` + taskColumns + ` FROM tasks WHERE team_id = ? AND status IN ('todo','in_progress') AND (current_stage IS NULL OR current_stage != 'Merging') AND NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_task_id = tasks.task_id AND c.status NOT IN ('done','failed','cancelled')) ORDER BY updated_at ASC LIMIT 1`},
 		{&s.stmtClaimTask, `UPDATE tasks SET status='in_progress', assignee=?, updated_at=?, dri=COALESCE(dri, ?) WHERE task_id=? AND team_id=? AND status='todo'`},
 		{&s.stmtUpdateTaskStatus, `UPDATE tasks SET status=?, assignee=?, updated_at=? WHERE task_id=?`},
 		{&s.stmtUpdateTaskAssign, `UPDATE tasks SET assignee=?, updated_at=? WHERE task_id=?`},
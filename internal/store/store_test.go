@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestMigrationsAndBasicCRUD(t *testing.T) {
@@ -115,6 +117,197 @@ func TestSetRepoApproval(t *testing.T) {
 	if err := st.SetRepoApproval(ctx, "t1", "nonexistent", "auto"); err == nil {
 		t.Fatal("expected error for nonexistent repo")
 	}
+	if err := st.SetRepoApproval(ctx, "t1", "r1", " "); err == nil {
+		t.Fatal("expected error for empty approval policy")
+	}
+}
+
+func TestTaskApprovalDecisions(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "home"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	taskID, _ := st.CreateTask(ctx, "t1", "task", "todo", nil)
+	if _, err := st.CreateTaskApprovalDecision(ctx, "t1", TaskApprovalDecision{TaskID: taskID, Stage: "InApproval", Policy: "auto", Reason: "changes requested by bob"}); err != nil {
+		t.Fatalf("CreateTaskApprovalDecision: %v", err)
+	}
+	id, _ := st.CreateTaskApprovalDecision(ctx, "t1", TaskApprovalDecision{TaskID: taskID, Stage: "InApproval", Policy: "auto", Approved: true, Reason: "ok"})
+	got, err := st.ListTaskApprovalDecisions(ctx, "t1", taskID)
+	if err != nil || len(got) != 2 {
+		t.Fatalf("ListTaskApprovalDecisions = %+v, %v", got, err)
+	}
+	if got[0].Approved || got[0].Reason != "changes requested by bob" || !got[1].Approved || got[1].DecisionID != id || got[1].CreatedAt.IsZero() {
+		t.Fatalf("decisions = %+v", got)
+	}
+}
+
+func TestReviewComments(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "home"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	taskID, _ := st.CreateTask(ctx, "t1", "task", "todo", nil)
+	for _, bad := range []ReviewComment{
+		{TaskID: taskID, Path: "a.go", StartLine: 1},
+		{TaskID: taskID, StartLine: 1, Body: "x"},
+		{TaskID: taskID, Path: "a.go", StartLine: 5, EndLine: 2, Body: "x"},
+		{TaskID: taskID, Body: "x", InReplyTo: 99},
+	} {
+		if _, err := st.CreateReviewComment(ctx, "t1", bad); err == nil {
+			t.Errorf("CreateReviewComment(%+v): expected error", bad)
+		}
+	}
+	root, err := st.CreateReviewComment(ctx, "t1", ReviewComment{TaskID: taskID, Author: "bob", Path: "a.go", StartLine: 3, CommitSHA: "abc", Body: "nil check?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, _ := st.CreateReviewComment(ctx, "t1", ReviewComment{TaskID: taskID, Author: "alice", Body: "done", InReplyTo: root, Path: "b.go", StartLine: 9})
+	// A reply to a reply joins the same thread.
+	_, _ = st.CreateReviewComment(ctx, "t1", ReviewComment{TaskID: taskID, Author: "bob", Body: "thanks", InReplyTo: reply})
+
+	if err := st.SetReviewThreadResolved(ctx, "t1", taskID, reply, true); err == nil {
+		t.Fatal("resolving a reply should fail")
+	}
+	if err := st.SetReviewThreadResolved(ctx, "t1", taskID, root, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.UpdateReviewCommentAnchor(ctx, root, 4, 4, "def", true); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.ListReviewComments(ctx, "t1", taskID)
+	if err != nil || len(got) != 3 {
+		t.Fatalf("ListReviewComments = %+v, %v", got, err)
+	}
+	if c := got[0]; c.EndLine != 4 || c.CommitSHA != "def" || !c.Resolved || !c.Outdated || c.CreatedAt.IsZero() {
+		t.Fatalf("thread root = %+v", c)
+	}
+	if got[1].InReplyTo != root || got[1].Path != "" || got[2].InReplyTo != root {
+		t.Fatalf("replies = %+v", got[1:])
+	}
+}
+
+func TestSetRepoMergeSettings(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	st, err := Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	if err := st.CreateRepo(ctx, "t1", "r1", "/tmp", "manual", nil); err != nil {
+		t.Fatalf("CreateRepo: %v", err)
+	}
+	repos, _ := st.ListRepos(ctx, "t1")
+	if repos[0].MergeStrategy != "merge" || repos[0].TargetBranch != "" {
+		t.Fatalf("defaults = %+v, want merge into the default branch", repos[0])
+	}
+	if err := st.SetRepoMergeSettings(ctx, "t1", "r1", "develop", "squash", "{{.Title}}"); err != nil {
+		t.Fatalf("SetRepoMergeSettings: %v", err)
+	}
+	repos, _ = st.ListRepos(ctx, "t1")
+	if r := repos[0]; r.TargetBranch != "develop" || r.MergeStrategy != "squash" || r.CommitTemplate != "{{.Title}}" {
+		t.Fatalf("repo = %+v", r)
+	}
+	if err := st.SetRepoMergeSettings(ctx, "t1", "r1", "", "octopus", ""); err == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+	if err := st.SetRepoMergeSettings(ctx, "t1", "nonexistent", "", "merge", ""); err == nil {
+		t.Fatal("expected error for nonexistent repo")
+	}
+}
+
+func TestSetTeamWorktreeQuota(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	st, err := Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	if team, _ := st.GetTeamByName(ctx, "t1"); team.WorktreeQuota != 0 {
+		t.Fatalf("default quota = %d, want none", team.WorktreeQuota)
+	}
+	if err := st.SetTeamWorktreeQuota(ctx, "t1", 1<<30); err != nil {
+		t.Fatalf("SetTeamWorktreeQuota: %v", err)
+	}
+	if team, _ := st.GetTeamByName(ctx, "t1"); team.WorktreeQuota != 1<<30 {
+		t.Fatalf("quota = %d", team.WorktreeQuota)
+	}
+	if teams, _ := st.ListTeams(ctx); teams[0].WorktreeQuota != 1<<30 {
+		t.Fatalf("ListTeams quota = %d", teams[0].WorktreeQuota)
+	}
+	if err := st.SetTeamWorktreeQuota(ctx, "t1", -1); err == nil {
+		t.Fatal("expected error for a negative quota")
+	}
+	if err := st.SetTeamWorktreeQuota(ctx, "nonexistent", 1); err == nil {
+		t.Fatal("expected error for nonexistent team")
+	}
+}
+
+func TestRepoChecks(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "home"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	_ = st.CreateRepo(ctx, "t1", "r1", "/tmp", "manual", nil)
+	want := []RepoCheck{
+		{Name: "lint", Command: "make lint", Required: false},
+		{Name: "unit", Command: "go test ./...", Timeout: 5 * time.Minute, Env: []string{"CGO_ENABLED=0", "GOFLAGS=-count=1"}, Required: true},
+	}
+	if err := st.SetRepoChecks(ctx, "t1", "r1", want); err != nil {
+		t.Fatalf("SetRepoChecks: %v", err)
+	}
+	got, err := st.ListRepoChecks(ctx, "t1", "r1")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("ListRepoChecks = %+v, %v; want %+v", got, err, want)
+	}
+	_ = st.SetRepoChecks(ctx, "t1", "r1", want[1:])
+	if got, _ := st.ListRepoChecks(ctx, "t1", "r1"); len(got) != 1 || got[0].Name != "unit" {
+		t.Fatalf("after replace: %+v", got)
+	}
+
+	taskID, _ := st.CreateTask(ctx, "t1", "task", "todo", nil)
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	runs := []TaskCheckRun{
+		{Name: "lint", Status: "failed", ExitCode: 2, Log: "bad\n", StartedAt: start, FinishedAt: start.Add(time.Second)},
+		{Name: "unit", Required: true, Status: "passed", HeadSHA: "abc", StartedAt: start, FinishedAt: start.Add(2 * time.Second)},
+	}
+	for want := 1; want <= 2; want++ {
+		if n, err := st.CreateTaskCheckRuns(ctx, "t1", taskID, runs); err != nil || n != want {
+			t.Fatalf("CreateTaskCheckRuns = %d, %v; want attempt %d", n, err, want)
+		}
+	}
+	stored, err := st.ListTaskCheckRuns(ctx, "t1", taskID)
+	if err != nil || len(stored) != 4 {
+		t.Fatalf("ListTaskCheckRuns = %d runs, %v", len(stored), err)
+	}
+	if r := stored[2]; r.Attempt != 2 || r.Name != "lint" || r.ExitCode != 2 || r.Log != "bad\n" || !r.FinishedAt.Equal(start.Add(time.Second)) {
+		t.Errorf("run = %+v", r)
+	}
+	if r := stored[3]; !r.Required || r.HeadSHA != "abc" {
+		t.Errorf("run = %+v", r)
+	}
 }
 
 func TestMessages(t *testing.T) {
@@ -608,3 +801,108 @@ func BenchmarkNextRunnableTaskForTeam(b *testing.B) {
 		_, _ = st.NextRunnableTaskForTeam(ctx, "t1")
 	}
 }
+
+func TestTaskTransitions(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	wfID, _ := st.CreateWorkflow(ctx, "t1", "default", 1, "builtin:default")
+	taskID, _ := st.CreateTask(ctx, "t1", "task1", "todo", &wfID)
+
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	if task.CurrentStage == nil || *task.CurrentStage != "Coding" {
+		t.Fatalf("expected initial stage Coding, got %+v", task.CurrentStage)
+	}
+	actx := WithTransitionCause(ctx, TransitionCause{Actor: "a1"})
+	if err := st.UpdateTaskStage(WithTransitionCause(actx, TransitionCause{Outcome: "submit_for_review"}), taskID, "InReview"); err != nil {
+		t.Fatal(err)
+	}
+	// Same stage again: no transition recorded.
+	if err := st.SetTaskWorkflowAndStage(ctx, taskID, wfID, "InReview"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.ListTaskTransitions(ctx, "t1", taskID)
+	if err != nil {
+		t.Fatalf("ListTaskTransitions: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("want 1 transition, got %+v", got)
+	}
+	if got[0].FromStage != "Coding" || got[0].ToStage != "InReview" || got[0].Actor != "a1" || got[0].Outcome != "submit_for_review" || got[0].FromEnteredAt == nil {
+		t.Fatalf("transition: %+v", got[0])
+	}
+
+	if err := st.RewindTask(WithTransitionCause(ctx, TransitionCause{Actor: "cli", Note: "rewind"}), "t1", taskID); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = st.ListTaskTransitions(ctx, "t1", taskID)
+	if last := got[len(got)-1]; len(got) != 2 || last.FromStage != "InReview" || last.ToStage != "Coding" || last.Actor != "cli" || last.Note != "rewind" {
+		t.Fatalf("rewind transition: %+v", last)
+	}
+
+	now := time.Now().UTC()
+	if _, err := st.CreateTaskTurn(ctx, "t1", TaskTurn{TaskID: taskID, Agent: "a1", Stage: "Coding", Outcome: "done", StartedAt: now, FinishedAt: now}); err != nil {
+		t.Fatalf("CreateTaskTurn: %v", err)
+	}
+	turns, err := st.ListTaskTurns(ctx, "t1", taskID)
+	if err != nil || len(turns) != 1 || turns[0].Agent != "a1" || turns[0].Outcome != "done" {
+		t.Fatalf("ListTaskTurns: %+v, %v", turns, err)
+	}
+}
+
+func TestSetTaskParent(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	_, _ = st.CreateTeam(ctx, "t2")
+	epic, _ := st.CreateTask(ctx, "t1", "epic", "todo", nil)
+	child, _ := st.CreateTask(ctx, "t1", "child", "todo", nil)
+	grandchild, _ := st.CreateTask(ctx, "t1", "grandchild", "todo", nil)
+	other, _ := st.CreateTask(ctx, "t2", "other team", "todo", nil)
+
+	if err := st.SetTaskParent(ctx, "t1", child, &epic); err != nil {
+		t.Fatalf("SetTaskParent: %v", err)
+	}
+	if err := st.SetTaskParent(ctx, "t1", grandchild, &child); err != nil {
+		t.Fatalf("SetTaskParent: %v", err)
+	}
+	if err := st.SetTaskParent(ctx, "t1", epic, &grandchild); err == nil {
+		t.Fatal("expected cycle to be rejected")
+	}
+	if err := st.SetTaskParent(ctx, "t1", epic, &epic); err == nil {
+		t.Fatal("expected self-parent to be rejected")
+	}
+	if err := st.SetTaskParent(ctx, "t1", child, &other); err == nil {
+		t.Fatal("expected parent from another team to be rejected")
+	}
+	subs, err := st.ListSubtasks(ctx, "t1", epic)
+	if err != nil || len(subs) != 1 || subs[0].TaskID != child || subs[0].ParentTaskID == nil || *subs[0].ParentTaskID != epic {
+		t.Fatalf("ListSubtasks: %+v, %v", subs, err)
+	}
+	if err := st.SetTaskParent(ctx, "t1", child, nil); err != nil {
+		t.Fatalf("detach: %v", err)
+	}
+	if subs, _ := st.ListSubtasks(ctx, "t1", epic); len(subs) != 0 {
+		t.Fatalf("expected no subtasks after detach, got %+v", subs)
+	}
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SetTaskParent makes taskID a subtask of parentID (both in teamName), or detaches it when parentID is nil.
+// A task cannot become its own ancestor. Attaching re-arms the parent's roll-up.
+func (s *sqliteStore) SetTaskParent(ctx context.Context, teamName string, taskID int64, parentID *int64) error {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Unix()
+	if parentID == nil {
+		_, err := s.DB.ExecContext(ctx, `UPDATE tasks SET parent_task_id=NULL, updated_at=? WHERE task_id=? AND team_id=?`, now, taskID, team.TeamID)
+		return err
+	}
+	// Walk up from the parent: reaching taskID would make a cycle.
+	for id := *parentID; ; {
+		if id == taskID {
+			return fmt.Errorf("task %d cannot be a subtask of its own subtask %d", taskID, *parentID)
+		}
+		var next sql.NullInt64
+		err := s.DB.QueryRowContext(ctx, `SELECT parent_task_id FROM tasks WHERE task_id=? AND team_id=?`, id, team.TeamID).Scan(&next)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("task %d not found in team %q", id, teamName)
+		}
+		if err != nil {
+			return err
+		}
+		if !next.Valid {
+			break
+		}
+		id = next.Int64
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.ExecContext(ctx, `UPDATE tasks SET parent_task_id=?, updated_at=? WHERE task_id=? AND team_id=?`, *parentID, now, taskID, team.TeamID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("task %d not found in team %q", taskID, teamName)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE tasks SET subtasks_rolled_up_at=NULL WHERE task_id=?`, *parentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetTaskSubtaskPolicy sets what a failed subtask does to the task ("fail", "ignore" or "wait").
+func (s *sqliteStore) SetTaskSubtaskPolicy(ctx context.Context, taskID int64, policy string) error {
+	_, err := s.DB.ExecContext(ctx, `UPDATE tasks SET subtask_policy=?, updated_at=? WHERE task_id=?`, policy, time.Now().UTC().Unix(), taskID)
+	return err
+}
+
+// ListSubtasks returns the direct subtasks of parentID, oldest first.
+func (s *sqliteStore) ListSubtasks(ctx context.Context, teamName string, parentID int64) ([]Task, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE team_id = ? AND parent_task_id = ? ORDER BY task_id ASC`, team.TeamID, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []Task
+	for rows.Next() {
+		task, err := scanTaskRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *task)
+	}
+	return out, rows.Err()
+}
+
+// ListSubtaskRollups returns open parents whose subtasks have all finished and that have not been rolled up
+// since their last subtask was attached.
+func (s *sqliteStore) ListSubtaskRollups(ctx context.Context) ([]SubtaskRollup, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+SELECT p.task_id, tm.name,
+  SUM(CASE WHEN c.status = 'done' THEN 1 ELSE 0 END),
+  SUM(CASE WHEN c.status = 'failed' THEN 1 ELSE 0 END),
+  SUM(CASE WHEN c.status = 'cancelled' THEN 1 ELSE 0 END)
+FROM tasks p
+JOIN teams tm ON tm.team_id = p.team_id
+JOIN tasks c ON c.parent_task_id = p.task_id
+WHERE p.status NOT IN ('done','failed','cancelled') AND p.subtasks_rolled_up_at IS NULL
+GROUP BY p.task_id, tm.name
+HAVING SUM(CASE WHEN c.status IN ('done','failed','cancelled') THEN 0 ELSE 1 END) = 0
+ORDER BY p.task_id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	var out []SubtaskRollup
+	var ids []int64
+	for rows.Next() {
+		var r SubtaskRollup
+		var id int64
+		if err := rows.Scan(&id, &r.TeamName, &r.Done, &r.Failed, &r.Cancelled); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		out = append(out, r)
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		task, err := s.GetTaskByIDAndTeam(ctx, out[i].TeamName, ids[i])
+		if err != nil {
+			return nil, err
+		}
+		if task != nil {
+			out[i].Parent = *task
+		}
+	}
+	return out, nil
+}
+
+// MarkSubtasksRolledUp records that the task's finished subtasks have been rolled up.
+func (s *sqliteStore) MarkSubtasksRolledUp(ctx context.Context, taskID int64, at time.Time) error {
+	_, err := s.DB.ExecContext(ctx, `UPDATE tasks SET subtasks_rolled_up_at=? WHERE task_id=?`, at.UTC().Unix(), taskID)
+	return err
+}
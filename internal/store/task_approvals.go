@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// CreateTaskApprovalDecision records an approval policy decision on the task.
+func (s *sqliteStore) CreateTaskApprovalDecision(ctx context.Context, teamName string, d TaskApprovalDecision) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	approved := 0
+	if d.Approved {
+		approved = 1
+	}
+	res, err := s.DB.ExecContext(ctx, `INSERT INTO task_approval_decisions(team_id, task_id, stage, policy, approved, reason, created_at) VALUES(?, ?, ?, ?, ?, ?, ?)`,
+		team.TeamID, d.TaskID, d.Stage, d.Policy, approved, d.Reason, time.Now().UTC().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListTaskApprovalDecisions returns the task's approval policy decisions, oldest first.
+func (s *sqliteStore) ListTaskApprovalDecisions(ctx context.Context, teamName string, taskID int64) ([]TaskApprovalDecision, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT decision_id, task_id, stage, policy, approved, reason, created_at FROM task_approval_decisions WHERE task_id=? AND team_id=? ORDER BY decision_id ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []TaskApprovalDecision
+	for rows.Next() {
+		var d TaskApprovalDecision
+		var approved int
+		var createdAt int64
+		if err := rows.Scan(&d.DecisionID, &d.TaskID, &d.Stage, &d.Policy, &approved, &d.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		d.Approved = approved != 0
+		d.CreatedAt = time.Unix(createdAt, 0).UTC()
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type transitionCauseKey struct{}
+
+// WithTransitionCause returns a context whose stage changes are recorded as made by c.
+// An empty Actor or Note keeps the one already on ctx, so callers can add the outcome to an actor set further up.
+func WithTransitionCause(ctx context.Context, c TransitionCause) context.Context {
+	prev := TransitionCauseFrom(ctx)
+	if c.Actor == "" {
+		c.Actor = prev.Actor
+	}
+	if c.Note == "" {
+		c.Note = prev.Note
+	}
+	return context.WithValue(ctx, transitionCauseKey{}, c)
+}
+
+// TransitionCauseFrom returns the cause attached to ctx; Actor is "system" if none was set.
+func TransitionCauseFrom(ctx context.Context) TransitionCause {
+	c, _ := ctx.Value(transitionCauseKey{}).(TransitionCause)
+	if c.Actor == "" {
+		c.Actor = "system"
+	}
+	return c
+}
+
+// insertTransitionSQL records the move of task_id to a new stage, reading the stage it is leaving. It must run
+// before the UPDATE and inserts nothing when the stage does not change.
+const insertTransitionSQL = `INSERT INTO task_transitions(task_id, team_id, from_stage, to_stage, outcome, actor, note, from_entered_at, created_at)
+SELECT task_id, team_id, COALESCE(current_stage,''), ?, ?, ?, ?, stage_entered_at, ? FROM tasks WHERE task_id=? AND COALESCE(current_stage,'') != ?`
+
+func recordTransition(ctx context.Context, tx *sql.Tx, taskID int64, toStage string, now int64) error {
+	c := TransitionCauseFrom(ctx)
+	_, err := tx.ExecContext(ctx, insertTransitionSQL, toStage, c.Outcome, c.Actor, c.Note, now, taskID, toStage)
+	return err
+}
+
+// ListTaskTransitions returns the task's stage changes, oldest first.
+func (s *sqliteStore) ListTaskTransitions(ctx context.Context, teamName string, taskID int64) ([]TaskTransition, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT transition_id, task_id, from_stage, to_stage, outcome, actor, note, from_entered_at, created_at FROM task_transitions WHERE task_id=? AND team_id=? ORDER BY transition_id ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []TaskTransition
+	for rows.Next() {
+		var t TaskTransition
+		var entered sql.NullInt64
+		var createdAt int64
+		if err := rows.Scan(&t.TransitionID, &t.TaskID, &t.FromStage, &t.ToStage, &t.Outcome, &t.Actor, &t.Note, &entered, &createdAt); err != nil {
+			return nil, err
+		}
+		t.FromEnteredAt = timeOrNil(entered)
+		t.CreatedAt = time.Unix(createdAt, 0).UTC()
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// CreateTaskTurn records an agent turn on a task.
+func (s *sqliteStore) CreateTaskTurn(ctx context.Context, teamName string, turn TaskTurn) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	res, err := s.DB.ExecContext(ctx, `INSERT INTO task_turns(task_id, team_id, agent, stage, outcome, error, started_at, finished_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+		turn.TaskID, team.TeamID, turn.Agent, turn.Stage, turn.Outcome, turn.Error, turn.StartedAt.UTC().Unix(), turn.FinishedAt.UTC().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListTaskTurns returns the task's agent turns, oldest first.
+func (s *sqliteStore) ListTaskTurns(ctx context.Context, teamName string, taskID int64) ([]TaskTurn, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT turn_id, task_id, agent, stage, outcome, error, started_at, finished_at FROM task_turns WHERE task_id=? AND team_id=? ORDER BY turn_id ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []TaskTurn
+	for rows.Next() {
+		var t TaskTurn
+		var started, finished int64
+		if err := rows.Scan(&t.TurnID, &t.TaskID, &t.Agent, &t.Stage, &t.Outcome, &t.Error, &started, &finished); err != nil {
+			return nil, err
+		}
+		t.StartedAt = time.Unix(started, 0).UTC()
+		t.FinishedAt = time.Unix(finished, 0).UTC()
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CreateTaskPlan stores a new plan revision (numbered after the task's latest) as pending and marks earlier
+// pending revisions superseded.
+func (s *sqliteStore) CreateTaskPlan(ctx context.Context, teamName string, plan TaskPlan) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	var revision int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(revision),0)+1 FROM task_plans WHERE task_id=?`, plan.TaskID).Scan(&revision); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE task_plans SET status='superseded' WHERE task_id=? AND status='pending'`, plan.TaskID); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO task_plans(task_id, team_id, revision, author, body, status, created_at) VALUES(?, ?, ?, ?, ?, 'pending', ?)`,
+		plan.TaskID, team.TeamID, revision, plan.Author, plan.Body, time.Now().UTC().Unix())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// ListTaskPlans returns the task's plan revisions, oldest first.
+func (s *sqliteStore) ListTaskPlans(ctx context.Context, teamName string, taskID int64) ([]TaskPlan, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT plan_id, task_id, revision, author, body, status, feedback, decided_by, decided_at, created_at FROM task_plans WHERE task_id=? AND team_id=? ORDER BY revision ASC`, taskID, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []TaskPlan
+	for rows.Next() {
+		var p TaskPlan
+		var decided sql.NullInt64
+		var createdAt int64
+		if err := rows.Scan(&p.PlanID, &p.TaskID, &p.Revision, &p.Author, &p.Body, &p.Status, &p.Feedback, &p.DecidedBy, &decided, &createdAt); err != nil {
+			return nil, err
+		}
+		p.DecidedAt = timeOrNil(decided)
+		p.CreatedAt = time.Unix(createdAt, 0).UTC()
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// DecideTaskPlan approves or rejects a pending plan revision.
+func (s *sqliteStore) DecideTaskPlan(ctx context.Context, planID int64, status, decidedBy, feedback string, at time.Time) error {
+	res, err := s.DB.ExecContext(ctx, `UPDATE task_plans SET status=?, decided_by=?, feedback=?, decided_at=? WHERE plan_id=? AND status='pending'`,
+		status, decidedBy, feedback, at.UTC().Unix(), planID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("plan %d is not pending", planID)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ListOpenTasksInWorkflow returns tasks pinned to workflowID that are not done, failed or cancelled.
+func (s *sqliteStore) ListOpenTasksInWorkflow(ctx context.Context, workflowID string) ([]Task, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE workflow_id = ? AND status NOT IN ('done','failed','cancelled') ORDER BY task_id ASC`, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []Task
+	for rows.Next() {
+		task, err := scanTaskRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *task)
+	}
+	return out, rows.Err()
+}
+
+// MigrateWorkflowTasks re-points every task in m.Moves to m.ToWorkflowID and its new stage, and records
+// the migration, in one transaction. It fails without changing anything if any task is no longer open in
+// m.FromWorkflowID at its planned FromStage. Tasks that keep their stage name keep their SLA clock.
+func (s *sqliteStore) MigrateWorkflowTasks(ctx context.Context, teamName string, m WorkflowMigration) (int64, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return 0, err
+	}
+	stageMap, err := json.Marshal(m.StageMap)
+	if err != nil {
+		return 0, err
+	}
+	moves, err := json.Marshal(m.Moves)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC().Unix()
+	for _, mv := range m.Moves {
+		if err := recordTransition(ctx, tx, mv.TaskID, mv.ToStage, now); err != nil {
+			return 0, err
+		}
+		res, err := tx.ExecContext(ctx, `UPDATE tasks SET workflow_id=?, current_stage=NULLIF(?, ''),
+  stage_entered_at=CASE WHEN COALESCE(current_stage,'') = ? THEN stage_entered_at ELSE ? END,
+  sla_breached_at=CASE WHEN COALESCE(current_stage,'') = ? THEN sla_breached_at ELSE NULL END,
+  updated_at=?
+WHERE task_id=? AND team_id=? AND workflow_id=? AND COALESCE(current_stage,'')=? AND status NOT IN ('done','failed','cancelled')`,
+			m.ToWorkflowID, mv.ToStage, mv.ToStage, now, mv.ToStage, now,
+			mv.TaskID, team.TeamID, m.FromWorkflowID, mv.FromStage)
+		if err != nil {
+			return 0, err
+		}
+		if n, _ := res.RowsAffected(); n != 1 {
+			return 0, fmt.Errorf("task %d changed since the migration was planned", mv.TaskID)
+		}
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO workflow_migrations(team_id, from_workflow_id, to_workflow_id, stage_map, moves, created_at) VALUES(?, ?, ?, ?, ?, ?)`,
+		team.TeamID, m.FromWorkflowID, m.ToWorkflowID, string(stageMap), string(moves), now)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ListWorkflowMigrations returns the team's migration audit records, newest first.
+func (s *sqliteStore) ListWorkflowMigrations(ctx context.Context, teamName string) ([]WorkflowMigration, error) {
+	team, err := s.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT migration_id, from_workflow_id, to_workflow_id, stage_map, moves, created_at FROM workflow_migrations WHERE team_id = ? ORDER BY migration_id DESC`, team.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []WorkflowMigration
+	for rows.Next() {
+		var m WorkflowMigration
+		var stageMap, moves string
+		var createdAt int64
+		if err := rows.Scan(&m.MigrationID, &m.FromWorkflowID, &m.ToWorkflowID, &stageMap, &moves, &createdAt); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(stageMap), &m.StageMap)
+		_ = json.Unmarshal([]byte(moves), &m.Moves)
+		m.CreatedAt = time.Unix(createdAt, 0).UTC()
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,349 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ankittk/agentary/internal/sandbox"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// Action kinds for auto stages.
+const (
+	ActionRun     = "run"     // run:<command> runs sh -c in the task worktree under the sandbox
+	ActionLint    = "lint"    // lint[:<command>] runs a lint pass; defaults to go vet ./... for Go repos
+	ActionFormat  = "format"  // format[:<command>] runs a formatter over the worktree; defaults to gofmt -l -w .
+	ActionWebhook = "webhook" // webhook:<url> POSTs the task as JSON; 2xx is exit 0, or the body may name an outcome
+	ActionGo      = "go"      // go:<name> calls an action registered with RegisterAction
+)
+
+// actionTimeout bounds a single auto-stage action.
+const actionTimeout = 10 * time.Minute
+
+// actionOutputLimit caps the action output posted to the task.
+const actionOutputLimit = 4000
+
+// Action is a parsed auto-stage action spec.
+type Action struct {
+	Kind string
+	Arg  string
+}
+
+func (a Action) String() string {
+	if a.Arg == "" {
+		return a.Kind
+	}
+	return a.Kind + ":" + a.Arg
+}
+
+// ActionInput is what an action sees of the task it runs for.
+type ActionInput struct {
+	Team     string `json:"team"`
+	TaskID   int64  `json:"task_id"`
+	Title    string `json:"title"`
+	Stage    string `json:"stage"`
+	Worktree string `json:"worktree,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	Repo     string `json:"repo,omitempty"`
+}
+
+// ActionResult is the result of running an action. If Outcome is set it is applied directly;
+// otherwise ExitCode is mapped through the stage's action_outcomes.
+type ActionResult struct {
+	Outcome  string
+	ExitCode int
+	Output   string
+}
+
+// ActionFunc is a Go-registered action, run by "go:<name>" auto stages.
+type ActionFunc func(ctx context.Context, in ActionInput) (ActionResult, error)
+
+var (
+	actionsMu sync.RWMutex
+	actions   = make(map[string]ActionFunc)
+)
+
+// RegisterAction makes fn available to auto stages as "go:<name>". It panics if name is empty or
+// already registered, like other init-time registries.
+func RegisterAction(name string, fn ActionFunc) {
+	actionsMu.Lock()
+	defer actionsMu.Unlock()
+	if name == "" || fn == nil {
+		panic("workflow: RegisterAction needs a name and a function")
+	}
+	if _, dup := actions[name]; dup {
+		panic("workflow: RegisterAction called twice for " + name)
+	}
+	actions[name] = fn
+}
+
+// RegisteredActions returns the names of Go-registered actions, sorted.
+func RegisteredActions() []string {
+	actionsMu.RLock()
+	defer actionsMu.RUnlock()
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupAction(name string) ActionFunc {
+	actionsMu.RLock()
+	defer actionsMu.RUnlock()
+	return actions[name]
+}
+
+// ParseAction parses an action spec: run:<command>, lint[:<command>], format[:<command>], webhook:<url> or go:<name>.
+func ParseAction(spec string) (Action, error) {
+	kind, arg, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	a := Action{Kind: strings.TrimSpace(kind), Arg: strings.TrimSpace(arg)}
+	switch a.Kind {
+	case ActionRun, ActionGo:
+		if a.Arg == "" {
+			return a, fmt.Errorf("action %s needs an argument", a.Kind)
+		}
+	case ActionLint, ActionFormat:
+	case ActionWebhook:
+		if !strings.HasPrefix(a.Arg, "http://") && !strings.HasPrefix(a.Arg, "https://") {
+			return a, fmt.Errorf("action webhook needs an http(s) URL")
+		}
+	default:
+		return a, fmt.Errorf("unknown action %q (want run:<command>, lint[:<command>], format[:<command>], webhook:<url> or go:<name>)", a.Kind)
+	}
+	if (a.Kind == ActionRun || a.Kind == ActionLint || a.Kind == ActionFormat) && sandbox.BlockedShellCommand(a.Arg) {
+		return a, fmt.Errorf("action %s: command is blocked by the sandbox deny list", a.Kind)
+	}
+	return a, nil
+}
+
+// ParseActionOutcomes parses an exit-code map such as "0=done,2=needs_codegen,*=failed".
+// "*" matches any exit code without its own entry.
+func ParseActionOutcomes(s string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, pair := range splitList(s) {
+		code, outcome, ok := strings.Cut(pair, "=")
+		code, outcome = strings.TrimSpace(code), strings.TrimSpace(outcome)
+		if !ok || outcome == "" {
+			return nil, fmt.Errorf("action_outcomes: %q is not code=outcome", pair)
+		}
+		if code != "*" {
+			if _, err := strconv.Atoi(code); err != nil {
+				return nil, fmt.Errorf("action_outcomes: %q is not an exit code or *", code)
+			}
+		}
+		if _, dup := m[code]; dup {
+			return nil, fmt.Errorf("action_outcomes: exit code %s mapped twice", code)
+		}
+		m[code] = outcome
+	}
+	return m, nil
+}
+
+// formatActionOutcomes renders an exit-code map in a stable order: numeric codes ascending, then "*".
+func formatActionOutcomes(m map[string]string) string {
+	codes := make([]string, 0, len(m))
+	for code := range m {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if codes[i] == "*" || codes[j] == "*" {
+			return codes[j] == "*" && codes[i] != "*"
+		}
+		a, _ := strconv.Atoi(codes[i])
+		b, _ := strconv.Atoi(codes[j])
+		return a < b
+	})
+	parts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		parts = append(parts, code+"="+m[code])
+	}
+	return strings.Join(parts, ",")
+}
+
+// actionOutcome maps a result to an outcome: an explicit Outcome wins, then the exit code's entry,
+// then "*", then the defaults 0=done and non-zero=failed.
+func actionOutcome(res ActionResult, outcomes map[string]string) string {
+	if res.Outcome != "" {
+		return res.Outcome
+	}
+	if o, ok := outcomes[strconv.Itoa(res.ExitCode)]; ok {
+		return o
+	}
+	if o, ok := outcomes["*"]; ok {
+		return o
+	}
+	if res.ExitCode == 0 {
+		return "done"
+	}
+	return "failed"
+}
+
+// runAutoStage runs the stage's action and applies the mapped outcome. A stage without an action
+// applies "done". If the mapped outcome is not one of the stage's outcomes the task is failed.
+func (e *Engine) runAutoStage(ctx context.Context, teamName string, task *store.Task, stage *store.WorkflowStage) error {
+	if strings.TrimSpace(stage.Action) == "" {
+		_, err := e.ApplyOutcome(ctx, teamName, task, "done")
+		return err
+	}
+	a, err := ParseAction(stage.Action)
+	if err != nil {
+		_ = e.Store.SetTaskFailed(ctx, task.TaskID)
+		return err
+	}
+	outcomes, err := ParseActionOutcomes(stage.ActionOutcomes)
+	if err != nil {
+		_ = e.Store.SetTaskFailed(ctx, task.TaskID)
+		return err
+	}
+	in := ActionInput{Team: teamName, TaskID: task.TaskID, Title: task.Title, Stage: stage.StageName}
+	if task.WorktreePath != nil {
+		in.Worktree = *task.WorktreePath
+	}
+	if task.BranchName != nil {
+		in.Branch = *task.BranchName
+	}
+	if task.RepoName != nil {
+		in.Repo = *task.RepoName
+	}
+	res, runErr := e.RunAction(ctx, a, in)
+	if runErr != nil {
+		res.ExitCode = -1
+		if res.Output == "" {
+			res.Output = runErr.Error()
+		}
+	}
+	outcome := actionOutcome(res, outcomes)
+	e.comment(ctx, teamName, task.TaskID, actionComment(a, res, outcome))
+	if !containsString(splitList(stage.Outcomes), outcome) {
+		_ = e.Store.SetTaskFailed(ctx, task.TaskID)
+		if runErr != nil {
+			return fmt.Errorf("action %s: %w", a, runErr)
+		}
+		return fmt.Errorf("action %s: outcome %q is not an outcome of stage %s", a, outcome, stage.StageName)
+	}
+	_, err = e.ApplyOutcome(ctx, teamName, task, outcome)
+	return err
+}
+
+// RunAction runs one action for a task. A command that runs and exits non-zero is a result, not an
+// error; errors mean the action could not run at all.
+func (e *Engine) RunAction(ctx context.Context, a Action, in ActionInput) (ActionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, actionTimeout)
+	defer cancel()
+	switch a.Kind {
+	case ActionRun, ActionLint, ActionFormat:
+		if in.Worktree == "" {
+			return ActionResult{}, errors.New("task has no worktree")
+		}
+		command := a.Arg
+		if command == "" {
+			command = defaultActionCommand(a.Kind, in.Worktree)
+			if command == "" {
+				return ActionResult{}, fmt.Errorf("no default %s command for this repo; use %s:<command>", a.Kind, a.Kind)
+			}
+		}
+		return e.runShellAction(ctx, command, in)
+	case ActionWebhook:
+		return runWebhookAction(ctx, a.Arg, in)
+	case ActionGo:
+		fn := lookupAction(a.Arg)
+		if fn == nil {
+			return ActionResult{}, fmt.Errorf("action %q is not registered", a.Arg)
+		}
+		return fn(ctx, in)
+	}
+	return ActionResult{}, fmt.Errorf("unknown action %q", a.Kind)
+}
+
+// defaultActionCommand picks the lint or format command for the worktree's language.
+func defaultActionCommand(kind, worktree string) string {
+	if _, err := os.Stat(filepath.Join(worktree, "go.mod")); err != nil {
+		return ""
+	}
+	if kind == ActionLint {
+		return "go vet ./..."
+	}
+	return "gofmt -l -w ."
+}
+
+// runShellAction runs command with sh -c in the worktree. When the worktree is under Home the command
+// runs in the sandbox with only the worktree writable.
+func (e *Engine) runShellAction(ctx context.Context, command string, in ActionInput) (ActionResult, error) {
+	cmd := sandbox.WrapCommand(ctx, sandbox.HomeFor(e.Home, in.Worktree), in.Worktree, "sh", []string{"-c", command})
+	cmd.Dir = in.Worktree
+	cmd.Env = append(os.Environ(),
+		"AGENTARY_TEAM="+in.Team,
+		fmt.Sprintf("AGENTARY_TASK_ID=%d", in.TaskID),
+		"AGENTARY_STAGE="+in.Stage,
+		"AGENTARY_BRANCH="+in.Branch,
+	)
+	out, err := cmd.CombinedOutput()
+	res := ActionResult{Output: string(out)}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		res.ExitCode = exitErr.ExitCode()
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// runWebhookAction POSTs the task to url. A 2xx response is exit code 0, any other status is
+// exit code 1; a JSON body {"outcome": "..."} names the outcome directly.
+func runWebhookAction(ctx context.Context, url string, in ActionInput) (ActionResult, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return ActionResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	res := ActionResult{Output: fmt.Sprintf("%s\n%s", resp.Status, data)}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		res.ExitCode = 1
+	}
+	var reply struct {
+		Outcome string `json:"outcome"`
+	}
+	if json.Unmarshal(data, &reply) == nil {
+		res.Outcome = strings.TrimSpace(reply.Outcome)
+	}
+	return res, nil
+}
+
+func actionComment(a Action, res ActionResult, outcome string) string {
+	out := strings.TrimSpace(res.Output)
+	if len(out) > actionOutputLimit {
+		out = "…" + out[len(out)-actionOutputLimit:]
+	}
+	msg := fmt.Sprintf("auto action `%s` exited %d: outcome %q", a, res.ExitCode, outcome)
+	if out != "" {
+		msg += "\n```\n" + out + "\n```"
+	}
+	return msg
+}
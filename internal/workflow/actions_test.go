@@ -0,0 +1,172 @@
+package workflow
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+func TestParseActionAndOutcomes(t *testing.T) {
+	t.Parallel()
+	for _, ok := range []string{"run:go generate ./...", "lint", "format:prettier -w .", "webhook:https://ci.example.com/hook", "go:codegen"} {
+		if _, err := ParseAction(ok); err != nil {
+			t.Errorf("ParseAction(%q): %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"run:", "go:", "webhook:ftp://x", "shell:ls", "run:rm -rf .git"} {
+		if _, err := ParseAction(bad); err == nil {
+			t.Errorf("ParseAction(%q): expected error", bad)
+		}
+	}
+	m, err := ParseActionOutcomes("0=done, 2=needs_codegen, *=failed")
+	if err != nil || m["0"] != "done" || m["2"] != "needs_codegen" || m["*"] != "failed" {
+		t.Fatalf("ParseActionOutcomes = %v, %v", m, err)
+	}
+	if got := formatActionOutcomes(m); got != "0=done,2=needs_codegen,*=failed" {
+		t.Errorf("formatActionOutcomes = %q", got)
+	}
+	for _, bad := range []string{"done", "x=done", "0=done,0=failed"} {
+		if _, err := ParseActionOutcomes(bad); err == nil {
+			t.Errorf("ParseActionOutcomes(%q): expected error", bad)
+		}
+	}
+	for _, tc := range []struct {
+		res  ActionResult
+		want string
+	}{
+		{ActionResult{ExitCode: 2}, "needs_codegen"},
+		{ActionResult{ExitCode: 7}, "failed"},
+		{ActionResult{ExitCode: 0, Outcome: "skip"}, "skip"},
+	} {
+		if got := actionOutcome(tc.res, m); got != tc.want {
+			t.Errorf("actionOutcome(%+v) = %q, want %q", tc.res, got, tc.want)
+		}
+	}
+	if got := actionOutcome(ActionResult{ExitCode: 1}, nil); got != "failed" {
+		t.Errorf("default non-zero outcome = %q", got)
+	}
+}
+
+func TestLintActions(t *testing.T) {
+	t.Parallel()
+	def := &Definition{
+		Initial: "Vet",
+		Stages: []StageDef{
+			{Name: "Vet", Type: "auto", Outcomes: []string{"done", "fix"}, Action: "lint", ActionOutcomes: map[string]string{"0": "done", "1": "nope"}},
+			{Name: "Fix", Type: "agent", Outcomes: []string{"done"}, Action: "run:make"},
+			{Name: "Done", Type: "terminal"},
+		},
+		Transitions: []TransitionDef{
+			{From: "Vet", Outcome: "done", To: "Done"},
+			{From: "Vet", Outcome: "fix", To: "Fix"},
+			{From: "Fix", Outcome: "done", To: "Vet"},
+		},
+	}
+	codes := map[string]bool{}
+	for _, d := range Lint(def, nil) {
+		codes[d.Code+"/"+d.Severity] = true
+	}
+	for _, want := range []string{"action_outcome/error", "action_outcome/warning", "action/error"} {
+		if !codes[want] {
+			t.Errorf("missing %s in %v", want, codes)
+		}
+	}
+}
+
+func TestRunTurn_autoActions(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+
+	var hooked ActionInput
+	RegisterAction("test-autoactions", func(_ context.Context, in ActionInput) (ActionResult, error) {
+		hooked = in
+		return ActionResult{Outcome: "done"}, nil
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"outcome": "done"}`))
+	}))
+	defer srv.Close()
+
+	def, err := ParseDefinition([]byte(`name: auto
+stages:
+  - name: Gen
+    type: auto
+    outcomes: [done, regen]
+    action: "run:test -f generated || { touch generated; exit 3; }"
+    action_outcomes: {0: done, 3: regen}
+  - name: Hook
+    type: auto
+    outcomes: [done]
+    action: go:test-autoactions
+  - name: Notify
+    type: auto
+    outcomes: [done, failed]
+    action: webhook:`+srv.URL+`
+  - name: Vet
+    type: auto
+    outcomes: [done, failed]
+    action: run:exit 1
+  - name: Done
+    type: terminal
+  - name: Failed
+    type: terminal
+transitions:
+  - {from: Gen, outcome: regen, to: Gen}
+  - {from: Gen, outcome: done, to: Hook}
+  - {from: Hook, outcome: done, to: Notify}
+  - {from: Notify, outcome: done, to: Vet}
+  - {from: Notify, outcome: failed, to: Failed}
+  - {from: Vet, outcome: done, to: Done}
+  - {from: Vet, outcome: failed, to: Failed}
+`), "auto.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wfID, _, err := CreateFromDefinition(ctx, st, "t1", def, "", 0, "auto.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree := t.TempDir()
+	taskID, _ := st.CreateTask(ctx, "t1", "auto task", models.StatusInProgress, &wfID)
+	_ = st.UpdateTaskGitFields(ctx, taskID, &worktree, nil, nil, nil)
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	eng := &Engine{Store: st, Home: home}
+
+	for _, want := range []string{"Gen", "Hook", "Notify", "Vet", "Failed"} {
+		if _, err := eng.RunTurn(ctx, "t1", task, nil, nil); err != nil {
+			t.Fatalf("RunTurn (-> %s): %v", want, err)
+		}
+		if *task.CurrentStage != want {
+			t.Fatalf("stage = %s, want %s", *task.CurrentStage, want)
+		}
+	}
+	if hooked.TaskID != taskID || hooked.Stage != "Hook" || hooked.Worktree != worktree {
+		t.Errorf("go action input = %+v", hooked)
+	}
+	comments, _ := st.ListTaskComments(ctx, "t1", taskID)
+	var bodies []string
+	for _, c := range comments {
+		bodies = append(bodies, c.Body)
+	}
+	if joined := strings.Join(bodies, "\n"); !strings.Contains(joined, "exited 3: outcome \"regen\"") || !strings.Contains(joined, "202 Accepted") {
+		t.Errorf("comments = %q", joined)
+	}
+}
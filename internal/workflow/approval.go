@@ -0,0 +1,246 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ankittk/agentary/internal/review"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// Builtin approval policies. A repo's approval setting names a policy, optionally with an argument
+// after a colon ("auto_if:max_diff_lines:200;no_protected_paths:infra/").
+const (
+	ApprovalManual = "manual"  // always wait for a human
+	ApprovalAuto   = "auto"    // approve once reviews and the repo's required checks pass
+	ApprovalAutoIf = "auto_if" // like auto, and only if every guard in the ;-separated argument passes
+)
+
+// ApprovalActor is the actor recorded on transitions made by an approval policy.
+const ApprovalActor = "approval-policy"
+
+// ApprovalResult is what an approval policy decided for a task in an approval stage.
+type ApprovalResult struct {
+	Approve bool
+	Reason  string
+}
+
+// ApprovalPolicy decides whether a task entering a human approval stage (a human stage with an approved
+// outcome) is approved without waiting for a human. arg is the text after the policy name in the repo's
+// approval setting. An error leaves the task for a human.
+type ApprovalPolicy interface {
+	Decide(ctx context.Context, e *Engine, teamName string, task *store.Task, arg string) (ApprovalResult, error)
+}
+
+// ApprovalArgChecker is optionally implemented by an ApprovalPolicy to validate its argument when a
+// repo is configured with it.
+type ApprovalArgChecker interface {
+	CheckApprovalArg(arg string) error
+}
+
+// ApprovalPolicyFunc adapts a function to an ApprovalPolicy.
+type ApprovalPolicyFunc func(ctx context.Context, e *Engine, teamName string, task *store.Task, arg string) (ApprovalResult, error)
+
+// Decide calls f(ctx, e, teamName, task, arg).
+func (f ApprovalPolicyFunc) Decide(ctx context.Context, e *Engine, teamName string, task *store.Task, arg string) (ApprovalResult, error) {
+	return f(ctx, e, teamName, task, arg)
+}
+
+var (
+	approvalPoliciesMu sync.RWMutex
+	approvalPolicies   = make(map[string]ApprovalPolicy)
+)
+
+// RegisterApprovalPolicy makes name usable as a repo approval setting. It panics if name is empty,
+// contains a colon or is already registered, like RegisterStageHandler.
+func RegisterApprovalPolicy(name string, p ApprovalPolicy) {
+	approvalPoliciesMu.Lock()
+	defer approvalPoliciesMu.Unlock()
+	if name == "" || strings.Contains(name, ":") || p == nil {
+		panic("workflow: RegisterApprovalPolicy needs a name without colons and a policy")
+	}
+	if _, dup := approvalPolicies[name]; dup {
+		panic("workflow: RegisterApprovalPolicy called twice for " + name)
+	}
+	approvalPolicies[name] = p
+}
+
+// ApprovalPolicyFor returns the policy registered as name, or nil.
+func ApprovalPolicyFor(name string) ApprovalPolicy {
+	approvalPoliciesMu.RLock()
+	defer approvalPoliciesMu.RUnlock()
+	return approvalPolicies[name]
+}
+
+// ApprovalPolicies returns the registered policy names, sorted.
+func ApprovalPolicies() []string {
+	approvalPoliciesMu.RLock()
+	defer approvalPoliciesMu.RUnlock()
+	names := make([]string, 0, len(approvalPolicies))
+	for n := range approvalPolicies {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseApprovalPolicy splits a repo approval setting into a registered policy name and its argument,
+// which the policy validates when it implements ApprovalArgChecker.
+func ParseApprovalPolicy(spec string) (name, arg string, err error) {
+	name, arg, _ = strings.Cut(strings.TrimSpace(spec), ":")
+	name, arg = strings.TrimSpace(name), strings.TrimSpace(arg)
+	p := ApprovalPolicyFor(name)
+	if p == nil {
+		return name, arg, fmt.Errorf("unknown approval policy %q (want %s)", name, strings.Join(ApprovalPolicies(), ", "))
+	}
+	if c, ok := p.(ApprovalArgChecker); ok {
+		if err := c.CheckApprovalArg(arg); err != nil {
+			return name, arg, fmt.Errorf("approval policy %s: %w", name, err)
+		}
+	}
+	return name, arg, nil
+}
+
+func init() {
+	RegisterApprovalPolicy(ApprovalManual, manualPolicy{})
+	RegisterApprovalPolicy(ApprovalAuto, autoPolicy{})
+	RegisterApprovalPolicy(ApprovalAutoIf, autoIfPolicy{})
+}
+
+type manualPolicy struct{}
+
+func (manualPolicy) Decide(context.Context, *Engine, string, *store.Task, string) (ApprovalResult, error) {
+	return ApprovalResult{Reason: "repo requires human approval"}, nil
+}
+
+func (manualPolicy) CheckApprovalArg(arg string) error { return noApprovalArg(arg) }
+
+// autoPolicy approves when no reviewer's latest review requests changes and the repo's required checks
+// pass in the task worktree.
+type autoPolicy struct{}
+
+func (autoPolicy) Decide(ctx context.Context, e *Engine, teamName string, task *store.Task, _ string) (ApprovalResult, error) {
+	reviews, err := e.Store.ListTaskReviews(ctx, teamName, task.TaskID)
+	if err != nil {
+		return ApprovalResult{}, err
+	}
+	latest := make(map[string]string)
+	var blocking []string
+	for _, r := range reviews { // newest first: keep each reviewer's latest
+		if _, seen := latest[r.ReviewerAgent]; seen {
+			continue
+		}
+		latest[r.ReviewerAgent] = r.Outcome
+		if r.Outcome == review.ChangesRequested {
+			blocking = append(blocking, r.ReviewerAgent)
+		}
+	}
+	if len(blocking) > 0 {
+		sort.Strings(blocking)
+		return ApprovalResult{Reason: "changes requested by " + strings.Join(blocking, ", ")}, nil
+	}
+	if results, ok := e.CheckGuards(ctx, teamName, task, GuardTestsPass); !ok {
+		return ApprovalResult{Reason: failedGuards(results)}, nil
+	}
+	return ApprovalResult{Approve: true, Reason: "reviews and required checks passed"}, nil
+}
+
+func (autoPolicy) CheckApprovalArg(arg string) error { return noApprovalArg(arg) }
+
+// autoIfPolicy is autoPolicy restricted to tasks passing the guards in its argument, separated by
+// semicolons: "max_diff_lines:200;no_protected_paths:infra/,*.pem".
+type autoIfPolicy struct{}
+
+func (autoIfPolicy) Decide(ctx context.Context, e *Engine, teamName string, task *store.Task, arg string) (ApprovalResult, error) {
+	if results, ok := e.CheckGuards(ctx, teamName, task, strings.ReplaceAll(arg, ";", "\n")); !ok {
+		return ApprovalResult{Reason: failedGuards(results)}, nil
+	}
+	return autoPolicy{}.Decide(ctx, e, teamName, task, "")
+}
+
+func (autoIfPolicy) CheckApprovalArg(arg string) error {
+	specs := splitLines(strings.ReplaceAll(arg, ";", "\n"))
+	if len(specs) == 0 {
+		return errors.New("needs guards (e.g. auto_if:max_diff_lines:200;no_protected_paths:infra/)")
+	}
+	for _, spec := range specs {
+		if _, err := ParseGuard(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func noApprovalArg(arg string) error {
+	if arg != "" {
+		return errors.New("takes no argument")
+	}
+	return nil
+}
+
+// isApprovalStage reports whether stage is a human stage that can be approved.
+func isApprovalStage(stage *store.WorkflowStage) bool {
+	return stage != nil && stage.StageType == "human" && containsString(splitList(stage.Outcomes), review.Approved)
+}
+
+// DecideApproval evaluates the task repo's approval policy in the task's current stage and records the
+// decision on the task. A task without a repo, or whose repo names an unknown policy, waits for a
+// human. When the policy approves, the approved outcome is applied as ApprovalActor and the task is set
+// back to todo so the next stage gets a turn.
+func (e *Engine) DecideApproval(ctx context.Context, teamName string, task *store.Task) (*store.TaskApprovalDecision, error) {
+	d := store.TaskApprovalDecision{TaskID: task.TaskID, Stage: derefString(task.CurrentStage), Policy: ApprovalManual}
+	if repo := e.taskRepo(ctx, teamName, task); repo != nil && repo.Approval != "" {
+		d.Policy = repo.Approval
+	}
+	name, arg, err := ParseApprovalPolicy(d.Policy)
+	if err == nil {
+		var res ApprovalResult
+		res, err = ApprovalPolicyFor(name).Decide(ctx, e, teamName, task, arg)
+		d.Approved, d.Reason = res.Approve, res.Reason
+	}
+	if err != nil {
+		d.Approved, d.Reason = false, err.Error()
+	}
+	id, err := e.Store.CreateTaskApprovalDecision(ctx, teamName, d)
+	if err != nil {
+		return nil, err
+	}
+	d.DecisionID = id
+	if !d.Approved {
+		e.comment(ctx, teamName, task.TaskID, fmt.Sprintf("Approval policy %s: waiting for human approval (%s)", d.Policy, d.Reason))
+		return &d, nil
+	}
+	e.comment(ctx, teamName, task.TaskID, fmt.Sprintf("Approval policy %s: approved (%s)", d.Policy, d.Reason))
+	ctx = store.WithTransitionCause(ctx, store.TransitionCause{Actor: ApprovalActor, Note: fmt.Sprintf("approval policy %s: %s", d.Policy, d.Reason)})
+	var guardErr *GuardError
+	if _, err := e.ApplyOutcome(ctx, teamName, task, review.Approved); errors.As(err, &guardErr) {
+		return &d, nil // blocked by a transition guard (already commented); a human takes it from here
+	} else if err != nil {
+		return &d, err
+	}
+	if updated, _ := e.Store.GetTaskByIDAndTeam(ctx, teamName, task.TaskID); updated != nil && updated.Status == models.StatusInProgress {
+		if err := e.Store.UpdateTask(ctx, task.TaskID, models.StatusTodo, nil); err != nil {
+			return &d, err
+		}
+	}
+	return &d, nil
+}
+
+// approvalDecided reports whether the approval policy has already decided since the task entered its
+// current stage.
+func (e *Engine) approvalDecided(ctx context.Context, teamName string, task *store.Task) (bool, error) {
+	decisions, err := e.Store.ListTaskApprovalDecisions(ctx, teamName, task.TaskID)
+	if err != nil || len(decisions) == 0 {
+		return false, err
+	}
+	last := decisions[len(decisions)-1]
+	if last.Stage != derefString(task.CurrentStage) {
+		return false, nil
+	}
+	return task.StageEnteredAt == nil || !last.CreatedAt.Before(*task.StageEnteredAt), nil
+}
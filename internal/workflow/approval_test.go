@@ -0,0 +1,191 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	agentrt "github.com/ankittk/agentary/internal/agent/runtime"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// gitRun runs git in dir with a fixed identity.
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestParseApprovalPolicy(t *testing.T) {
+	t.Parallel()
+	for _, ok := range []string{"manual", "auto", " auto ", "auto_if:max_diff_lines:200", "auto_if:max_diff_lines:200;no_protected_paths:infra/,*.pem"} {
+		if _, _, err := ParseApprovalPolicy(ok); err != nil {
+			t.Errorf("ParseApprovalPolicy(%q): %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "yolo", "auto:now", "manual:x", "auto_if", "auto_if:vibes", "auto_if:max_diff_lines:lots"} {
+		if _, _, err := ParseApprovalPolicy(bad); err == nil {
+			t.Errorf("ParseApprovalPolicy(%q): expected error", bad)
+		}
+	}
+	name, arg, _ := ParseApprovalPolicy("auto_if:max_diff_lines:5")
+	if name != ApprovalAutoIf || arg != "max_diff_lines:5" {
+		t.Fatalf("ParseApprovalPolicy = %q, %q", name, arg)
+	}
+}
+
+func TestDecideApproval(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+	_ = st.CreateAgent(ctx, "t1", "alice", "engineer")
+	testCmd := "true"
+	if err := st.CreateRepo(ctx, "t1", "r1", "/tmp/r1", "auto_if:max_diff_lines:5", &testCmd); err != nil {
+		t.Fatal(err)
+	}
+	def, err := ParseDefinition([]byte(`name: approvals
+stages:
+  - name: InReview
+    type: agent
+    outcomes: [approved]
+  - name: InApproval
+    type: human
+    outcomes: [approved, changes_requested]
+  - name: Done
+    type: terminal
+transitions:
+  - {from: InReview, outcome: approved, to: InApproval}
+  - {from: InApproval, outcome: approved, to: Done}
+  - {from: InApproval, outcome: changes_requested, to: InReview}
+`), "approvals.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wfID, _, err := CreateFromDefinition(ctx, st, "t1", def, "", 0, "approvals.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Engine{Store: st, Home: home}
+
+	// newTask is a task in InReview whose branch changes lines lines of one file.
+	newTask := func(lines int) int64 {
+		wt := t.TempDir()
+		gitRun(t, wt, "init", "-q", "-b", "main")
+		gitRun(t, wt, "commit", "-q", "--allow-empty", "-m", "base")
+		base := gitRun(t, wt, "rev-parse", "HEAD")
+		gitRun(t, wt, "checkout", "-q", "-b", "feature")
+		if err := os.WriteFile(filepath.Join(wt, "f.txt"), []byte(strings.Repeat("x\n", lines)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		gitRun(t, wt, "add", "f.txt")
+		gitRun(t, wt, "commit", "-q", "-m", "change")
+		id, _ := st.CreateTask(ctx, "t1", "change", models.StatusTodo, &wfID)
+		branch, repo := "feature", "r1"
+		_ = st.UpdateTaskGitFields(ctx, id, &wt, &branch, &base, &repo)
+		_ = st.SetTaskWorkflowAndStage(ctx, id, wfID, "InReview")
+		if ok, _ := st.ClaimTask(ctx, "t1", id, "alice"); !ok {
+			t.Fatal("claim failed")
+		}
+		return id
+	}
+	load := func(id int64) *store.Task {
+		task, err := st.GetTaskByIDAndTeam(ctx, "t1", id)
+		if err != nil || task == nil {
+			t.Fatalf("GetTask: %v", err)
+		}
+		return task
+	}
+	// review approves the task into InApproval and gives it its stage turn.
+	review := func(id int64) {
+		if next, err := e.ApplyOutcome(ctx, "t1", load(id), "approved"); err != nil || next != "InApproval" {
+			t.Fatalf("approved = %q, %v", next, err)
+		}
+		if task := load(id); task.Status != models.StatusTodo {
+			t.Fatalf("status in InApproval = %s, want todo for the policy turn", task.Status)
+		}
+		_, _ = st.ClaimTask(ctx, "t1", id, "alice")
+		if _, err := e.RunTurn(ctx, "t1", load(id), &inputRuntime{}, func(agentrt.Event) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A small change passes the policy and is approved without a human.
+	small := newTask(3)
+	review(small)
+	if task := load(small); derefString(task.CurrentStage) != "Done" || task.Status != models.StatusDone {
+		t.Fatalf("small change: stage %s status %s, want Done", derefString(task.CurrentStage), task.Status)
+	}
+	decisions, _ := st.ListTaskApprovalDecisions(ctx, "t1", small)
+	if len(decisions) != 1 || !decisions[0].Approved || decisions[0].Stage != "InApproval" || decisions[0].Policy != "auto_if:max_diff_lines:5" {
+		t.Fatalf("decisions = %+v", decisions)
+	}
+	transitions, _ := st.ListTaskTransitions(ctx, "t1", small)
+	if last := transitions[len(transitions)-1]; last.Actor != ApprovalActor || last.ToStage != "Done" || !strings.Contains(last.Note, "auto_if") {
+		t.Fatalf("last transition = %+v", last)
+	}
+	if runs, _ := st.ListTaskCheckRuns(ctx, "t1", small); len(runs) != 1 || runs[0].Status != "passed" {
+		t.Fatalf("check runs = %+v", runs)
+	}
+
+	// A large change waits for a human, and later turns in the stage do not decide again.
+	large := newTask(10)
+	review(large)
+	_, _ = st.ClaimTask(ctx, "t1", large, "alice")
+	if _, err := e.RunTurn(ctx, "t1", load(large), &inputRuntime{}, func(agentrt.Event) {}); err != nil {
+		t.Fatal(err)
+	}
+	if task := load(large); derefString(task.CurrentStage) != "InApproval" {
+		t.Fatalf("large change moved to %s", derefString(task.CurrentStage))
+	}
+	decisions, _ = st.ListTaskApprovalDecisions(ctx, "t1", large)
+	if len(decisions) != 1 || decisions[0].Approved || !strings.Contains(decisions[0].Reason, "10 lines changed (limit 5)") {
+		t.Fatalf("decisions = %+v", decisions)
+	}
+	timeline, _ := BuildTimeline(ctx, st, "t1", large)
+	found := false
+	for _, entry := range timeline {
+		found = found || entry.Kind == TimelineApproval
+	}
+	if !found {
+		t.Fatalf("timeline has no approval entry: %+v", timeline)
+	}
+
+	// Manual repos and requested changes always leave the task for a human.
+	if err := st.SetRepoApproval(ctx, "t1", "r1", ApprovalManual); err != nil {
+		t.Fatal(err)
+	}
+	manual := newTask(1)
+	review(manual)
+	if task := load(manual); derefString(task.CurrentStage) != "InApproval" {
+		t.Fatalf("manual repo moved to %s", derefString(task.CurrentStage))
+	}
+	if err := st.SetRepoApproval(ctx, "t1", "r1", ApprovalAuto); err != nil {
+		t.Fatal(err)
+	}
+	blocked := newTask(1)
+	_, _ = st.CreateTaskReview(ctx, "t1", blocked, "bob", "approved", "")
+	_, _ = st.CreateTaskReview(ctx, "t1", blocked, "carol", "changes_requested", "needs tests")
+	review(blocked)
+	decisions, _ = st.ListTaskApprovalDecisions(ctx, "t1", blocked)
+	if len(decisions) != 1 || decisions[0].Approved || decisions[0].Reason != "changes requested by carol" {
+		t.Fatalf("decisions = %+v", decisions)
+	}
+}
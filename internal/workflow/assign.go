@@ -0,0 +1,60 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// PickAssignee chooses the agent the scheduler assigns a task to. A task handed back to its DRI (assignee
+// set to the DRI, e.g. after requested changes or a merge conflict) stays with the DRI when it is one of
+// the agents and allowed in the stage. Otherwise, if the task's current stage has candidate_agents, it
+// picks from that pool (a manager first); else it prefers the team's manager, else the first agent.
+// agents must not be empty.
+func PickAssignee(ctx context.Context, st store.Store, teamName string, task *store.Task, agents []store.Agent) string {
+	var pool map[string]bool
+	if task.WorkflowID != nil && *task.WorkflowID != "" && task.CurrentStage != nil && *task.CurrentStage != "" {
+		stages, err := st.GetWorkflowStages(ctx, *task.WorkflowID)
+		if err == nil {
+			for _, s := range stages {
+				if s.StageName == *task.CurrentStage && strings.TrimSpace(s.CandidateAgents) != "" {
+					pool = make(map[string]bool)
+					for _, p := range strings.Split(s.CandidateAgents, ",") {
+						pool[strings.TrimSpace(p)] = true
+					}
+				}
+			}
+		}
+	}
+	if dri := derefString(task.DRI); dri != "" && derefString(task.Assignee) == dri && (pool == nil || pool[dri]) {
+		for _, a := range agents {
+			if a.Name == dri {
+				return dri
+			}
+		}
+	}
+	if pool != nil {
+		var candidates []store.Agent
+		for _, a := range agents {
+			if pool[a.Name] {
+				candidates = append(candidates, a)
+			}
+		}
+		if len(candidates) > 0 {
+			for _, a := range candidates {
+				if a.Role == "manager" {
+					return a.Name
+				}
+			}
+			return candidates[0].Name
+		}
+	}
+	// Default: prefer manager, else first agent.
+	for _, a := range agents {
+		if a.Role == "manager" {
+			return a.Name
+		}
+	}
+	return agents[0].Name
+}
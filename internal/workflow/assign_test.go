@@ -0,0 +1,46 @@
+package workflow
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+func TestPickAssignee_keepsTaskWithDRI(t *testing.T) {
+	t.Parallel()
+	st, err := store.Open(filepath.Join(t.TempDir(), "home"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	_ = st.CreateAgent(ctx, "t1", "boss", "manager")
+	_ = st.CreateAgent(ctx, "t1", "alice", "engineer")
+	agents, _ := st.ListAgents(ctx, "t1")
+	taskID, _ := st.CreateTask(ctx, "t1", "task1", models.StatusTodo, nil)
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	if got := PickAssignee(ctx, st, "t1", task, agents); got != "boss" {
+		t.Fatalf("new task: got %q, want the manager", got)
+	}
+
+	// Claimed by alice (now the DRI), then handed back to her, e.g. after a merge conflict.
+	_, _ = st.ClaimTask(ctx, "t1", taskID, "alice")
+	dri := "alice"
+	_ = st.UpdateTask(ctx, taskID, models.StatusTodo, &dri)
+	task, _ = st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	if got := PickAssignee(ctx, st, "t1", task, agents); got != "alice" {
+		t.Errorf("task handed back to its DRI: got %q, want alice", got)
+	}
+	// Reassigned to someone else: the usual pick applies.
+	other := "boss"
+	_ = st.UpdateTask(ctx, taskID, models.StatusTodo, &other)
+	task, _ = st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	if got := PickAssignee(ctx, st, "t1", task, agents); got != "boss" {
+		t.Errorf("reassigned task: got %q, want the manager", got)
+	}
+}
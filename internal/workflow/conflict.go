@@ -0,0 +1,59 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/merge"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// conflictInput prepares a task the merge queue sent back with the conflict outcome for its agent turn: the
+// target branch is merged into the task branch without committing, leaving conflict markers to resolve, and
+// the conflicted files are appended to the input. Other turns get input unchanged.
+func conflictInput(ctx context.Context, st store.Store, teamName string, task *store.Task, input string) string {
+	if !enteredOnConflict(ctx, st, teamName, task) {
+		return input
+	}
+	wt, branch := derefString(task.WorktreePath), derefString(task.BranchName)
+	if wt == "" || branch == "" {
+		return input
+	}
+	targetName := ""
+	if repo := merge.TaskRepo(ctx, st, teamName, task); repo != nil {
+		targetName = repo.TargetBranch
+	}
+	target := git.ResolveTarget(ctx, wt, targetName)
+	head, err := git.TargetHead(ctx, wt, target)
+	var files []string
+	if err == nil {
+		files, err = git.StartResolution(ctx, wt, branch, head, fmt.Sprintf("Merge %s into %s", target, branch))
+	}
+	if err != nil {
+		slog.Warn("conflict resolution setup failed", "task_id", task.TaskID, "err", err)
+		return input + fmt.Sprintf("\n\nYour branch conflicts with %s and could not be merged; see the merge queue comment on the task.", target)
+	}
+	if len(files) == 0 {
+		return input + fmt.Sprintf("\n\nYour branch conflicted with %s; %s has now been merged into it cleanly. Check that the change still works.", target, target)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\nYour branch conflicts with %s. %s has been merged into it without committing; "+
+		"resolve the conflict markers in these files, then commit to conclude the merge:\n", input, target, target)
+	for _, f := range files {
+		fmt.Fprintf(&b, "- %s\n", f)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// enteredOnConflict reports whether the task entered its current stage with the conflict outcome.
+func enteredOnConflict(ctx context.Context, st store.Store, teamName string, task *store.Task) bool {
+	transitions, err := st.ListTaskTransitions(ctx, teamName, task.TaskID)
+	if err != nil || len(transitions) == 0 {
+		return false
+	}
+	last := transitions[len(transitions)-1]
+	return last.Outcome == merge.OutcomeConflict && last.ToStage == derefString(task.CurrentStage)
+}
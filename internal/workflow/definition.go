@@ -0,0 +1,435 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed builtin/*.yaml
+var builtinFS embed.FS
+
+// BuiltinPrefix marks a source path that names an embedded workflow definition (e.g. "builtin:default").
+const BuiltinPrefix = "builtin:"
+
+// Definition is a declarative workflow loaded from YAML:
+//
+//	name: review-heavy
+//	version: 2
+//	initial: Coding            # optional; defaults to the first stage
+//	stages:
+//	  - name: Coding
+//	    type: agent
+//	    outcomes: [submit_for_review]
+//	    candidate_agents: [alice, bob]
+//	    max_duration: 4h         # optional stage SLA
+//	    on_timeout: notify:slack
+//	  - name: Done
+//	    type: terminal
+//	transitions:
+//	  - {from: Coding, outcome: submit_for_review, to: Done}
+type Definition struct {
+	File        string // file name used in error messages
+	Name        string
+	Version     int
+	Initial     string
+	InitialLine int
+	Stages      []StageDef
+	Transitions []TransitionDef
+}
+
+// StageDef is one stage of a Definition. Line is the 1-based line of the stage in the source.
+type StageDef struct {
+	Name            string
+	Type            string
+	Outcomes        []string
+	CandidateAgents []string
+	MaxDuration     time.Duration
+	OnTimeout       string
+	OnEnter         []string // hook specs, see ParseHook
+	OnExit          []string
+	Action          string            // auto stages only, see ParseAction
+	ActionOutcomes  map[string]string // exit code (or "*") -> outcome, see ParseActionOutcomes
+	Reviewers       int               // review stages only: reviewers to fan out to
+	Quorum          int               // review stages only: approvals needed (0 = all reviewers)
+	Line            int
+}
+
+// TransitionDef maps (From, Outcome) to To. Line is the 1-based line of the transition in the source.
+// Guards must all pass (see ParseGuard); if one fails, OnGuardFail is applied instead when set.
+type TransitionDef struct {
+	From        string
+	Outcome     string
+	To          string
+	Guards      []string
+	OnGuardFail string
+	Line        int
+}
+
+// ReadSource returns the YAML for a workflow source: an embedded definition for "builtin:<name>", else a file path.
+func ReadSource(source string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(source, BuiltinPrefix); ok {
+		data, err := builtinFS.ReadFile("builtin/" + name + ".yaml")
+		if err != nil {
+			return nil, fmt.Errorf("unknown builtin workflow %q (available: %s)", name, strings.Join(BuiltinNames(), ", "))
+		}
+		return data, nil
+	}
+	return os.ReadFile(source)
+}
+
+// BuiltinNames lists the embedded workflow definitions.
+func BuiltinNames() []string {
+	entries, _ := builtinFS.ReadDir("builtin")
+	var out []string
+	for _, e := range entries {
+		out = append(out, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// LoadSource reads and parses the definition at source (file path or builtin:<name>).
+func LoadSource(source string) (*Definition, error) {
+	data, err := ReadSource(source)
+	if err != nil {
+		return nil, err
+	}
+	file := source
+	if !strings.HasPrefix(source, BuiltinPrefix) {
+		file = filepath.Base(source)
+	}
+	return ParseDefinition(data, file)
+}
+
+// ParseDefinition parses and validates a YAML workflow definition. File is used only in error messages.
+// All problems found are returned together, one per line, each prefixed with file:line.
+// Lint warnings do not fail parsing; use LintDefinition to see them.
+func ParseDefinition(data []byte, file string) (*Definition, error) {
+	def, diags := LintDefinition(data, file, nil)
+	if err := DiagnosticsError(diags); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// parseDefinition decodes the YAML into a Definition, reporting syntax and field errors only.
+func parseDefinition(data []byte, file string) (*Definition, []Diagnostic) {
+	def := &Definition{File: file}
+	var doc yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&doc); err != nil {
+		return nil, []Diagnostic{{Severity: SeverityError, Code: "syntax", File: file, Message: err.Error()}}
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, []Diagnostic{{Severity: SeverityError, Code: "syntax", File: file, Line: doc.Line, Message: "workflow definition must be a mapping"}}
+	}
+	var diags []Diagnostic
+	fail := func(line int, format string, args ...any) {
+		diags = append(diags, Diagnostic{Severity: SeverityError, Code: "syntax", File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, val := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "name":
+			def.Name = val.Value
+		case "version":
+			if err := val.Decode(&def.Version); err != nil || def.Version <= 0 {
+				fail(val.Line, "version must be a positive integer")
+			}
+		case "initial":
+			def.Initial = val.Value
+			def.InitialLine = val.Line
+		case "stages":
+			if val.Kind != yaml.SequenceNode {
+				fail(val.Line, "stages must be a list")
+				continue
+			}
+			for _, n := range val.Content {
+				st, d := parseStage(n, file)
+				diags = append(diags, d...)
+				if st != nil {
+					def.Stages = append(def.Stages, *st)
+				}
+			}
+		case "transitions":
+			if val.Kind != yaml.SequenceNode {
+				fail(val.Line, "transitions must be a list")
+				continue
+			}
+			for _, n := range val.Content {
+				tr, d := parseTransition(n, file)
+				diags = append(diags, d...)
+				if tr != nil {
+					def.Transitions = append(def.Transitions, *tr)
+				}
+			}
+		default:
+			fail(key.Line, "unknown field %q", key.Value)
+		}
+	}
+	if len(diags) > 0 {
+		return nil, diags
+	}
+	if def.Initial == "" && len(def.Stages) > 0 {
+		def.Initial = def.Stages[0].Name
+	}
+	return def, nil
+}
+
+func parseStage(n *yaml.Node, file string) (*StageDef, []Diagnostic) {
+	if n.Kind != yaml.MappingNode {
+		return nil, []Diagnostic{{Severity: SeverityError, Code: "syntax", File: file, Line: n.Line, Message: "stage must be a mapping"}}
+	}
+	st := &StageDef{Line: n.Line}
+	var diags []Diagnostic
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		var err error
+		switch key.Value {
+		case "name":
+			st.Name = val.Value
+		case "type":
+			st.Type = val.Value
+		case "outcomes":
+			st.Outcomes, err = decodeList(val)
+		case "candidate_agents":
+			st.CandidateAgents, err = decodeList(val)
+		case "max_duration":
+			st.MaxDuration, err = time.ParseDuration(val.Value)
+			if err == nil && st.MaxDuration < 0 {
+				err = errors.New("must not be negative")
+			}
+			if err != nil {
+				err = fmt.Errorf("max_duration: %v", err)
+			}
+		case "on_timeout":
+			st.OnTimeout = val.Value
+			if _, _, perr := ParseOnTimeout(val.Value); perr != nil {
+				err = perr
+			}
+		case "on_enter", "on_exit":
+			var hooks []string
+			hooks, err = decodeSpecs(val)
+			for _, h := range hooks {
+				if _, herr := ParseHook(h); herr != nil && err == nil {
+					err = fmt.Errorf("%s: %v", key.Value, herr)
+				}
+			}
+			if key.Value == "on_enter" {
+				st.OnEnter = hooks
+			} else {
+				st.OnExit = hooks
+			}
+		case "action":
+			st.Action = strings.TrimSpace(val.Value)
+			if _, aerr := ParseAction(val.Value); aerr != nil {
+				err = aerr
+			}
+		case "action_outcomes":
+			st.ActionOutcomes, err = decodeActionOutcomes(val)
+		case "reviewers", "quorum":
+			var n int
+			if n, err = strconv.Atoi(val.Value); err != nil || n < 0 {
+				err = fmt.Errorf("%s must be a non-negative number", key.Value)
+			} else if key.Value == "reviewers" {
+				st.Reviewers = n
+			} else {
+				st.Quorum = n
+			}
+		default:
+			err = fmt.Errorf("unknown stage field %q", key.Value)
+		}
+		if err != nil {
+			diags = append(diags, Diagnostic{Severity: SeverityError, Code: "syntax", File: file, Line: key.Line, Stage: st.Name, Message: err.Error()})
+		}
+	}
+	return st, diags
+}
+
+func parseTransition(n *yaml.Node, file string) (*TransitionDef, []Diagnostic) {
+	if n.Kind != yaml.MappingNode {
+		return nil, []Diagnostic{{Severity: SeverityError, Code: "syntax", File: file, Line: n.Line, Message: "transition must be a mapping"}}
+	}
+	tr := &TransitionDef{Line: n.Line}
+	var diags []Diagnostic
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		switch key.Value {
+		case "from":
+			tr.From = val.Value
+		case "outcome":
+			tr.Outcome = val.Value
+		case "to":
+			tr.To = val.Value
+		case "guards":
+			var err error
+			tr.Guards, err = decodeSpecs(val)
+			for _, g := range tr.Guards {
+				if _, gerr := ParseGuard(g); gerr != nil && err == nil {
+					err = gerr
+				}
+			}
+			if err != nil {
+				diags = append(diags, Diagnostic{Severity: SeverityError, Code: "syntax", File: file, Line: key.Line, Message: err.Error()})
+			}
+		case "on_guard_fail":
+			tr.OnGuardFail = val.Value
+		default:
+			diags = append(diags, Diagnostic{Severity: SeverityError, Code: "syntax", File: file, Line: key.Line, Message: fmt.Sprintf("unknown transition field %q", key.Value)})
+		}
+	}
+	return tr, diags
+}
+
+// decodeList accepts a YAML list of strings or a comma-separated string.
+func decodeList(n *yaml.Node) ([]string, error) {
+	var raw []string
+	switch n.Kind {
+	case yaml.SequenceNode:
+		if err := n.Decode(&raw); err != nil {
+			return nil, err
+		}
+	case yaml.ScalarNode:
+		raw = strings.Split(n.Value, ",")
+	default:
+		return nil, errors.New("expected a list")
+	}
+	var out []string
+	for _, v := range raw {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// decodeActionOutcomes accepts a mapping of exit code to outcome ({0: done, "*": failed}) or the
+// stored "0=done,*=failed" form.
+func decodeActionOutcomes(n *yaml.Node) (map[string]string, error) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		var pairs []string
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			pairs = append(pairs, n.Content[i].Value+"="+n.Content[i+1].Value)
+		}
+		return ParseActionOutcomes(strings.Join(pairs, ","))
+	case yaml.ScalarNode:
+		return ParseActionOutcomes(n.Value)
+	}
+	return nil, errors.New("action_outcomes: expected a mapping of exit code to outcome")
+}
+
+// decodeSpecs accepts a YAML list of strings or a single string; unlike decodeList it does not split on
+// commas, since hook commands and guard patterns may contain them.
+func decodeSpecs(n *yaml.Node) ([]string, error) {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		var raw []string
+		if err := n.Decode(&raw); err != nil {
+			return nil, err
+		}
+		var out []string
+		for _, v := range raw {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+		return out, nil
+	case yaml.ScalarNode:
+		if v := strings.TrimSpace(n.Value); v != "" {
+			return []string{v}, nil
+		}
+		return nil, nil
+	}
+	return nil, errors.New("expected a list")
+}
+
+// ToStore converts the definition to store rows. The initial stage is placed first,
+// which CreateWorkflowWithStages records as the workflow's initial stage.
+func (d *Definition) ToStore() ([]store.WorkflowStage, []store.WorkflowTransition) {
+	stages := make([]store.WorkflowStage, 0, len(d.Stages))
+	for _, st := range d.Stages {
+		row := store.WorkflowStage{
+			StageName:       st.Name,
+			StageType:       st.Type,
+			Outcomes:        strings.Join(st.Outcomes, ","),
+			CandidateAgents: strings.Join(st.CandidateAgents, ","),
+			MaxDuration:     st.MaxDuration,
+			OnTimeout:       st.OnTimeout,
+			OnEnter:         strings.Join(st.OnEnter, "\n"),
+			OnExit:          strings.Join(st.OnExit, "\n"),
+			Action:          st.Action,
+			ActionOutcomes:  formatActionOutcomes(st.ActionOutcomes),
+			Reviewers:       st.Reviewers,
+			Quorum:          st.Quorum,
+		}
+		if st.Name == d.Initial {
+			stages = append([]store.WorkflowStage{row}, stages...)
+		} else {
+			stages = append(stages, row)
+		}
+	}
+	transitions := make([]store.WorkflowTransition, 0, len(d.Transitions))
+	for _, tr := range d.Transitions {
+		transitions = append(transitions, store.WorkflowTransition{
+			FromStage:   tr.From,
+			Outcome:     tr.Outcome,
+			ToStage:     tr.To,
+			Guards:      strings.Join(tr.Guards, "\n"),
+			OnGuardFail: tr.OnGuardFail,
+		})
+	}
+	return stages, transitions
+}
+
+// CreateFromDefinition lints def against the team's agents and, if it has no errors, stores it in
+// one transaction. Name and version override the values in the definition when set. It returns the
+// new workflow ID and any warnings.
+func CreateFromDefinition(ctx context.Context, st store.Store, teamName string, def *Definition, name string, version int, sourcePath string) (string, []Diagnostic, error) {
+	if name == "" {
+		name = def.Name
+	}
+	if version <= 0 {
+		version = def.Version
+	}
+	if version <= 0 {
+		version = 1
+	}
+	if name == "" {
+		return "", nil, Diagnostic{Severity: SeverityError, Code: "name", File: def.File, Message: "workflow name is required (set name: in the file or pass a name)"}
+	}
+	agents, err := teamAgentNames(ctx, st, teamName)
+	if err != nil {
+		return "", nil, err
+	}
+	diags := Lint(def, agents)
+	if err := DiagnosticsError(diags); err != nil {
+		return "", diags, err
+	}
+	stages, transitions := def.ToStore()
+	wfID, err := st.CreateWorkflowWithStages(ctx, teamName, name, version, sourcePath, stages, transitions)
+	return wfID, diags, err
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
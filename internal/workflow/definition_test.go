@@ -0,0 +1,127 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func TestParseDefinition(t *testing.T) {
+	t.Parallel()
+	src := `name: triage
+version: 2
+initial: Triage
+stages:
+  - name: Done
+    type: terminal
+  - name: Triage
+    type: human
+    outcomes: [accept, reject]
+    max_duration: 24h
+    on_timeout: notify:slack
+  - name: Coding
+    type: agent
+    outcomes: done
+    candidate_agents: [alice, bob]
+transitions:
+  - {from: Triage, outcome: accept, to: Coding}
+  - {from: Triage, outcome: reject, to: Done}
+  - {from: Coding, outcome: done, to: Done}
+`
+	def, err := ParseDefinition([]byte(src), "triage.yaml")
+	if err != nil {
+		t.Fatalf("ParseDefinition: %v", err)
+	}
+	if def.Name != "triage" || def.Version != 2 || def.Initial != "Triage" {
+		t.Fatalf("header = %q v%d initial %q", def.Name, def.Version, def.Initial)
+	}
+	if def.Stages[1].MaxDuration != 24*time.Hour || def.Stages[2].CandidateAgents[1] != "bob" {
+		t.Fatalf("stages = %+v", def.Stages)
+	}
+	stages, transitions := def.ToStore()
+	if stages[0].StageName != "Triage" || stages[0].Outcomes != "accept,reject" || len(transitions) != 3 {
+		t.Fatalf("ToStore: stages %+v transitions %+v", stages, transitions)
+	}
+}
+
+func TestParseDefinition_errorsCarryLineNumbers(t *testing.T) {
+	t.Parallel()
+	src := `name: broken
+stages:
+  - name: Coding
+    type: agent
+    outcomes: [done]
+  - name: Coding
+    type: robot
+transitions:
+  - {from: Coding, outcome: shipped, to: Done}
+`
+	_, err := ParseDefinition([]byte(src), "broken.yaml")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		`broken.yaml:6: duplicate stage "Coding" (first defined on line 3)`,
+		`broken.yaml:9: stage "Coding" has no outcome "shipped"`,
+		`broken.yaml:9: transition to unknown stage "Done"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+
+	_, err = ParseDefinition([]byte("name: x\nstages:\n  - name: A\n    type: agent\n    colour: red\n"), "x.yaml")
+	if err == nil || !strings.Contains(err.Error(), `x.yaml:5: unknown stage field "colour"`) {
+		t.Fatalf("unknown field error = %v", err)
+	}
+}
+
+func TestLoadSource_builtinsAndCreate(t *testing.T) {
+	t.Parallel()
+	for _, name := range BuiltinNames() {
+		if _, err := LoadSource(BuiltinPrefix + name); err != nil {
+			t.Errorf("builtin %s: %v", name, err)
+		}
+	}
+	if _, err := LoadSource("builtin:nope"); err == nil {
+		t.Fatal("expected error for unknown builtin")
+	}
+
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+
+	def, err := LoadSource("builtin:default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wfID, _, err := CreateFromDefinition(ctx, st, "t1", def, "review", 3, "builtin:default")
+	if err != nil {
+		t.Fatalf("CreateFromDefinition: %v", err)
+	}
+	initial, err := st.GetWorkflowInitialStage(ctx, wfID)
+	if err != nil || initial != "Coding" {
+		t.Fatalf("initial stage = %q, %v", initial, err)
+	}
+	transitions, _ := st.GetWorkflowTransitions(ctx, wfID)
+	if len(transitions) != len(def.Transitions) {
+		t.Fatalf("transitions = %d, want %d", len(transitions), len(def.Transitions))
+	}
+	// Same name and version again fails as a whole and leaves no partial rows.
+	if _, _, err := CreateFromDefinition(ctx, st, "t1", def, "review", 3, "builtin:default"); err == nil {
+		t.Fatal("expected duplicate workflow error")
+	}
+}
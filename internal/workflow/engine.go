@@ -4,21 +4,28 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"time"
 
 	agentrt "github.com/ankittk/agentary/internal/agent/runtime"
-	"github.com/ankittk/agentary/internal/git"
-	"github.com/ankittk/agentary/internal/memory"
+	"github.com/ankittk/agentary/internal/capabilities"
+	"github.com/ankittk/agentary/internal/merge"
+	"github.com/ankittk/agentary/internal/review"
 	"github.com/ankittk/agentary/internal/store"
 	"github.com/ankittk/agentary/pkg/models"
 )
 
-// Engine runs workflow stages: guard (no-op for minimal), assign (use current or pick), enter (no-op), dispatch, exit (no-op).
-// For agent stage: runs runtime once; outcome drives transition. For terminal: marks task done. For human/auto: minimal stub.
+// Engine runs workflow stages: assign (use current or pick), dispatch, then guard, exit and enter on each transition.
+// Each turn is dispatched to the StageHandler registered for the stage type. Builtin handlers: agent runs the
+// runtime once and its outcome drives the transition; human waits; review fans out to reviewers and advances on a
+// quorum; auto runs the stage action (see ParseAction); plan has the agent write a plan and waits for a human to
+// approve it (ApprovePlan, RejectPlan); merge tests and merges the branch (merge.Land); terminal marks the task done.
+// Transition guards are checked in ApplyOutcome; stage on_exit/on_enter hooks run around each stage change.
+// A task entering a human approval stage is set back to todo so its turn can consult the repo's approval
+// policy (DecideApproval), which may approve it without a human.
 // If Home is set, per-agent config is loaded and journal is appended after each agent turn.
 type Engine struct {
-	Store store.Store
-	Home  string // optional: for agent config and journal
+	Store        store.Store
+	Home         string                 // optional: for agent config and journal
+	Capabilities *capabilities.Registry // optional: for notify hooks
 }
 
 // RunTurn runs one workflow turn for the task. If task has no workflow_id, returns (false, nil) so caller can use legacy flow.
@@ -59,124 +66,118 @@ func (e *Engine) RunTurn(ctx context.Context, teamName string, task *store.Task,
 		return true, nil
 	}
 
-	switch stage.StageType {
-	case "terminal":
-		_ = e.Store.UpdateTask(ctx, task.TaskID, models.StatusDone, nil)
-		return true, nil
-	case "agent":
-		// Dispatch: run runtime; outcome drives transition
-		agentName := ""
-		if task.Assignee != nil {
-			agentName = *task.Assignee
-		}
-		allowlist, _ := e.Store.ListAllowedDomains(ctx)
-		req := agentrt.TurnRequest{
-			Team:             teamName,
-			Agent:            agentName,
-			TaskID:           &task.TaskID,
-			Input:            task.Title,
-			NetworkAllowlist: allowlist,
-		}
-		if e.Home != "" && agentName != "" {
-			teamDir := memory.TeamDir(e.Home, teamName)
-			agentDir := memory.AgentDir(teamDir, agentName)
-			if cfg, _ := memory.LoadAgentConfig(agentDir); cfg != nil {
-				req.Model = cfg.Model
-				req.MaxTokens = cfg.MaxTokens
-			}
-		}
-		result, runErr := rt.RunTurn(ctx, req, emit)
-		if runErr != nil {
-			_ = e.Store.SetTaskFailed(ctx, task.TaskID)
-			return true, runErr
-		}
-		outcome := strings.TrimSpace(result.Output)
-		if outcome == "" || outcome == "stub: ok" {
-			outcome = "done"
-		}
-		// Append to agent journal after successful turn
-		if e.Home != "" && agentName != "" {
-			teamDir := memory.TeamDir(e.Home, teamName)
-			j := &memory.Journal{AgentName: agentName, TeamDir: teamDir}
-			_ = j.Append(ctx, memory.JournalEntry{
-				TaskID:    task.TaskID,
-				TaskTitle: task.Title,
-				Outcome:   outcome,
-				CreatedAt: time.Now().UTC(),
-			})
-		}
-		nextStage, err := e.transition(ctx, wfID, stageName, outcome)
-		if err != nil {
-			return true, err
-		}
-		if nextStage != "" {
-			_ = e.Store.UpdateTaskStage(ctx, task.TaskID, nextStage)
-			if e.isTerminalStage(ctx, wfID, nextStage) {
-				_ = e.Store.UpdateTask(ctx, task.TaskID, models.StatusDone, nil)
-			}
-		}
+	h := StageHandlerFor(stage.StageType)
+	if h == nil {
 		return true, nil
-	case "human", "auto":
-		if stage.StageType == "auto" {
-			nextStage, _ := e.transition(ctx, wfID, stageName, "done")
-			if nextStage != "" {
-				_ = e.Store.UpdateTaskStage(ctx, task.TaskID, nextStage)
-				if e.isTerminalStage(ctx, wfID, nextStage) {
-					_ = e.Store.UpdateTask(ctx, task.TaskID, models.StatusDone, nil)
-				}
-			}
-		}
-		return true, nil
-	case "merge":
-		// Run repo test_cmd in worktree first (CI); then merge.
-		if task.WorktreePath != nil && *task.WorktreePath != "" {
-			repos, _ := e.Store.ListRepos(ctx, teamName)
-			var repo *store.Repo
-			for i := range repos {
-				if task.RepoName != nil && repos[i].Name == *task.RepoName {
-					repo = &repos[i]
-					break
+	}
+	// Moves made by the handler are the workflow's unless it names an actor (e.g. the agent).
+	ctx = store.WithTransitionCause(ctx, store.TransitionCause{Actor: "workflow"})
+	return true, h.RunStage(ctx, e, &Turn{Team: teamName, Task: task, Stage: stage, Runtime: rt, Emit: emit})
+}
+
+// ApplyOutcome moves the task from its current stage along the transition for outcome.
+// If the transition has guards and one fails, the transition's on_guard_fail outcome is applied instead
+// (unguarded); without one the task stays put and a *GuardError is returned. The old stage's on_exit and
+// the new stage's on_enter hooks run around the move.
+// It returns the new stage ("" if no transition matched) and marks the task done when the new stage is terminal.
+func (e *Engine) ApplyOutcome(ctx context.Context, teamName string, task *store.Task, outcome string) (string, error) {
+	if task.WorkflowID == nil || *task.WorkflowID == "" || task.CurrentStage == nil {
+		return "", nil
+	}
+	wfID := *task.WorkflowID
+	from := *task.CurrentStage
+	tr, err := e.findTransition(ctx, wfID, from, outcome)
+	if err != nil || tr == nil {
+		return "", err
+	}
+	if strings.TrimSpace(tr.Guards) != "" {
+		results, ok := e.CheckGuards(ctx, teamName, task, tr.Guards)
+		if !ok {
+			gerr := &GuardError{Outcome: outcome, Results: results}
+			var fallback *store.WorkflowTransition
+			if tr.OnGuardFail != "" && tr.OnGuardFail != outcome {
+				fallback, err = e.findTransition(ctx, wfID, from, tr.OnGuardFail)
+				if err != nil {
+					return "", err
 				}
 			}
-			if repo == nil && len(repos) > 0 {
-				repo = &repos[0]
-			}
-			if repo != nil && repo.TestCmd != nil && *repo.TestCmd != "" {
-				if err := git.RunTestCmd(ctx, *task.WorktreePath, *repo.TestCmd); err != nil {
-					_ = e.Store.SetTaskFailed(ctx, task.TaskID)
-					return true, err
-				}
+			if fallback == nil {
+				e.comment(ctx, teamName, task.TaskID, gerr.Error())
+				return "", gerr
 			}
+			e.comment(ctx, teamName, task.TaskID, fmt.Sprintf("%s; applying %q instead", gerr.Error(), tr.OnGuardFail))
+			ctx = store.WithTransitionCause(ctx, store.TransitionCause{Note: gerr.Error()})
+			outcome = tr.OnGuardFail
+			tr = fallback
 		}
-		if task.WorktreePath != nil && *task.WorktreePath != "" && task.BranchName != nil && *task.BranchName != "" {
-			if err := git.MergeInWorktree(ctx, *task.WorktreePath, *task.BranchName); err != nil {
-				return true, fmt.Errorf("merge failed: %w", err)
-			}
+	}
+	stages, err := e.Store.GetWorkflowStages(ctx, wfID)
+	if err != nil {
+		return "", err
+	}
+	fromStage, toStage := findStage(stages, from), findStage(stages, tr.ToStage)
+	if fromStage != nil && fromStage.OnExit != "" {
+		e.runHooks(ctx, teamName, task, from, "on_exit", fromStage.OnExit)
+	}
+	nextStage := tr.ToStage
+	ctx = store.WithTransitionCause(ctx, store.TransitionCause{Outcome: outcome})
+	if err := e.Store.UpdateTaskStage(ctx, task.TaskID, nextStage); err != nil {
+		return "", err
+	}
+	task.CurrentStage = &nextStage
+	if toStage != nil && toStage.StageType == "review" {
+		if err := review.StartRound(ctx, e.Store, teamName, task); err != nil {
+			return nextStage, err
 		}
-		nextStage, _ := e.transition(ctx, wfID, stageName, "done")
-		if nextStage != "" {
-			_ = e.Store.UpdateTaskStage(ctx, task.TaskID, nextStage)
-			if e.isTerminalStage(ctx, wfID, nextStage) {
-				_ = e.Store.UpdateTask(ctx, task.TaskID, models.StatusDone, nil)
-			}
+	}
+	if toStage != nil && toStage.OnEnter != "" {
+		e.runHooks(ctx, teamName, task, nextStage, "on_enter", toStage.OnEnter)
+	}
+	if toStage != nil && toStage.StageType == "terminal" {
+		_ = e.Store.UpdateTask(ctx, task.TaskID, models.StatusDone, nil)
+	}
+	if isApprovalStage(toStage) {
+		// Give the stage a turn so the repo's approval policy decides (see DecideApproval).
+		if updated, _ := e.Store.GetTaskByIDAndTeam(ctx, teamName, task.TaskID); updated != nil && updated.Status == models.StatusInProgress {
+			_ = e.Store.UpdateTask(ctx, task.TaskID, models.StatusTodo, nil)
 		}
-		return true, nil
-	default:
-		return true, nil
 	}
+	return nextStage, nil
 }
 
 func (e *Engine) transition(ctx context.Context, workflowID, fromStage, outcome string) (toStage string, err error) {
+	tr, err := e.findTransition(ctx, workflowID, fromStage, outcome)
+	if err != nil || tr == nil {
+		return "", err
+	}
+	return tr.ToStage, nil
+}
+
+func (e *Engine) findTransition(ctx context.Context, workflowID, fromStage, outcome string) (*store.WorkflowTransition, error) {
 	transitions, err := e.Store.GetWorkflowTransitions(ctx, workflowID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	for _, t := range transitions {
-		if t.FromStage == fromStage && t.Outcome == outcome {
-			return t.ToStage, nil
+	for i := range transitions {
+		if transitions[i].FromStage == fromStage && transitions[i].Outcome == outcome {
+			return &transitions[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// taskRepo returns the task's repo, or the team's first repo when the task names none.
+func (e *Engine) taskRepo(ctx context.Context, teamName string, task *store.Task) *store.Repo {
+	return merge.TaskRepo(ctx, e.Store, teamName, task)
+}
+
+func findStage(stages []store.WorkflowStage, name string) *store.WorkflowStage {
+	for i := range stages {
+		if stages[i].StageName == name {
+			return &stages[i]
 		}
 	}
-	return "", nil
+	return nil
 }
 
 func (e *Engine) isTerminalStage(ctx context.Context, workflowID, stageName string) bool {
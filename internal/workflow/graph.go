@@ -0,0 +1,236 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// Graph formats accepted by Graph.Render.
+const (
+	GraphMermaid = "mermaid"
+	GraphDot     = "dot"
+	GraphJSON    = "json"
+)
+
+// Graph is a workflow as nodes (stages) and edges (transitions), for diagrams.
+type Graph struct {
+	Workflow string      `json:"workflow"` // name@version
+	Initial  string      `json:"initial"`
+	Nodes    []GraphNode `json:"nodes"`
+	Edges    []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a stage. Tasks is the number of open tasks in the stage, set only when counts were requested.
+type GraphNode struct {
+	Stage           string   `json:"stage"`
+	Type            string   `json:"type"`
+	CandidateAgents []string `json:"candidate_agents,omitempty"`
+	Tasks           *int     `json:"tasks,omitempty"`
+}
+
+// GraphEdge is a transition labeled by its outcome.
+type GraphEdge struct {
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Outcome     string   `json:"outcome"`
+	Guards      []string `json:"guards,omitempty"`
+	OnGuardFail string   `json:"on_guard_fail,omitempty"`
+}
+
+// BuildGraph loads the workflow ref (name@version) as a Graph. With counts, each node carries the number
+// of open tasks currently in that stage.
+func BuildGraph(ctx context.Context, st store.Store, teamName, ref string, counts bool) (*Graph, error) {
+	name, version, err := ParseWorkflowRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	wfID, err := resolveWorkflowRef(ctx, st, teamName, ref)
+	if err != nil {
+		return nil, err
+	}
+	stages, err := st.GetWorkflowStages(ctx, wfID)
+	if err != nil {
+		return nil, err
+	}
+	transitions, err := st.GetWorkflowTransitions(ctx, wfID)
+	if err != nil {
+		return nil, err
+	}
+	g := &Graph{Workflow: fmt.Sprintf("%s@%d", name, version)}
+	g.Initial, _ = st.GetWorkflowInitialStage(ctx, wfID)
+	var perStage map[string]int
+	if counts {
+		tasks, err := st.ListOpenTasksInWorkflow(ctx, wfID)
+		if err != nil {
+			return nil, err
+		}
+		perStage = make(map[string]int)
+		for _, t := range tasks {
+			perStage[derefString(t.CurrentStage)]++
+		}
+	}
+	for _, s := range stages {
+		n := GraphNode{Stage: s.StageName, Type: s.StageType, CandidateAgents: splitList(s.CandidateAgents)}
+		if counts {
+			c := perStage[s.StageName]
+			n.Tasks = &c
+		}
+		g.Nodes = append(g.Nodes, n)
+	}
+	for _, tr := range transitions {
+		g.Edges = append(g.Edges, GraphEdge{From: tr.FromStage, To: tr.ToStage, Outcome: tr.Outcome, Guards: splitLines(tr.Guards), OnGuardFail: tr.OnGuardFail})
+	}
+	return g, nil
+}
+
+// Render formats the graph as a Mermaid flowchart, a Graphviz digraph or indented JSON.
+func (g *Graph) Render(format string) (string, error) {
+	switch format {
+	case GraphMermaid, "":
+		return g.mermaid(), nil
+	case GraphDot:
+		return g.dot(), nil
+	case GraphJSON:
+		b, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(b) + "\n", nil
+	}
+	return "", fmt.Errorf("graph format %q: want mermaid, dot or json", format)
+}
+
+// graphStyle is how a stage type is drawn: Mermaid node brackets, Graphviz shape and a fill colour.
+type graphStyle struct {
+	open, close string
+	shape       string
+	fill        string
+}
+
+var graphStyles = map[string]graphStyle{
+	"agent":    {`["`, `"]`, "box", "#dbeafe"},
+	"review":   {`{{"`, `"}}`, "hexagon", "#fef3c7"},
+	"plan":     {`[/"`, `"/]`, "note", "#ffedd5"},
+	"human":    {`(["`, `"])`, "ellipse", "#fce7f3"},
+	"auto":     {`[["`, `"]]`, "component", "#e0e7ff"},
+	"merge":    {`[("`, `")]`, "cylinder", "#dcfce7"},
+	"terminal": {`(("`, `"))`, "doublecircle", "#e5e7eb"},
+}
+
+func styleFor(stageType string) graphStyle {
+	if s, ok := graphStyles[stageType]; ok {
+		return s
+	}
+	return graphStyles["agent"]
+}
+
+// labelLines is a node's label: stage name, type, candidate pool and task count.
+func (n GraphNode) labelLines() []string {
+	lines := []string{n.Stage, n.Type}
+	if len(n.CandidateAgents) > 0 {
+		lines = append(lines, "agents: "+strings.Join(n.CandidateAgents, ", "))
+	}
+	if n.Tasks != nil {
+		lines = append(lines, fmt.Sprintf("%d open", *n.Tasks))
+	}
+	return lines
+}
+
+func (e GraphEdge) labelLines() []string {
+	lines := []string{e.Outcome}
+	if len(e.Guards) > 0 {
+		guard := "if " + strings.Join(e.Guards, ", ")
+		if e.OnGuardFail != "" {
+			guard += " else " + e.OnGuardFail
+		}
+		lines = append(lines, guard)
+	}
+	return lines
+}
+
+func (g *Graph) mermaid() string {
+	ids := make(map[string]string, len(g.Nodes))
+	var b strings.Builder
+	fmt.Fprintf(&b, "---\ntitle: %s\n---\nflowchart LR\n", g.Workflow)
+	for i, n := range g.Nodes {
+		ids[n.Stage] = fmt.Sprintf("s%d", i)
+		st := styleFor(n.Type)
+		fmt.Fprintf(&b, "  %s%s%s%s:::%s\n", ids[n.Stage], st.open, mermaidText(n.labelLines()), st.close, mermaidClass(n.Type))
+	}
+	if id, ok := ids[g.Initial]; ok {
+		fmt.Fprintf(&b, "  start((\" \")) --> %s\n", id)
+	}
+	for _, e := range g.Edges {
+		from, to := ids[e.From], ids[e.To]
+		if from == "" || to == "" {
+			continue // dangling transitions are reported by lint, not drawn
+		}
+		arrow := "-->"
+		if len(e.Guards) > 0 {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|\"%s\"| %s\n", from, arrow, mermaidText(e.labelLines()), to)
+	}
+	used := make(map[string]bool)
+	var types []string
+	for _, n := range g.Nodes {
+		if t := mermaidClass(n.Type); !used[t] {
+			used[t] = true
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:#374151\n", t, graphStyles[t].fill)
+	}
+	return b.String()
+}
+
+func mermaidClass(stageType string) string {
+	if _, ok := graphStyles[stageType]; ok {
+		return stageType
+	}
+	return "agent"
+}
+
+// mermaidText joins label lines with <br/> and escapes characters that end a quoted label.
+func mermaidText(lines []string) string {
+	r := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "|", "#124;")
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = r.Replace(l)
+	}
+	return strings.Join(out, "<br/>")
+}
+
+func (g *Graph) dot() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n  rankdir=LR;\n  node [style=filled, fontname=\"Helvetica\"];\n  edge [fontname=\"Helvetica\", fontsize=10];\n", dotQuote(g.Workflow))
+	for _, n := range g.Nodes {
+		st := styleFor(n.Type)
+		fmt.Fprintf(&b, "  %s [shape=%s, fillcolor=%s, label=%s];\n", dotQuote(n.Stage), st.shape, dotQuote(st.fill), dotQuote(strings.Join(n.labelLines(), "\n")))
+	}
+	if g.Initial != "" {
+		fmt.Fprintf(&b, "  __start [shape=point, label=\"\"];\n  __start -> %s;\n", dotQuote(g.Initial))
+	}
+	for _, e := range g.Edges {
+		attrs := "label=" + dotQuote(strings.Join(e.labelLines(), "\n"))
+		if len(e.Guards) > 0 {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(e.From), dotQuote(e.To), attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote quotes s as a Graphviz ID; newlines become centred line breaks.
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
@@ -0,0 +1,115 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func TestBuildGraph(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+
+	def, err := ParseDefinition([]byte(`name: graph
+stages:
+  - name: Coding
+    type: agent
+    outcomes: [done]
+    candidate_agents: [alice, bob]
+  - name: Code Review
+    type: review
+    outcomes: [approved, changes_requested]
+  - name: Done
+    type: terminal
+transitions:
+  - {from: Coding, outcome: done, to: Code Review}
+  - {from: Code Review, outcome: approved, to: Done, guards: ["min_approvals:2"], on_guard_fail: changes_requested}
+  - {from: Code Review, outcome: changes_requested, to: Coding}
+`), "graph.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wfID, _, err := CreateFromDefinition(ctx, st, "t1", def, "", 0, "graph.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = st.CreateTask(ctx, "t1", "a", "todo", &wfID)
+	_, _ = st.CreateTask(ctx, "t1", "b", "todo", &wfID)
+
+	g, err := BuildGraph(ctx, st, "t1", "graph@1", true)
+	if err != nil {
+		t.Fatalf("BuildGraph: %v", err)
+	}
+	if g.Initial != "Coding" || len(g.Nodes) != 3 || len(g.Edges) != 3 {
+		t.Fatalf("graph = %+v", g)
+	}
+	for _, n := range g.Nodes {
+		want := 0
+		if n.Stage == "Coding" {
+			want = 2
+		}
+		if n.Tasks == nil || *n.Tasks != want {
+			t.Errorf("%s tasks = %v, want %d", n.Stage, n.Tasks, want)
+		}
+	}
+
+	mermaid, _ := g.Render(GraphMermaid)
+	for _, want := range []string{
+		"flowchart LR",
+		`["Coding<br/>agent<br/>agents: alice, bob<br/>2 open"]:::agent`,
+		`{{"Code Review<br/>review<br/>0 open"}}:::review`,
+		`-.->|"approved<br/>if min_approvals:2 else changes_requested"|`,
+		"classDef terminal",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("mermaid missing %q:\n%s", want, mermaid)
+		}
+	}
+	if strings.Contains(mermaid, "classDef merge") {
+		t.Errorf("mermaid defines unused classes:\n%s", mermaid)
+	}
+
+	dot, _ := g.Render(GraphDot)
+	for _, want := range []string{
+		`digraph "graph@1" {`,
+		`"Code Review" [shape=hexagon`,
+		`__start -> "Coding";`,
+		`"Code Review" -> "Done" [label="approved\nif min_approvals:2 else changes_requested", style=dashed];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot missing %q:\n%s", want, dot)
+		}
+	}
+
+	js, _ := g.Render(GraphJSON)
+	var back Graph
+	if err := json.Unmarshal([]byte(js), &back); err != nil || len(back.Edges) != 3 {
+		t.Errorf("json round trip: %v %+v", err, back)
+	}
+	if _, err := g.Render("svg"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+
+	g, _ = BuildGraph(ctx, st, "t1", "graph", false)
+	if g.Nodes[0].Tasks != nil {
+		t.Error("counts set without being requested")
+	}
+	if _, err := BuildGraph(ctx, st, "t1", "graph@2", false); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
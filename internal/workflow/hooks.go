@@ -0,0 +1,150 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ankittk/agentary/internal/sandbox"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// Hook kinds for stage on_enter/on_exit.
+const (
+	HookRun     = "run"     // run:<command> runs sh -c in the task worktree under the sandbox
+	HookNotify  = "notify"  // notify:<capability>[:<message>] sends through the capability registry
+	HookComment = "comment" // comment:<text> posts a task comment
+)
+
+// hookTimeout bounds run: hooks so a stuck command cannot hold up the transition.
+const hookTimeout = 5 * time.Minute
+
+// hookAuthor is the comment author for hook and guard comments.
+const hookAuthor = "workflow"
+
+// Hook is a parsed on_enter/on_exit spec. Target is the capability for notify hooks.
+type Hook struct {
+	Kind   string
+	Target string
+	Arg    string
+}
+
+// ParseHook parses a hook spec: run:<command>, notify:<capability>[:<message>] or comment:<text>.
+// Messages and comments are Go templates over .Team, .TaskID, .Title and .Stage.
+func ParseHook(spec string) (Hook, error) {
+	kind, rest, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	h := Hook{Kind: strings.TrimSpace(kind)}
+	switch h.Kind {
+	case HookRun, HookComment:
+		h.Arg = strings.TrimSpace(rest)
+		if h.Arg == "" {
+			return h, fmt.Errorf("hook %s needs an argument", h.Kind)
+		}
+	case HookNotify:
+		target, msg, _ := strings.Cut(rest, ":")
+		h.Target, h.Arg = strings.TrimSpace(target), strings.TrimSpace(msg)
+		if h.Target == "" {
+			return h, fmt.Errorf("hook notify needs a capability (e.g. notify:slack)")
+		}
+	default:
+		return h, fmt.Errorf("unknown hook %q (want run:<command>, notify:<capability>[:<message>] or comment:<text>)", h.Kind)
+	}
+	if h.Kind == HookRun && sandbox.BlockedShellCommand(h.Arg) {
+		return h, fmt.Errorf("hook run: command is blocked by the sandbox deny list")
+	}
+	if h.Kind != HookRun && h.Arg != "" {
+		if _, err := template.New("hook").Parse(h.Arg); err != nil {
+			return h, fmt.Errorf("hook %s: %w", h.Kind, err)
+		}
+	}
+	return h, nil
+}
+
+type hookData struct {
+	Team   string
+	TaskID int64
+	Title  string
+	Stage  string
+}
+
+// runHooks runs the newline-separated hook specs for a task entering or leaving stage. Hooks are
+// best effort: a failure is logged and posted as a task comment but does not block the transition.
+func (e *Engine) runHooks(ctx context.Context, teamName string, task *store.Task, stage, when, specs string) {
+	for _, spec := range splitLines(specs) {
+		h, err := ParseHook(spec)
+		if err == nil {
+			err = e.runHook(ctx, teamName, task, stage, h)
+		}
+		if err != nil {
+			slog.Warn("workflow hook failed", "team", teamName, "task_id", task.TaskID, "stage", stage, "hook", when, "spec", spec, "err", err)
+			e.comment(ctx, teamName, task.TaskID, fmt.Sprintf("%s hook `%s` on %s failed: %v", when, spec, stage, err))
+		}
+	}
+}
+
+func (e *Engine) runHook(ctx context.Context, teamName string, task *store.Task, stage string, h Hook) error {
+	data := hookData{Team: teamName, TaskID: task.TaskID, Title: task.Title, Stage: stage}
+	switch h.Kind {
+	case HookRun:
+		if task.WorktreePath == nil || *task.WorktreePath == "" {
+			return fmt.Errorf("task has no worktree")
+		}
+		ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+		defer cancel()
+		cmd := sandbox.WrapCommand(ctx, sandbox.HomeFor(e.Home, *task.WorktreePath), *task.WorktreePath, "sh", []string{"-c", h.Arg})
+		cmd.Dir = *task.WorktreePath
+		cmd.Env = append(os.Environ(),
+			"AGENTARY_TEAM="+teamName,
+			fmt.Sprintf("AGENTARY_TASK_ID=%d", task.TaskID),
+			"AGENTARY_STAGE="+stage,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	case HookNotify:
+		msg := h.Arg
+		if msg == "" {
+			msg = "[{{.Team}}] Task #{{.TaskID}} {{printf \"%q\" .Title}} is now in {{.Stage}}"
+		}
+		text, err := renderHookText(msg, data)
+		if err != nil {
+			return err
+		}
+		if e.Capabilities == nil || e.Capabilities.Get(h.Target) == nil {
+			return fmt.Errorf("capability %q not configured", h.Target)
+		}
+		return e.Capabilities.Notify(ctx, h.Target, text)
+	case HookComment:
+		text, err := renderHookText(h.Arg, data)
+		if err != nil {
+			return err
+		}
+		_, err = e.Store.CreateTaskComment(ctx, teamName, task.TaskID, hookAuthor, text)
+		return err
+	}
+	return fmt.Errorf("unknown hook %q", h.Kind)
+}
+
+func renderHookText(text string, data hookData) (string, error) {
+	tmpl, err := template.New("hook").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (e *Engine) comment(ctx context.Context, teamName string, taskID int64, body string) {
+	if _, err := e.Store.CreateTaskComment(ctx, teamName, taskID, hookAuthor, body); err != nil {
+		slog.Warn("workflow comment failed", "task_id", taskID, "err", err)
+	}
+}
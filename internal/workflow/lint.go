@@ -0,0 +1,317 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// Diagnostic severities. Errors block creating a workflow; warnings are reported only.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is one problem found in a workflow definition. Line is 0 when the
+// workflow was loaded from the store rather than from YAML.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Stage    string `json:"stage,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) Error() string {
+	file := d.File
+	if file == "" {
+		file = "workflow"
+	}
+	msg := d.Message
+	if d.Severity == SeverityWarning {
+		msg = "warning: " + msg
+	}
+	if d.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", file, d.Line, msg)
+	}
+	return fmt.Sprintf("%s: %s", file, msg)
+}
+
+// DiagnosticsError joins the error-severity diagnostics into one error, or returns nil if there are none.
+func DiagnosticsError(diags []Diagnostic) error {
+	var errs []error
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LintDefinition parses data and lints the result. The definition is nil if it could not be parsed.
+// If agents is nil, candidate agents are not checked.
+func LintDefinition(data []byte, file string, agents []string) (*Definition, []Diagnostic) {
+	def, diags := parseDefinition(data, file)
+	if def == nil {
+		return nil, diags
+	}
+	return def, Lint(def, agents)
+}
+
+// LintStored lints a workflow already in the store against the team's agents.
+func LintStored(ctx context.Context, st store.Store, teamName, workflowID string) ([]Diagnostic, error) {
+	def, err := LoadStored(ctx, st, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	agents, err := teamAgentNames(ctx, st, teamName)
+	if err != nil {
+		return nil, err
+	}
+	return Lint(def, agents), nil
+}
+
+// LoadStored rebuilds a Definition from a stored workflow's stages and transitions.
+func LoadStored(ctx context.Context, st store.Store, workflowID string) (*Definition, error) {
+	stages, err := st.GetWorkflowStages(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	transitions, err := st.GetWorkflowTransitions(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	def := &Definition{File: "workflow " + workflowID}
+	def.Initial, _ = st.GetWorkflowInitialStage(ctx, workflowID)
+	for _, s := range stages {
+		actionOutcomes, _ := ParseActionOutcomes(s.ActionOutcomes)
+		def.Stages = append(def.Stages, StageDef{
+			Name:            s.StageName,
+			Type:            s.StageType,
+			Outcomes:        splitList(s.Outcomes),
+			CandidateAgents: splitList(s.CandidateAgents),
+			MaxDuration:     s.MaxDuration,
+			OnTimeout:       s.OnTimeout,
+			OnEnter:         splitLines(s.OnEnter),
+			OnExit:          splitLines(s.OnExit),
+			Action:          s.Action,
+			ActionOutcomes:  actionOutcomes,
+			Reviewers:       s.Reviewers,
+			Quorum:          s.Quorum,
+		})
+	}
+	for _, t := range transitions {
+		def.Transitions = append(def.Transitions, TransitionDef{
+			From:        t.FromStage,
+			Outcome:     t.Outcome,
+			To:          t.ToStage,
+			Guards:      splitLines(t.Guards),
+			OnGuardFail: t.OnGuardFail,
+		})
+	}
+	return def, nil
+}
+
+// Lint checks the workflow graph: stage names and types, the initial and terminal stages,
+// transitions (missing stages, undeclared or unmapped outcomes, duplicates, guard fallbacks), stages that cannot
+// be reached or cannot be left, auto-stage actions and their exit-code mapping, and candidate agents (or an
+// on_timeout reassign agent) that are not on the team. If agents is nil, agents are not checked.
+func Lint(def *Definition, agents []string) []Diagnostic {
+	var diags []Diagnostic
+	add := func(severity, code string, line int, stage, format string, args ...any) {
+		diags = append(diags, Diagnostic{Severity: severity, Code: code, File: def.File, Line: line, Stage: stage, Message: fmt.Sprintf(format, args...)})
+	}
+	if len(def.Stages) == 0 {
+		add(SeverityError, "no_stages", 0, "", "at least one stage is required")
+		return diags
+	}
+
+	stages := make(map[string]*StageDef)
+	hasTerminal := false
+	for i := range def.Stages {
+		st := &def.Stages[i]
+		if st.Name == "" {
+			add(SeverityError, "stage_name", st.Line, "", "stage name is required")
+			continue
+		}
+		if prev, dup := stages[st.Name]; dup {
+			add(SeverityError, "duplicate_stage", st.Line, st.Name, "duplicate stage %q (first defined on line %d)", st.Name, prev.Line)
+			continue
+		}
+		stages[st.Name] = st
+		if h := StageHandlerFor(st.Type); h == nil {
+			add(SeverityError, "stage_type", st.Line, st.Name, "stage %q: unknown type %q (want one of %s)", st.Name, st.Type, strings.Join(StageTypes(), ", "))
+		} else if l, ok := h.(StageLinter); ok {
+			for _, msg := range l.LintStage(st) {
+				add(SeverityError, "stage_config", st.Line, st.Name, "stage %q: %s", st.Name, msg)
+			}
+		}
+		if st.Type == "terminal" {
+			hasTerminal = true
+			if len(st.Outcomes) > 0 {
+				add(SeverityError, "terminal_outcomes", st.Line, st.Name, "stage %q: terminal stages have no outcomes", st.Name)
+			}
+		}
+		lintAction(st, add)
+		if (st.Reviewers > 0 || st.Quorum > 0) && st.Type != "review" {
+			add(SeverityError, "stage_config", st.Line, st.Name, "stage %q: reviewers and quorum apply only to review stages (type is %q)", st.Name, st.Type)
+		}
+		if agents != nil {
+			for _, a := range st.CandidateAgents {
+				if !containsString(agents, a) {
+					add(SeverityWarning, "unknown_agent", st.Line, st.Name, "stage %q: candidate agent %q is not on the team", st.Name, a)
+				}
+			}
+			if action, arg, err := ParseOnTimeout(st.OnTimeout); err == nil && action == OnTimeoutReassign && arg != "" && !containsString(agents, arg) {
+				add(SeverityWarning, "unknown_agent", st.Line, st.Name, "stage %q: on_timeout reassigns to %q, which is not on the team", st.Name, arg)
+			}
+		}
+	}
+	if !hasTerminal {
+		add(SeverityError, "no_terminal", 0, "", "no terminal stage; tasks can never finish")
+	}
+	switch {
+	case def.Initial == "":
+		add(SeverityError, "no_initial", 0, "", "no initial stage")
+	case stages[def.Initial] == nil:
+		add(SeverityError, "no_initial", def.InitialLine, def.Initial, "initial stage %q is not defined", def.Initial)
+	}
+
+	mapped := make(map[[2]string]int)
+	next := make(map[string][]string)
+	for _, tr := range def.Transitions {
+		if tr.From == "" || tr.Outcome == "" || tr.To == "" {
+			add(SeverityError, "transition", tr.Line, tr.From, "transition requires from, outcome and to")
+			continue
+		}
+		from := stages[tr.From]
+		if from == nil {
+			add(SeverityError, "missing_stage", tr.Line, tr.From, "transition from unknown stage %q", tr.From)
+		} else if !containsString(from.Outcomes, tr.Outcome) {
+			add(SeverityError, "undeclared_outcome", tr.Line, tr.From, "stage %q has no outcome %q (outcomes: %s)", tr.From, tr.Outcome, strings.Join(from.Outcomes, ", "))
+		}
+		if stages[tr.To] == nil {
+			add(SeverityError, "missing_stage", tr.Line, tr.From, "transition to unknown stage %q", tr.To)
+		}
+		key := [2]string{tr.From, tr.Outcome}
+		if prev, dup := mapped[key]; dup {
+			add(SeverityError, "duplicate_transition", tr.Line, tr.From, "duplicate transition for %s/%s (first defined on line %d)", tr.From, tr.Outcome, prev)
+			continue
+		}
+		mapped[key] = tr.Line
+		next[tr.From] = append(next[tr.From], tr.To)
+	}
+
+	for _, tr := range def.Transitions {
+		if tr.OnGuardFail == "" {
+			continue
+		}
+		if len(tr.Guards) == 0 {
+			add(SeverityWarning, "guard_fallback", tr.Line, tr.From, "transition %s/%s has on_guard_fail but no guards", tr.From, tr.Outcome)
+		} else if _, ok := mapped[[2]string{tr.From, tr.OnGuardFail}]; !ok || tr.OnGuardFail == tr.Outcome {
+			add(SeverityError, "guard_fallback", tr.Line, tr.From, "transition %s/%s: on_guard_fail %q must be another outcome of %s with a transition", tr.From, tr.Outcome, tr.OnGuardFail, tr.From)
+		}
+	}
+
+	for i := range def.Stages {
+		st := &def.Stages[i]
+		if stages[st.Name] != st || st.Type == "terminal" {
+			continue // unnamed or duplicate (already reported), or terminal
+		}
+		if len(next[st.Name]) == 0 {
+			add(SeverityError, "no_exit", st.Line, st.Name, "stage %q is not terminal but has no transitions out; tasks would be stuck", st.Name)
+			continue
+		}
+		for _, o := range st.Outcomes {
+			if _, ok := mapped[[2]string{st.Name, o}]; !ok {
+				add(SeverityError, "unmapped_outcome", st.Line, st.Name, "stage %q: outcome %q has no transition; tasks would stay in the stage", st.Name, o)
+			}
+		}
+	}
+
+	if stages[def.Initial] != nil {
+		reached := map[string]bool{def.Initial: true}
+		queue := []string{def.Initial}
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, to := range next[cur] {
+				if !reached[to] {
+					reached[to] = true
+					queue = append(queue, to)
+				}
+			}
+		}
+		for i := range def.Stages {
+			st := &def.Stages[i]
+			if stages[st.Name] == st && !reached[st.Name] {
+				add(SeverityWarning, "unreachable", st.Line, st.Name, "stage %q is unreachable from initial stage %q", st.Name, def.Initial)
+			}
+		}
+	}
+	return diags
+}
+
+// lintAction checks an auto stage's action: only auto stages run one, every exit code must map to a
+// declared outcome (including the 0=done / non-zero=failed defaults when not overridden), and go: actions
+// should be registered in this binary.
+func lintAction(st *StageDef, add func(severity, code string, line int, stage, format string, args ...any)) {
+	if st.Action == "" {
+		if len(st.ActionOutcomes) > 0 {
+			add(SeverityError, "action", st.Line, st.Name, "stage %q: action_outcomes without an action", st.Name)
+		}
+		return
+	}
+	if st.Type != "auto" {
+		add(SeverityError, "action", st.Line, st.Name, "stage %q: only auto stages run an action (type is %q)", st.Name, st.Type)
+		return
+	}
+	a, err := ParseAction(st.Action)
+	if err != nil {
+		add(SeverityError, "action", st.Line, st.Name, "stage %q: %v", st.Name, err)
+		return
+	}
+	if a.Kind == ActionGo && lookupAction(a.Arg) == nil {
+		add(SeverityWarning, "unknown_action", st.Line, st.Name, "stage %q: action %q is not registered in this binary", st.Name, a.Arg)
+	}
+	for _, code := range sortedKeys(st.ActionOutcomes) {
+		if o := st.ActionOutcomes[code]; !containsString(st.Outcomes, o) {
+			add(SeverityError, "action_outcome", st.Line, st.Name, "stage %q: exit code %s maps to undeclared outcome %q", st.Name, code, o)
+		}
+	}
+	if _, ok := st.ActionOutcomes["*"]; ok {
+		return
+	}
+	if _, ok := st.ActionOutcomes["0"]; !ok && !containsString(st.Outcomes, "done") {
+		add(SeverityWarning, "action_outcome", st.Line, st.Name, "stage %q: exit code 0 defaults to outcome \"done\", which is not declared; the task would fail", st.Name)
+	}
+	if !containsString(st.Outcomes, "failed") {
+		add(SeverityWarning, "action_outcome", st.Line, st.Name, "stage %q: non-zero exit codes default to outcome \"failed\", which is not declared; map them with \"*\" or the task fails", st.Name)
+	}
+}
+
+func teamAgentNames(ctx context.Context, st store.Store, teamName string) ([]string, error) {
+	list, err := st.ListAgents(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(list))
+	for _, a := range list {
+		names = append(names, a.Name)
+	}
+	return names, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
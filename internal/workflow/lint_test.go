@@ -0,0 +1,136 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func diagCodes(diags []Diagnostic) map[string]Diagnostic {
+	out := make(map[string]Diagnostic)
+	for _, d := range diags {
+		out[d.Code+"/"+d.Stage] = d
+	}
+	return out
+}
+
+func TestLint_graphProblems(t *testing.T) {
+	t.Parallel()
+	src := `name: messy
+stages:
+  - name: Coding
+    type: agent
+    outcomes: [submit, abandon]
+    candidate_agents: [alice, ghost]
+  - name: Review
+    type: human
+    outcomes: [approved]
+    on_timeout: reassign:casper
+  - name: Orphan
+    type: auto
+    outcomes: [done]
+  - name: Done
+    type: terminal
+transitions:
+  - {from: Coding, outcome: submit, to: Review}
+  - {from: Orphan, outcome: done, to: Done}
+`
+	def, diags := LintDefinition([]byte(src), "messy.yaml", []string{"alice"})
+	if def == nil {
+		t.Fatalf("parse failed: %v", diags)
+	}
+	got := diagCodes(diags)
+	for key, want := range map[string]struct {
+		severity string
+		line     int
+	}{
+		"unmapped_outcome/Coding": {SeverityError, 3},
+		"unknown_agent/Coding":    {SeverityWarning, 3},
+		"no_exit/Review":          {SeverityError, 7},
+		"unknown_agent/Review":    {SeverityWarning, 7},
+		"unreachable/Orphan":      {SeverityWarning, 11},
+		"unreachable/Done":        {SeverityWarning, 14},
+	} {
+		d, ok := got[key]
+		if !ok {
+			t.Errorf("missing diagnostic %s in %v", key, diags)
+			continue
+		}
+		if d.Severity != want.severity || d.Line != want.line {
+			t.Errorf("%s = %s line %d, want %s line %d", key, d.Severity, d.Line, want.severity, want.line)
+		}
+	}
+	if DiagnosticsError(diags) == nil {
+		t.Fatal("expected errors")
+	}
+
+	_, diags = LintDefinition([]byte("name: x\ninitial: Nope\nstages:\n  - name: A\n    type: agent\n    outcomes: [go]\ntransitions:\n  - {from: A, outcome: go, to: A}\n"), "x.yaml", nil)
+	got = diagCodes(diags)
+	if _, ok := got["no_terminal/"]; !ok {
+		t.Errorf("missing no_terminal in %v", diags)
+	}
+	if d, ok := got["no_initial/Nope"]; !ok || d.Line != 2 {
+		t.Errorf("no_initial = %+v (ok=%v)", d, ok)
+	}
+}
+
+func TestLint_builtinsAreClean(t *testing.T) {
+	t.Parallel()
+	for _, name := range BuiltinNames() {
+		data, err := ReadSource(BuiltinPrefix + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, diags := LintDefinition(data, name, nil); len(diags) > 0 {
+			t.Errorf("builtin %s: %v", name, diags)
+		}
+	}
+}
+
+func TestLintStored_andCreateRejectsErrors(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+	_ = st.CreateAgent(ctx, "t1", "alice", "engineer")
+
+	// Stored without going through the linter: InReview can never be left.
+	wfID, err := st.CreateWorkflowWithStages(ctx, "t1", "legacy", 1, "test", []store.WorkflowStage{
+		{StageName: "Coding", StageType: "agent", Outcomes: "submit", CandidateAgents: "alice"},
+		{StageName: "InReview", StageType: "agent", Outcomes: "approved"},
+		{StageName: "Done", StageType: "terminal"},
+	}, []store.WorkflowTransition{{FromStage: "Coding", Outcome: "submit", ToStage: "InReview"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags, err := LintStored(ctx, st, "t1", wfID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := diagCodes(diags)
+	if _, ok := got["no_exit/InReview"]; !ok {
+		t.Errorf("missing no_exit in %v", diags)
+	}
+	if _, ok := got["unknown_agent/Coding"]; ok {
+		t.Errorf("alice is on the team: %v", diags)
+	}
+
+	def, _ := LoadStored(ctx, st, wfID)
+	if _, _, err := CreateFromDefinition(ctx, st, "t1", def, "legacy2", 1, "test"); err == nil {
+		t.Fatal("CreateFromDefinition should reject a workflow with lint errors")
+	}
+	if id, _ := st.GetWorkflowIDByTeamAndName(ctx, "t1", "legacy2", 1); id != "" {
+		t.Fatal("rejected workflow was stored")
+	}
+}
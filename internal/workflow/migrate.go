@@ -0,0 +1,158 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// ParseWorkflowRef parses "name@version" (version defaults to 1).
+func ParseWorkflowRef(ref string) (string, int, error) {
+	name, v, hasVersion := strings.Cut(strings.TrimSpace(ref), "@")
+	if name == "" {
+		return "", 0, fmt.Errorf("invalid workflow %q (want name@version)", ref)
+	}
+	if !hasVersion {
+		return name, 1, nil
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version <= 0 {
+		return "", 0, fmt.Errorf("invalid workflow version in %q", ref)
+	}
+	return name, version, nil
+}
+
+// ParseStageMap parses "From=To,From2=To2" into a stage mapping.
+func ParseStageMap(s string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid stage mapping %q (want From=To)", pair)
+		}
+		out[from] = to
+	}
+	return out, nil
+}
+
+// MigrationPlan is the set of task moves a workflow migration would make.
+type MigrationPlan struct {
+	From      string // name@version
+	To        string
+	Migration store.WorkflowMigration
+}
+
+// PlanMigration works out where each open task in the from workflow lands in the to workflow. A task
+// keeps its stage name unless stageMap maps it elsewhere. It refuses if a mapped stage does not exist in
+// the target, a mapping names a stage the source does not have, or a task's stage has no destination.
+func PlanMigration(ctx context.Context, st store.Store, teamName, fromRef, toRef string, stageMap map[string]string) (*MigrationPlan, error) {
+	fromID, err := resolveWorkflowRef(ctx, st, teamName, fromRef)
+	if err != nil {
+		return nil, err
+	}
+	toID, err := resolveWorkflowRef(ctx, st, teamName, toRef)
+	if err != nil {
+		return nil, err
+	}
+	if fromID == toID {
+		return nil, errors.New("source and target workflows are the same")
+	}
+	fromStages, err := stageNames(ctx, st, fromID)
+	if err != nil {
+		return nil, err
+	}
+	toStages, err := stageNames(ctx, st, toID)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, from := range sortedKeys(stageMap) {
+		to := stageMap[from]
+		if !fromStages[from] {
+			problems = append(problems, fmt.Sprintf("mapping %s=%s: %s has no stage %q", from, to, fromRef, from))
+		}
+		if !toStages[to] {
+			problems = append(problems, fmt.Sprintf("mapping %s=%s: %s has no stage %q", from, to, toRef, to))
+		}
+	}
+	tasks, err := st.ListOpenTasksInWorkflow(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	plan := &MigrationPlan{
+		From:      fromRef,
+		To:        toRef,
+		Migration: store.WorkflowMigration{FromWorkflowID: fromID, ToWorkflowID: toID, StageMap: stageMap},
+	}
+	for _, t := range tasks {
+		cur := ""
+		if t.CurrentStage != nil {
+			cur = *t.CurrentStage
+		}
+		next, mapped := stageMap[cur]
+		if !mapped {
+			next = cur
+		}
+		if next != "" && !toStages[next] {
+			problems = append(problems, fmt.Sprintf("task #%d is in %q, which %s does not have; add a mapping %s=<stage>", t.TaskID, cur, toRef, cur))
+			continue
+		}
+		plan.Migration.Moves = append(plan.Migration.Moves, store.TaskStageMove{TaskID: t.TaskID, FromStage: cur, ToStage: next})
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("cannot migrate %s to %s:\n  %s", fromRef, toRef, strings.Join(problems, "\n  "))
+	}
+	return plan, nil
+}
+
+// ApplyMigration re-points the planned tasks atomically and records the audit entry. It returns the migration ID.
+func ApplyMigration(ctx context.Context, st store.Store, teamName string, plan *MigrationPlan) (int64, error) {
+	ctx = store.WithTransitionCause(ctx, store.TransitionCause{Actor: "workflow-migration", Note: fmt.Sprintf("%s -> %s", plan.Migration.FromWorkflowID, plan.Migration.ToWorkflowID)})
+	return st.MigrateWorkflowTasks(ctx, teamName, plan.Migration)
+}
+
+func resolveWorkflowRef(ctx context.Context, st store.Store, teamName, ref string) (string, error) {
+	name, version, err := ParseWorkflowRef(ref)
+	if err != nil {
+		return "", err
+	}
+	id, err := st.GetWorkflowIDByTeamAndName(ctx, teamName, name, version)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("workflow not found: %s v%d", name, version)
+	}
+	return id, nil
+}
+
+func stageNames(ctx context.Context, st store.Store, workflowID string) (map[string]bool, error) {
+	stages, err := st.GetWorkflowStages(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(stages))
+	for _, s := range stages {
+		out[s.StageName] = true
+	}
+	return out, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,129 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+func TestParseWorkflowRefAndStageMap(t *testing.T) {
+	t.Parallel()
+	if name, v, err := ParseWorkflowRef("default@2"); err != nil || name != "default" || v != 2 {
+		t.Errorf("ParseWorkflowRef(default@2) = %q, %d, %v", name, v, err)
+	}
+	if name, v, err := ParseWorkflowRef("solo"); err != nil || name != "solo" || v != 1 {
+		t.Errorf("ParseWorkflowRef(solo) = %q, %d, %v", name, v, err)
+	}
+	if _, _, err := ParseWorkflowRef("x@zero"); err == nil {
+		t.Error("expected error for bad version")
+	}
+	m, err := ParseStageMap("InReview=Review, InApproval=Review")
+	if err != nil || m["InReview"] != "Review" || m["InApproval"] != "Review" {
+		t.Errorf("ParseStageMap = %v, %v", m, err)
+	}
+	if _, err := ParseStageMap("InReview"); err == nil {
+		t.Error("expected error for missing =")
+	}
+}
+
+func TestPlanAndApplyMigration(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+
+	v1, err := LoadSource("builtin:default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1ID, _, err := CreateFromDefinition(ctx, st, "t1", v1, "flow", 1, "builtin:default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := ParseDefinition([]byte(`name: flow
+version: 2
+stages:
+  - {name: Coding, type: agent, outcomes: [submit_for_review]}
+  - {name: Review, type: human, outcomes: [approved, changes_requested]}
+  - {name: Merging, type: merge, outcomes: [done]}
+  - {name: Done, type: terminal}
+transitions:
+  - {from: Coding, outcome: submit_for_review, to: Review}
+  - {from: Review, outcome: approved, to: Merging}
+  - {from: Review, outcome: changes_requested, to: Coding}
+  - {from: Merging, outcome: done, to: Done}
+`), "v2.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2ID, _, err := CreateFromDefinition(ctx, st, "t1", v2, "", 0, "v2.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coding, _ := st.CreateTask(ctx, "t1", "coding", models.StatusTodo, &v1ID)
+	review, _ := st.CreateTask(ctx, "t1", "review", models.StatusTodo, &v1ID)
+	_ = st.UpdateTaskStage(ctx, review, "InReview")
+	done, _ := st.CreateTask(ctx, "t1", "done", models.StatusTodo, &v1ID)
+	_ = st.UpdateTask(ctx, done, models.StatusDone, nil)
+	before, _ := st.GetTaskByIDAndTeam(ctx, "t1", coding)
+
+	if _, err := PlanMigration(ctx, st, "t1", "flow@1", "flow@2", nil); err == nil || !strings.Contains(err.Error(), fmt.Sprintf("task #%d is in \"InReview\"", review)) {
+		t.Fatalf("unmapped stage: err = %v", err)
+	}
+	if _, err := PlanMigration(ctx, st, "t1", "flow@1", "flow@2", map[string]string{"InReview": "Nope"}); err == nil || !strings.Contains(err.Error(), `flow@2 has no stage "Nope"`) {
+		t.Fatalf("missing target stage: err = %v", err)
+	}
+
+	plan, err := PlanMigration(ctx, st, "t1", "flow@1", "flow@2", map[string]string{"InReview": "Review"})
+	if err != nil {
+		t.Fatalf("PlanMigration: %v", err)
+	}
+	if len(plan.Migration.Moves) != 2 {
+		t.Fatalf("moves = %+v, want the two open tasks", plan.Migration.Moves)
+	}
+	id, err := ApplyMigration(ctx, st, "t1", plan)
+	if err != nil {
+		t.Fatalf("ApplyMigration: %v", err)
+	}
+
+	after, _ := st.GetTaskByIDAndTeam(ctx, "t1", coding)
+	if *after.WorkflowID != v2ID || *after.CurrentStage != "Coding" {
+		t.Fatalf("coding task = %s/%s", *after.WorkflowID, *after.CurrentStage)
+	}
+	if !after.StageEnteredAt.Equal(*before.StageEnteredAt) {
+		t.Error("unchanged stage should keep its SLA clock")
+	}
+	moved, _ := st.GetTaskByIDAndTeam(ctx, "t1", review)
+	if *moved.WorkflowID != v2ID || *moved.CurrentStage != "Review" {
+		t.Fatalf("review task = %s/%s", *moved.WorkflowID, *moved.CurrentStage)
+	}
+	finished, _ := st.GetTaskByIDAndTeam(ctx, "t1", done)
+	if *finished.WorkflowID != v1ID {
+		t.Fatal("done task should stay on v1")
+	}
+
+	audit, err := st.ListWorkflowMigrations(ctx, "t1")
+	if err != nil || len(audit) != 1 || audit[0].MigrationID != id || audit[0].StageMap["InReview"] != "Review" || len(audit[0].Moves) != 2 {
+		t.Fatalf("audit = %+v, %v", audit, err)
+	}
+
+	// A stale plan fails as a whole.
+	if _, err := ApplyMigration(ctx, st, "t1", plan); err == nil {
+		t.Fatal("re-applying a stale plan should fail")
+	}
+}
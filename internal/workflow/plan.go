@@ -0,0 +1,237 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	agentrt "github.com/ankittk/agentary/internal/agent/runtime"
+	"github.com/ankittk/agentary/internal/review"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// Plan statuses (store.TaskPlan.Status).
+const (
+	PlanPending    = "pending"
+	PlanApproved   = "approved"
+	PlanRejected   = "rejected"
+	PlanSuperseded = "superseded"
+)
+
+// ErrNoPendingPlan is returned by ApprovePlan and RejectPlan when the task has no plan awaiting a decision.
+var ErrNoPendingPlan = errors.New("no plan awaiting approval")
+
+// ErrNotInPlanStage is returned by ApprovePlan and RejectPlan when the task has moved out of its plan stage
+// (e.g. it was force-transitioned or rewound) and a pending plan was left behind.
+var ErrNotInPlanStage = errors.New("task is not in a plan stage")
+
+// ErrNoPlanTransition is returned by ApprovePlan and RejectPlan when the plan stage has no transition for
+// the decision's outcome; the plan stays pending.
+var ErrNoPlanTransition = errors.New("plan stage has no transition for the decision")
+
+// planPrompt is prepended to the task title in a plan stage turn; the runtime's output is the plan.
+const planPrompt = "Write an implementation plan for this task. Do not change any code: describe the approach, " +
+	"the files to touch and how the change will be tested.\n\nTask: "
+
+// planStage has the assignee write an implementation plan and then waits for a human to approve it
+// (outcome approved) or reject it with feedback (changes_requested), which has the agent write a new one.
+type planStage struct{}
+
+func (planStage) RunStage(ctx context.Context, e *Engine, t *Turn) error {
+	task := t.Task
+	plans, err := e.Store.ListTaskPlans(ctx, t.Team, task.TaskID)
+	if err != nil {
+		return err
+	}
+	var latest *store.TaskPlan
+	if len(plans) > 0 {
+		latest = &plans[len(plans)-1]
+	}
+	if latest != nil && latest.Status == PlanPending {
+		return nil // waiting for a human
+	}
+	if latest != nil && latest.Status == PlanApproved && !decidedBeforeStage(latest, task) {
+		return nil // approved in this stage; the approval has already applied its outcome
+	}
+
+	agentName := derefString(task.Assignee)
+	allowlist, _ := e.Store.ListAllowedDomains(ctx)
+	input := planPrompt + task.Title
+	if latest != nil && latest.Status == PlanRejected {
+		input += fmt.Sprintf("\n\nYour previous plan (revision %d) was rejected", latest.Revision)
+		if latest.Feedback != "" {
+			input += ": " + latest.Feedback
+		}
+		input += "\n\n" + latest.Body
+	}
+	started := time.Now().UTC()
+	result, runErr := t.Runtime.RunTurn(ctx, agentrt.TurnRequest{
+		Team:             t.Team,
+		Agent:            agentName,
+		TaskID:           &task.TaskID,
+		Input:            input,
+		NetworkAllowlist: allowlist,
+	}, t.Emit)
+	body := strings.TrimSpace(result.Output)
+	if runErr == nil && body == "" {
+		runErr = errors.New("agent returned an empty plan")
+	}
+	if runErr != nil {
+		e.recordTurn(ctx, t, agentName, "", runErr, started)
+		_ = e.Store.SetTaskFailed(ctx, task.TaskID)
+		return runErr
+	}
+	e.recordTurn(ctx, t, agentName, "plan_ready", nil, started)
+	if _, err := e.Store.CreateTaskPlan(ctx, t.Team, store.TaskPlan{TaskID: task.TaskID, Author: agentName, Body: body}); err != nil {
+		return err
+	}
+	e.comment(ctx, t.Team, task.TaskID, "Plan ready for approval (approve, edit or reject it via /plan)")
+	return nil
+}
+
+// LintStage checks that the stage can report both plan decisions.
+func (planStage) LintStage(st *StageDef) []string {
+	var msgs []string
+	for _, o := range []string{review.Approved, review.ChangesRequested} {
+		if !containsString(st.Outcomes, o) {
+			msgs = append(msgs, fmt.Sprintf("plan stages need the %q outcome", o))
+		}
+	}
+	return msgs
+}
+
+// decidedBeforeStage reports whether the plan was decided before the task entered its current stage,
+// i.e. it belongs to an earlier pass through a plan stage.
+func decidedBeforeStage(p *store.TaskPlan, task *store.Task) bool {
+	return p.DecidedAt != nil && task.StageEnteredAt != nil && p.DecidedAt.Before(*task.StageEnteredAt)
+}
+
+// LatestPlan returns the task's newest plan revision, or nil.
+func LatestPlan(ctx context.Context, st store.Store, teamName string, taskID int64) (*store.TaskPlan, error) {
+	plans, err := st.ListTaskPlans(ctx, teamName, taskID)
+	if err != nil || len(plans) == 0 {
+		return nil, err
+	}
+	return &plans[len(plans)-1], nil
+}
+
+// ApprovedPlan returns the task's most recently approved plan, or nil.
+func ApprovedPlan(ctx context.Context, st store.Store, teamName string, taskID int64) (*store.TaskPlan, error) {
+	plans, err := st.ListTaskPlans(ctx, teamName, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(plans) - 1; i >= 0; i-- {
+		if plans[i].Status == PlanApproved {
+			return &plans[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// EditPlan stores body as a new pending revision by author, replacing the pending one.
+func EditPlan(ctx context.Context, st store.Store, teamName string, task *store.Task, author, body string) (*store.TaskPlan, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, errors.New("plan body is empty")
+	}
+	if _, err := st.CreateTaskPlan(ctx, teamName, store.TaskPlan{TaskID: task.TaskID, Author: author, Body: body}); err != nil {
+		return nil, err
+	}
+	return LatestPlan(ctx, st, teamName, task.TaskID)
+}
+
+// ApprovePlan approves the task's pending plan, first storing body as an edited revision when it differs,
+// and applies the approved outcome. The task is set back to todo so the next stage gets a turn.
+// It returns the new stage.
+func (e *Engine) ApprovePlan(ctx context.Context, teamName string, task *store.Task, by, body string) (string, error) {
+	plan, err := LatestPlan(ctx, e.Store, teamName, task.TaskID)
+	if err != nil {
+		return "", err
+	}
+	if plan == nil || plan.Status != PlanPending {
+		return "", ErrNoPendingPlan
+	}
+	if err := e.checkPlanStage(ctx, task); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(body) != "" && body != plan.Body {
+		if plan, err = EditPlan(ctx, e.Store, teamName, task, by, body); err != nil {
+			return "", err
+		}
+	}
+	note := fmt.Sprintf("Plan revision %d approved by %s", plan.Revision, by)
+	return e.decidePlan(ctx, teamName, task, plan, by, PlanApproved, "", review.Approved, note)
+}
+
+// RejectPlan rejects the task's pending plan with feedback and applies changes_requested; the plan stage
+// then has the agent write a new revision that sees the feedback.
+func (e *Engine) RejectPlan(ctx context.Context, teamName string, task *store.Task, by, feedback string) (string, error) {
+	plan, err := LatestPlan(ctx, e.Store, teamName, task.TaskID)
+	if err != nil {
+		return "", err
+	}
+	if plan == nil || plan.Status != PlanPending {
+		return "", ErrNoPendingPlan
+	}
+	if err := e.checkPlanStage(ctx, task); err != nil {
+		return "", err
+	}
+	note := fmt.Sprintf("Plan revision %d rejected by %s", plan.Revision, by)
+	if feedback != "" {
+		note += ": " + feedback
+	}
+	return e.decidePlan(ctx, teamName, task, plan, by, PlanRejected, feedback, review.ChangesRequested, note)
+}
+
+// checkPlanStage returns ErrNotInPlanStage unless the task's current stage is a plan stage, so a stale plan
+// decision cannot apply its outcome from another stage.
+func (e *Engine) checkPlanStage(ctx context.Context, task *store.Task) error {
+	if task.WorkflowID == nil || task.CurrentStage == nil {
+		return ErrNotInPlanStage
+	}
+	stages, err := e.Store.GetWorkflowStages(ctx, *task.WorkflowID)
+	if err != nil {
+		return err
+	}
+	if stage := findStage(stages, *task.CurrentStage); stage == nil || stage.StageType != "plan" {
+		return fmt.Errorf("%w (current stage %s)", ErrNotInPlanStage, *task.CurrentStage)
+	}
+	return nil
+}
+
+// decidePlan applies outcome for a plan decision and only then records the decision (status, feedback) and
+// posts note, so a guard failure or a missing transition leaves the plan pending and the task where it was.
+// The decision is timestamped before the transition, so it never looks decided within the next stage.
+func (e *Engine) decidePlan(ctx context.Context, teamName string, task *store.Task, plan *store.TaskPlan, by, status, feedback, outcome, note string) (string, error) {
+	at := time.Now().UTC()
+	ctx = store.WithTransitionCause(ctx, store.TransitionCause{Actor: by})
+	next, err := e.ApplyOutcome(ctx, teamName, task, outcome)
+	if err != nil {
+		return "", err
+	}
+	if next == "" {
+		return "", fmt.Errorf("%w: %s has no %q transition", ErrNoPlanTransition, derefString(task.CurrentStage), outcome)
+	}
+	if err := e.Store.DecideTaskPlan(ctx, plan.PlanID, status, by, feedback, at); err != nil {
+		return next, err
+	}
+	e.comment(ctx, teamName, task.TaskID, note)
+	if updated, _ := e.Store.GetTaskByIDAndTeam(ctx, teamName, task.TaskID); updated != nil && updated.Status == models.StatusInProgress {
+		if err := e.Store.UpdateTask(ctx, task.TaskID, models.StatusTodo, nil); err != nil {
+			return next, err
+		}
+	}
+	return next, nil
+}
+
+// planInput appends the task's approved plan, if any, to an agent turn's input.
+func planInput(ctx context.Context, st store.Store, teamName string, task *store.Task, input string) string {
+	plan, err := ApprovedPlan(ctx, st, teamName, task.TaskID)
+	if err != nil || plan == nil {
+		return input
+	}
+	return fmt.Sprintf("%s\n\nApproved implementation plan (revision %d):\n\n%s", input, plan.Revision, plan.Body)
+}
@@ -0,0 +1,206 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	agentrt "github.com/ankittk/agentary/internal/agent/runtime"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// inputRuntime records each turn's input and replies with the next output.
+type inputRuntime struct {
+	inputs  []string
+	outputs []string
+}
+
+func (r *inputRuntime) Name() string { return "input" }
+
+func (r *inputRuntime) RunTurn(_ context.Context, req agentrt.TurnRequest, _ func(agentrt.Event)) (agentrt.TurnResult, error) {
+	r.inputs = append(r.inputs, req.Input)
+	out := ""
+	if len(r.outputs) > 0 {
+		out, r.outputs = r.outputs[0], r.outputs[1:]
+	}
+	return agentrt.TurnResult{Output: out}, nil
+}
+
+func TestPlanStage(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+	_ = st.CreateAgent(ctx, "t1", "alice", "engineer")
+	wfID, err := st.CreateWorkflow(ctx, "t1", "default", 1, store.DefaultPlanSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if initial, _ := st.GetWorkflowInitialStage(ctx, wfID); initial != "Planning" {
+		t.Fatalf("initial stage = %q, want Planning", initial)
+	}
+	taskID, _ := st.CreateTask(ctx, "t1", "add retries", models.StatusTodo, &wfID)
+	load := func() *store.Task {
+		task, err := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+		if err != nil || task == nil {
+			t.Fatalf("GetTask: %v", err)
+		}
+		return task
+	}
+	if ok, _ := st.ClaimTask(ctx, "t1", taskID, "alice"); !ok {
+		t.Fatal("claim failed")
+	}
+	e := &Engine{Store: st}
+	rt := &inputRuntime{outputs: []string{"1. wrap the client", "1. wrap the client\n2. add backoff", "submit_for_review"}}
+
+	// First turn writes a plan; the task then waits for a human.
+	if _, err := e.RunTurn(ctx, "t1", load(), rt, func(agentrt.Event) {}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.RunTurn(ctx, "t1", load(), rt, func(agentrt.Event) {}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.inputs) != 1 || !strings.Contains(rt.inputs[0], "add retries") {
+		t.Fatalf("inputs = %q, want one plan turn", rt.inputs)
+	}
+	plan, _ := LatestPlan(ctx, st, "t1", taskID)
+	if plan == nil || plan.Status != PlanPending || plan.Author != "alice" || plan.Body != "1. wrap the client" {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	// Rejecting re-runs the agent with the feedback.
+	if _, err := e.RejectPlan(ctx, "t1", load(), "human", "what about backoff?"); err != nil {
+		t.Fatal(err)
+	}
+	task := load()
+	if task.Status != models.StatusTodo || derefString(task.CurrentStage) != "Planning" {
+		t.Fatalf("after reject: status %s stage %s", task.Status, derefString(task.CurrentStage))
+	}
+	if _, err := e.RunTurn(ctx, "t1", task, rt, func(agentrt.Event) {}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.inputs) != 2 || !strings.Contains(rt.inputs[1], "what about backoff?") {
+		t.Fatalf("replan input = %q", rt.inputs)
+	}
+
+	// Approving with an edit stores a human revision and moves on to Coding.
+	edited := "1. wrap the client\n2. add backoff\n3. test it"
+	next, err := e.ApprovePlan(ctx, "t1", load(), "human", edited)
+	if err != nil || next != "Coding" {
+		t.Fatalf("ApprovePlan = %q, %v", next, err)
+	}
+	plans, _ := st.ListTaskPlans(ctx, "t1", taskID)
+	if len(plans) != 3 || plans[0].Status != PlanRejected || plans[1].Status != PlanSuperseded || plans[2].Status != PlanApproved || plans[2].Author != "human" {
+		t.Fatalf("plans = %+v", plans)
+	}
+	if _, err := e.ApprovePlan(ctx, "t1", load(), "human", ""); err != ErrNoPendingPlan {
+		t.Errorf("second approve: %v, want ErrNoPendingPlan", err)
+	}
+
+	// The Coding turn sees the approved plan.
+	if _, err := e.RunTurn(ctx, "t1", load(), rt, func(agentrt.Event) {}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.inputs) != 3 || !strings.Contains(rt.inputs[2], "Approved implementation plan (revision 3)") || !strings.Contains(rt.inputs[2], "3. test it") {
+		t.Fatalf("coding input = %q", rt.inputs[len(rt.inputs)-1])
+	}
+	if stage := derefString(load().CurrentStage); stage != "InReview" {
+		t.Errorf("stage after coding = %s, want InReview", stage)
+	}
+}
+
+func TestApprovePlan_outsidePlanStage(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+	wfID, _ := st.CreateWorkflow(ctx, "t1", "default", 1, store.DefaultPlanSource)
+	taskID, _ := st.CreateTask(ctx, "t1", "add retries", models.StatusTodo, &wfID)
+	if _, err := st.CreateTaskPlan(ctx, "t1", store.TaskPlan{TaskID: taskID, Author: "alice", Body: "1. wrap the client", Status: PlanPending}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A human moved the task past Planning, leaving the plan pending: deciding it must not move the task.
+	_ = st.SetTaskWorkflowAndStage(ctx, taskID, wfID, "InApproval")
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	e := &Engine{Store: st}
+	if _, err := e.ApprovePlan(ctx, "t1", task, "human", ""); !errors.Is(err, ErrNotInPlanStage) {
+		t.Fatalf("ApprovePlan in InApproval: %v, want ErrNotInPlanStage", err)
+	}
+	if _, err := e.RejectPlan(ctx, "t1", task, "human", "no"); !errors.Is(err, ErrNotInPlanStage) {
+		t.Fatalf("RejectPlan in InApproval: %v, want ErrNotInPlanStage", err)
+	}
+	task, _ = st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	if stage := derefString(task.CurrentStage); stage != "InApproval" {
+		t.Fatalf("stage = %s, want InApproval", stage)
+	}
+	if plan, _ := LatestPlan(ctx, st, "t1", taskID); plan == nil || plan.Status != PlanPending {
+		t.Fatalf("plan = %+v, want still pending", plan)
+	}
+}
+
+func TestApprovePlan_guardedTransitionKeepsPlanPending(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+	wfID, err := st.CreateWorkflowWithStages(ctx, "t1", "guarded", 1, "builtin:guarded",
+		[]store.WorkflowStage{
+			{StageName: "Planning", StageType: "plan", Outcomes: "approved,changes_requested"},
+			{StageName: "Coding", StageType: "agent", Outcomes: "done"},
+			{StageName: "Done", StageType: "terminal"},
+		},
+		[]store.WorkflowTransition{
+			{FromStage: "Planning", Outcome: "approved", ToStage: "Coding", Guards: "min_approvals:1"},
+			{FromStage: "Coding", Outcome: "done", ToStage: "Done"},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskID, _ := st.CreateTask(ctx, "t1", "add retries", models.StatusTodo, &wfID)
+	_ = st.SetTaskWorkflowAndStage(ctx, taskID, wfID, "Planning")
+	if _, err := st.CreateTaskPlan(ctx, "t1", store.TaskPlan{TaskID: taskID, Author: "alice", Body: "1. wrap the client", Status: PlanPending}); err != nil {
+		t.Fatal(err)
+	}
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	e := &Engine{Store: st}
+
+	// The guard fails with no fallback: the approval must not be recorded.
+	var guardErr *GuardError
+	if _, err := e.ApprovePlan(ctx, "t1", task, "human", ""); !errors.As(err, &guardErr) {
+		t.Fatalf("ApprovePlan: %v, want a GuardError", err)
+	}
+	// Planning has no changes_requested transition: the rejection is not recorded either.
+	if _, err := e.RejectPlan(ctx, "t1", task, "human", "no"); !errors.Is(err, ErrNoPlanTransition) {
+		t.Fatalf("RejectPlan: %v, want ErrNoPlanTransition", err)
+	}
+	task, _ = st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	if stage := derefString(task.CurrentStage); stage != "Planning" {
+		t.Fatalf("stage = %s, want Planning", stage)
+	}
+	if plan, _ := LatestPlan(ctx, st, "t1", taskID); plan == nil || plan.Status != PlanPending || plan.DecidedBy != "" {
+		t.Fatalf("plan = %+v, want still pending", plan)
+	}
+}
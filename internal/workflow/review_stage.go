@@ -0,0 +1,192 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	agentrt "github.com/ankittk/agentary/internal/agent/runtime"
+	"github.com/ankittk/agentary/internal/review"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// reviewStage fans a task out to the stage's reviewers and advances on the quorum: any
+// changes_requested moves the task back, enough approvals move it on, otherwise it waits.
+// Agent reviewers run concurrently in the turn; human reviewers submit through the API.
+type reviewStage struct{}
+
+func (reviewStage) RunStage(ctx context.Context, e *Engine, t *Turn) error {
+	task, stage := t.Task, t.Stage
+	agents, err := e.Store.ListAgents(ctx, t.Team)
+	if err != nil {
+		return err
+	}
+	reviewers := review.SplitReviewers(task.Reviewers)
+	if len(reviewers) == 0 {
+		reviewers = review.PickReviewers(task, agents, splitList(stage.CandidateAgents), max(stage.Reviewers, 1))
+		if len(reviewers) == 0 {
+			return fmt.Errorf("stage %s: no reviewers available", stage.StageName)
+		}
+		if err := e.Store.SetTaskReviewers(ctx, task.TaskID, reviewers, task.ReviewRound); err != nil {
+			return err
+		}
+		task.Reviewers = strings.Join(reviewers, ",")
+	}
+	tally, err := review.TallyTask(ctx, e.Store, t.Team, task, stage)
+	if err != nil {
+		return err
+	}
+
+	isAgent := make(map[string]bool, len(agents))
+	for _, a := range agents {
+		isAgent[a.Name] = true
+	}
+	var pending []string
+	for _, name := range tally.Pending {
+		if isAgent[name] {
+			pending = append(pending, name)
+		}
+	}
+	var runErrs []error
+	if len(pending) > 0 && t.Runtime != nil && tally.Outcome() == "" {
+		var (
+			wg sync.WaitGroup
+			mu sync.Mutex
+		)
+		for _, name := range pending {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				if err := e.runAgentReview(ctx, t, name); err != nil {
+					mu.Lock()
+					runErrs = append(runErrs, fmt.Errorf("reviewer %s: %w", name, err))
+					mu.Unlock()
+				}
+			}(name)
+		}
+		wg.Wait()
+		if tally, err = review.TallyTask(ctx, e.Store, t.Team, task, stage); err != nil {
+			return err
+		}
+	}
+
+	outcome := tally.Outcome()
+	if outcome == "" {
+		return errors.Join(runErrs...)
+	}
+	e.comment(ctx, t.Team, task.TaskID, fmt.Sprintf("Review %s: %s", outcome, tally))
+	if _, err := e.ApplyOutcome(ctx, t.Team, task, outcome); err != nil {
+		return err
+	}
+	// Return task to author when changes requested
+	if outcome == review.ChangesRequested && task.DRI != nil && *task.DRI != "" {
+		_ = e.Store.UpdateTask(ctx, task.TaskID, models.StatusInProgress, task.DRI)
+	}
+	return nil
+}
+
+// LintStage checks the quorum settings and that the stage can report both review outcomes.
+func (reviewStage) LintStage(st *StageDef) []string {
+	var msgs []string
+	for _, o := range []string{review.Approved, review.ChangesRequested} {
+		if !containsString(st.Outcomes, o) {
+			msgs = append(msgs, fmt.Sprintf("review stages need the %q outcome", o))
+		}
+	}
+	if st.Quorum > max(st.Reviewers, 1) {
+		msgs = append(msgs, fmt.Sprintf("quorum %d is more than the %d reviewer(s)", st.Quorum, max(st.Reviewers, 1)))
+	}
+	if st.Reviewers > 0 && len(st.CandidateAgents) > 0 && len(st.CandidateAgents) < st.Reviewers {
+		msgs = append(msgs, fmt.Sprintf("%d reviewers requested but candidate_agents lists only %d", st.Reviewers, len(st.CandidateAgents)))
+	}
+	return msgs
+}
+
+// runAgentReview runs one reviewer agent's turn and records its review. The first word of the output is
+// the outcome (approved or changes_requested) and the rest its comments; an empty or stub reply approves,
+// anything else is treated as a request for changes. Comment lines written as "path:line: text" or
+// "path:start-end: text" also start inline threads on those lines.
+func (e *Engine) runAgentReview(ctx context.Context, t *Turn, reviewer string) error {
+	allowlist, _ := e.Store.ListAllowedDomains(ctx)
+	input := fmt.Sprintf("Review task #%d: %s\nReply %q or %q, then your comments. Comment on code with lines like %q.",
+		t.Task.TaskID, t.Task.Title, review.Approved, review.ChangesRequested, "path:line: text")
+	req := agentrt.TurnRequest{
+		Team:             t.Team,
+		Agent:            reviewer,
+		TaskID:           &t.Task.TaskID,
+		Input:            input,
+		NetworkAllowlist: allowlist,
+	}
+	started := time.Now().UTC()
+	result, err := t.Runtime.RunTurn(ctx, req, t.Emit)
+	if err != nil {
+		e.recordTurn(ctx, t, reviewer, "", err, started)
+		return err
+	}
+	outcome, comments := parseReviewReply(result.Output)
+	e.recordTurn(ctx, t, reviewer, outcome, nil, started)
+	reviewID, err := e.Store.CreateTaskReview(ctx, t.Team, t.Task.TaskID, reviewer, outcome, comments)
+	if err != nil {
+		return err
+	}
+	return review.AddInlineComments(ctx, e.Store, t.Team, t.Task, reviewID, reviewer, review.ParseInlineComments(comments))
+}
+
+// reviewCommentsInput appends the task's open inline review threads to an agent turn's input, after
+// following them to the current branch tip.
+func reviewCommentsInput(ctx context.Context, st store.Store, teamName string, task *store.Task, input string) string {
+	threads, err := review.TaskThreads(ctx, st, teamName, task)
+	if err != nil {
+		slog.Warn("review comments unavailable", "task_id", task.TaskID, "err", err)
+		return input
+	}
+	var b strings.Builder
+	for _, th := range threads {
+		if th.Resolved {
+			continue
+		}
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "%s\n\nOpen review comments:\n", input)
+		}
+		lines := fmt.Sprintf("%d", th.StartLine)
+		if th.EndLine != th.StartLine {
+			lines = fmt.Sprintf("%d-%d", th.StartLine, th.EndLine)
+		}
+		outdated := ""
+		if th.Outdated {
+			outdated = " (outdated: the code has changed since)"
+		}
+		for i, c := range th.Comments {
+			if i == 0 {
+				fmt.Fprintf(&b, "- [#%d] %s:%s%s %s: %s\n", th.ID, th.Path, lines, outdated, c.Author, c.Body)
+				continue
+			}
+			fmt.Fprintf(&b, "  - %s: %s\n", c.Author, c.Body)
+		}
+	}
+	if b.Len() == 0 {
+		return input
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func parseReviewReply(out string) (outcome, comments string) {
+	out = strings.TrimSpace(out)
+	if out == "" || out == "stub: ok" {
+		return review.Approved, ""
+	}
+	first, rest, _ := strings.Cut(out, "\n")
+	word, tail, _ := strings.Cut(strings.TrimSpace(first), " ")
+	switch strings.Trim(strings.ToLower(word), ".:,") {
+	case review.Approved:
+		return review.Approved, strings.TrimSpace(tail + "\n" + rest)
+	case review.ChangesRequested:
+		return review.ChangesRequested, strings.TrimSpace(tail + "\n" + rest)
+	}
+	return review.ChangesRequested, out
+}
@@ -0,0 +1,203 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	agentrt "github.com/ankittk/agentary/internal/agent/runtime"
+	"github.com/ankittk/agentary/internal/review"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// replyRuntime answers each agent's turn with a fixed reply.
+type replyRuntime struct {
+	mu      sync.Mutex
+	replies map[string]string
+	calls   []string
+}
+
+func (r *replyRuntime) Name() string { return "reply" }
+
+func (r *replyRuntime) RunTurn(_ context.Context, req agentrt.TurnRequest, _ func(agentrt.Event)) (agentrt.TurnResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, req.Agent)
+	return agentrt.TurnResult{Output: r.replies[req.Agent]}, nil
+}
+
+func TestRunTurn_reviewQuorum(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+	for _, a := range []string{"dev", "rev1", "rev2"} {
+		_ = st.CreateAgent(ctx, "t1", a, "engineer")
+	}
+
+	if _, diags := LintDefinition([]byte(`name: q
+stages:
+  - {name: Review, type: review, outcomes: [approved], reviewers: 1, quorum: 2}
+  - {name: Done, type: terminal}
+transitions:
+  - {from: Review, outcome: approved, to: Done}
+`), "q.yaml", nil); len(diags) != 2 {
+		t.Fatalf("lint diagnostics = %+v, want missing outcome and quorum > reviewers", diags)
+	}
+
+	def, err := ParseDefinition([]byte(`name: quorum
+stages:
+  - {name: Coding, type: human, outcomes: [done]}
+  - name: Review
+    type: review
+    outcomes: [approved, changes_requested]
+    candidate_agents: [rev1, rev2, ops-lead]
+    reviewers: 3
+    quorum: 2
+  - {name: Done, type: terminal}
+transitions:
+  - {from: Coding, outcome: done, to: Review}
+  - {from: Review, outcome: approved, to: Done}
+  - {from: Review, outcome: changes_requested, to: Coding}
+`), "quorum.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wfID, _, err := CreateFromDefinition(ctx, st, "t1", def, "", 0, "quorum.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskID, _ := st.CreateTask(ctx, "t1", "sensitive change", models.StatusTodo, &wfID)
+	_, _ = st.ClaimTask(ctx, "t1", taskID, "dev")
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	eng := &Engine{Store: st}
+	if _, err := eng.ApplyOutcome(ctx, "t1", task, "done"); err != nil {
+		t.Fatal(err)
+	}
+
+	// One agent approves, one requests changes: any changes_requested blocks and returns the task to the DRI.
+	rt := &replyRuntime{replies: map[string]string{"rev1": "approved LGTM", "rev2": "changes_requested\nplease add tests"}}
+	if _, err := eng.RunTurn(ctx, "t1", task, rt, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.calls) != 2 || *task.CurrentStage != "Coding" {
+		t.Fatalf("calls = %v, stage = %s", rt.calls, *task.CurrentStage)
+	}
+	back, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	if back.Reviewers != "rev1,rev2,ops-lead" || back.Assignee == nil || *back.Assignee != "dev" {
+		t.Fatalf("reviewers = %q, assignee = %v", back.Reviewers, back.Assignee)
+	}
+
+	// Next round: earlier reviews stop counting. The human approves through the API, then the agents'
+	// turn meets the quorum of 2; an outsider cannot review.
+	if _, err := eng.ApplyOutcome(ctx, "t1", task, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if err := review.SubmitReviewVia(ctx, st, eng, "t1", taskID, "mallory", "approved", ""); err == nil || !strings.Contains(err.Error(), "not a reviewer") {
+		t.Fatalf("outsider review: err = %v", err)
+	}
+	if err := review.SubmitReviewVia(ctx, st, eng, "t1", taskID, "ops-lead", "approved", ""); err != nil {
+		t.Fatal(err)
+	}
+	task, _ = st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	if *task.CurrentStage != "Review" {
+		t.Fatalf("one approval of 2 should wait, stage = %s", *task.CurrentStage)
+	}
+	rt.replies["rev2"] = "approved"
+	rt.calls = nil
+	if _, err := eng.RunTurn(ctx, "t1", task, rt, nil); err != nil {
+		t.Fatal(err)
+	}
+	if *task.CurrentStage != "Done" {
+		t.Fatalf("stage = %s, want Done", *task.CurrentStage)
+	}
+}
+
+func TestRunTurn_inlineReviewComments(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+	for _, a := range []string{"dev", "rev1"} {
+		_ = st.CreateAgent(ctx, "t1", a, "engineer")
+	}
+	def, err := ParseDefinition([]byte(`name: inline
+stages:
+  - {name: Coding, type: agent, outcomes: [done]}
+  - {name: Review, type: review, outcomes: [approved, changes_requested], candidate_agents: [rev1]}
+  - {name: Done, type: terminal}
+transitions:
+  - {from: Coding, outcome: done, to: Review}
+  - {from: Review, outcome: approved, to: Done}
+  - {from: Review, outcome: changes_requested, to: Coding}
+`), "inline.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wfID, _, err := CreateFromDefinition(ctx, st, "t1", def, "", 0, "inline.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt := t.TempDir()
+	gitRun(t, wt, "init", "-q", "-b", "feature")
+	if err := os.WriteFile(filepath.Join(wt, "f.txt"), []byte("a\nb\nc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, wt, "add", "f.txt")
+	gitRun(t, wt, "commit", "-q", "-m", "change")
+	taskID, _ := st.CreateTask(ctx, "t1", "trim letters", models.StatusTodo, &wfID)
+	branch := "feature"
+	_ = st.UpdateTaskGitFields(ctx, taskID, &wt, &branch, nil, nil)
+	_, _ = st.ClaimTask(ctx, "t1", taskID, "dev")
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	eng := &Engine{Store: st}
+	if _, err := eng.ApplyOutcome(ctx, "t1", task, "done"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The reviewer's "path:line: text" lines become threads, and the next coding turn sees them.
+	rt := &replyRuntime{replies: map[string]string{"rev1": "changes_requested\nf.txt:2: drop b\nalso update the docs"}}
+	if _, err := eng.RunTurn(ctx, "t1", task, rt, nil); err != nil {
+		t.Fatal(err)
+	}
+	comments, _ := st.ListReviewComments(ctx, "t1", taskID)
+	if len(comments) != 1 || comments[0].Author != "rev1" || comments[0].StartLine != 2 || comments[0].ReviewID == 0 {
+		t.Fatalf("review comments = %+v", comments)
+	}
+	_, _ = st.CreateReviewComment(ctx, "t1", store.ReviewComment{TaskID: taskID, Author: "dev", Body: "which b?", InReplyTo: comments[0].CommentID})
+	task, _ = st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	coder := &inputRuntime{outputs: []string{"done"}}
+	if _, err := eng.RunTurn(ctx, "t1", task, coder, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "Open review comments:\n- [#1] f.txt:2 rev1: drop b\n  - dev: which b?"
+	if len(coder.inputs) != 1 || !strings.Contains(coder.inputs[0], want) {
+		t.Fatalf("coding input = %q, want it to contain %q", coder.inputs, want)
+	}
+
+	// Resolved threads are left out.
+	_ = st.SetReviewThreadResolved(ctx, "t1", taskID, comments[0].CommentID, true)
+	if got := reviewCommentsInput(ctx, st, "t1", task, "title"); got != "title" {
+		t.Fatalf("input with only resolved threads = %q", got)
+	}
+}
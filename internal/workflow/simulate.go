@@ -0,0 +1,219 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ankittk/agentary/internal/review"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// SimOptions describes the hypothetical task a simulation walks through a workflow. Nothing is written
+// to the store.
+type SimOptions struct {
+	// Outcomes are applied in order, starting from the workflow's initial stage.
+	Outcomes []string
+	// ChangedFiles stands in for the task branch's diff (diff_not_empty, no_protected_paths guards).
+	// nil means the branch is assumed to change something that is not protected.
+	ChangedFiles []string
+	// FailTests makes the tests_pass guard fail.
+	FailTests bool
+}
+
+// SimStep is one stage the simulated task passes through.
+type SimStep struct {
+	Stage     string        `json:"stage"`
+	StageType string        `json:"stage_type"`
+	Assignee  string        `json:"assignee,omitempty"`
+	Reviewers []string      `json:"reviewers,omitempty"` // quorum review stages
+	Outcome   string        `json:"outcome,omitempty"`   // outcome applied in this stage ("" for the last step)
+	Guards    []GuardResult `json:"guards,omitempty"`
+	Applied   string        `json:"applied,omitempty"` // on_guard_fail outcome taken instead of Outcome
+	ToStage   string        `json:"to_stage,omitempty"`
+	Error     string        `json:"error,omitempty"` // why the walk stopped here
+}
+
+// Simulation is the result of Simulate.
+type Simulation struct {
+	Workflow string    `json:"workflow"` // name@version
+	Steps    []SimStep `json:"steps"`
+	// Final is the stage the task ends in; Terminal reports whether it is a terminal stage.
+	Final    string `json:"final"`
+	Terminal bool   `json:"terminal"`
+}
+
+// Simulate walks a hypothetical task through the workflow ref (name@version) by applying opts.Outcomes,
+// reporting each stage with the assignee the scheduler would pick (PickAssignee, or the reviewer from
+// review.PickReviewer / review.PickReviewers in review stages) and the guard results on each transition.
+// Guards are evaluated against simulated state: approvals come from earlier approved outcomes, diffs from
+// opts.ChangedFiles. The walk stops at an outcome with no transition or a guard failure with no fallback.
+func Simulate(ctx context.Context, st store.Store, teamName, ref string, opts SimOptions) (*Simulation, error) {
+	name, version, err := ParseWorkflowRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	wfID, err := resolveWorkflowRef(ctx, st, teamName, ref)
+	if err != nil {
+		return nil, err
+	}
+	stages, err := st.GetWorkflowStages(ctx, wfID)
+	if err != nil {
+		return nil, err
+	}
+	initial, err := st.GetWorkflowInitialStage(ctx, wfID)
+	if err != nil {
+		return nil, err
+	}
+	agents, err := st.ListAgents(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	e := &Engine{Store: st}
+	sim := &Simulation{Workflow: fmt.Sprintf("%s@%d", name, version)}
+	task := &store.Task{Title: "simulated task", WorkflowID: &wfID}
+	approvals := make(map[string]bool) // reviewer -> latest review approved
+	returnToDRI := false
+	stage := initial
+	for i := 0; ; i++ {
+		cur := stage
+		task.CurrentStage = &cur
+		def := findStage(stages, stage)
+		if def == nil {
+			sim.Steps = append(sim.Steps, SimStep{Stage: stage, Error: "stage not found in workflow"})
+			break
+		}
+		step := SimStep{Stage: stage, StageType: def.StageType}
+		switch {
+		case def.StageType == "review":
+			step.Reviewers = review.PickReviewers(task, agents, splitList(def.CandidateAgents), max(def.Reviewers, 1))
+		case def.StageType == "terminal" || def.StageType == "human" || def.StageType == "merge":
+		case returnToDRI && task.DRI != nil:
+			step.Assignee = *task.DRI
+		case stage == "InReview" && len(agents) > 0:
+			step.Assignee = review.PickReviewer(ctx, st, teamName, task, agents)
+		case len(agents) > 0:
+			step.Assignee = PickAssignee(ctx, st, teamName, task, agents)
+			if task.DRI == nil {
+				dri := step.Assignee
+				task.DRI = &dri // the first claim sets the DRI
+			}
+		}
+		if i >= len(opts.Outcomes) || def.StageType == "terminal" {
+			if i < len(opts.Outcomes) {
+				step.Error = fmt.Sprintf("terminal stage reached with %d outcome(s) left", len(opts.Outcomes)-i)
+			}
+			sim.Steps = append(sim.Steps, step)
+			break
+		}
+		outcome := strings.TrimSpace(opts.Outcomes[i])
+		step.Outcome = outcome
+		tr, err := e.findTransition(ctx, wfID, stage, outcome)
+		if err != nil {
+			return nil, err
+		}
+		if tr == nil {
+			step.Error = fmt.Sprintf("no transition for %q from %s", outcome, stage)
+			sim.Steps = append(sim.Steps, step)
+			break
+		}
+		// A reviewer's outcome is a review: it counts towards min_approvals on this and later transitions.
+		for _, r := range append(append([]string(nil), step.Reviewers...), reviewerOf(step, def)...) {
+			approvals[r] = outcome == review.Approved
+		}
+		if strings.TrimSpace(tr.Guards) != "" {
+			results, ok := simulateGuards(tr.Guards, approvals, opts)
+			step.Guards = results
+			if !ok {
+				var fallback *store.WorkflowTransition
+				if tr.OnGuardFail != "" && tr.OnGuardFail != outcome {
+					if fallback, err = e.findTransition(ctx, wfID, stage, tr.OnGuardFail); err != nil {
+						return nil, err
+					}
+				}
+				if fallback == nil {
+					step.Error = (&GuardError{Outcome: outcome, Results: results}).Error()
+					sim.Steps = append(sim.Steps, step)
+					break
+				}
+				step.Applied = tr.OnGuardFail
+				outcome, tr = tr.OnGuardFail, fallback
+			}
+		}
+		returnToDRI = outcome == review.ChangesRequested
+		step.ToStage = tr.ToStage
+		sim.Steps = append(sim.Steps, step)
+		stage = tr.ToStage
+	}
+	last := sim.Steps[len(sim.Steps)-1]
+	sim.Final = last.Stage
+	if def := findStage(stages, sim.Final); def != nil {
+		sim.Terminal = def.StageType == "terminal"
+	}
+	return sim, nil
+}
+
+// reviewerOf is who reports a review outcome in a non-quorum stage: the InReview reviewer or a human.
+func reviewerOf(step SimStep, def *store.WorkflowStage) []string {
+	switch {
+	case len(step.Reviewers) > 0:
+		return nil
+	case step.Assignee != "" && step.Stage == "InReview":
+		return []string{step.Assignee}
+	case def.StageType == "human":
+		return []string{"human"}
+	}
+	return nil
+}
+
+// simulateGuards mirrors CheckGuards against the simulated task state.
+func simulateGuards(specs string, approvals map[string]bool, opts SimOptions) ([]GuardResult, bool) {
+	var results []GuardResult
+	ok := true
+	for _, spec := range splitLines(specs) {
+		res := GuardResult{Guard: spec}
+		g, err := ParseGuard(spec)
+		switch {
+		case err != nil:
+			res.Reason = err.Error()
+		case g.Kind == GuardDiffNotEmpty:
+			res.Passed, res.Reason = true, "assumed: the branch has changes"
+			if opts.ChangedFiles != nil {
+				res.Passed = len(opts.ChangedFiles) > 0
+				res.Reason = fmt.Sprintf("%d file(s) changed", len(opts.ChangedFiles))
+			}
+		case g.Kind == GuardTestsPass:
+			res.Passed, res.Reason = !opts.FailTests, "assumed: test_cmd passes"
+			if opts.FailTests {
+				res.Reason = "assumed: test_cmd fails"
+			}
+		case g.Kind == GuardMinApprovals:
+			want, _ := strconv.Atoi(g.Arg)
+			got := 0
+			for _, approved := range approvals {
+				if approved {
+					got++
+				}
+			}
+			res.Passed = got >= want
+			res.Reason = fmt.Sprintf("%d of %d approvals", got, want)
+		case g.Kind == GuardMaxDiffLines:
+			res.Passed, res.Reason = true, "assumed: the diff is within the limit"
+		case g.Kind == GuardNoProtectedPaths:
+			res.Passed = true
+			for _, f := range opts.ChangedFiles {
+				for _, p := range splitList(g.Arg) {
+					if res.Passed && matchProtected(p, f) {
+						res.Passed, res.Reason = false, fmt.Sprintf("%s matches protected path %s", f, p)
+					}
+				}
+			}
+		}
+		if !res.Passed {
+			ok = false
+		}
+		results = append(results, res)
+	}
+	return results, ok
+}
@@ -0,0 +1,114 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func TestSimulate(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+	_ = st.CreateAgent(ctx, "t1", "alice", "engineer")
+	_ = st.CreateAgent(ctx, "t1", "bob", "engineer")
+	_ = st.CreateAgent(ctx, "t1", "carol", "engineer")
+
+	def, err := ParseDefinition([]byte(`name: sim
+stages:
+  - name: Coding
+    type: agent
+    outcomes: [done]
+    candidate_agents: [bob]
+  - name: Review
+    type: review
+    outcomes: [approved, changes_requested]
+    reviewers: 2
+  - name: Ship
+    type: human
+    outcomes: [approved, changes_requested]
+  - name: Done
+    type: terminal
+transitions:
+  - {from: Coding, outcome: done, to: Review, guards: ["no_protected_paths:infra/"]}
+  - {from: Review, outcome: approved, to: Ship}
+  - {from: Review, outcome: changes_requested, to: Coding}
+  - {from: Ship, outcome: approved, to: Done, guards: ["min_approvals:3", "tests_pass"], on_guard_fail: changes_requested}
+  - {from: Ship, outcome: changes_requested, to: Coding}
+`), "sim.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := CreateFromDefinition(ctx, st, "t1", def, "", 0, "sim.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	sim, err := Simulate(ctx, st, "t1", "sim", SimOptions{Outcomes: []string{"done", "changes_requested", "done", "approved", "approved"}})
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+	var path []string
+	for _, s := range sim.Steps {
+		path = append(path, s.Stage)
+	}
+	if got, want := strings.Join(path, ","), "Coding,Review,Coding,Review,Ship,Done"; got != want {
+		t.Fatalf("path = %s, want %s", got, want)
+	}
+	if a := sim.Steps[0].Assignee; a != "bob" {
+		t.Errorf("Coding assignee = %q, want bob (candidate pool)", a)
+	}
+	if r := sim.Steps[1].Reviewers; len(r) != 2 || r[0] != "alice" || r[1] != "carol" {
+		t.Errorf("Review reviewers = %v, want [alice carol] (not the DRI)", r)
+	}
+	if a := sim.Steps[2].Assignee; a != "bob" {
+		t.Errorf("Coding after changes_requested: assignee %q, want the DRI bob", a)
+	}
+	// Two reviewers and the human make three approvals.
+	if s := sim.Steps[4]; s.ToStage != "Done" || len(s.Guards) != 2 || !s.Guards[0].Passed || !s.Guards[1].Passed {
+		t.Errorf("Ship step = %+v", s)
+	}
+	if !sim.Terminal || sim.Final != "Done" {
+		t.Errorf("final = %s terminal=%v", sim.Final, sim.Terminal)
+	}
+
+	// Failing tests take the on_guard_fail fallback back to Coding.
+	sim, err = Simulate(ctx, st, "t1", "sim", SimOptions{Outcomes: []string{"done", "approved", "approved"}, FailTests: true})
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+	if s := sim.Steps[2]; s.Applied != "changes_requested" || s.ToStage != "Coding" || s.Guards[1].Passed {
+		t.Errorf("Ship step with failing tests = %+v", s)
+	}
+	if sim.Terminal || sim.Final != "Coding" {
+		t.Errorf("final = %s terminal=%v", sim.Final, sim.Terminal)
+	}
+
+	// A protected path blocks the first transition (no fallback); an unknown outcome stops the walk.
+	sim, err = Simulate(ctx, st, "t1", "sim@1", SimOptions{Outcomes: []string{"done"}, ChangedFiles: []string{"infra/main.tf"}})
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+	if len(sim.Steps) != 1 || sim.Steps[0].Error == "" || sim.Final != "Coding" {
+		t.Errorf("protected path: %+v", sim.Steps)
+	}
+	sim, _ = Simulate(ctx, st, "t1", "sim", SimOptions{Outcomes: []string{"merge"}})
+	if len(sim.Steps) != 1 || sim.Steps[0].Error != `no transition for "merge" from Coding` {
+		t.Errorf("unknown outcome: %+v", sim.Steps)
+	}
+	if _, err := Simulate(ctx, st, "t1", "missing", SimOptions{}); err == nil {
+		t.Error("expected error for unknown workflow")
+	}
+}
@@ -0,0 +1,210 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ankittk/agentary/internal/capabilities"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// SLA timeout actions (WorkflowStage.OnTimeout). An optional argument follows a colon,
+// e.g. "notify:slack", "reassign:alice", "transition:changes_requested".
+const (
+	OnTimeoutNotify     = "notify"
+	OnTimeoutReassign   = "reassign"
+	OnTimeoutTransition = "transition"
+	OnTimeoutFail       = "fail"
+)
+
+// ParseOnTimeout splits an on_timeout value into action and argument and validates the action.
+// Empty means notify on the default capability.
+func ParseOnTimeout(v string) (action, arg string, err error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return OnTimeoutNotify, "", nil
+	}
+	action, arg, _ = strings.Cut(v, ":")
+	action, arg = strings.TrimSpace(action), strings.TrimSpace(arg)
+	switch action {
+	case OnTimeoutNotify, OnTimeoutReassign, OnTimeoutFail:
+		return action, arg, nil
+	case OnTimeoutTransition:
+		if arg == "" {
+			return "", "", fmt.Errorf("on_timeout %q: transition requires an outcome", v)
+		}
+		return action, arg, nil
+	default:
+		return "", "", fmt.Errorf("on_timeout %q: unknown action (want notify, reassign, transition:<outcome> or fail)", v)
+	}
+}
+
+// SLAMonitor polls for tasks that have exceeded their stage's max_duration and runs the stage's on_timeout action.
+// Each breach is handled once per stage entry; a new stage entry resets it.
+type SLAMonitor struct {
+	Store store.Store
+	// Home is passed to the engine so checks and actions on a transition run in the sandbox.
+	Home string
+	// Capabilities is used by the notify action; notify is logged only when nil.
+	Capabilities *capabilities.Registry
+	// Publish, when set, receives sla_breach and task_update events (e.g. SSEHub.PublishJSON).
+	Publish func(v any)
+	// Interval between poll rounds
+	Interval time.Duration
+	// Now overrides the clock (tests).
+	Now func() time.Time
+}
+
+const (
+	defaultSLAInterval   = 30 * time.Second
+	defaultNotifyChannel = "slack"
+)
+
+// Run runs the SLA monitor until ctx is cancelled.
+func (m *SLAMonitor) Run(ctx context.Context) {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = defaultSLAInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce handles every breach that is outstanding at the current time.
+func (m *SLAMonitor) RunOnce(ctx context.Context) {
+	now := time.Now().UTC()
+	if m.Now != nil {
+		now = m.Now().UTC()
+	}
+	breaches, err := m.Store.ListStageSLABreaches(ctx, now)
+	if err != nil {
+		slog.Error("sla monitor list breaches failed", "err", err)
+		return
+	}
+	for _, b := range breaches {
+		m.handle(ctx, b, now)
+	}
+}
+
+func (m *SLAMonitor) handle(ctx context.Context, b store.StageSLABreach, now time.Time) {
+	task := b.Task
+	stage := b.Stage.StageName
+	var elapsed time.Duration
+	if task.StageEnteredAt != nil {
+		elapsed = now.Sub(*task.StageEnteredAt).Truncate(time.Second)
+	}
+	if err := m.Store.MarkTaskSLABreached(ctx, task.TaskID, now); err != nil {
+		slog.Error("sla monitor mark breached failed", "task_id", task.TaskID, "err", err)
+		return
+	}
+	action, arg, err := ParseOnTimeout(b.Stage.OnTimeout)
+	if err != nil {
+		slog.Warn("sla monitor invalid on_timeout; notifying instead", "stage", stage, "err", err)
+		action, arg = OnTimeoutNotify, ""
+	}
+	result := ""
+	switch action {
+	case OnTimeoutNotify:
+		result = m.notify(ctx, arg, fmt.Sprintf("[%s] Task #%d %q has been in %s for %s (SLA %s)", b.TeamName, task.TaskID, task.Title, stage, elapsed, b.Stage.MaxDuration))
+	case OnTimeoutReassign:
+		agents, _ := m.Store.ListAgents(ctx, b.TeamName)
+		next := reassignTarget(arg, task.Assignee, b.Stage.CandidateAgents, agents)
+		if arg != "" && next != arg {
+			_, _ = m.Store.CreateTaskComment(ctx, b.TeamName, task.TaskID, "sla",
+				fmt.Sprintf("SLA breached in %s: %q is not an agent on the team; reassigning from the stage's pool instead", stage, arg))
+		}
+		if next == "" {
+			result = "no agent to reassign to"
+			break
+		}
+		if err := m.Store.UpdateTask(ctx, task.TaskID, "", &next); err != nil {
+			result = err.Error()
+			break
+		}
+		result = "reassigned to " + next
+	case OnTimeoutTransition:
+		eng := &Engine{Store: m.Store, Home: m.Home, Capabilities: m.Capabilities}
+		next, err := eng.ApplyOutcome(store.WithTransitionCause(ctx, store.TransitionCause{Actor: "sla", Note: "SLA breached in " + b.Stage.StageName}), b.TeamName, &task, arg)
+		switch {
+		case err != nil:
+			result = err.Error()
+		case next == "":
+			result = fmt.Sprintf("no transition for outcome %q", arg)
+		default:
+			result = "moved to " + next
+		}
+	case OnTimeoutFail:
+		if err := m.Store.SetTaskFailed(ctx, task.TaskID); err != nil {
+			result = err.Error()
+			break
+		}
+		result = "failed"
+	}
+	slog.Info("stage SLA breached", "team", b.TeamName, "task_id", task.TaskID, "stage", stage, "elapsed", elapsed, "action", action, "result", result)
+	if m.Publish != nil {
+		m.Publish(map[string]any{
+			"type":         "sla_breach",
+			"team":         b.TeamName,
+			"task_id":      task.TaskID,
+			"stage":        stage,
+			"max_duration": b.Stage.MaxDuration.String(),
+			"elapsed":      elapsed.String(),
+			"action":       action,
+			"result":       result,
+		})
+		if action != OnTimeoutNotify {
+			m.Publish(map[string]any{"type": "task_update", "team": b.TeamName, "task_id": task.TaskID})
+		}
+	}
+}
+
+func (m *SLAMonitor) notify(ctx context.Context, capability, message string) string {
+	if capability == "" {
+		capability = defaultNotifyChannel
+	}
+	if m.Capabilities == nil || m.Capabilities.Get(capability) == nil {
+		return fmt.Sprintf("capability %q not configured", capability)
+	}
+	if err := m.Capabilities.Notify(ctx, capability, message); err != nil {
+		return err.Error()
+	}
+	return "notified " + capability
+}
+
+// reassignTarget picks the agent to hand a breached task to: the explicit agent if it is on the team,
+// else the first agent in the stage's candidate pool (or the team) other than the current assignee.
+func reassignTarget(explicit string, current *string, candidateAgents string, agents []store.Agent) string {
+	for _, a := range agents {
+		if explicit != "" && a.Name == explicit {
+			return explicit
+		}
+	}
+	cur := ""
+	if current != nil {
+		cur = *current
+	}
+	pool := make(map[string]bool)
+	for _, name := range strings.Split(candidateAgents, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			pool[name] = true
+		}
+	}
+	for _, a := range agents {
+		if a.Name == cur || (len(pool) > 0 && !pool[a.Name]) {
+			continue
+		}
+		return a.Name
+	}
+	return ""
+}
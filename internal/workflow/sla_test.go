@@ -0,0 +1,194 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankittk/agentary/internal/capabilities"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+type recordingCapability struct{ messages []string }
+
+func (r *recordingCapability) Name() string { return "rec" }
+func (r *recordingCapability) Notify(_ context.Context, message string) error {
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func TestParseOnTimeout(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct{ in, action, arg string }{
+		{"", OnTimeoutNotify, ""},
+		{"notify:slack", OnTimeoutNotify, "slack"},
+		{"reassign", OnTimeoutReassign, ""},
+		{"transition:changes_requested", OnTimeoutTransition, "changes_requested"},
+		{"fail", OnTimeoutFail, ""},
+	} {
+		action, arg, err := ParseOnTimeout(tc.in)
+		if err != nil || action != tc.action || arg != tc.arg {
+			t.Errorf("ParseOnTimeout(%q) = %q, %q, %v", tc.in, action, arg, err)
+		}
+	}
+	for _, bad := range []string{"transition", "explode"} {
+		if _, _, err := ParseOnTimeout(bad); err == nil {
+			t.Errorf("ParseOnTimeout(%q): expected error", bad)
+		}
+	}
+}
+
+func TestSLAMonitor_actions(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	_ = st.CreateAgent(ctx, "t1", "alice", "engineer")
+	_ = st.CreateAgent(ctx, "t1", "bob", "engineer")
+	stages := []store.WorkflowStage{
+		{StageName: "Backlog", StageType: "human", Outcomes: "start"},
+		{StageName: "Coding", StageType: "agent", Outcomes: "done", MaxDuration: time.Hour, OnTimeout: "reassign"},
+		{StageName: "Review", StageType: "human", Outcomes: "approved,changes_requested", MaxDuration: 2 * time.Hour, OnTimeout: "transition:changes_requested"},
+		{StageName: "Done", StageType: "terminal"},
+	}
+	transitions := []store.WorkflowTransition{
+		{FromStage: "Backlog", Outcome: "start", ToStage: "Coding"},
+		{FromStage: "Coding", Outcome: "done", ToStage: "Review"},
+		{FromStage: "Review", Outcome: "approved", ToStage: "Done"},
+		{FromStage: "Review", Outcome: "changes_requested", ToStage: "Coding"},
+	}
+	wfID, err := st.CreateWorkflowWithStages(ctx, "t1", "sla", 1, "builtin:sla", stages, transitions)
+	if err != nil {
+		t.Fatalf("CreateWorkflowWithStages: %v", err)
+	}
+	coding, _ := st.CreateTask(ctx, "t1", "coding task", "todo", &wfID)
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", coding)
+	if task.CurrentStage == nil || *task.CurrentStage != "Backlog" || task.StageEnteredAt == nil {
+		t.Fatalf("expected stage_entered_at to be set on create, got %+v", task)
+	}
+	_ = st.UpdateTaskStage(ctx, coding, "Coding")
+	alice := "alice"
+	_ = st.UpdateTask(ctx, coding, models.StatusInProgress, &alice)
+	review, _ := st.CreateTask(ctx, "t1", "review task", "todo", &wfID)
+	_ = st.UpdateTaskStage(ctx, review, "Review")
+
+	var events []map[string]any
+	clock := time.Now().UTC().Add(30 * time.Minute)
+	m := &SLAMonitor{Store: st, Now: func() time.Time { return clock }, Publish: func(v any) {
+		if ev, ok := v.(map[string]any); ok && ev["type"] == "sla_breach" {
+			events = append(events, ev)
+		}
+	}}
+	m.RunOnce(ctx)
+	if len(events) != 0 {
+		t.Fatalf("no breach expected within SLA, got %v", events)
+	}
+
+	clock = clock.Add(time.Hour) // Coding breached (1h), Review not yet (2h)
+	m.RunOnce(ctx)
+	if len(events) != 1 || events[0]["task_id"] != coding {
+		t.Fatalf("expected one breach for coding task, got %v", events)
+	}
+	task, _ = st.GetTaskByIDAndTeam(ctx, "t1", coding)
+	if task.Assignee == nil || *task.Assignee != "bob" || task.SLABreachedAt == nil {
+		t.Fatalf("expected reassign to bob and breach recorded, got %+v", task)
+	}
+	m.RunOnce(ctx)
+	if len(events) != 1 {
+		t.Fatalf("breach must be handled once per stage entry, got %d events", len(events))
+	}
+
+	clock = clock.Add(time.Hour)
+	m.RunOnce(ctx)
+	task, _ = st.GetTaskByIDAndTeam(ctx, "t1", review)
+	if task.CurrentStage == nil || *task.CurrentStage != "Coding" || task.SLABreachedAt != nil {
+		t.Fatalf("expected review task moved back to Coding with fresh SLA, got %+v", task)
+	}
+}
+
+func TestSLAMonitor_notify(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	wfID, _ := st.CreateWorkflowWithStages(ctx, "t1", "wf", 1, "builtin:wf",
+		[]store.WorkflowStage{{StageName: "Coding", StageType: "agent", Outcomes: "done"}, {StageName: "Done", StageType: "terminal"}},
+		[]store.WorkflowTransition{{FromStage: "Coding", Outcome: "done", ToStage: "Done"}})
+	if err := st.SetWorkflowStageSLA(ctx, wfID, "Coding", 10*time.Minute, "notify:rec"); err != nil {
+		t.Fatalf("SetWorkflowStageSLA: %v", err)
+	}
+	if err := st.SetWorkflowStageSLA(ctx, wfID, "Nope", time.Minute, ""); err == nil {
+		t.Fatal("expected error for unknown stage")
+	}
+	_, _ = st.CreateTask(ctx, "t1", "slow", "todo", &wfID)
+
+	rec := &recordingCapability{}
+	reg := capabilities.NewRegistry()
+	reg.Register("rec", rec)
+	m := &SLAMonitor{Store: st, Capabilities: reg, Now: func() time.Time { return time.Now().Add(11 * time.Minute) }}
+	m.RunOnce(ctx)
+	if len(rec.messages) != 1 {
+		t.Fatalf("expected one notification, got %v", rec.messages)
+	}
+}
+
+func TestSLAMonitor_reassignUnknownAgent(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	_ = st.CreateAgent(ctx, "t1", "alice", "engineer")
+	_ = st.CreateAgent(ctx, "t1", "bob", "engineer")
+	wfID, _ := st.CreateWorkflowWithStages(ctx, "t1", "wf", 1, "builtin:wf",
+		[]store.WorkflowStage{{StageName: "Coding", StageType: "agent", Outcomes: "done"}, {StageName: "Done", StageType: "terminal"}},
+		[]store.WorkflowTransition{{FromStage: "Coding", Outcome: "done", ToStage: "Done"}})
+	// "ghost" left the team after the SLA was set.
+	if err := st.SetWorkflowStageSLA(ctx, wfID, "Coding", 10*time.Minute, "reassign:ghost"); err != nil {
+		t.Fatalf("SetWorkflowStageSLA: %v", err)
+	}
+	id, _ := st.CreateTask(ctx, "t1", "slow", "todo", &wfID)
+	alice := "alice"
+	_ = st.UpdateTask(ctx, id, models.StatusInProgress, &alice)
+
+	m := &SLAMonitor{Store: st, Now: func() time.Time { return time.Now().Add(11 * time.Minute) }}
+	m.RunOnce(ctx)
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", id)
+	if task.Assignee == nil || *task.Assignee != "bob" {
+		t.Fatalf("assignee = %v, want bob from the pool", task.Assignee)
+	}
+	comments, _ := st.ListTaskComments(ctx, "t1", id)
+	if len(comments) != 1 || comments[0].Author != "sla" || !strings.Contains(comments[0].Body, `"ghost" is not an agent`) {
+		t.Fatalf("comments = %+v, want a note about the unknown agent", comments)
+	}
+}
@@ -0,0 +1,188 @@
+package workflow
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	agentrt "github.com/ankittk/agentary/internal/agent/runtime"
+	"github.com/ankittk/agentary/internal/memory"
+	"github.com/ankittk/agentary/internal/merge"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// Turn is one scheduling turn for a task sitting in Stage.
+type Turn struct {
+	Team    string
+	Task    *store.Task
+	Stage   *store.WorkflowStage
+	Runtime agentrt.Runtime
+	Emit    func(ev agentrt.Event)
+}
+
+// StageHandler runs a turn for tasks in stages of one type. It typically does some work and then
+// moves the task on with e.ApplyOutcome; returning an error marks the task failed.
+type StageHandler interface {
+	RunStage(ctx context.Context, e *Engine, t *Turn) error
+}
+
+// StageLinter is optionally implemented by a StageHandler to check stages of its type during Lint.
+// Each returned message is reported as a stage_config error.
+type StageLinter interface {
+	LintStage(st *StageDef) []string
+}
+
+// StageHandlerFunc adapts a function to a StageHandler.
+type StageHandlerFunc func(ctx context.Context, e *Engine, t *Turn) error
+
+// RunStage calls f(ctx, e, t).
+func (f StageHandlerFunc) RunStage(ctx context.Context, e *Engine, t *Turn) error {
+	return f(ctx, e, t)
+}
+
+var (
+	stageHandlersMu sync.RWMutex
+	stageHandlers   = make(map[string]StageHandler)
+)
+
+// RegisterStageHandler makes stageType usable in workflows: the engine dispatches its turns to h and
+// lint accepts it. It panics if stageType is empty or already registered, like RegisterAction.
+func RegisterStageHandler(stageType string, h StageHandler) {
+	stageHandlersMu.Lock()
+	defer stageHandlersMu.Unlock()
+	if stageType == "" || h == nil {
+		panic("workflow: RegisterStageHandler needs a stage type and a handler")
+	}
+	if _, dup := stageHandlers[stageType]; dup {
+		panic("workflow: RegisterStageHandler called twice for " + stageType)
+	}
+	stageHandlers[stageType] = h
+}
+
+// StageHandlerFor returns the handler registered for stageType, or nil.
+func StageHandlerFor(stageType string) StageHandler {
+	stageHandlersMu.RLock()
+	defer stageHandlersMu.RUnlock()
+	return stageHandlers[stageType]
+}
+
+// StageTypes returns the registered stage types, sorted.
+func StageTypes() []string {
+	stageHandlersMu.RLock()
+	defer stageHandlersMu.RUnlock()
+	types := make([]string, 0, len(stageHandlers))
+	for t := range stageHandlers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func init() {
+	RegisterStageHandler("agent", StageHandlerFunc(runAgentStage))
+	RegisterStageHandler("human", StageHandlerFunc(runHumanStage))
+	RegisterStageHandler("auto", StageHandlerFunc(func(ctx context.Context, e *Engine, t *Turn) error {
+		return e.runAutoStage(ctx, t.Team, t.Task, t.Stage)
+	}))
+	RegisterStageHandler("review", reviewStage{})
+	RegisterStageHandler("plan", planStage{})
+	RegisterStageHandler("merge", StageHandlerFunc(runMergeStage))
+	RegisterStageHandler("terminal", StageHandlerFunc(runTerminalStage))
+}
+
+// runAgentStage runs the assignee's runtime once; its output is the outcome ("done" if empty).
+// The task's approved plan, if any, is appended to the input, and so are its open inline review comments
+// and the merge conflicts to resolve when the merge queue sent the task back.
+func runAgentStage(ctx context.Context, e *Engine, t *Turn) error {
+	task := t.Task
+	agentName := ""
+	if task.Assignee != nil {
+		agentName = *task.Assignee
+	}
+	allowlist, _ := e.Store.ListAllowedDomains(ctx)
+	req := agentrt.TurnRequest{
+		Team:             t.Team,
+		Agent:            agentName,
+		TaskID:           &task.TaskID,
+		Input:            conflictInput(ctx, e.Store, t.Team, task, reviewCommentsInput(ctx, e.Store, t.Team, task, planInput(ctx, e.Store, t.Team, task, task.Title))),
+		NetworkAllowlist: allowlist,
+	}
+	if e.Home != "" && agentName != "" {
+		teamDir := memory.TeamDir(e.Home, t.Team)
+		agentDir := memory.AgentDir(teamDir, agentName)
+		if cfg, _ := memory.LoadAgentConfig(agentDir); cfg != nil {
+			req.Model = cfg.Model
+			req.MaxTokens = cfg.MaxTokens
+		}
+	}
+	started := time.Now().UTC()
+	result, runErr := t.Runtime.RunTurn(ctx, req, t.Emit)
+	if runErr != nil {
+		e.recordTurn(ctx, t, agentName, "", runErr, started)
+		_ = e.Store.SetTaskFailed(ctx, task.TaskID)
+		return runErr
+	}
+	outcome := strings.TrimSpace(result.Output)
+	if outcome == "" || outcome == "stub: ok" {
+		outcome = "done"
+	}
+	e.recordTurn(ctx, t, agentName, outcome, nil, started)
+	// Append to agent journal after successful turn
+	if e.Home != "" && agentName != "" {
+		teamDir := memory.TeamDir(e.Home, t.Team)
+		j := &memory.Journal{AgentName: agentName, TeamDir: teamDir}
+		_ = j.Append(ctx, memory.JournalEntry{
+			TaskID:    task.TaskID,
+			TaskTitle: task.Title,
+			Outcome:   outcome,
+			CreatedAt: time.Now().UTC(),
+		})
+	}
+	if agentName != "" {
+		ctx = store.WithTransitionCause(ctx, store.TransitionCause{Actor: agentName})
+	}
+	_, err := e.ApplyOutcome(ctx, t.Team, task, outcome)
+	return err
+}
+
+// recordTurn stores an agent turn for the task timeline; failures to record are ignored.
+func (e *Engine) recordTurn(ctx context.Context, t *Turn, agent, outcome string, runErr error, started time.Time) {
+	turn := store.TaskTurn{TaskID: t.Task.TaskID, Agent: agent, Stage: t.Stage.StageName, Outcome: outcome, StartedAt: started, FinishedAt: time.Now().UTC()}
+	if runErr != nil {
+		turn.Error = runErr.Error()
+	}
+	_, _ = e.Store.CreateTaskTurn(ctx, t.Team, turn)
+}
+
+// runHumanStage waits: human stages move on through the approve and review APIs. In an approval stage
+// the repo's approval policy decides first, once per entry into the stage, and may approve the task.
+func runHumanStage(ctx context.Context, e *Engine, t *Turn) error {
+	if !isApprovalStage(t.Stage) {
+		return nil
+	}
+	decided, err := e.approvalDecided(ctx, t.Team, t.Task)
+	if err != nil || decided {
+		return err
+	}
+	_, err = e.DecideApproval(ctx, t.Team, t.Task)
+	return err
+}
+
+// runMergeStage puts the task branch in its repo's merge queue; the merge worker tests it on top of the
+// queue head, lands it and finishes the task. A task without a branch has nothing to land and moves on.
+func runMergeStage(ctx context.Context, e *Engine, t *Turn) error {
+	queued, err := merge.Enqueue(ctx, e.Store, t.Team, t.Task)
+	if err != nil || queued {
+		return err
+	}
+	_, _ = e.ApplyOutcome(ctx, t.Team, t.Task, "done")
+	return nil
+}
+
+func runTerminalStage(ctx context.Context, e *Engine, t *Turn) error {
+	_ = e.Store.UpdateTask(ctx, t.Task.TaskID, models.StatusDone, nil)
+	return nil
+}
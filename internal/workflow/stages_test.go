@@ -0,0 +1,94 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+type ciStage struct{ runs int }
+
+func (c *ciStage) RunStage(ctx context.Context, e *Engine, t *Turn) error {
+	c.runs++
+	_, err := e.ApplyOutcome(ctx, t.Team, t.Task, "green")
+	return err
+}
+
+func (c *ciStage) LintStage(st *StageDef) []string {
+	if !containsString(st.Outcomes, "green") {
+		return []string{`needs a "green" outcome`}
+	}
+	return nil
+}
+
+func TestStageHandlerRegistry(t *testing.T) {
+	t.Parallel()
+	ci := &ciStage{}
+	RegisterStageHandler("test-ci", ci)
+	for _, typ := range []string{"agent", "human", "review", "auto", "merge", "terminal", "test-ci"} {
+		if StageHandlerFor(typ) == nil {
+			t.Errorf("no handler for %q", typ)
+		}
+	}
+
+	_, diags := LintDefinition([]byte(`name: bad-ci
+stages:
+  - {name: CI, type: test-ci, outcomes: [red]}
+  - {name: Done, type: terminal}
+transitions:
+  - {from: CI, outcome: red, to: Done}
+`), "bad.yaml", nil)
+	if len(diags) != 1 || diags[0].Code != "stage_config" || diags[0].Stage != "CI" {
+		t.Fatalf("diagnostics = %+v, want one stage_config error", diags)
+	}
+
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+	def, err := ParseDefinition([]byte(`name: ci
+stages:
+  - {name: CI, type: test-ci, outcomes: [green]}
+  - {name: Merging, type: merge, outcomes: [done]}
+  - {name: Done, type: terminal}
+transitions:
+  - {from: CI, outcome: green, to: Merging}
+  - {from: Merging, outcome: done, to: Done}
+`), "ci.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wfID, _, err := CreateFromDefinition(ctx, st, "t1", def, "", 0, "ci.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskID, _ := st.CreateTask(ctx, "t1", "ci task", models.StatusInProgress, &wfID)
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	eng := &Engine{Store: st}
+	for _, want := range []string{"Merging", "Done"} {
+		if handled, err := eng.RunTurn(ctx, "t1", task, nil, nil); !handled || err != nil {
+			t.Fatalf("RunTurn: %v, %v", handled, err)
+		}
+		if *task.CurrentStage != want {
+			t.Fatalf("stage = %s, want %s", *task.CurrentStage, want)
+		}
+	}
+	if ci.runs != 1 {
+		t.Errorf("ci handler ran %d times", ci.runs)
+	}
+	done, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+	if done.Status != models.StatusDone {
+		t.Errorf("status = %s, want done", done.Status)
+	}
+}
@@ -0,0 +1,195 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ankittk/agentary/internal/capabilities"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+// Subtask policies (Task.SubtaskPolicy): what a failed subtask does to its parent once all subtasks have
+// finished. Cancelled subtasks never block or fail the parent.
+const (
+	SubtaskPolicyFail   = "fail"   // fail the parent (default)
+	SubtaskPolicyIgnore = "ignore" // advance the parent anyway
+	SubtaskPolicyWait   = "wait"   // hold the parent until failed subtasks are retried and done
+)
+
+// RollupOutcome is the outcome applied to a parent task when its subtasks have finished.
+const RollupOutcome = "done"
+
+// ParseSubtaskPolicy validates a subtask policy; empty means SubtaskPolicyFail.
+func ParseSubtaskPolicy(v string) (string, error) {
+	switch v = strings.TrimSpace(v); v {
+	case "":
+		return SubtaskPolicyFail, nil
+	case SubtaskPolicyFail, SubtaskPolicyIgnore, SubtaskPolicyWait:
+		return v, nil
+	}
+	return "", fmt.Errorf("subtask policy %q: want fail, ignore or wait", v)
+}
+
+// SubtaskMonitor polls for parent tasks whose subtasks have all finished and rolls them up: the parent is
+// moved on with RollupOutcome (or marked done if it has no workflow), or failed per its subtask policy.
+// While any subtask is open the scheduler does not run the parent.
+type SubtaskMonitor struct {
+	Store store.Store
+	// Home is passed to the engine so checks and actions on the parent's transition run in the sandbox.
+	Home string
+	// Capabilities is passed to the engine for guards and hooks on the parent's transition.
+	Capabilities *capabilities.Registry
+	// Publish, when set, receives task_update events (e.g. SSEHub.PublishJSON).
+	Publish func(v any)
+	// Interval between poll rounds
+	Interval time.Duration
+}
+
+const defaultSubtaskInterval = 10 * time.Second
+
+// Run runs the monitor until ctx is cancelled.
+func (m *SubtaskMonitor) Run(ctx context.Context) {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = defaultSubtaskInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce rolls up every parent whose subtasks have all finished.
+func (m *SubtaskMonitor) RunOnce(ctx context.Context) {
+	rollups, err := m.Store.ListSubtaskRollups(ctx)
+	if err != nil {
+		slog.Error("subtask monitor list rollups failed", "err", err)
+		return
+	}
+	for _, r := range rollups {
+		m.handle(ctx, r)
+	}
+}
+
+func (m *SubtaskMonitor) handle(ctx context.Context, r store.SubtaskRollup) {
+	parent := r.Parent
+	policy, err := ParseSubtaskPolicy(parent.SubtaskPolicy)
+	if err != nil {
+		slog.Warn("subtask monitor invalid policy; failing on failed subtasks", "task_id", parent.TaskID, "err", err)
+		policy = SubtaskPolicyFail
+	}
+	if r.Failed > 0 && policy == SubtaskPolicyWait {
+		return // re-checked every round until the failed subtasks are retried
+	}
+	summary := fmt.Sprintf("Subtasks finished: %d done, %d failed, %d cancelled", r.Done, r.Failed, r.Cancelled)
+	eng := &Engine{Store: m.Store, Home: m.Home, Capabilities: m.Capabilities}
+	status := parent.Status
+	switch {
+	case r.Failed > 0 && policy == SubtaskPolicyFail:
+		eng.comment(ctx, r.TeamName, parent.TaskID, summary+"; failing the parent (subtask policy fail)")
+		if err := m.Store.SetTaskFailed(ctx, parent.TaskID); err != nil {
+			slog.Error("subtask monitor fail parent failed", "task_id", parent.TaskID, "err", err)
+			return
+		}
+		status = models.StatusFailed
+	case parent.WorkflowID == nil || *parent.WorkflowID == "":
+		eng.comment(ctx, r.TeamName, parent.TaskID, summary)
+		if err := m.Store.UpdateTask(ctx, parent.TaskID, models.StatusDone, nil); err != nil {
+			slog.Error("subtask monitor complete parent failed", "task_id", parent.TaskID, "err", err)
+			return
+		}
+		status = models.StatusDone
+	default:
+		actx := store.WithTransitionCause(ctx, store.TransitionCause{Actor: "subtasks", Note: summary})
+		next, err := eng.ApplyOutcome(actx, r.TeamName, &parent, RollupOutcome)
+		var guardErr *GuardError
+		switch {
+		case errors.As(err, &guardErr):
+			// ApplyOutcome has commented; leave the parent for a human.
+		case err != nil:
+			slog.Error("subtask monitor advance parent failed", "task_id", parent.TaskID, "err", err)
+			return
+		case next == "":
+			eng.comment(ctx, r.TeamName, parent.TaskID, fmt.Sprintf("%s; no %q transition from %s, leaving the parent in place", summary, RollupOutcome, stageLabel(derefString(parent.CurrentStage))))
+		default:
+			eng.comment(ctx, r.TeamName, parent.TaskID, fmt.Sprintf("%s; moved to %s", summary, next))
+			if updated, _ := m.Store.GetTaskByIDAndTeam(ctx, r.TeamName, parent.TaskID); updated != nil {
+				status = updated.Status
+			}
+		}
+	}
+	if err := m.Store.MarkSubtasksRolledUp(ctx, parent.TaskID, time.Now().UTC()); err != nil {
+		slog.Error("subtask monitor mark rolled up failed", "task_id", parent.TaskID, "err", err)
+	}
+	if m.Publish != nil {
+		m.Publish(map[string]any{"type": "task_update", "team": r.TeamName, "task_id": parent.TaskID, "status": status})
+	}
+}
+
+// TaskNode is a task with its subtasks, for tree listings.
+type TaskNode struct {
+	store.Task
+	Subtasks []TaskNode `json:"Subtasks,omitempty"`
+}
+
+// TaskTree nests tasks under their parents, keeping the input order at each level. Tasks whose parent is
+// not in the list are roots.
+func TaskTree(tasks []store.Task) []TaskNode {
+	present := make(map[int64]bool, len(tasks))
+	for _, t := range tasks {
+		present[t.TaskID] = true
+	}
+	children := make(map[int64][]store.Task)
+	var roots []store.Task
+	for _, t := range tasks {
+		if t.ParentTaskID != nil && present[*t.ParentTaskID] && *t.ParentTaskID != t.TaskID {
+			children[*t.ParentTaskID] = append(children[*t.ParentTaskID], t)
+			continue
+		}
+		roots = append(roots, t)
+	}
+	var build func(ts []store.Task) []TaskNode
+	build = func(ts []store.Task) []TaskNode {
+		out := make([]TaskNode, 0, len(ts))
+		for _, t := range ts {
+			out = append(out, TaskNode{Task: t, Subtasks: build(children[t.TaskID])})
+		}
+		return out
+	}
+	return build(roots)
+}
+
+// SubtaskTree returns all descendants of taskID, nested.
+func SubtaskTree(ctx context.Context, st store.Store, teamName string, taskID int64) ([]TaskNode, error) {
+	subtasks, err := st.ListSubtasks(ctx, teamName, taskID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]TaskNode, 0, len(subtasks))
+	for _, t := range subtasks {
+		children, err := SubtaskTree(ctx, st, teamName, t.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, TaskNode{Task: t, Subtasks: children})
+	}
+	return out, nil
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
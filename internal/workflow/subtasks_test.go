@@ -0,0 +1,149 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+func TestSubtaskMonitor_rollup(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	stages := []store.WorkflowStage{
+		{StageName: "Epic", StageType: "human", Outcomes: "done"},
+		{StageName: "Review", StageType: "human", Outcomes: "approved"},
+		{StageName: "Done", StageType: "terminal"},
+	}
+	transitions := []store.WorkflowTransition{
+		{FromStage: "Epic", Outcome: "done", ToStage: "Review"},
+		{FromStage: "Review", Outcome: "approved", ToStage: "Done"},
+	}
+	wfID, err := st.CreateWorkflowWithStages(ctx, "t1", "epic", 1, "builtin:epic", stages, transitions)
+	if err != nil {
+		t.Fatalf("CreateWorkflowWithStages: %v", err)
+	}
+	newParent := func(policy string) int64 {
+		id, _ := st.CreateTask(ctx, "t1", "epic "+policy, "todo", &wfID)
+		if policy != "" {
+			_ = st.SetTaskSubtaskPolicy(ctx, id, policy)
+		}
+		return id
+	}
+	newChild := func(parent int64, status string) int64 {
+		id, _ := st.CreateTask(ctx, "t1", "child", "todo", nil)
+		if err := st.SetTaskParent(ctx, "t1", id, &parent); err != nil {
+			t.Fatalf("SetTaskParent: %v", err)
+		}
+		_ = st.UpdateTask(ctx, id, status, nil)
+		return id
+	}
+	stage := func(id int64) (string, string) {
+		task, _ := st.GetTaskByIDAndTeam(ctx, "t1", id)
+		return derefString(task.CurrentStage), task.Status
+	}
+
+	ok := newParent("")
+	newChild(ok, models.StatusDone)
+	newChild(ok, models.StatusCancelled)
+	open := newParent("")
+	newChild(open, models.StatusDone)
+	openChild := newChild(open, models.StatusInProgress)
+	failing := newParent("")
+	newChild(failing, models.StatusDone)
+	newChild(failing, models.StatusFailed)
+	ignoring := newParent(SubtaskPolicyIgnore)
+	newChild(ignoring, models.StatusFailed)
+	waiting := newParent(SubtaskPolicyWait)
+	waitChild := newChild(waiting, models.StatusFailed)
+
+	// The scheduler does not run a parent while a subtask is open.
+	for {
+		next, err := st.NextRunnableTaskForTeam(ctx, "t1")
+		if err != nil || next == nil {
+			break
+		}
+		if next.TaskID == open {
+			t.Fatal("parent with an open subtask was runnable")
+		}
+		_ = st.UpdateTask(ctx, next.TaskID, models.StatusInReview, nil)
+	}
+
+	var events int
+	m := &SubtaskMonitor{Store: st, Publish: func(any) { events++ }}
+	m.RunOnce(ctx)
+
+	if s, _ := stage(ok); s != "Review" {
+		t.Errorf("all subtasks finished: parent stage %q, want Review", s)
+	}
+	if s, _ := stage(open); s != "Epic" {
+		t.Errorf("open subtask: parent stage %q, want Epic", s)
+	}
+	if _, status := stage(failing); status != models.StatusFailed {
+		t.Errorf("policy fail: parent status %q, want failed", status)
+	}
+	if s, _ := stage(ignoring); s != "Review" {
+		t.Errorf("policy ignore: parent stage %q, want Review", s)
+	}
+	if s, _ := stage(waiting); s != "Epic" {
+		t.Errorf("policy wait: parent stage %q, want Epic", s)
+	}
+	if events != 3 {
+		t.Errorf("published %d events, want 3", events)
+	}
+	transitions2, _ := st.ListTaskTransitions(ctx, "t1", ok)
+	if len(transitions2) != 1 || transitions2[0].Actor != "subtasks" || transitions2[0].Outcome != RollupOutcome {
+		t.Errorf("rollup transition: %+v", transitions2)
+	}
+
+	// Rolled-up parents are not rolled up again; finishing the rest rolls up the others.
+	_ = st.UpdateTask(ctx, openChild, models.StatusDone, nil)
+	_ = st.UpdateTask(ctx, waitChild, models.StatusDone, nil)
+	m.RunOnce(ctx)
+	if s, _ := stage(ok); s != "Review" {
+		t.Errorf("second round moved a rolled-up parent to %q", s)
+	}
+	if s, _ := stage(open); s != "Review" {
+		t.Errorf("after the last subtask: parent stage %q, want Review", s)
+	}
+	if s, _ := stage(waiting); s != "Review" {
+		t.Errorf("policy wait after retry: parent stage %q, want Review", s)
+	}
+}
+
+func TestTaskTree(t *testing.T) {
+	t.Parallel()
+	p := func(id int64) *int64 { return &id }
+	tasks := []store.Task{
+		{TaskID: 4, ParentTaskID: p(2)},
+		{TaskID: 3, ParentTaskID: p(1)},
+		{TaskID: 2, ParentTaskID: p(1)},
+		{TaskID: 1},
+		{TaskID: 5, ParentTaskID: p(99)}, // parent not listed: a root
+	}
+	tree := TaskTree(tasks)
+	if len(tree) != 2 || tree[0].TaskID != 1 || tree[1].TaskID != 5 {
+		t.Fatalf("roots: %+v", tree)
+	}
+	kids := tree[0].Subtasks
+	if len(kids) != 2 || kids[0].TaskID != 3 || kids[1].TaskID != 2 || len(kids[1].Subtasks) != 1 || kids[1].Subtasks[0].TaskID != 4 {
+		t.Fatalf("children: %+v", kids)
+	}
+	if _, err := ParseSubtaskPolicy("explode"); err == nil {
+		t.Error("ParseSubtaskPolicy: expected error for unknown policy")
+	}
+}
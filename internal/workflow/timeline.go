@@ -0,0 +1,126 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ankittk/agentary/internal/store"
+)
+
+// Timeline entry kinds.
+const (
+	TimelineTransition = "transition"
+	TimelineComment    = "comment"
+	TimelineReview     = "review"
+	TimelineMessage    = "message"
+	TimelineTurn       = "turn"
+	TimelineApproval   = "approval"
+)
+
+// timelineMessageLimit caps how many recent team messages are scanned for references to the task.
+const timelineMessageLimit = 1000
+
+// TimelineEntry is one event in a task's history.
+type TimelineEntry struct {
+	At      time.Time `json:"at"`
+	Kind    string    `json:"kind"`
+	Actor   string    `json:"actor"`
+	Summary string    `json:"summary"`
+	Detail  any       `json:"detail,omitempty"`
+}
+
+// BuildTimeline merges a task's stage transitions, comments, reviews, agent turns, approval policy
+// decisions and the team messages that mention it (#N, TN or "task N") into one list, oldest first. Events at the same second keep the
+// order above.
+func BuildTimeline(ctx context.Context, st store.Store, teamName string, taskID int64) ([]TimelineEntry, error) {
+	var out []TimelineEntry
+	transitions, err := st.ListTaskTransitions(ctx, teamName, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transitions {
+		summary := fmt.Sprintf("%s → %s", stageLabel(t.FromStage), stageLabel(t.ToStage))
+		if t.Outcome != "" {
+			summary += fmt.Sprintf(" (%s)", t.Outcome)
+		}
+		detail := map[string]any{"from_stage": t.FromStage, "to_stage": t.ToStage, "outcome": t.Outcome, "note": t.Note}
+		if t.FromEnteredAt != nil {
+			detail["seconds_in_stage"] = int64(t.CreatedAt.Sub(*t.FromEnteredAt).Seconds())
+		}
+		out = append(out, TimelineEntry{At: t.CreatedAt, Kind: TimelineTransition, Actor: t.Actor, Summary: summary, Detail: detail})
+	}
+	comments, err := st.ListTaskComments(ctx, teamName, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range comments {
+		out = append(out, TimelineEntry{At: c.CreatedAt, Kind: TimelineComment, Actor: c.Author, Summary: c.Body})
+	}
+	reviews, err := st.ListTaskReviews(ctx, teamName, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(reviews) - 1; i >= 0; i-- { // newest first from the store
+		r := reviews[i]
+		out = append(out, TimelineEntry{At: r.CreatedAt, Kind: TimelineReview, Actor: r.ReviewerAgent, Summary: r.Outcome,
+			Detail: map[string]any{"review_id": r.ReviewID, "comments": r.Comments}})
+	}
+	turns, err := st.ListTaskTurns(ctx, teamName, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range turns {
+		summary := fmt.Sprintf("turn in %s", t.Stage)
+		switch {
+		case t.Error != "":
+			summary += ": error"
+		case t.Outcome != "":
+			summary += ": " + t.Outcome
+		}
+		out = append(out, TimelineEntry{At: t.StartedAt, Kind: TimelineTurn, Actor: t.Agent, Summary: summary,
+			Detail: map[string]any{"stage": t.Stage, "outcome": t.Outcome, "error": t.Error, "duration_seconds": int64(t.FinishedAt.Sub(t.StartedAt).Seconds())}})
+	}
+	decisions, err := st.ListTaskApprovalDecisions(ctx, teamName, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range decisions {
+		summary := fmt.Sprintf("approval policy %s in %s: ", d.Policy, d.Stage)
+		if d.Approved {
+			summary += "approved"
+		} else {
+			summary += "needs a human"
+		}
+		out = append(out, TimelineEntry{At: d.CreatedAt, Kind: TimelineApproval, Actor: ApprovalActor, Summary: summary,
+			Detail: map[string]any{"stage": d.Stage, "policy": d.Policy, "approved": d.Approved, "reason": d.Reason}})
+	}
+	messages, err := st.ListMessages(ctx, teamName, "", timelineMessageLimit)
+	if err != nil {
+		return nil, err
+	}
+	ref := taskRefPattern(taskID)
+	for i := len(messages) - 1; i >= 0; i-- { // newest first from the store
+		m := messages[i]
+		if !ref.MatchString(m.Content) {
+			continue
+		}
+		out = append(out, TimelineEntry{At: m.CreatedAt, Kind: TimelineMessage, Actor: m.Sender, Summary: m.Content,
+			Detail: map[string]any{"message_id": m.MessageID, "recipient": m.Recipient}})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out, nil
+}
+
+func taskRefPattern(taskID int64) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`(?i)(#|\bT|\btask\s+)%d\b`, taskID))
+}
+
+func stageLabel(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
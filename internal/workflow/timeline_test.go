@@ -0,0 +1,70 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	agentrt "github.com/ankittk/agentary/internal/agent/runtime"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+
+	_, _ = st.CreateTeam(ctx, "t1")
+	_ = st.CreateAgent(ctx, "t1", "a1", "role")
+	stages := []store.WorkflowStage{
+		{StageName: "start", StageType: "agent", Outcomes: "done"},
+		{StageName: "done", StageType: "terminal"},
+	}
+	transitions := []store.WorkflowTransition{{FromStage: "start", Outcome: "done", ToStage: "done"}}
+	wfID, err := st.CreateWorkflowWithStages(ctx, "t1", "wf", 1, "builtin:wf", stages, transitions)
+	if err != nil {
+		t.Fatalf("CreateWorkflowWithStages: %v", err)
+	}
+	taskID, _ := st.CreateTask(ctx, "t1", "agent task", "todo", &wfID)
+	agent := "a1"
+	_ = st.UpdateTask(ctx, taskID, "todo", &agent)
+	_, _ = st.CreateTaskComment(ctx, "t1", taskID, "alice", "looks fine")
+	_, _ = st.CreateMessage(ctx, "t1", "alice", "a1", "please pick up #1")
+	_, _ = st.CreateMessage(ctx, "t1", "alice", "a1", "unrelated: #10 and T11")
+	task, _ := st.GetTaskByIDAndTeam(ctx, "t1", taskID)
+
+	eng := &Engine{Store: st}
+	if _, err := eng.RunTurn(ctx, "t1", task, agentrt.StubRuntime{}, func(agentrt.Event) {}); err != nil {
+		t.Fatalf("RunTurn: %v", err)
+	}
+
+	timeline, err := BuildTimeline(ctx, st, "t1", taskID)
+	if err != nil {
+		t.Fatalf("BuildTimeline: %v", err)
+	}
+	kinds := map[string]int{}
+	for i, e := range timeline {
+		kinds[e.Kind]++
+		if i > 0 && e.At.Before(timeline[i-1].At) {
+			t.Fatalf("timeline not chronological: %+v", timeline)
+		}
+		if e.Kind == TimelineTransition && (e.Actor != "a1" || e.Summary != "start → done (done)") {
+			t.Fatalf("transition entry: %+v", e)
+		}
+	}
+	want := map[string]int{TimelineTransition: 1, TimelineComment: 1, TimelineMessage: 1, TimelineTurn: 1}
+	for k, n := range want {
+		if kinds[k] != n {
+			t.Fatalf("%s entries: got %d, want %d (%+v)", k, kinds[k], n, timeline)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+// Package worktrees finds task worktrees that are no longer needed and removes them, keeping each team
+// under its worktree disk quota.
+package worktrees
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/store"
+	"github.com/ankittk/agentary/pkg/models"
+)
+
+const (
+	// DefaultGrace is how long a worktree is kept after its task finished (or went missing).
+	DefaultGrace    = 24 * time.Hour
+	defaultInterval = 10 * time.Minute
+)
+
+// Worktree is one task worktree directory under a team's worktrees dir (<repo>-T<id>).
+type Worktree struct {
+	Path       string    `json:"path"`
+	Repo       string    `json:"repo"`
+	TaskID     int64     `json:"task_id"`
+	Bytes      int64     `json:"bytes"`
+	TaskStatus string    `json:"task_status"` // "" when the task no longer exists
+	IdleSince  time.Time `json:"idle_since"`  // when the task last changed, or the directory for a missing task
+	// Collectable is set when the task is done, failed, cancelled or missing: nothing will run in the
+	// worktree again.
+	Collectable bool `json:"collectable"`
+
+	task *store.Task
+}
+
+// Reason says why a collectable worktree can go.
+func (w Worktree) Reason() string {
+	if w.task == nil {
+		return "task missing"
+	}
+	return "task " + w.TaskStatus
+}
+
+// TeamUsage is the disk used by a team's task worktrees.
+type TeamUsage struct {
+	Team      string     `json:"team"`
+	Bytes     int64      `json:"bytes"`
+	Quota     int64      `json:"quota"` // 0 = no quota
+	Worktrees []Worktree `json:"worktrees"`
+}
+
+// Collectable returns the number of worktrees whose task is finished or missing.
+func (u TeamUsage) Collectable() int {
+	n := 0
+	for _, w := range u.Worktrees {
+		if w.Collectable {
+			n++
+		}
+	}
+	return n
+}
+
+// Dir returns the directory holding the team's task worktrees.
+func Dir(home, teamName string) string {
+	return filepath.Dir(git.WorktreePath(home, teamName, "x", 0))
+}
+
+// Scan measures the team's task worktrees and looks up their tasks. Directories not named like a task
+// worktree are left out.
+func Scan(ctx context.Context, st store.Store, home, teamName string) (TeamUsage, error) {
+	team, err := st.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return TeamUsage{}, err
+	}
+	usage := TeamUsage{Team: teamName, Quota: team.WorktreeQuota, Worktrees: []Worktree{}}
+	dir := Dir(home, teamName)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return usage, nil
+	}
+	if err != nil {
+		return usage, err
+	}
+	for _, e := range entries {
+		repo, taskID, ok := parseName(e.Name())
+		if !e.IsDir() || !ok {
+			continue
+		}
+		w := Worktree{Path: filepath.Join(dir, e.Name()), Repo: repo, TaskID: taskID}
+		if w.Bytes, err = dirSize(w.Path); err != nil {
+			return usage, err
+		}
+		task, err := st.GetTaskByIDAndTeam(ctx, teamName, taskID)
+		if err != nil {
+			return usage, err
+		}
+		switch {
+		case task != nil:
+			w.task, w.TaskStatus, w.IdleSince = task, task.Status, task.UpdatedAt
+			w.Collectable = task.Status == models.StatusDone || task.Status == models.StatusFailed || task.Status == models.StatusCancelled
+		default:
+			w.Collectable = true
+			if info, err := e.Info(); err == nil {
+				w.IdleSince = info.ModTime().UTC()
+			}
+		}
+		usage.Bytes += w.Bytes
+		usage.Worktrees = append(usage.Worktrees, w)
+	}
+	return usage, nil
+}
+
+// parseName splits a worktree directory name "<repo>-T<id>".
+func parseName(name string) (repo string, taskID int64, ok bool) {
+	i := strings.LastIndex(name, "-T")
+	if i <= 0 {
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(name[i+2:], 10, 64)
+	if err != nil || id <= 0 {
+		return "", 0, false
+	}
+	return name[:i], id, true
+}
+
+// dirSize sums the sizes of the regular files under dir.
+func dirSize(dir string) (int64, error) {
+	var n int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // removed while we walked
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				n += info.Size()
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Removal is a worktree the collector removed, or would remove in a dry run.
+type Removal struct {
+	Worktree
+	Reason string `json:"reason"`
+}
+
+// Report is the outcome of collecting one team.
+type Report struct {
+	Team    string    `json:"team"`
+	Removed []Removal `json:"removed"`
+	Freed   int64     `json:"freed"`
+	// Usage is what is left afterwards (what would be left, in a dry run).
+	Usage TeamUsage `json:"usage"`
+	// OverQuota is how far the team is still over its quota once every collectable worktree is gone;
+	// worktrees of running tasks are never removed.
+	OverQuota int64 `json:"over_quota"`
+}
+
+// Stats is what the collector last saw for a team, with running totals, for metrics.
+type Stats struct {
+	Team        string
+	Bytes       int64
+	Quota       int64
+	Active      int
+	Collectable int
+	OverQuota   int64
+	Removed     int64 // worktrees removed since start
+	Freed       int64 // bytes freed since start
+}
+
+// Collector removes worktrees whose task is finished or missing once they have been idle for Grace, and
+// earlier, oldest first, while the team is over its worktree quota. Branches stay in the repo mirror, so
+// a retried task gets its worktree back on the same branch.
+type Collector struct {
+	Store store.Store
+	Home  string
+	// Grace is how long a finished task's worktree is kept (default DefaultGrace).
+	Grace time.Duration
+	// Interval between collection rounds (default 10 minutes).
+	Interval time.Duration
+	// DryRun reports what would be removed without removing anything.
+	DryRun bool
+	// Publish, when set, receives worktree_gc and worktree_quota_exceeded events (e.g. SSEHub.PublishJSON).
+	Publish func(v any)
+	// Now overrides the clock (tests).
+	Now func() time.Time
+
+	mu    sync.Mutex
+	stats map[string]*Stats
+}
+
+// Run collects every team's worktrees until ctx is cancelled, starting right away.
+func (c *Collector) Run(ctx context.Context) {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := c.RunOnce(ctx); err != nil {
+			slog.Error("worktree gc failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce collects every team's worktrees. A team that fails is reported in the error; the others are
+// still collected.
+func (c *Collector) RunOnce(ctx context.Context) ([]Report, error) {
+	teams, err := c.Store.ListTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		reports []Report
+		errs    []error
+	)
+	for _, t := range teams {
+		r, err := c.CollectTeam(ctx, t.Name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("team %s: %w", t.Name, err))
+			continue
+		}
+		reports = append(reports, r)
+	}
+	return reports, errors.Join(errs...)
+}
+
+// CollectTeam collects one team's worktrees.
+func (c *Collector) CollectTeam(ctx context.Context, teamName string) (Report, error) {
+	usage, err := Scan(ctx, c.Store, c.Home, teamName)
+	if err != nil {
+		return Report{}, err
+	}
+	now := time.Now().UTC()
+	if c.Now != nil {
+		now = c.Now().UTC()
+	}
+	grace := c.Grace
+	if grace <= 0 {
+		grace = DefaultGrace
+	}
+	report := Report{Team: teamName, Removed: []Removal{}}
+	var (
+		kept []Worktree
+		errs []error
+	)
+	remove := func(w Worktree, reason string) bool {
+		if err := c.remove(ctx, teamName, w); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", w.Path, err))
+			return false
+		}
+		report.Removed = append(report.Removed, Removal{Worktree: w, Reason: reason})
+		report.Freed += w.Bytes
+		usage.Bytes -= w.Bytes
+		return true
+	}
+	for _, w := range usage.Worktrees {
+		if w.Collectable && now.Sub(w.IdleSince) >= grace && remove(w, w.Reason()) {
+			continue
+		}
+		kept = append(kept, w)
+	}
+	usage.Worktrees = kept
+	if usage.Quota > 0 && usage.Bytes > usage.Quota {
+		// Over quota: finished worktrees still in their grace period go too, oldest first.
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].IdleSince.Before(kept[j].IdleSince) })
+		usage.Worktrees = []Worktree{}
+		for _, w := range kept {
+			if w.Collectable && usage.Bytes > usage.Quota && remove(w, w.Reason()+", over quota") {
+				continue
+			}
+			usage.Worktrees = append(usage.Worktrees, w)
+		}
+		report.OverQuota = max(usage.Bytes-usage.Quota, 0)
+	}
+	if usage.Worktrees == nil {
+		usage.Worktrees = []Worktree{}
+	}
+	report.Usage = usage
+	if !c.DryRun {
+		c.record(report)
+		c.publish(report)
+	}
+	return report, errors.Join(errs...)
+}
+
+// remove deletes the worktree and forgets it on its task, keeping the branch for a retry.
+func (c *Collector) remove(ctx context.Context, teamName string, w Worktree) error {
+	if c.DryRun {
+		return nil
+	}
+	if err := git.DeleteWorktree(ctx, w.Path); err != nil {
+		return err
+	}
+	if t := w.task; t != nil && t.WorktreePath != nil && filepath.Clean(*t.WorktreePath) == filepath.Clean(w.Path) {
+		return c.Store.UpdateTaskGitFields(ctx, t.TaskID, nil, t.BranchName, t.BaseSHA, t.RepoName)
+	}
+	return nil
+}
+
+func (c *Collector) record(r Report) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stats == nil {
+		c.stats = make(map[string]*Stats)
+	}
+	s := c.stats[r.Team]
+	if s == nil {
+		s = &Stats{Team: r.Team}
+		c.stats[r.Team] = s
+	}
+	s.Bytes, s.Quota, s.OverQuota = r.Usage.Bytes, r.Usage.Quota, r.OverQuota
+	s.Collectable = r.Usage.Collectable()
+	s.Active = len(r.Usage.Worktrees) - s.Collectable
+	s.Removed += int64(len(r.Removed))
+	s.Freed += r.Freed
+}
+
+func (c *Collector) publish(r Report) {
+	if len(r.Removed) > 0 {
+		slog.Info("worktree gc", "team", r.Team, "removed", len(r.Removed), "freed_bytes", r.Freed)
+		if c.Publish != nil {
+			c.Publish(map[string]any{"type": "worktree_gc", "team": r.Team, "removed": len(r.Removed), "freed": r.Freed})
+		}
+	}
+	if r.OverQuota > 0 {
+		slog.Warn("worktree quota exceeded", "team", r.Team, "bytes", r.Usage.Bytes, "quota", r.Usage.Quota)
+		if c.Publish != nil {
+			c.Publish(map[string]any{"type": "worktree_quota_exceeded", "team": r.Team, "bytes": r.Usage.Bytes, "quota": r.Usage.Quota})
+		}
+	}
+}
+
+// Stats returns the latest figures for each team the collector has seen, sorted by team.
+func (c *Collector) Stats() []Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Stats, 0, len(c.stats))
+	for _, s := range c.stats {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Team < out[j].Team })
+	return out
+}
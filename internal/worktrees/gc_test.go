@@ -0,0 +1,128 @@
+package worktrees
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankittk/agentary/internal/git"
+	"github.com/ankittk/agentary/internal/store"
+)
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+	for in, want := range map[string]int64{"none": 0, "0": 0, "512": 512, "10GiB": 10 << 30, "500MB": 500e6, "1.5G": 3 << 29, "2k": 2048} {
+		if got, err := ParseSize(in); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "-1", "ten", "5XB"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q): expected error", in)
+		}
+	}
+	if got := FormatSize(1536); got != "1.5 KiB" {
+		t.Errorf("FormatSize(1536) = %q", got)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+	home := filepath.Join(t.TempDir(), "home")
+	st, err := store.Open(home)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ctx := context.Background()
+	_, _ = st.CreateTeam(ctx, "t1")
+
+	// One worktree per task state; each holds size bytes.
+	const size = 1000
+	worktree := func(taskID int64) string {
+		path := git.WorktreePath(home, "t1", "r1", taskID)
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "f"), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	task := func(status string) int64 {
+		id, _ := st.CreateTask(ctx, "t1", status, "todo", nil)
+		if status != "todo" {
+			_ = st.UpdateTask(ctx, id, status, nil)
+		}
+		path := worktree(id)
+		branch := fmt.Sprintf("agentary/T%d", id)
+		_ = st.UpdateTaskGitFields(ctx, id, &path, &branch, nil, nil)
+		return id
+	}
+	running, done, failed := task("in_progress"), task("done"), task("failed")
+	worktree(99) // task deleted long ago
+	_ = os.MkdirAll(filepath.Join(Dir(home, "t1"), "not-a-worktree"), 0o755)
+
+	usage, err := Scan(ctx, st, home, "t1")
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(usage.Worktrees) != 4 || usage.Bytes != 4*size || usage.Collectable() != 3 {
+		t.Fatalf("Scan = %+v", usage)
+	}
+
+	// Within the grace period only the missing task's worktree (idle since its mtime) could go, and it is
+	// still fresh: nothing is removed.
+	c := &Collector{Store: st, Home: home, Grace: time.Hour}
+	if r, err := c.CollectTeam(ctx, "t1"); err != nil || len(r.Removed) != 0 {
+		t.Fatalf("CollectTeam within grace = %+v, %v", r, err)
+	}
+
+	// Over quota: finished worktrees go early, running ones stay even though the team is still over.
+	var events []map[string]any
+	c.Publish = func(v any) { events = append(events, v.(map[string]any)) }
+	_ = st.SetTeamWorktreeQuota(ctx, "t1", size/2)
+	c.DryRun = true
+	r, err := c.CollectTeam(ctx, "t1")
+	if err != nil || len(r.Removed) != 3 || r.Freed != 3*size || r.OverQuota != size/2 {
+		t.Fatalf("dry run = %+v, %v", r, err)
+	}
+	if _, err := os.Stat(git.WorktreePath(home, "t1", "r1", done)); err != nil || len(events) != 0 || len(c.Stats()) != 1 {
+		t.Fatalf("dry run removed something or published: %v, %v", err, events)
+	}
+	c.DryRun = false
+	r, err = c.CollectTeam(ctx, "t1")
+	if err != nil || len(r.Removed) != 3 || !strings.HasSuffix(r.Removed[0].Reason, ", over quota") {
+		t.Fatalf("CollectTeam over quota = %+v, %v", r, err)
+	}
+	if len(r.Usage.Worktrees) != 1 || r.Usage.Worktrees[0].TaskID != running {
+		t.Fatalf("left = %+v, want only the running task's worktree", r.Usage.Worktrees)
+	}
+	if len(events) != 2 || events[0]["type"] != "worktree_gc" || events[1]["type"] != "worktree_quota_exceeded" {
+		t.Fatalf("events = %+v", events)
+	}
+	got, _ := st.GetTaskByIDAndTeam(ctx, "t1", failed)
+	if got.WorktreePath != nil || got.BranchName == nil {
+		t.Fatalf("failed task = %+v, want worktree cleared and branch kept", got)
+	}
+	stats := c.Stats()
+	if len(stats) != 1 || stats[0].Removed != 3 || stats[0].Freed != 3*size || stats[0].Active != 1 {
+		t.Fatalf("Stats = %+v", stats)
+	}
+
+	// Past the grace period a finished task's worktree goes without any quota.
+	_ = st.SetTeamWorktreeQuota(ctx, "t1", 0)
+	_ = st.UpdateTask(ctx, running, "cancelled", nil)
+	c.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	reports, err := c.RunOnce(ctx)
+	if err != nil || len(reports) != 1 || len(reports[0].Removed) != 1 || reports[0].Removed[0].Reason != "task cancelled" {
+		t.Fatalf("RunOnce = %+v, %v", reports, err)
+	}
+	if entries, _ := os.ReadDir(Dir(home, "t1")); len(entries) != 1 {
+		t.Fatalf("left %d entries, want only the unrelated directory", len(entries))
+	}
+}
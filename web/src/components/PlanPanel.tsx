@@ -0,0 +1,109 @@
+import { useEffect, useState } from "react";
+import type { Task, TaskPlan } from "@/lib/api";
+import { fetchTasks, fetchTaskPlan, approvePlan, rejectPlan } from "@/lib/api";
+import { Button } from "@/components/ui/button";
+import { Card, CardContent, CardHeader } from "@/components/ui/card";
+
+// PlanPanel lists tasks whose plan is waiting for approval. The plan can be edited before approving;
+// rejecting sends the feedback to the agent for a new revision.
+export function PlanPanel({ team, onUpdate }: Readonly<{ team: string; onUpdate?: () => void }>) {
+  const [items, setItems] = useState<{ task: Task; plan: TaskPlan }[]>([]);
+  const [drafts, setDrafts] = useState<Record<number, string>>({});
+  const [feedback, setFeedback] = useState<Record<number, string>>({});
+  const [actionLoading, setActionLoading] = useState<number | null>(null);
+
+  useEffect(() => {
+    let cancelled = false;
+    fetchTasks(team)
+      .then((list) => list.filter((t) => t.status === "todo" || t.status === "in_progress"))
+      .then((open) => Promise.all(open.map((t) => fetchTaskPlan(team, t.task_id).then((p) => ({ task: t, plan: p.plan })))))
+      .then((results) => {
+        if (cancelled) return;
+        const pending = results.filter((r): r is { task: Task; plan: TaskPlan } => r.plan?.Status === "pending");
+        setItems(pending);
+        setDrafts(Object.fromEntries(pending.map((r) => [r.task.task_id, r.plan.Body])));
+      })
+      .catch(() => {
+        if (!cancelled) setItems([]);
+      });
+    return () => { cancelled = true; };
+  }, [team]);
+
+  async function decide(item: { task: Task; plan: TaskPlan }, approve: boolean) {
+    const id = item.task.task_id;
+    setActionLoading(id);
+    try {
+      if (approve) {
+        const draft = drafts[id];
+        await approvePlan(team, id, draft !== item.plan.Body ? draft : undefined);
+      } else {
+        await rejectPlan(team, id, feedback[id] ?? "");
+      }
+      onUpdate?.();
+      setItems((prev) => prev.filter((i) => i.task.task_id !== id));
+    } catch (err) {
+      console.error(err);
+    } finally {
+      setActionLoading(null);
+    }
+  }
+
+  if (items.length === 0) return null;
+
+  return (
+    <div className="space-y-3">
+      <h2 className="font-semibold text-lg" id="plan-panel-heading">Plans awaiting approval</h2>
+      <ul className="space-y-3" aria-labelledby="plan-panel-heading">
+        {items.map((item) => (
+          <li key={item.task.task_id}>
+            <Card className="border border-[var(--border)]">
+              <CardHeader className="p-3 pb-0">
+                <div className="flex justify-between items-start gap-2">
+                  <span className="font-medium text-sm">{item.task.title}</span>
+                  <span className="text-xs text-[var(--muted)]">#{item.task.task_id}</span>
+                </div>
+                <p className="text-xs text-[var(--muted)] mt-1">
+                  Revision {item.plan.Revision} by @{item.plan.Author}
+                </p>
+              </CardHeader>
+              <CardContent className="p-3 pt-1 space-y-2">
+                <textarea
+                  className="w-full h-40 text-xs font-mono p-2 rounded border border-[var(--border)] bg-transparent"
+                  value={drafts[item.task.task_id] ?? ""}
+                  onChange={(e) => setDrafts((d) => ({ ...d, [item.task.task_id]: e.target.value }))}
+                  aria-label={`Plan for task ${item.task.task_id}`}
+                />
+                <input
+                  className="w-full text-xs p-2 rounded border border-[var(--border)] bg-transparent"
+                  placeholder="Feedback (for reject)"
+                  value={feedback[item.task.task_id] ?? ""}
+                  onChange={(e) => setFeedback((f) => ({ ...f, [item.task.task_id]: e.target.value }))}
+                  aria-label={`Feedback for task ${item.task.task_id}`}
+                />
+                <div className="flex flex-wrap gap-2">
+                  <Button
+                    size="sm"
+                    disabled={actionLoading === item.task.task_id}
+                    onClick={() => decide(item, true)}
+                    aria-label={`Approve plan for task ${item.task.task_id}`}
+                  >
+                    Approve plan
+                  </Button>
+                  <Button
+                    variant="outline"
+                    size="sm"
+                    disabled={actionLoading === item.task.task_id}
+                    onClick={() => decide(item, false)}
+                    aria-label={`Reject plan for task ${item.task.task_id}`}
+                  >
+                    Reject
+                  </Button>
+                </div>
+              </CardContent>
+            </Card>
+          </li>
+        ))}
+      </ul>
+    </div>
+  );
+}
@@ -4,6 +4,7 @@ import { fetchTasks, fetchTaskReviews, approveTask } from "@/lib/api";
 import { Button } from "@/components/ui/button";
 import { Card, CardContent, CardHeader } from "@/components/ui/card";
 import { DiffViewer } from "@/components/DiffViewer";
+import { PlanPanel } from "@/components/PlanPanel";
 
 export function ReviewPanel({ team, onUpdate }: Readonly<{ team: string; onUpdate?: () => void }>) {
   const [tasks, setTasks] = useState<Task[]>([]);
@@ -64,6 +65,7 @@ export function ReviewPanel({ team, onUpdate }: Readonly<{ team: string; onUpdat
 
   return (
     <div className="space-y-4 max-w-2xl">
+      <PlanPanel team={team} onUpdate={onUpdate} />
       <h2 className="font-semibold text-lg" id="review-panel-heading">Tasks in approval</h2>
       {tasks.length === 0 ? (
         <p className="text-[var(--muted)] text-sm" aria-live="polite">No tasks waiting for approval.</p>
@@ -286,3 +286,43 @@ export async function requestReview(team: string, taskId: number): Promise<{ ok:
   if (!r.ok) throw new Error("Failed to request review");
   return r.json();
 }
+
+// Task plans (plan stages)
+export interface TaskPlan {
+  PlanID: number;
+  TaskID: number;
+  Revision: number;
+  Author: string;
+  Body: string;
+  Status: string;
+  Feedback: string;
+  DecidedBy: string;
+  CreatedAt: string;
+}
+
+export async function fetchTaskPlan(team: string, taskId: number): Promise<{ plan: TaskPlan | null; revisions: TaskPlan[] }> {
+  const r = await fetch(`${base()}/teams/${encodeURIComponent(team)}/tasks/${taskId}/plan`);
+  if (!r.ok) throw new Error("Failed to fetch plan");
+  const data = await r.json();
+  return { plan: data.plan ?? null, revisions: data.revisions ?? [] };
+}
+
+export async function approvePlan(team: string, taskId: number, body?: string): Promise<{ ok: boolean; current_stage?: string }> {
+  const r = await fetch(`${base()}/teams/${encodeURIComponent(team)}/tasks/${taskId}/plan/approve`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(body ? { body } : {}),
+  });
+  if (!r.ok) throw new Error("Failed to approve plan");
+  return r.json();
+}
+
+export async function rejectPlan(team: string, taskId: number, feedback: string): Promise<{ ok: boolean; current_stage?: string }> {
+  const r = await fetch(`${base()}/teams/${encodeURIComponent(team)}/tasks/${taskId}/plan/reject`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ feedback }),
+  });
+  if (!r.ok) throw new Error("Failed to reject plan");
+  return r.json();
+}